@@ -0,0 +1,182 @@
+// binlog_inspector pages through the binlogs of a db or table, decodes them into records,
+// and prints them as text or dumps them as json lines for offline replay.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/binlog"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	u "github.com/selectdb/ccr_syncer/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	host       string
+	port       string
+	thriftPort string
+	user       string
+	password   string
+	db         string
+	table      string
+	tableId    int64
+
+	fromCommitSeq int64
+	toCommitSeq   int64
+	limit         int
+	types         string
+	tableIds      string
+	format        string
+	outFile       string
+	raw           bool
+)
+
+func init_flags() {
+	flag.StringVar(&host, "host", "localhost", "fe host")
+	flag.StringVar(&port, "port", "9030", "fe query port")
+	flag.StringVar(&thriftPort, "thrift_port", "9020", "fe rpc port")
+	flag.StringVar(&user, "user", "root", "user")
+	flag.StringVar(&password, "password", "", "password")
+	flag.StringVar(&db, "db", "ccr", "db")
+	flag.StringVar(&table, "table", "", "table, empty means the binlogs of db")
+	flag.Int64Var(&tableId, "table_id", 0, "table id, required by the FE which filters table binlogs by id")
+
+	flag.Int64Var(&fromCommitSeq, "from", 0, "dump the binlogs after this commit seq, exclusive")
+	flag.Int64Var(&toCommitSeq, "to", 0, "dump the binlogs until this commit seq, inclusive, 0 means the latest")
+	flag.IntVar(&limit, "limit", 0, "max binlogs to dump after filtering, 0 means no limit")
+	flag.StringVar(&types, "types", "", "comma separated binlog types to dump, e.g. UPSERT,ALTER_JOB, empty means all")
+	flag.StringVar(&tableIds, "table_ids", "", "comma separated table ids to dump, empty means all")
+	flag.StringVar(&format, "format", "text", "output format, text or json (json lines for offline replay)")
+	flag.StringVar(&outFile, "out", "", "output file, empty means stdout")
+	flag.BoolVar(&raw, "raw", false, "don't decode binlog data into records")
+
+	flag.Parse()
+}
+
+func init() {
+	init_flags()
+
+	// the binlogs are the output, only warnings are logged by default
+	logLevelSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "log_level" {
+			logLevelSet = true
+		}
+	})
+	if !logLevelSet {
+		u.SetLogOptions("warn", "", false)
+	}
+	u.InitLog()
+	log.SetOutput(os.Stderr)
+}
+
+func parseTableIds(s string) ([]int64, error) {
+	ids := make([]int64, 0)
+	for _, id := range strings.Split(s, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		tableId, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid table id %s", id)
+		}
+		ids = append(ids, tableId)
+	}
+	return ids, nil
+}
+
+func printText(w io.Writer, entry *binlog.Entry) {
+	fmt.Fprintf(w, "commit_seq: %d, time: %s, type: %s, db_id: %d, table_ids: %v\n",
+		entry.CommitSeq, time.UnixMilli(entry.Timestamp).Format("2006-01-02 15:04:05"), entry.Type, entry.DbId, entry.TableIds)
+	switch {
+	case entry.RecordError != "":
+		fmt.Fprintf(w, "  decode failed: %s\n  data: %s\n", entry.RecordError, entry.Data)
+	case entry.Record != nil:
+		fmt.Fprintf(w, "  %v\n", entry.Record)
+	default:
+		fmt.Fprintf(w, "  data: %s\n", entry.Data)
+	}
+}
+
+func inspect() error {
+	filterTypes, err := binlog.ParseTypes(types)
+	if err != nil {
+		return err
+	}
+	filterTableIds, err := parseTableIds(tableIds)
+	if err != nil {
+		return err
+	}
+	filter := &binlog.Filter{Types: filterTypes, TableIds: filterTableIds}
+
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %s, want text or json", format)
+	}
+
+	var out io.Writer = os.Stdout
+	if outFile != "" {
+		file, err := os.Create(outFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := binlog.NewWriter(out)
+
+	spec := &base.Spec{
+		Frontend: base.Frontend{
+			Host:       host,
+			Port:       port,
+			ThriftPort: thriftPort,
+		},
+		User:     user,
+		Password: password,
+		Database: db,
+		Table:    table,
+		TableId:  tableId,
+	}
+	feRpc, err := rpc.NewRpcFactory().NewFeRpc(spec)
+	if err != nil {
+		return err
+	}
+
+	scanned, dumped := 0, 0
+	err = binlog.NewScanner(feRpc, spec).Scan(fromCommitSeq, toCommitSeq, func(b *festruct.TBinlog) (bool, error) {
+		scanned++
+		if !filter.Match(b) {
+			return true, nil
+		}
+
+		entry := binlog.NewEntry(b, !raw)
+		if format == "json" {
+			if err := writer.Write(entry); err != nil {
+				return false, err
+			}
+		} else {
+			printText(out, entry)
+		}
+		dumped++
+		return limit == 0 || dumped < limit, nil
+	})
+	fmt.Fprintf(os.Stderr, "scanned %d binlogs, dumped %d\n", scanned, dumped)
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func main() {
+	if err := inspect(); err != nil {
+		log.Fatalf("inspect binlog failed: %+v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -10,15 +11,16 @@ import (
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
 	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/secret"
 	"github.com/selectdb/ccr_syncer/pkg/service"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	"github.com/selectdb/ccr_syncer/pkg/utils"
 	"github.com/selectdb/ccr_syncer/pkg/version"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xtrace"
 
-	"github.com/hashicorp/go-metrics"
-	"github.com/hashicorp/go-metrics/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -31,16 +33,33 @@ type Syncer struct {
 	Db_port     int
 	Db_user     string
 	Db_password string
+
+	History_retention time.Duration
+	History_max_rows  int
+
+	Auth_file string
+
+	Tls_cert_file      string
+	Tls_key_file       string
+	Tls_client_ca_file string
+
+	Trace_exporter     string
+	Trace_endpoint     string
+	Trace_insecure     bool
+	Trace_file         string
+	Trace_sample_ratio float64
 }
 
 var (
 	dbPath       string
 	syncer       Syncer
 	printVersion bool
+	configFile   string
 )
 
 func init() {
 	flag.BoolVar(&printVersion, "version", false, "The program's version")
+	flag.StringVar(&configFile, "config", "", "yaml config file, the explicitly set flags override it, reload it by SIGHUP")
 
 	flag.StringVar(&dbPath, "db_dir", "ccr.db", "sqlite3 db file")
 	flag.StringVar(&syncer.Db_type, "db_type", "sqlite3", "meta db type")
@@ -51,9 +70,18 @@ func init() {
 
 	flag.StringVar(&syncer.Host, "host", "127.0.0.1", "syncer host")
 	flag.IntVar(&syncer.Port, "port", 9190, "syncer port")
+	flag.DurationVar(&syncer.History_retention, "history_retention", 7*24*time.Hour, "retention of job progress histories and audit logs, 0 means no limit")
+	flag.IntVar(&syncer.History_max_rows, "history_max_rows", 10000, "max progress histories and audit logs kept per job, 0 means no limit")
+	flag.StringVar(&syncer.Auth_file, "auth_file", "", "json file of http api bearer tokens and mTLS client certs with roles, empty means auth disabled")
+	flag.StringVar(&syncer.Tls_cert_file, "tls_cert_file", "", "http server tls cert file, empty means serving plain http")
+	flag.StringVar(&syncer.Tls_key_file, "tls_key_file", "", "http server tls key file")
+	flag.StringVar(&syncer.Tls_client_ca_file, "tls_client_ca_file", "", "ca file to verify mTLS client certs")
+	flag.StringVar(&syncer.Trace_exporter, "trace_exporter", "none", "trace exporter: none, otlp, stdout or file")
+	flag.StringVar(&syncer.Trace_endpoint, "trace_endpoint", "127.0.0.1:4318", "otlp http endpoint of the trace collector")
+	flag.BoolVar(&syncer.Trace_insecure, "trace_insecure", false, "send traces to the collector by plain http")
+	flag.StringVar(&syncer.Trace_file, "trace_file", "", "trace file of file exporter")
+	flag.Float64Var(&syncer.Trace_sample_ratio, "trace_sample_ratio", 1, "ratio of traced binlogs, in [0, 1]")
 	flag.Parse()
-
-	utils.InitLog()
 }
 
 func main() {
@@ -62,20 +90,48 @@ func main() {
 		os.Exit(0)
 	}
 
+	// flags are merged into config, use config below
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Printf("load config failed: %+v\n", err)
+		os.Exit(1)
+	}
+	utils.SetLogOptions(config.Log.Level, config.Log.Filename, config.Log.AlsoToStderr)
+	utils.InitLog()
+
 	// print version
 	log.Infof("ccr start, version: %s", version.GetVersion())
 
+	// load secret keys before any job is read from or written to meta db
+	if err := secret.Init(); err != nil {
+		log.Fatalf("init secret error: %+v", err)
+	}
+
+	shutdownTracer, err := xtrace.Init(&xtrace.Config{
+		Exporter:    config.Trace.Exporter,
+		Endpoint:    config.Trace.Endpoint,
+		Insecure:    config.Trace.Insecure,
+		File:        config.Trace.File,
+		SampleRatio: config.Trace.SampleRatio,
+	}, version.GetVersion())
+	if err != nil {
+		log.Fatalf("init tracer error: %+v", err)
+	}
+
+	// settings need restart, they must be set before any job or checker start
+	rpc.SetTimeouts(config.Timeouts.RpcConnect, config.Timeouts.Rpc)
+	ccr.SetCheckDuration(config.Intervals.Check)
+
 	// Step 1: Check db
-	if dbPath == "" {
+	if config.Storage.Dir == "" {
 		log.Fatal("db_dir is empty")
 	}
 	var db storage.DB
-	var err error
-	switch syncer.Db_type {
+	switch config.Storage.Type {
 	case "sqlite3":
-		db, err = storage.NewSQLiteDB(dbPath)
+		db, err = storage.NewSQLiteDB(config.Storage.Dir)
 	case "mysql":
-		db, err = storage.NewMysqlDB(syncer.Db_host, syncer.Db_port, syncer.Db_user, syncer.Db_password)
+		db, err = storage.NewMysqlDB(config.Storage.Host, config.Storage.Port, config.Storage.User, config.Storage.Password)
 	default:
 		err = xerror.Wrap(err, xerror.Normal, "new meta db failed.")
 	}
@@ -84,13 +140,52 @@ func main() {
 	}
 
 	// Step 2: init factory
-	factory := ccr.NewFactory(rpc.NewRpcFactory(), ccr.NewMetaFactory(), base.NewSpecerFactory(), ccr.DefaultThriftMetaFactory)
+	rpcFactory, specerFactory := rpc.NewRpcFactory(), base.NewSpecerFactory()
+	var faultInjector *fault.Injector
+	if config.Fault.Enable {
+		log.Warn("fault injection is enabled, never enable it in production")
+		faultInjector = fault.NewInjector()
+		rpcFactory = fault.NewRpcFactory(rpcFactory, faultInjector)
+		specerFactory = fault.NewSpecerFactory(specerFactory, faultInjector)
+	}
+	factory := ccr.NewFactory(rpcFactory, ccr.NewMetaFactory(rpcFactory), specerFactory, ccr.DefaultThriftMetaFactory)
 
 	// Step 3: create job manager && http service && checker
-	hostInfo := fmt.Sprintf("%s:%d", syncer.Host, syncer.Port)
+	hostInfo := fmt.Sprintf("%s:%d", config.Http.Host, config.Http.Port)
 	jobManager := ccr.NewJobManager(db, factory, hostInfo)
-	httpService := service.NewHttpServer(syncer.Host, syncer.Port, db, jobManager)
+	httpService := service.NewHttpServer(config.Http.Host, config.Http.Port, db, jobManager)
+	if config.Http.AuthFile != "" {
+		authenticators, err := service.LoadAuthenticators(config.Http.AuthFile)
+		if err != nil {
+			log.Fatalf("load auth file error: %+v", err)
+		}
+		httpService.SetAuthenticators(authenticators)
+	} else {
+		log.Warn("http api auth is disabled, set -auth_file to enable it")
+	}
+	if config.Http.TlsCertFile != "" || config.Http.TlsKeyFile != "" {
+		if err := httpService.EnableTLS(&service.TLSConfig{
+			CertFile:     config.Http.TlsCertFile,
+			KeyFile:      config.Http.TlsKeyFile,
+			ClientCAFile: config.Http.TlsClientCaFile,
+		}); err != nil {
+			log.Fatalf("enable http tls error: %+v", err)
+		}
+	} else if config.Http.TlsClientCaFile != "" {
+		log.Fatal("tls_client_ca_file needs tls_cert_file and tls_key_file")
+	}
+	if faultInjector != nil {
+		httpService.SetFaultInjector(faultInjector)
+	}
 	checker := ccr.NewChecker(hostInfo, db, jobManager)
+	httpService.SetChecker(checker)
+	historyPruner := ccr.NewHistoryPruner(db, storage.HistoryRetention{
+		MaxAge:        config.History.Retention,
+		MaxRowsPerJob: config.History.MaxRows,
+	})
+	verifier := ccr.NewVerifier(jobManager)
+	reloader := newConfigReloader(config, httpService, historyPruner, verifier, faultInjector)
+	reloader.apply(config)
 
 	// Step 4: http service start
 	var wg sync.WaitGroup
@@ -118,15 +213,22 @@ func main() {
 		checker.Start()
 	}()
 
-	// Step 7: init metrics
-	sink, err := prometheus.NewPrometheusSink()
-	if err != nil {
-		log.Fatalf("new prometheus sink failed: %+v", err)
-	}
-	metrics.NewGlobal(metrics.DefaultConfig("ccr-metrics"), sink)
+	// Step 7: start history pruner
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		historyPruner.Start()
+	}()
+
+	// Step 8: start verifier
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		verifier.Start()
+	}()
 
-	// Step 8: start signal mux
-	// use closure to capture httpService, checker, jobManager
+	// Step 9: start signal mux
+	// use closure to capture httpService, checker, jobManager, historyPruner, verifier, reloader
 	signalHandler := func(signal os.Signal) bool {
 		switch signal {
 		case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
@@ -134,11 +236,21 @@ func main() {
 			// stop httpService first, denied new request
 			httpService.Stop()
 			checker.Stop()
+			historyPruner.Stop()
+			verifier.Stop()
 			jobManager.Stop()
+			// flush the pending spans
+			if err := shutdownTracer(context.Background()); err != nil {
+				log.Warnf("shutdown tracer failed: %+v", err)
+			}
 			log.Info("all service stop")
 			return true
 		case syscall.SIGHUP:
-			log.Infof("receive signal: %s", signal.String())
+			log.Infof("receive signal: %s, reload config and tls cert", signal.String())
+			reloader.reload()
+			if err := httpService.ReloadTLS(); err != nil {
+				log.Errorf("reload tls cert failed, keep the old one: %+v", err)
+			}
 			return false
 		default:
 			log.Infof("receive signal: %s", signal.String())
@@ -152,6 +264,6 @@ func main() {
 		signalMux.Serve()
 	}()
 
-	// Step 9: wait for all task done
+	// Step 10: wait for all task done
 	wg.Wait()
 }
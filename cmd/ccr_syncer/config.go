@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/config"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/service"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// flagSetters copies the flag value into config, the explicitly set flags override the config file
+var flagSetters = map[string]func(c *config.Config, value any){
+	"db_dir":             func(c *config.Config, value any) { c.Storage.Dir = value.(string) },
+	"db_type":            func(c *config.Config, value any) { c.Storage.Type = value.(string) },
+	"db_host":            func(c *config.Config, value any) { c.Storage.Host = value.(string) },
+	"db_port":            func(c *config.Config, value any) { c.Storage.Port = value.(int) },
+	"db_user":            func(c *config.Config, value any) { c.Storage.User = value.(string) },
+	"db_password":        func(c *config.Config, value any) { c.Storage.Password = value.(string) },
+	"host":               func(c *config.Config, value any) { c.Http.Host = value.(string) },
+	"port":               func(c *config.Config, value any) { c.Http.Port = value.(int) },
+	"auth_file":          func(c *config.Config, value any) { c.Http.AuthFile = value.(string) },
+	"tls_cert_file":      func(c *config.Config, value any) { c.Http.TlsCertFile = value.(string) },
+	"tls_key_file":       func(c *config.Config, value any) { c.Http.TlsKeyFile = value.(string) },
+	"tls_client_ca_file": func(c *config.Config, value any) { c.Http.TlsClientCaFile = value.(string) },
+	"log_level":          func(c *config.Config, value any) { c.Log.Level = value.(string) },
+	"log_filename":       func(c *config.Config, value any) { c.Log.Filename = value.(string) },
+	"log_also_to_stderr": func(c *config.Config, value any) { c.Log.AlsoToStderr = value.(bool) },
+	"history_retention":  func(c *config.Config, value any) { c.History.Retention = value.(time.Duration) },
+	"history_max_rows":   func(c *config.Config, value any) { c.History.MaxRows = value.(int) },
+	"trace_exporter":     func(c *config.Config, value any) { c.Trace.Exporter = value.(string) },
+	"trace_endpoint":     func(c *config.Config, value any) { c.Trace.Endpoint = value.(string) },
+	"trace_insecure":     func(c *config.Config, value any) { c.Trace.Insecure = value.(bool) },
+	"trace_file":         func(c *config.Config, value any) { c.Trace.File = value.(string) },
+	"trace_sample_ratio": func(c *config.Config, value any) { c.Trace.SampleRatio = value.(float64) },
+}
+
+// loadConfig loads the config file if set, and then overrides it by the explicitly set flags
+func loadConfig() (*config.Config, error) {
+	c := config.Default()
+	if configFile != "" {
+		var err error
+		if c, err = config.Load(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if setter, ok := flagSetters[f.Name]; ok {
+			setter(c, f.Value.(flag.Getter).Get())
+		}
+	})
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// configReloader applies the reloadable settings of config file on SIGHUP
+type configReloader struct {
+	current       *config.Config
+	httpService   *service.HttpService
+	historyPruner *ccr.HistoryPruner
+	verifier      *ccr.Verifier
+	// nil means fault injection is disabled
+	faultInjector *fault.Injector
+}
+
+func newConfigReloader(current *config.Config, httpService *service.HttpService, historyPruner *ccr.HistoryPruner, verifier *ccr.Verifier, faultInjector *fault.Injector) *configReloader {
+	return &configReloader{
+		current:       current,
+		httpService:   httpService,
+		historyPruner: historyPruner,
+		verifier:      verifier,
+		faultInjector: faultInjector,
+	}
+}
+
+// apply sets the reloadable settings of c to the running services
+func (r *configReloader) apply(c *config.Config) {
+	if err := utils.SetLogLevel(c.Log.Level); err != nil {
+		log.Errorf("set log level %s failed: %+v", c.Log.Level, err)
+	}
+	ccr.SetSyncDuration(c.Intervals.Sync)
+	ccr.SetMetaCacheTTL(c.Intervals.MetaCacheTTL)
+	base.SetCheckSettings(c.Intervals.BackupCheck, c.Intervals.RestoreCheck, c.Timeouts.MaxCheckRetryTimes)
+	// the policies are validated with the config
+	if err := rpc.SetCallPolicies(c.Timeouts.RpcMethods); err != nil {
+		log.Errorf("set rpc call policies failed: %+v", err)
+	}
+	rpc.SetBreaker(c.Timeouts.RpcBreaker.Failures, c.Timeouts.RpcBreaker.OpenTimeout)
+	r.historyPruner.Reload(storage.HistoryRetention{
+		MaxAge:        c.History.Retention,
+		MaxRowsPerJob: c.History.MaxRows,
+	}, c.Intervals.PruneHistory)
+	r.httpService.SetJobDefaults(c.JobDefaults.SkipError)
+	r.httpService.SetJobHealthThresholds(ccr.JobHealthThresholds{
+		MaxLag:        c.Health.MaxLag,
+		MaxNoProgress: c.Health.MaxNoProgress,
+	})
+	r.verifier.Reload(ccr.VerifyOptions{
+		Checksum: c.Verify.Checksum,
+		Resync:   c.Verify.Resync,
+	}, c.Verify.Interval)
+	if r.faultInjector != nil {
+		// the rules are validated with the config
+		if err := r.faultInjector.SetRules(c.Fault.Rules); err != nil {
+			log.Errorf("set fault rules failed: %+v", err)
+		}
+	}
+}
+
+// reload loads config file again, applies the reloadable changes and reports all changes,
+// the current config is kept if the file is invalid.
+func (r *configReloader) reload() {
+	if configFile == "" {
+		log.Info("no config file, skip reload config")
+		return
+	}
+
+	newConfig, err := loadConfig()
+	if err != nil {
+		log.Errorf("reload config failed, keep the current one: %+v", err)
+		return
+	}
+
+	changes := r.current.Diff(newConfig)
+	if len(changes) == 0 {
+		log.Infof("config file %s reloaded, nothing changed", configFile)
+		return
+	}
+	for _, change := range changes {
+		if change.Reloadable {
+			log.Infof("config changed, %s", &change)
+		} else {
+			log.Warnf("config changed but not applied until restart, %s", &change)
+		}
+	}
+
+	r.current = r.current.Reload(newConfig)
+	r.apply(r.current)
+}
@@ -0,0 +1,72 @@
+// ccrctl is the command line client of the syncer http api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// output format of all commands, table or json
+var output string
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: ccrctl [flags] <command> [args]\n\nCommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(os.Stderr, "  %-8s %-54s %s\n", cmd.name, cmd.args, cmd.usage)
+		}
+		fmt.Fprintf(os.Stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+}
+
+func main() {
+	var config clientConfig
+	fs := flag.NewFlagSet("ccrctl", flag.ExitOnError)
+	fs.StringVar(&config.addr, "addr", getEnv("CCRCTL_ADDR", "127.0.0.1:9190"), "syncer address, host:port or url, env CCRCTL_ADDR")
+	fs.StringVar(&config.token, "token", os.Getenv("CCRCTL_TOKEN"), "bearer token, env CCRCTL_TOKEN")
+	fs.StringVar(&config.tokenFile, "token_file", "", "file of bearer token, override -token")
+	fs.BoolVar(&config.tls, "tls", false, "use https")
+	fs.StringVar(&config.caFile, "ca_file", "", "CA to verify the syncer cert, implies -tls")
+	fs.StringVar(&config.certFile, "cert_file", "", "client cert for mTLS, implies -tls")
+	fs.StringVar(&config.keyFile, "key_file", "", "client key for mTLS")
+	fs.BoolVar(&config.insecureSkipVerify, "insecure_skip_verify", false, "skip verifying the syncer cert")
+	fs.DurationVar(&config.timeout, "timeout", 30*time.Second, "timeout of each request")
+	fs.StringVar(&output, "o", outputTable, "output format, table or json")
+	fs.Usage = usage(fs)
+	_ = fs.Parse(os.Args[1:])
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if output != outputTable && output != outputJson {
+		fmt.Fprintf(os.Stderr, "unknown output format %s, want table or json\n", output)
+		os.Exit(2)
+	}
+	cmd := findCommand(fs.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n", fs.Arg(0))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	client, err := newClient(&config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cmd.run(client, fs.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
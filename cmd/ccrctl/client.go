@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// a job is owned by one syncer, the others redirect to it, more hops means a loop
+const maxRedirects = 3
+
+type clientConfig struct {
+	addr               string
+	token              string
+	tokenFile          string
+	tls                bool
+	caFile             string
+	certFile           string
+	keyFile            string
+	insecureSkipVerify bool
+	timeout            time.Duration
+}
+
+// client calls the syncer http api, it follows the redirects to the syncer owns the job
+type client struct {
+	scheme string
+	addr   string
+	token  string
+	http   *http.Client
+}
+
+func newClient(config *clientConfig) (*client, error) {
+	scheme := "http"
+	addr := config.addr
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		scheme = u.Scheme
+		addr = u.Host
+	}
+	if config.tls || config.caFile != "" || config.certFile != "" {
+		scheme = "https"
+	}
+
+	token := config.token
+	if config.tokenFile != "" {
+		data, err := os.ReadFile(config.tokenFile)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "read token file %s failed", config.tokenFile)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if scheme == "https" {
+		tlsConfig, err := newTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &client{
+		scheme: scheme,
+		addr:   addr,
+		token:  token,
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   config.timeout,
+			// the redirect of syncer changes the host only, resend the body to the same path by ourselves
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+func newTLSConfig(config *clientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.insecureSkipVerify,
+	}
+
+	if config.caFile != "" {
+		data, err := os.ReadFile(config.caFile)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "read ca file %s failed", config.caFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, xerror.Errorf(xerror.Normal, "no certificate found in ca file %s", config.caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.certFile != "" || config.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.certFile, config.keyFile)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "load client cert %s failed", config.certFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// response is the raw http response, body is json except metrics
+type response struct {
+	statusCode int
+	body       []byte
+}
+
+// call sends request as json body to path, request == nil means no body.
+// Both the non 2xx status and the failed result of syncer are returned as error,
+// except the 503 of probes which is a valid result.
+func (c *client) call(path string, request any) (*response, error) {
+	var body []byte
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "marshal request of %s failed", path)
+		}
+		body = data
+	}
+
+	addr := c.addr
+	for i := 0; i <= maxRedirects; i++ {
+		resp, err := c.send(c.scheme, addr, path, body)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.statusCode >= 300 && resp.statusCode < 400:
+			location, err := url.Parse(resp.location)
+			if err != nil || location.Host == "" {
+				return nil, xerror.Errorf(xerror.Normal, "invalid redirect location %q from %s", resp.location, addr)
+			}
+			fmt.Fprintf(os.Stderr, "job is located in syncer %s, redirect to it\n", location.Host)
+			addr = location.Host
+			continue
+		case resp.statusCode == http.StatusServiceUnavailable && isProbe(path):
+			return &resp.response, nil
+		case resp.statusCode < 200 || resp.statusCode >= 300:
+			return nil, xerror.Errorf(xerror.Normal, "%s %s: %s", path, http.StatusText(resp.statusCode), strings.TrimSpace(string(resp.body)))
+		}
+
+		if err := checkResult(resp.body); err != nil {
+			return nil, err
+		}
+		return &resp.response, nil
+	}
+
+	return nil, xerror.Errorf(xerror.Normal, "too many redirects of %s, last syncer %s", path, addr)
+}
+
+type rawResponse struct {
+	response
+	location string
+}
+
+func (c *client) send(scheme, addr, path string, body []byte) (*rawResponse, error) {
+	method := http.MethodGet
+	var reader io.Reader
+	if body != nil {
+		method = http.MethodPost
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", scheme, addr, path), reader)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "new request of %s failed", path)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "call %s of syncer %s failed", path, addr)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read response of %s failed", path)
+	}
+	return &rawResponse{
+		response: response{statusCode: resp.StatusCode, body: data},
+		location: resp.Header.Get("Location"),
+	}, nil
+}
+
+func isProbe(path string) bool {
+	return path == "/healthz" || path == "/readyz"
+}
+
+// checkResult returns the error_msg of the failed result, responses without success field are ok
+func checkResult(body []byte) error {
+	var result struct {
+		Success  *bool  `json:"success"`
+		ErrorMsg string `json:"error_msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		// not json, e.g. metrics
+		return nil
+	}
+	if result.Success != nil && !*result.Success {
+		return xerror.Errorf(xerror.Normal, "%s", result.ErrorMsg)
+	}
+	return nil
+}
@@ -0,0 +1,592 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/service"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+type command struct {
+	name  string
+	args  string
+	usage string
+	run   func(c *client, args []string) error
+}
+
+var commands = []*command{
+	{name: "version", usage: "show the version of syncer", run: runVersion},
+	{name: "create", args: "-f <spec file> [-name <job>] [-skip_error=true|false]", usage: "create a job from the json spec file of create_ccr", run: runCreate},
+	{name: "list", usage: "list the jobs of the syncer", run: runList},
+	{name: "status", args: "<job>", usage: "show the state of a job", run: runStatus},
+	{name: "lag", args: "<job>", usage: "show the binlog lag of a job", run: runLag},
+	{name: "pause", args: "<job>", usage: "pause a job", run: jobAction("/pause")},
+	{name: "resume", args: "<job>", usage: "resume a paused job", run: jobAction("/resume")},
+	{name: "delete", args: "<job>", usage: "delete a job", run: jobAction("/delete")},
+	{name: "desync", args: "<job>", usage: "stop syncing of a job and make the dest tables writable", run: jobAction("/desync")},
+	{name: "update", args: "<job> [-skip_error=true|false] [-backup_timeout=2h] [-restore_timeout=2h]", usage: "update the settings of a job", run: runUpdate},
+	{name: "history", args: "<job> [-limit n]", usage: "show the progress history of a job, newest first", run: runHistory},
+	{name: "audit", args: "[<job>] [-limit n]", usage: "show the audit logs, empty job means all jobs", run: runAudit},
+	{name: "health", usage: "show the health of the jobs", run: runHealth},
+	{name: "verify", args: "<job> [-tables t1,t2] [-checksum expr] [-resync] [-last]", usage: "compare the partitions of src and dest, or show the last report", run: runVerify},
+	{name: "healthz", usage: "check the liveness of syncer", run: probe("/healthz")},
+	{name: "readyz", usage: "check the readiness of syncer", run: probe("/readyz")},
+	{name: "metrics", usage: "dump the prometheus metrics", run: runMetrics},
+	{name: "watch", args: "[<job>] [-interval 5s] [-count n]", usage: "poll the lag and status of a job, or the health of all jobs", run: runWatch},
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ExitOnError)
+}
+
+// parseJobFlags parses the flags of a job command, the job name is from -name or the first argument,
+// flags are allowed after the job name
+func parseJobFlags(fs *flag.FlagSet, args []string, required bool) (string, error) {
+	var name string
+	fs.StringVar(&name, "name", "", "job name")
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if name == "" && fs.NArg() > 0 {
+		name = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return "", err
+		}
+	}
+	if fs.NArg() > 0 {
+		return "", xerror.Errorf(xerror.Normal, "unexpected arguments %v", fs.Args())
+	}
+	if required && name == "" {
+		return "", xerror.Errorf(xerror.Normal, "job name is required")
+	}
+	return name, nil
+}
+
+func runVersion(c *client, args []string) error {
+	resp, err := c.call("/version", nil)
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(resp.body)
+	}
+
+	var result struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "parse version failed")
+	}
+	fmt.Fprintln(stdout, result.Version)
+	return nil
+}
+
+func runCreate(c *client, args []string) error {
+	fs := newFlagSet("create")
+	specFile := fs.String("f", "", "json file of the job spec, same as the body of create_ccr, - means stdin")
+	name := fs.String("name", "", "job name, override the name in spec file")
+	skipError := fs.String("skip_error", "", "true or false, override the skip_error in spec file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *specFile == "" {
+		return xerror.Errorf(xerror.Normal, "spec file is required")
+	}
+
+	var data []byte
+	var err error
+	if *specFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*specFile)
+	}
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "read spec file %s failed", *specFile)
+	}
+
+	// keep src and dest as they are, the spec is parsed by syncer
+	var request struct {
+		Name      string          `json:"name"`
+		Src       json.RawMessage `json:"src"`
+		Dest      json.RawMessage `json:"dest"`
+		SkipError *bool           `json:"skip_error,omitempty"`
+		// durations like "2h", checked by syncer
+		BackupTimeout  string `json:"backup_timeout,omitempty"`
+		RestoreTimeout string `json:"restore_timeout,omitempty"`
+		// checked by syncer too
+		RestoreOptions json.RawMessage `json:"restore_options,omitempty"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// a typo in spec file should fail instead of creating a wrong job
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "parse spec file %s failed", *specFile)
+	}
+	if *name != "" {
+		request.Name = *name
+	}
+	if *skipError != "" {
+		value, err := strconv.ParseBool(*skipError)
+		if err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "invalid skip_error %s", *skipError)
+		}
+		request.SkipError = &value
+	}
+	if request.Name == "" {
+		return xerror.Errorf(xerror.Normal, "job name is required")
+	}
+
+	resp, err := c.call("/create_ccr", &request)
+	if err != nil {
+		return err
+	}
+	return printDone(resp, "create", request.Name)
+}
+
+func runList(c *client, args []string) error {
+	resp, err := c.call("/list_jobs", nil)
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(resp.body)
+	}
+
+	var result struct {
+		Jobs []string `json:"jobs"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "parse jobs failed")
+	}
+	rows := make([][]string, 0, len(result.Jobs))
+	for _, job := range result.Jobs {
+		rows = append(rows, []string{job})
+	}
+	return printTable([]string{"NAME"}, rows)
+}
+
+func getStatus(c *client, name string) (*ccr.JobStatus, []byte, error) {
+	resp, err := c.call("/job_status", &service.CcrCommonRequest{Name: name})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		Status *ccr.JobStatus `json:"status"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil || result.Status == nil {
+		return nil, nil, xerror.Errorf(xerror.Normal, "parse status of job %s failed: %s", name, string(resp.body))
+	}
+	return result.Status, resp.body, nil
+}
+
+func runStatus(c *client, args []string) error {
+	name, err := parseJobFlags(newFlagSet("status"), args, true)
+	if err != nil {
+		return err
+	}
+
+	status, body, err := getStatus(c, name)
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(body)
+	}
+	fullSync := "-"
+	if wait := status.FullSync; wait != nil {
+		fullSync = fmt.Sprintf("%s %s %s, unfinished tasks: %d", wait.Phase, wait.Label, wait.State, wait.UnfinishedTasks)
+	}
+	return printTable([]string{"NAME", "STATE", "PROGRESS_STATE", "FULL_SYNC"},
+		[][]string{{status.Name, status.State, status.ProgressState, fullSync}})
+}
+
+func getLag(c *client, name string) (int64, []byte, error) {
+	resp, err := c.call("/get_lag", &service.CcrCommonRequest{Name: name})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var result struct {
+		Lag int64 `json:"lag"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return 0, nil, xerror.Wrapf(err, xerror.Normal, "parse lag of job %s failed", name)
+	}
+	return result.Lag, resp.body, nil
+}
+
+func runLag(c *client, args []string) error {
+	name, err := parseJobFlags(newFlagSet("lag"), args, true)
+	if err != nil {
+		return err
+	}
+
+	lag, body, err := getLag(c, name)
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(body)
+	}
+	return printTable([]string{"NAME", "LAG"}, [][]string{{name, strconv.FormatInt(lag, 10)}})
+}
+
+// jobAction returns the command which only sends the job name to path
+func jobAction(path string) func(c *client, args []string) error {
+	action := strings.TrimPrefix(path, "/")
+	return func(c *client, args []string) error {
+		name, err := parseJobFlags(newFlagSet(action), args, true)
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.call(path, &service.CcrCommonRequest{Name: name})
+		if err != nil {
+			return err
+		}
+		return printDone(resp, action, name)
+	}
+}
+
+func printDone(resp *response, action, name string) error {
+	if output == outputJson {
+		return printJson(resp.body)
+	}
+	fmt.Fprintf(stdout, "%s job %s done\n", action, name)
+	return nil
+}
+
+func runUpdate(c *client, args []string) error {
+	fs := newFlagSet("update")
+	skipError := fs.String("skip_error", "", "true or false, skip the binlog failed to sync")
+	backupTimeout := fs.String("backup_timeout", "", "max duration of waiting backup in full sync, 0s means the default of syncer")
+	restoreTimeout := fs.String("restore_timeout", "", "max duration of waiting restore in full sync, 0s means the default of syncer")
+	name, err := parseJobFlags(fs, args, true)
+	if err != nil {
+		return err
+	}
+	if *skipError == "" && *backupTimeout == "" && *restoreTimeout == "" {
+		return xerror.Errorf(xerror.Normal, "nothing to update, one of skip_error, backup_timeout and restore_timeout is required")
+	}
+
+	request := &service.UpdateJobRequest{Name: name, BackupTimeout: *backupTimeout, RestoreTimeout: *restoreTimeout}
+	if *skipError != "" {
+		value, err := strconv.ParseBool(*skipError)
+		if err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "invalid skip_error %s", *skipError)
+		}
+		request.SkipError = &value
+	}
+
+	resp, err := c.call("/update_job", request)
+	if err != nil {
+		return err
+	}
+	return printDone(resp, "update", name)
+}
+
+func runHistory(c *client, args []string) error {
+	fs := newFlagSet("history")
+	limit := fs.Int("limit", 0, "max rows to show, 0 means the default of syncer")
+	name, err := parseJobFlags(fs, args, true)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.call("/job_progress_history", &service.HistoryRequest{Name: name, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(resp.body)
+	}
+
+	var result struct {
+		Histories []*storage.ProgressHistory `json:"histories"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "parse progress histories failed")
+	}
+	rows := make([][]string, 0, len(result.Histories))
+	for _, history := range result.Histories {
+		rows = append(rows, []string{
+			formatMilli(history.CreatedAt),
+			history.SyncState,
+			history.SubSyncState,
+			strconv.FormatInt(history.PrevCommitSeq, 10),
+			strconv.FormatInt(history.CommitSeq, 10),
+			strconv.FormatInt(history.TxnId, 10),
+			history.ErrorMsg,
+		})
+	}
+	return printTable([]string{"TIME", "SYNC_STATE", "SUB_SYNC_STATE", "PREV_COMMIT_SEQ", "COMMIT_SEQ", "TXN_ID", "ERROR"}, rows)
+}
+
+func runAudit(c *client, args []string) error {
+	fs := newFlagSet("audit")
+	limit := fs.Int("limit", 0, "max rows to show, 0 means the default of syncer")
+	name, err := parseJobFlags(fs, args, false)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.call("/audit_logs", &service.HistoryRequest{Name: name, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(resp.body)
+	}
+
+	var result struct {
+		AuditLogs []*storage.AuditLog `json:"audit_logs"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "parse audit logs failed")
+	}
+	rows := make([][]string, 0, len(result.AuditLogs))
+	for _, auditLog := range result.AuditLogs {
+		rows = append(rows, []string{
+			formatMilli(auditLog.CreatedAt),
+			auditLog.JobName,
+			auditLog.Action,
+			auditLog.Caller,
+			auditLog.Detail,
+		})
+	}
+	return printTable([]string{"TIME", "JOB", "ACTION", "CALLER", "DETAIL"}, rows)
+}
+
+func runVerify(c *client, args []string) error {
+	fs := newFlagSet("verify")
+	tables := fs.String("tables", "", "comma separated src tables, empty means all tables of the job")
+	checksum := fs.String("checksum", "", "aggregate expression of the partition checksum, {columns} is all columns of the table")
+	resync := fs.Bool("resync", false, "full sync the mismatched tables again")
+	last := fs.Bool("last", false, "show the last report instead of verifying")
+	name, err := parseJobFlags(fs, args, true)
+	if err != nil {
+		return err
+	}
+
+	var resp *response
+	if *last {
+		resp, err = c.call("/verify_report", &service.CcrCommonRequest{Name: name})
+	} else {
+		request := &service.VerifyRequest{Name: name}
+		if *tables != "" {
+			request.Tables = strings.Split(*tables, ",")
+		}
+		request.Checksum = *checksum
+		request.Resync = *resync
+		resp, err = c.call("/verify", request)
+	}
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(resp.body)
+	}
+
+	var result struct {
+		Report *ccr.VerifyReport `json:"report"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil || result.Report == nil {
+		return xerror.Errorf(xerror.Normal, "parse verify report of job %s failed: %s", name, string(resp.body))
+	}
+	report := result.Report
+	fmt.Fprintf(stdout, "verified at %s in %dms, consistent: %s, mismatched partitions: %d, resynced: %s\n",
+		formatMilli(report.StartedAt), report.DurationMs, formatBool(report.Consistent), report.MismatchNum, formatBool(report.Resynced))
+
+	rows := make([][]string, 0)
+	for _, table := range report.Tables {
+		if table.Error != "" {
+			rows = append(rows, []string{table.Table, "", "error", "", "", "", table.Error})
+			continue
+		}
+		for _, partition := range table.Partitions {
+			rows = append(rows, []string{
+				table.Table,
+				partition.Name,
+				partition.State,
+				fmt.Sprintf("%d/%d", partition.SrcVersion, partition.DestVersion),
+				fmt.Sprintf("%d/%d", partition.SrcRows, partition.DestRows),
+				fmt.Sprintf("%s/%s", partition.SrcChecksum, partition.DestChecksum),
+				partition.Reason,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return printTable([]string{"TABLE", "PARTITION", "STATE", "VERSION(SRC/DEST)", "ROWS(SRC/DEST)", "CHECKSUM(SRC/DEST)", "REASON"}, rows)
+}
+
+func getHealth(c *client) ([]*ccr.JobHealth, []byte, error) {
+	resp, err := c.call("/job_health", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		Jobs []*ccr.JobHealth `json:"jobs"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return nil, nil, xerror.Wrap(err, xerror.Normal, "parse job health failed")
+	}
+	return result.Jobs, resp.body, nil
+}
+
+func printHealth(jobs []*ccr.JobHealth) error {
+	rows := make([][]string, 0, len(jobs))
+	for _, job := range jobs {
+		rows = append(rows, []string{
+			job.Name,
+			job.State,
+			job.SyncState,
+			strconv.FormatInt(job.Lag, 10),
+			strconv.FormatInt(job.SecondsSinceProgress, 10),
+			formatBool(job.Healthy),
+			strings.Join(job.Reasons, "; "),
+		})
+	}
+	return printTable([]string{"NAME", "STATE", "SYNC_STATE", "LAG", "SECONDS_SINCE_PROGRESS", "HEALTHY", "REASONS"}, rows)
+}
+
+func runHealth(c *client, args []string) error {
+	jobs, body, err := getHealth(c)
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		return printJson(body)
+	}
+	return printHealth(jobs)
+}
+
+// probe returns the command checks the probe, it fails if the probe is not ok
+func probe(path string) func(c *client, args []string) error {
+	return func(c *client, args []string) error {
+		resp, err := c.call(path, nil)
+		if err != nil {
+			return err
+		}
+		if err := printJson(resp.body); err != nil {
+			return err
+		}
+		if resp.statusCode != 200 {
+			return xerror.Errorf(xerror.Normal, "%s is not ok", strings.TrimPrefix(path, "/"))
+		}
+		return nil
+	}
+}
+
+func runMetrics(c *client, args []string) error {
+	resp, err := c.call("/metrics", nil)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(resp.body)
+	return err
+}
+
+func runWatch(c *client, args []string) error {
+	fs := newFlagSet("watch")
+	interval := fs.Duration("interval", 5*time.Second, "poll interval")
+	count := fs.Int("count", 0, "stop after polling count times, 0 means forever")
+	name, err := parseJobFlags(fs, args, false)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return xerror.Errorf(xerror.Normal, "interval must be positive")
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for i := 0; *count == 0 || i < *count; i++ {
+		if i > 0 {
+			<-ticker.C
+		}
+
+		// a failed poll is printed and retried, the syncer may be restarting
+		var err error
+		if name == "" {
+			err = watchHealth(c)
+		} else {
+			err = watchJob(c, name, i == 0)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s poll failed: %v\n", time.Now().Format("15:04:05"), err)
+		}
+	}
+	return nil
+}
+
+func watchJob(c *client, name string, first bool) error {
+	status, statusBody, err := getStatus(c, name)
+	if err != nil {
+		return err
+	}
+	lag, _, err := getLag(c, name)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJson {
+		var line struct {
+			Time   string          `json:"time"`
+			Lag    int64           `json:"lag"`
+			Status json.RawMessage `json:"status"`
+		}
+		var result struct {
+			Status json.RawMessage `json:"status"`
+		}
+		_ = json.Unmarshal(statusBody, &result)
+		line.Time = time.Now().Format(time.RFC3339)
+		line.Lag = lag
+		line.Status = result.Status
+		data, _ := json.Marshal(&line)
+		fmt.Fprintln(stdout, string(data))
+		return nil
+	}
+
+	if first {
+		fmt.Fprintf(stdout, "%-10s %-12s %-24s %s\n", "TIME", "STATE", "PROGRESS_STATE", "LAG")
+	}
+	fmt.Fprintf(stdout, "%-10s %-12s %-24s %d\n", time.Now().Format("15:04:05"), status.State, status.ProgressState, lag)
+	return nil
+}
+
+func watchHealth(c *client) error {
+	jobs, body, err := getHealth(c)
+	if err != nil {
+		return err
+	}
+	if output == outputJson {
+		fmt.Fprintln(stdout, strings.TrimSpace(string(body)))
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "--- %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	return printHealth(jobs)
+}
@@ -0,0 +1,118 @@
+// fake_doris starts a src and a dest fake Doris cluster in one process, so the ccr_syncer binary
+// can be exercised end-to-end in CI. Both clusters must live in the same process, the dest backends
+// download binlogs from the src backends in memory.
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/selectdb/ccr_syncer/pkg/fakedoris"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	host     string
+	backends int
+
+	srcQueryPort  int
+	srcRpcPort    int
+	srcBePort     int
+	srcHttpPort   int
+	destQueryPort int
+	destRpcPort   int
+	destBePort    int
+	destHttpPort  int
+
+	srcInit  string
+	destInit string
+)
+
+func init_flags() {
+	flag.StringVar(&host, "host", "127.0.0.1", "host of all listeners")
+	flag.IntVar(&backends, "backends", 3, "backends of each cluster")
+
+	flag.IntVar(&srcQueryPort, "src_query_port", 9030, "src fe query port")
+	flag.IntVar(&srcRpcPort, "src_rpc_port", 9020, "src fe rpc port")
+	flag.IntVar(&srcBePort, "src_be_port", 9060, "be port of the first src backend, the others follow")
+	flag.IntVar(&srcHttpPort, "src_http_port", 8040, "http port of the first src backend, the others follow")
+	flag.IntVar(&destQueryPort, "dest_query_port", 19030, "dest fe query port")
+	flag.IntVar(&destRpcPort, "dest_rpc_port", 19020, "dest fe rpc port")
+	flag.IntVar(&destBePort, "dest_be_port", 19060, "be port of the first dest backend, the others follow")
+	flag.IntVar(&destHttpPort, "dest_http_port", 18040, "http port of the first dest backend, the others follow")
+
+	flag.StringVar(&srcInit, "src_init", "", "sql file executed on the src cluster after start, statements end with ';'")
+	flag.StringVar(&destInit, "dest_init", "", "sql file executed on the dest cluster after start, statements end with ';'")
+
+	flag.Parse()
+}
+
+func initCluster(cluster *fakedoris.Cluster, sqlFile string) error {
+	if sqlFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(sqlFile)
+	if err != nil {
+		return err
+	}
+	for _, query := range strings.Split(string(data), ";") {
+		if strings.TrimSpace(query) == "" {
+			continue
+		}
+		if _, err := cluster.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startCluster(cfg fakedoris.Config, sqlFile string) *fakedoris.Cluster {
+	cluster, err := fakedoris.Start(cfg)
+	if err != nil {
+		log.Fatalf("start %s cluster failed: %+v", cfg.Name, err)
+	}
+	if err := initCluster(cluster, sqlFile); err != nil {
+		log.Fatalf("init %s cluster failed: %+v", cfg.Name, err)
+	}
+
+	host, queryPort, rpcPort := cluster.Addr()
+	log.Infof("%s cluster started, host: %s, query port: %d, rpc port: %d", cfg.Name, host, queryPort, rpcPort)
+	return cluster
+}
+
+func main() {
+	init_flags()
+
+	src := startCluster(fakedoris.Config{
+		Name:         "src",
+		Host:         host,
+		QueryPort:    srcQueryPort,
+		RpcPort:      srcRpcPort,
+		BePortBase:   srcBePort,
+		HttpPortBase: srcHttpPort,
+		Backends:     backends,
+		IdStart:      10000,
+	}, srcInit)
+	defer src.Close()
+
+	dest := startCluster(fakedoris.Config{
+		Name:         "dest",
+		Host:         host,
+		QueryPort:    destQueryPort,
+		RpcPort:      destRpcPort,
+		BePortBase:   destBePort,
+		HttpPortBase: destHttpPort,
+		Backends:     backends,
+		IdStart:      90000,
+	}, destInit)
+	defer dest.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	log.Infof("receive signal %s, exit", sig)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
 	"github.com/selectdb/ccr_syncer/pkg/utils"
 )
 
@@ -113,7 +114,7 @@ func main() {
 		Table:    tableName,
 	}
 
-	metaFactory := ccr.NewMetaFactory()
+	metaFactory := ccr.NewMetaFactory(rpc.NewRpcFactory())
 	meta := metaFactory.NewMeta(src)
 
 	test_init_meta(meta, src)
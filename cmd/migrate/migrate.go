@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// migrate is the offline tool for the syncer meta db schema
+//
+//	migrate [flags] status  print the applied and pending schema versions
+//	migrate [flags] up      apply all pending schema migrations
+var (
+	dbPath     string
+	dbType     string
+	dbHost     string
+	dbPort     int
+	dbUser     string
+	dbPassword string
+)
+
+func init() {
+	flag.StringVar(&dbPath, "db_dir", "ccr.db", "sqlite3 db file")
+	flag.StringVar(&dbType, "db_type", "sqlite3", "meta db type")
+	flag.StringVar(&dbHost, "db_host", "127.0.0.1", "meta db host")
+	flag.IntVar(&dbPort, "db_port", 3306, "meta db port")
+	flag.StringVar(&dbUser, "db_user", "root", "meta db user")
+	flag.StringVar(&dbPassword, "db_password", "", "meta db password")
+	flag.Parse()
+
+	utils.InitLog()
+}
+
+func newMigrator() (*storage.Migrator, error) {
+	switch dbType {
+	case "sqlite3":
+		return storage.NewSQLiteMigrator(dbPath)
+	case "mysql":
+		return storage.NewMysqlMigrator(dbHost, dbPort, dbUser, dbPassword)
+	default:
+		return nil, fmt.Errorf("unknown db_type: %s", dbType)
+	}
+}
+
+func status(migrator *storage.Migrator) error {
+	current, err := migrator.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("current schema version: %d, latest schema version: %d\n", current, storage.LatestSchemaVersion())
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	for _, migration := range pending {
+		fmt.Printf("pending: %d %s\n", migration.Version, migration.Description)
+	}
+	return nil
+}
+
+func main() {
+	action := flag.Arg(0)
+	if action == "" {
+		action = "status"
+	}
+
+	migrator, err := newMigrator()
+	if err != nil {
+		log.Fatalf("open meta db failed: %+v", err)
+	}
+	defer migrator.Close()
+
+	switch action {
+	case "status":
+		err = status(migrator)
+	case "up":
+		if err = migrator.Up(); err == nil {
+			err = status(migrator)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown action: %s, use status or up\n", action)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("migrate %s failed: %+v", action, err)
+	}
+}
@@ -103,7 +103,7 @@ func test_restore_snapshot(src *base.Spec, dest *base.Spec) {
 	if err != nil {
 		panic(err)
 	}
-	restoreResp, err := destRpc.RestoreSnapshot(dest, nil, labelName, snapshotResp)
+	restoreResp, err := destRpc.RestoreSnapshot(dest, nil, labelName, snapshotResp, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -129,7 +129,7 @@ func init() {
 type JobInfo map[string]interface{}
 
 func genExtraInfo(src *base.Spec, token string) *base.ExtraInfo {
-	metaFactory := ccr.NewMetaFactory()
+	metaFactory := ccr.NewMetaFactory(rpc.NewRpcFactory())
 	meta := metaFactory.NewMeta(src)
 	backends, err := meta.GetBackends()
 	if err != nil {
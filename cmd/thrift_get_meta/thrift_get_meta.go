@@ -134,7 +134,7 @@ func main() {
 		Table:    tableName,
 	}
 
-	metaFactory := ccr.NewMetaFactory()
+	metaFactory := ccr.NewMetaFactory(rpc.NewRpcFactory())
 	meta := metaFactory.NewMeta(src)
 
 	if tableName != "" {
@@ -0,0 +1,154 @@
+package base
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// frontendLock guards the current frontend and frontends of all specs, they are switched by failover while
+// the other goroutines are reading them, e.g. the status and verify of a job
+var frontendLock sync.RWMutex
+
+func (f *Frontend) addr() string {
+	return net.JoinHostPort(f.Host, f.Port)
+}
+
+// IsConnectionErr returns true if the frontend is unreachable, e.g. it is down or restarting
+func IsConnectionErr(err error) bool {
+	return errors.Is(err, mysql.ErrInvalidConn) || isNotSentErr(err)
+}
+
+// isNotSentErr returns true if the connection failed before the statement is sent, e.g. dial failed or the
+// pooled connection is closed. mysql.ErrInvalidConn is excluded, the statement may be executed by the frontend.
+func isNotSentErr(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	errMsg := err.Error()
+	return strings.Contains(errMsg, "connection refused") || strings.Contains(errMsg, "no route to host")
+}
+
+// isNotMasterErr returns true if a follower rejects the master-only statement, e.g. backup and restore
+func isNotMasterErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "not master")
+}
+
+// CurrentFrontend returns the frontend the sql and rpc are sent to
+func (s *Spec) CurrentFrontend() Frontend {
+	frontendLock.RLock()
+	defer frontendLock.RUnlock()
+
+	return s.Frontend
+}
+
+// KnownFrontends returns the copy of all frontends of the cluster
+func (s *Spec) KnownFrontends() []Frontend {
+	frontendLock.RLock()
+	defer frontendLock.RUnlock()
+
+	return append([]Frontend(nil), s.Frontends...)
+}
+
+// switchFrontend switches the current frontend to the first one not in tried, returns false if all are tried
+func (s *Spec) switchFrontend(tried map[string]bool, err error) bool {
+	frontendLock.Lock()
+	defer frontendLock.Unlock()
+
+	tried[s.Frontend.addr()] = true
+	next := s.nextFrontend(tried)
+	if next == nil {
+		return false
+	}
+	log.Warnf("frontend %s failed, switch to frontend %s, err: %v", s.Frontend.addr(), next.addr(), err)
+	s.Frontend = *next
+	return true
+}
+
+// nextFrontend returns the first frontend not tried yet, the master first, frontendLock must be held
+func (s *Spec) nextFrontend(tried map[string]bool) *Frontend {
+	for _, master := range []bool{true, false} {
+		for i := range s.Frontends {
+			frontend := &s.Frontends[i]
+			if frontend.IsMaster == master && !tried[frontend.addr()] {
+				return frontend
+			}
+		}
+	}
+	return nil
+}
+
+// WithFailover runs op on the current frontend, if the frontend is unreachable or it is not master,
+// switch to the next frontend and run op again, until op succeeds or all frontends are tried.
+// The frontend op succeeds on becomes the current one, the metas share the spec follow it too.
+func (s *Spec) WithFailover(op func() error) error {
+	return s.failover(op, IsConnectionErr)
+}
+
+// WithExecFailover is WithFailover for the op not idempotent, it only fails over if the statement is not
+// sent, so the statement is never executed twice
+func (s *Spec) WithExecFailover(op func() error) error {
+	return s.failover(op, isNotSentErr)
+}
+
+func (s *Spec) failover(op func() error, isConnectionErr func(error) bool) error {
+	tried := make(map[string]bool)
+	for {
+		err := op()
+		if err == nil || !(isConnectionErr(err) || isNotMasterErr(err)) {
+			return err
+		}
+		if !s.switchFrontend(tried, err) {
+			return err
+		}
+	}
+}
+
+// withFailover is WithFailover for the op returns a result
+func withFailover[T any](s *Spec, op func() (T, error)) (T, error) {
+	var result T
+	err := s.WithFailover(func() (err error) {
+		result, err = op()
+		return err
+	})
+	return result, err
+}
+
+// UpdateFrontends replaces the frontends by the latest ones of the cluster, returns true if they are changed
+func (s *Spec) UpdateFrontends(frontends []*Frontend) bool {
+	frontendLock.Lock()
+	defer frontendLock.Unlock()
+
+	changed := len(frontends) != len(s.Frontends)
+	updated := make([]Frontend, 0, len(frontends))
+	for i, frontend := range frontends {
+		if !changed && *frontend != s.Frontends[i] {
+			changed = true
+		}
+		updated = append(updated, *frontend)
+
+		// the current frontend may become master or not
+		if frontend.addr() == s.Frontend.addr() && s.Frontend.IsMaster != frontend.IsMaster {
+			s.Frontend.IsMaster = frontend.IsMaster
+			changed = true
+		}
+	}
+
+	if changed {
+		log.Infof("frontends changed, %+v => %+v", s.Frontends, updated)
+		s.Frontends = updated
+	}
+	return changed
+}
@@ -4,7 +4,7 @@ import (
 	"database/sql"
 	"sync"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 )
 
@@ -27,6 +27,18 @@ func init() {
 	}
 }
 
+// redactDsn hides the password in dsn for logs and errors
+func redactDsn(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "<invalid dsn>"
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = "******"
+	}
+	return cfg.FormatDSN()
+}
+
 func GetMysqlDB(dsn string) (*sql.DB, error) {
 	cachedSqlDbPool.mu.Lock()
 	defer cachedSqlDbPool.mu.Unlock()
@@ -36,7 +48,7 @@ func GetMysqlDB(dsn string) (*sql.DB, error) {
 	}
 
 	if db, err := sql.Open("mysql", dsn); err != nil {
-		return nil, xerror.Wrapf(err, xerror.DB, "connect to mysql failed, dsn: %s", dsn)
+		return nil, xerror.Wrapf(err, xerror.DB, "connect to mysql failed, dsn: %s", redactDsn(dsn))
 	} else {
 		db.SetMaxOpenConns(MaxOpenConns)
 		db.SetMaxIdleConns(MaxIdleConns)
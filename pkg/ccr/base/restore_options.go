@@ -0,0 +1,76 @@
+package base
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const (
+	StorageMediumHdd              = "hdd"
+	StorageMediumSsd              = "ssd"
+	StorageMediumSameWithUpstream = "same_with_upstream"
+)
+
+// replicationAllocationPattern matches the replica numbers of the resource tags, like "tag.location.default: 1, tag.location.g1: 2"
+var replicationAllocationPattern = regexp.MustCompile(`^tag\.location\.\w+\s*:\s*[1-9]\d*(\s*,\s*tag\.location\.\w+\s*:\s*[1-9]\d*)*$`)
+
+// RestoreOptions overrides the table properties of src on dest, e.g. a dest cluster with fewer backends
+// than src. They are used by the restore of full sync and the tables and partitions created by incremental sync.
+type RestoreOptions struct {
+	// like "tag.location.default: 1", empty keeps the replicas of src
+	ReplicationAllocation string `json:"replication_allocation,omitempty"`
+	// hdd, ssd or same_with_upstream, empty keeps the storage medium of src
+	StorageMedium string `json:"storage_medium,omitempty"`
+	// keeps the dynamic partition of src enabled on dest, otherwise the partitions are only created by sync
+	ReserveDynamicPartition bool `json:"reserve_dynamic_partition,omitempty"`
+	// keeps the colocate groups of src on dest, otherwise the tables are restored without colocate_with
+	ReserveColocate bool `json:"reserve_colocate,omitempty"`
+}
+
+func (o *RestoreOptions) Valid() error {
+	if o == nil {
+		return nil
+	}
+
+	// it is put into the sql of create table and add partition, so only the allocation format is accepted
+	if o.ReplicationAllocation != "" && !IsValidReplicationAllocation(o.ReplicationAllocation) {
+		return xerror.Errorf(xerror.Normal, "invalid replication_allocation %q, should be like \"tag.location.default: 1\"",
+			o.ReplicationAllocation)
+	}
+
+	switch o.StorageMedium {
+	case "", StorageMediumHdd, StorageMediumSsd, StorageMediumSameWithUpstream:
+	default:
+		return xerror.Errorf(xerror.Normal, "invalid storage_medium %q, should be one of %s, %s and %s",
+			o.StorageMedium, StorageMediumHdd, StorageMediumSsd, StorageMediumSameWithUpstream)
+	}
+	return nil
+}
+
+// IsValidReplicationAllocation returns true if the allocation is a list of "tag.location.<name>: <num>"
+func IsValidReplicationAllocation(allocation string) bool {
+	return replicationAllocationPattern.MatchString(allocation)
+}
+
+// Properties returns the properties of the restore, the replicas of src are reserved unless the
+// replication allocation is set. Nil options restore the tables as they are on src.
+func (o *RestoreOptions) Properties() map[string]string {
+	properties := make(map[string]string)
+	if o == nil || o.ReplicationAllocation == "" {
+		properties["reserve_replica"] = "true"
+	} else {
+		properties["replication_allocation"] = o.ReplicationAllocation
+	}
+	if o == nil {
+		return properties
+	}
+
+	if o.StorageMedium != "" {
+		properties["storage_medium"] = o.StorageMedium
+	}
+	properties["reserve_dynamic_partition_enable"] = strconv.FormatBool(o.ReserveDynamicPartition)
+	properties["reserve_colocate"] = strconv.FormatBool(o.ReserveColocate)
+	return properties
+}
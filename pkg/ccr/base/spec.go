@@ -2,12 +2,15 @@ package base
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/selectdb/ccr_syncer/pkg/secret"
 	"github.com/selectdb/ccr_syncer/pkg/utils"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 
@@ -20,6 +23,37 @@ const (
 	MAX_CHECK_RETRY_TIMES  = 86400 // 3 day
 )
 
+// check settings of backup/restore, the defaults are the consts above, they can be reloaded at runtime
+var (
+	backupCheckDuration  atomic.Int64
+	restoreCheckDuration atomic.Int64
+	maxCheckRetryTimes   atomic.Int64
+)
+
+func init() {
+	SetCheckSettings(BACKUP_CHECK_DURATION, RESTORE_CHECK_DURATION, MAX_CHECK_RETRY_TIMES)
+}
+
+// SetCheckSettings changes the backup/restore state check interval and max retry times,
+// the running checks use the new value in the next round.
+func SetCheckSettings(backupCheck, restoreCheck time.Duration, maxRetryTimes int) {
+	backupCheckDuration.Store(int64(backupCheck))
+	restoreCheckDuration.Store(int64(restoreCheck))
+	maxCheckRetryTimes.Store(int64(maxRetryTimes))
+}
+
+// CheckDurations returns the intervals to check the backup and restore state
+func CheckDurations() (backupCheck, restoreCheck time.Duration) {
+	return time.Duration(backupCheckDuration.Load()), time.Duration(restoreCheckDuration.Load())
+}
+
+// DefaultWaitTimeouts returns the timeouts of waiting backup and restore, for the jobs not set them,
+// the state is checked max retry times at most
+func DefaultWaitTimeouts() (backup, restore time.Duration) {
+	backupCheck, restoreCheck := CheckDurations()
+	return backupCheck * time.Duration(maxCheckRetryTimes.Load()), restoreCheck * time.Duration(maxCheckRetryTimes.Load())
+}
+
 type BackupState int
 
 const (
@@ -112,19 +146,75 @@ type Spec struct {
 
 	User     string `json:"user"`
 	Password string `json:"password"`
-	Cluster  string `json:"cluster"`
+	// name of the password in secrets file, the password is never persisted if it is set
+	PasswordSecret string `json:"password_secret,omitempty"`
+	Cluster        string `json:"cluster"`
 
 	Database string `json:"database"`
 	DbId     int64  `json:"db_id"`
 	Table    string `json:"table"`
 	TableId  int64  `json:"table_id"`
 
+	// name of the repository on this cluster for full sync, src and dest share it when the dest can't reach src backends,
+	// empty means the snapshot is kept on src backends and the dest downloads it from them
+	Repository string `json:"repository,omitempty"`
+
 	observers []utils.Observer[SpecEvent]
+	// persisted password is in plain text or encrypted by a rotated key
+	staleSecret bool
+}
+
+// specJson has the same fields as Spec, but without json methods
+type specJson Spec
+
+// MarshalJSON seals the password, so credentials are encrypted at rest
+func (s Spec) MarshalJSON() ([]byte, error) {
+	persisted := specJson(s)
+	if s.PasswordSecret != "" {
+		persisted.Password = ""
+	} else if sealed, err := secret.SealPassword(s.Password); err != nil {
+		return nil, err
+	} else {
+		persisted.Password = sealed
+	}
+	return json.Marshal(&persisted)
+}
+
+// UnmarshalJSON opens the sealed password or resolves it from secrets file
+func (s *Spec) UnmarshalJSON(data []byte) error {
+	var persisted specJson
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return err
+	}
+	*s = Spec(persisted)
+
+	if s.PasswordSecret != "" {
+		password, err := secret.LookupSecret(s.PasswordSecret)
+		if err != nil {
+			return err
+		}
+		s.Password = password
+		return nil
+	}
+
+	password, stale, err := secret.OpenPassword(s.Password)
+	if err != nil {
+		return err
+	}
+	s.Password = password
+	s.staleSecret = stale
+	return nil
+}
+
+// IsSecretStale returns true if the persisted password need to be sealed again
+func (s *Spec) IsSecretStale() bool {
+	return s.staleSecret
 }
 
 func (s *Spec) String() string {
+	frontend := s.CurrentFrontend()
 	return fmt.Sprintf("host: %s, port: %s, thrift_port: %s, user: %s, cluster: %s, database: %s, database id: %d, table: %s, table id: %d",
-		s.Host, s.Port, s.ThriftPort, s.User, s.Cluster, s.Database, s.DbId, s.Table, s.TableId)
+		frontend.Host, frontend.Port, frontend.ThriftPort, s.User, s.Cluster, s.Database, s.DbId, s.Table, s.TableId)
 }
 
 // valid table spec
@@ -168,12 +258,14 @@ func (s *Spec) connect(dsn string) (*sql.DB, error) {
 
 // create mysql connection from spec
 func (s *Spec) Connect() (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/", s.User, s.Password, s.Host, s.Port)
+	frontend := s.CurrentFrontend()
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/", s.User, s.Password, frontend.Host, frontend.Port)
 	return s.connect(dsn)
 }
 
 func (s *Spec) ConnectDB() (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", s.User, s.Password, s.Host, s.Port, s.Database)
+	frontend := s.CurrentFrontend()
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", s.User, s.Password, frontend.Host, frontend.Port, s.Database)
 	return s.connect(dsn)
 }
 
@@ -188,6 +280,10 @@ func (s *Spec) ConnectDB() (*sql.DB, error) {
 // ) |
 // +----------+----------------------------------------------------------------------------------------------+
 func (s *Spec) IsDatabaseEnableBinlog() (bool, error) {
+	return withFailover(s, s.isDatabaseEnableBinlog)
+}
+
+func (s *Spec) isDatabaseEnableBinlog() (bool, error) {
 	log.Infof("check database %s enable binlog", s.Database)
 
 	db, err := s.Connect()
@@ -226,6 +322,10 @@ func (s *Spec) IsDatabaseEnableBinlog() (bool, error) {
 }
 
 func (s *Spec) IsTableEnableBinlog() (bool, error) {
+	return withFailover(s, s.isTableEnableBinlog)
+}
+
+func (s *Spec) isTableEnableBinlog() (bool, error) {
 	log.Infof("check table %s.%s enable binlog", s.Database, s.Table)
 
 	db, err := s.Connect()
@@ -264,6 +364,10 @@ func (s *Spec) IsTableEnableBinlog() (bool, error) {
 }
 
 func (s *Spec) GetAllTables() ([]string, error) {
+	return withFailover(s, s.getAllTables)
+}
+
+func (s *Spec) getAllTables() ([]string, error) {
 	log.Debugf("get all tables in database %s", s.Database)
 
 	db, err := s.ConnectDB()
@@ -311,18 +415,12 @@ func (s *Spec) dropTable(table string) error {
 func (s *Spec) ClearDB() error {
 	log.Infof("clear database %s", s.Database)
 
-	db, err := s.Connect()
-	if err != nil {
-		return err
-	}
-
 	sql := fmt.Sprintf("DROP DATABASE %s", s.Database)
-	_, err = db.Exec(sql)
-	if err != nil {
+	if err := s.WithExecFailover(func() error { return s.exec(sql) }); err != nil {
 		return xerror.Wrapf(err, xerror.Normal, "drop database %s failed", s.Database)
 	}
 
-	if _, err = db.Exec("CREATE DATABASE " + s.Database); err != nil {
+	if err := s.WithExecFailover(func() error { return s.exec("CREATE DATABASE " + s.Database) }); err != nil {
 		return xerror.Wrapf(err, xerror.Normal, "create database %s failed", s.Database)
 	}
 	return nil
@@ -331,6 +429,10 @@ func (s *Spec) ClearDB() error {
 func (s *Spec) CreateDatabase() error {
 	log.Debug("create database")
 
+	return s.WithFailover(s.createDatabase)
+}
+
+func (s *Spec) createDatabase() error {
 	db, err := s.Connect()
 	if err != nil {
 		return nil
@@ -343,6 +445,10 @@ func (s *Spec) CreateDatabase() error {
 }
 
 func (s *Spec) CreateTable(stmt string) error {
+	return s.WithExecFailover(func() error { return s.createTable(stmt) })
+}
+
+func (s *Spec) createTable(stmt string) error {
 	db, err := s.Connect()
 	if err != nil {
 		return nil
@@ -355,6 +461,10 @@ func (s *Spec) CreateTable(stmt string) error {
 }
 
 func (s *Spec) CheckDatabaseExists() (bool, error) {
+	return withFailover(s, s.checkDatabaseExists)
+}
+
+func (s *Spec) checkDatabaseExists() (bool, error) {
 	log.Debugf("check database exist by spec: %s", s.String())
 	db, err := s.Connect()
 	if err != nil {
@@ -389,6 +499,10 @@ func (s *Spec) CheckDatabaseExists() (bool, error) {
 
 // check table exits in database dir by spec
 func (s *Spec) CheckTableExists() (bool, error) {
+	return withFailover(s, s.checkTableExists)
+}
+
+func (s *Spec) checkTableExists() (bool, error) {
 	log.Debugf("check table exist by spec: %s", s.String())
 
 	db, err := s.Connect()
@@ -421,8 +535,12 @@ func (s *Spec) CheckTableExists() (bool, error) {
 	return table != "", nil
 }
 
+// the snapshots kept on the backends of the cluster are in this repository
+const LocalRepository = "__keep_on_local__"
+
 // mysql> BACKUP SNAPSHOT ccr.snapshot_20230605 TO `__keep_on_local__` ON (      src_1 ) PROPERTIES ("type" = "full");
-func (s *Spec) CreateSnapshotAndWaitForDone(tables []string) (string, error) {
+// CreateSnapshot starts the backup and returns the snapshot name, the backup is checked by GetBackupProgress
+func (s *Spec) CreateSnapshot(tables []string) (string, error) {
 	if tables == nil {
 		tables = make([]string, 0)
 	}
@@ -446,90 +564,233 @@ func (s *Spec) CreateSnapshotAndWaitForDone(tables []string) (string, error) {
 
 	log.Infof("create snapshot %s.%s", s.Database, snapshotName)
 
+	repository := LocalRepository
+	if s.Repository != "" {
+		repository = s.Repository
+	}
+	backupSnapshotSql := fmt.Sprintf("BACKUP SNAPSHOT %s.%s TO `%s` ON ( %s ) PROPERTIES (\"type\" = \"full\")", s.Database, snapshotName, repository, tableRefs)
+	log.Debugf("backup snapshot sql: %s", backupSnapshotSql)
+	err := s.WithExecFailover(func() error {
+		db, err := s.Connect()
+		if err != nil {
+			return err
+		}
+
+		_, err = db.Exec(backupSnapshotSql)
+		return err
+	})
+	if err != nil {
+		return "", xerror.Wrapf(err, xerror.Normal, "backup snapshot %s failed, sql: %s", snapshotName, backupSnapshotSql)
+	}
+
+	return snapshotName, nil
+}
+
+var ErrBackupRestoreCancelled = xerror.NewWithoutStack(xerror.Normal, "backup or restore failed or canceled")
+var ErrBackupRestoreNotFound = xerror.NewWithoutStack(xerror.Normal, "backup or restore not found")
+
+// RepositorySnapshot is a snapshot backed up to the repository of spec
+type RepositorySnapshot struct {
+	Name      string
+	Timestamp string
+}
+
+// CheckRepositoryExists checks the repository of spec is created on the cluster
+func (s *Spec) CheckRepositoryExists() (bool, error) {
+	log.Debugf("check repository exist by spec: %s", s.String())
+
+	return withFailover(s, func() (bool, error) {
+		db, err := s.Connect()
+		if err != nil {
+			return false, err
+		}
+
+		rows, err := db.Query("SHOW REPOSITORIES")
+		if err != nil {
+			return false, xerror.Wrap(err, xerror.Normal, "show repositories failed")
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rowParser := utils.NewRowParser()
+			if err := rowParser.Parse(rows); err != nil {
+				return false, xerror.Wrap(err, xerror.Normal, "scan repository failed")
+			}
+			name, err := rowParser.GetString("RepoName")
+			if err != nil {
+				return false, xerror.Wrap(err, xerror.Normal, "scan repository failed")
+			}
+			if name == s.Repository {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// GetRepositorySnapshot gets the timestamp of a snapshot in the repository of spec, the details shown with
+// the timestamp are the brief job info without the table commit seqs, so they are not read
+func (s *Spec) GetRepositorySnapshot(snapshotName string) (*RepositorySnapshot, error) {
+	log.Debugf("get snapshot %s in repository %s", snapshotName, s.Repository)
+
+	return withFailover(s, func() (*RepositorySnapshot, error) { return s.getRepositorySnapshot(snapshotName) })
+}
+
+func (s *Spec) getRepositorySnapshot(snapshotName string) (*RepositorySnapshot, error) {
 	db, err := s.Connect()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	backupSnapshotSql := fmt.Sprintf("BACKUP SNAPSHOT %s.%s TO `__keep_on_local__` ON ( %s ) PROPERTIES (\"type\" = \"full\")", s.Database, snapshotName, tableRefs)
-	log.Debugf("backup snapshot sql: %s", backupSnapshotSql)
-	_, err = db.Exec(backupSnapshotSql)
+	// the repository may have several snapshots with the same name, the latest one is backed up by this job
+	query := fmt.Sprintf("SHOW SNAPSHOT ON `%s` WHERE SNAPSHOT = \"%s\"", s.Repository, snapshotName)
+	timestamp, err := queryRepositorySnapshot(db, query, "Timestamp")
 	if err != nil {
-		return "", xerror.Wrapf(err, xerror.Normal, "backup snapshot %s failed, sql: %s", snapshotName, backupSnapshotSql)
+		return nil, err
 	}
 
-	backupFinished, err := s.CheckBackupFinished(snapshotName)
+	return &RepositorySnapshot{
+		Name:      snapshotName,
+		Timestamp: timestamp,
+	}, nil
+}
+
+// queryRepositorySnapshot returns the column of the last snapshot with OK status
+func queryRepositorySnapshot(db *sql.DB, query string, column string) (string, error) {
+	rows, err := db.Query(query)
 	if err != nil {
-		return "", err
+		return "", xerror.Wrapf(err, xerror.Normal, "show snapshot failed, sql: %s", query)
+	}
+	defer rows.Close()
+
+	var value string
+	for rows.Next() {
+		rowParser := utils.NewRowParser()
+		if err := rowParser.Parse(rows); err != nil {
+			return "", xerror.Wrap(err, xerror.Normal, query)
+		}
+		if status, err := rowParser.GetString("Status"); err != nil {
+			return "", xerror.Wrap(err, xerror.Normal, query)
+		} else if status != "OK" {
+			continue
+		}
+		if value, err = rowParser.GetString(column); err != nil {
+			return "", xerror.Wrap(err, xerror.Normal, query)
+		}
 	}
-	if !backupFinished {
-		err = xerror.Errorf(xerror.Normal, "check backup state timeout, max try times: %d, sql: %s", MAX_CHECK_RETRY_TIMES, backupSnapshotSql)
-		return "", err
+	if value == "" {
+		return "", xerror.XWrapf(ErrBackupRestoreNotFound, "no snapshot found, sql: %s", query)
 	}
+	return value, nil
+}
 
-	return snapshotName, nil
+var ErrDropSnapshotUnsupported = xerror.NewWithoutStack(xerror.Normal, "drop snapshot is not supported by frontend")
+
+// DropSnapshot removes the local snapshot of a finished or cancelled backup and its data on backends,
+// the snapshot already removed is not an error.
+func (s *Spec) DropSnapshot(snapshotName string) error {
+	log.Infof("drop snapshot %s.%s", s.Database, snapshotName)
+
+	dropSnapshotSql := fmt.Sprintf("DROP SNAPSHOT `%s` FROM `%s`", snapshotName, s.Database)
+	err := s.Exec(dropSnapshotSql)
+	if err == nil {
+		return nil
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(errMsg, "not exist") || strings.Contains(errMsg, "not found"):
+		return nil
+	case strings.Contains(errMsg, "syntax error") || strings.Contains(errMsg, "not support"):
+		// the older frontends keep the local snapshots until they are expired
+		return xerror.XWrapf(ErrDropSnapshotUnsupported, "snapshot: %s, err: %v", snapshotName, err)
+	default:
+		return err
+	}
+}
+
+// BackupRestoreProgress is the state and tasks of a backup or restore job, from SHOW BACKUP/RESTORE
+type BackupRestoreProgress struct {
+	Label string `json:"label"`
+	State string `json:"state"`
+	// tasks not finished by the backends yet
+	UnfinishedTasks int `json:"unfinished_tasks"`
+	// finished/total of each uploading or downloading task
+	Progress string `json:"progress"`
+}
+
+func (p *BackupRestoreProgress) Finished() bool {
+	return p.State == "FINISHED"
+}
+
+// parseBackupRestoreProgress parses a row of SHOW BACKUP/RESTORE, UnfinishedTasks is like [taskId=backendId, ...]
+func parseBackupRestoreProgress(label string, rowParser *utils.RowParser) (*BackupRestoreProgress, error) {
+	state, err := rowParser.GetString("State")
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &BackupRestoreProgress{Label: label, State: state}
+	if unfinishedTasks, err := rowParser.GetString("UnfinishedTasks"); err == nil {
+		progress.UnfinishedTasks = strings.Count(unfinishedTasks, "=")
+	}
+	if tasks, err := rowParser.GetString("Progress"); err == nil {
+		progress.Progress = tasks
+	}
+	return progress, nil
 }
 
 // TODO: Add TaskErrMsg
-func (s *Spec) checkBackupFinished(snapshotName string) (BackupState, error) {
+func (s *Spec) getBackupProgress(snapshotName string) (*BackupRestoreProgress, error) {
 	log.Debugf("check backup state of snapshot %s", snapshotName)
 
 	db, err := s.Connect()
 	if err != nil {
-		return BackupStateUnknown, err
+		return nil, err
 	}
 
 	sql := fmt.Sprintf("SHOW BACKUP FROM %s WHERE SnapshotName = \"%s\"", s.Database, snapshotName)
 	log.Debugf("check backup state sql: %s", sql)
 	rows, err := db.Query(sql)
 	if err != nil {
-		return BackupStateUnknown, xerror.Wrapf(err, xerror.Normal, "show backup failed, sql: %s", sql)
+		return nil, xerror.Wrapf(err, xerror.Normal, "show backup failed, sql: %s", sql)
 	}
 	defer rows.Close()
 
-	var backupStateStr string
 	if rows.Next() {
 		rowParser := utils.NewRowParser()
 		if err := rowParser.Parse(rows); err != nil {
-			return BackupStateUnknown, xerror.Wrap(err, xerror.Normal, sql)
+			return nil, xerror.Wrap(err, xerror.Normal, sql)
 		}
-		backupStateStr, err = rowParser.GetString("State")
+		progress, err := parseBackupRestoreProgress(snapshotName, rowParser)
 		if err != nil {
-			return BackupStateUnknown, xerror.Wrap(err, xerror.Normal, sql)
+			return nil, xerror.Wrap(err, xerror.Normal, sql)
 		}
 
-		log.Infof("check snapshot %s backup state: [%v]", snapshotName, backupStateStr)
-		return ParseBackupState(backupStateStr), nil
+		log.Infof("check snapshot %s backup state: [%v], unfinished tasks: %d", snapshotName, progress.State, progress.UnfinishedTasks)
+		if ParseBackupState(progress.State) == BackupStateCancelled {
+			status, _ := rowParser.GetString("Status")
+			return nil, xerror.XWrapf(ErrBackupRestoreCancelled, "backup snapshot: %s, status: %s", snapshotName, status)
+		}
+		return progress, nil
 	}
-	return BackupStateUnknown, xerror.Errorf(xerror.Normal, "no backup state found, sql: %s", sql)
+	return nil, xerror.XWrapf(ErrBackupRestoreNotFound, "no backup state found, sql: %s", sql)
 }
 
-func (s *Spec) CheckBackupFinished(snapshotName string) (bool, error) {
+// GetBackupProgress checks the backup once, it returns error if the backup is cancelled
+func (s *Spec) GetBackupProgress(snapshotName string) (*BackupRestoreProgress, error) {
 	log.Debugf("check backup state, datebase: %s, snapshot: %s", s.Database, snapshotName)
 
-	for i := 0; i < MAX_CHECK_RETRY_TIMES; i++ {
-		if backupState, err := s.checkBackupFinished(snapshotName); err != nil {
-			return false, err
-		} else if backupState == BackupStateFinished {
-			return true, nil
-		} else if backupState == BackupStateCancelled {
-			return false, xerror.Errorf(xerror.Normal, "backup failed or canceled")
-		} else {
-			// BackupStatePending, BackupStateUnknown
-			time.Sleep(BACKUP_CHECK_DURATION)
-		}
-	}
-
-	return false, xerror.Errorf(xerror.Normal, "check backup state timeout, max try times: %d", MAX_CHECK_RETRY_TIMES)
+	return withFailover(s, func() (*BackupRestoreProgress, error) { return s.getBackupProgress(snapshotName) })
 }
 
 // TODO: Add TaskErrMsg
-func (s *Spec) checkRestoreFinished(snapshotName string) (RestoreState, error) {
+func (s *Spec) getRestoreProgress(snapshotName string) (*BackupRestoreProgress, error) {
 	log.Debugf("check restore state %s", snapshotName)
 
 	db, err := s.Connect()
 	if err != nil {
-		return RestoreStateUnknown, err
+		return nil, err
 	}
 
 	query := fmt.Sprintf("SHOW RESTORE FROM %s WHERE Label = \"%s\"", s.Database, snapshotName)
@@ -537,46 +798,36 @@ func (s *Spec) checkRestoreFinished(snapshotName string) (RestoreState, error) {
 	log.Debugf("check restore state sql: %s", query)
 	rows, err := db.Query(query)
 	if err != nil {
-		return RestoreStateUnknown, xerror.Wrap(err, xerror.Normal, "query restore state failed")
+		return nil, xerror.Wrap(err, xerror.Normal, "query restore state failed")
 	}
 	defer rows.Close()
 
-	var restoreStateStr string
 	if rows.Next() {
 		rowParser := utils.NewRowParser()
 		if err := rowParser.Parse(rows); err != nil {
-			return RestoreStateUnknown, xerror.Wrap(err, xerror.Normal, "scan restore state failed")
+			return nil, xerror.Wrap(err, xerror.Normal, "scan restore state failed")
 		}
-		restoreStateStr, err = rowParser.GetString("State")
+		progress, err := parseBackupRestoreProgress(snapshotName, rowParser)
 		if err != nil {
-			return RestoreStateUnknown, xerror.Wrap(err, xerror.Normal, "scan restore state failed")
+			return nil, xerror.Wrap(err, xerror.Normal, "scan restore state failed")
 		}
 
-		log.Infof("check snapshot %s restore state: [%v]", snapshotName, restoreStateStr)
-
-		return _parseRestoreState(restoreStateStr), nil
+		log.Infof("check snapshot %s restore state: [%v], unfinished tasks: %d", snapshotName, progress.State, progress.UnfinishedTasks)
+		if _parseRestoreState(progress.State) == RestoreStateCancelled {
+			// the status tells why it is cancelled, e.g. the master token of src is expired
+			status, _ := rowParser.GetString("Status")
+			return nil, xerror.XWrapf(ErrBackupRestoreCancelled, "restore spec: %s, snapshot: %s, status: %s", s.String(), snapshotName, status)
+		}
+		return progress, nil
 	}
-	return RestoreStateUnknown, xerror.Errorf(xerror.Normal, "no restore state found")
+	return nil, xerror.XWrapf(ErrBackupRestoreNotFound, "no restore state found, sql: %s", query)
 }
 
-func (s *Spec) CheckRestoreFinished(snapshotName string) (bool, error) {
-	log.Debugf("check restore state is finished, spec: %s, datebase: %s, snapshot: %s", s.String(), s.Database, snapshotName)
-
-	for i := 0; i < MAX_CHECK_RETRY_TIMES; i++ {
-		if backupState, err := s.checkRestoreFinished(snapshotName); err != nil {
-			return false, err
-		} else if backupState == RestoreStateFinished {
-			return true, nil
-		} else if backupState == RestoreStateCancelled {
-			return false, xerror.Errorf(xerror.Normal, "backup failed or canceled, spec: %s, snapshot: %s", s.String(), snapshotName)
-		} else {
-			// RestoreStatePending, RestoreStateUnknown
-			time.Sleep(RESTORE_CHECK_DURATION)
-		}
-	}
+// GetRestoreProgress checks the restore once, it returns error if the restore is cancelled
+func (s *Spec) GetRestoreProgress(snapshotName string) (*BackupRestoreProgress, error) {
+	log.Debugf("check restore state, spec: %s, datebase: %s, snapshot: %s", s.String(), s.Database, snapshotName)
 
-	log.Warnf("check restore state timeout, max try times: %d, spec: %s, snapshot: %s", MAX_CHECK_RETRY_TIMES, s, snapshotName)
-	return false, nil
+	return withFailover(s, func() (*BackupRestoreProgress, error) { return s.getRestoreProgress(snapshotName) })
 }
 
 func (s *Spec) waitTransactionDone(txnId int64) error {
@@ -623,7 +874,7 @@ func (s *Spec) waitTransactionDone(txnId int64) error {
 
 func (s *Spec) WaitTransactionDone(txnId int64) {
 	for {
-		if err := s.waitTransactionDone(txnId); err != nil {
+		if err := s.WithFailover(func() error { return s.waitTransactionDone(txnId) }); err != nil {
 			log.Errorf("wait transaction done failed, err +%v", err)
 			time.Sleep(time.Second)
 		} else {
@@ -634,6 +885,10 @@ func (s *Spec) WaitTransactionDone(txnId int64) {
 
 // Exec sql
 func (s *Spec) Exec(sql string) error {
+	return s.WithExecFailover(func() error { return s.exec(sql) })
+}
+
+func (s *Spec) exec(sql string) error {
 	db, err := s.Connect()
 	if err != nil {
 		return err
@@ -648,6 +903,10 @@ func (s *Spec) Exec(sql string) error {
 
 // Db Exec sql
 func (s *Spec) DbExec(sql string) error {
+	return s.WithExecFailover(func() error { return s.dbExec(sql) })
+}
+
+func (s *Spec) dbExec(sql string) error {
 	db, err := s.ConnectDB()
 	if err != nil {
 		return err
@@ -696,8 +955,14 @@ func (s *Spec) Notify(event SpecEvent) {
 func (s *Spec) Update(event SpecEvent) {
 	switch event {
 	case feNotMasterEvent:
+		frontendLock.Lock()
 		log.Infof("frontend %s:%s is not master, try next", s.Host, s.Port)
-		// TODO(Drogon): impl switch fe
+		if next := s.nextFrontend(map[string]bool{s.Frontend.addr(): true}); next != nil {
+			s.Frontend = *next
+		} else {
+			log.Warnf("no other frontend to switch, frontends: %+v", s.Frontends)
+		}
+		frontendLock.Unlock()
 	default:
 		break
 	}
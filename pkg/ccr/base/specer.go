@@ -26,8 +26,12 @@ type Specer interface {
 	CreateTable(stmt string) error
 	CheckDatabaseExists() (bool, error)
 	CheckTableExists() (bool, error)
-	CreateSnapshotAndWaitForDone(tables []string) (string, error)
-	CheckRestoreFinished(snapshotName string) (bool, error)
+	CreateSnapshot(tables []string) (string, error)
+	GetBackupProgress(snapshotName string) (*BackupRestoreProgress, error)
+	GetRestoreProgress(snapshotName string) (*BackupRestoreProgress, error)
+	DropSnapshot(snapshotName string) error
+	CheckRepositoryExists() (bool, error)
+	GetRepositorySnapshot(snapshotName string) (*RepositorySnapshot, error)
 	WaitTransactionDone(txnId int64) // busy wait
 
 	Exec(sql string) error
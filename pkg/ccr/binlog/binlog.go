@@ -0,0 +1,137 @@
+// Package binlog fetches, decodes and dumps the binlogs of upstream,
+// the dump is in json lines and can be loaded back as TBinlog for offline replay.
+package binlog
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// Entry is one binlog in the dump, all fields of TBinlog are kept,
+// Record is the decoded data for reading and is ignored when loading.
+type Entry struct {
+	CommitSeq         int64   `json:"commit_seq"`
+	Timestamp         int64   `json:"timestamp"`
+	Type              string  `json:"type"`
+	DbId              int64   `json:"db_id"`
+	TableIds          []int64 `json:"table_ids,omitempty"`
+	Data              string  `json:"data"`
+	Belong            *int64  `json:"belong,omitempty"`
+	TableRef          *int64  `json:"table_ref,omitempty"`
+	RemoveEnableCache *bool   `json:"remove_enable_cache,omitempty"`
+	Record            any     `json:"record,omitempty"`
+	// decode error of data, the binlog is still dumped
+	RecordError string `json:"record_error,omitempty"`
+}
+
+// NewEntry converts the binlog to entry, decode the data to record if decode is true
+func NewEntry(binlog *festruct.TBinlog, decode bool) *Entry {
+	entry := &Entry{
+		CommitSeq:         binlog.GetCommitSeq(),
+		Timestamp:         binlog.GetTimestamp(),
+		Type:              binlog.GetType().String(),
+		DbId:              binlog.GetDbId(),
+		TableIds:          binlog.GetTableIds(),
+		Data:              binlog.GetData(),
+		Belong:            binlog.Belong,
+		TableRef:          binlog.TableRef,
+		RemoveEnableCache: binlog.RemoveEnableCache,
+	}
+
+	if decode {
+		if rec, err := record.NewRecordFromBinlog(binlog.GetType(), binlog.GetData()); err != nil {
+			entry.RecordError = err.Error()
+		} else {
+			entry.Record = rec
+		}
+	}
+	return entry
+}
+
+// ToBinlog converts the entry back to the binlog returned by FE
+func (e *Entry) ToBinlog() (*festruct.TBinlog, error) {
+	binlogType, err := festruct.TBinlogTypeFromString(e.Type)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "invalid binlog type %s of commit seq %d", e.Type, e.CommitSeq)
+	}
+
+	commitSeq := e.CommitSeq
+	timestamp := e.Timestamp
+	dbId := e.DbId
+	data := e.Data
+	return &festruct.TBinlog{
+		CommitSeq:         &commitSeq,
+		Timestamp:         &timestamp,
+		Type:              &binlogType,
+		DbId:              &dbId,
+		TableIds:          e.TableIds,
+		Data:              &data,
+		Belong:            e.Belong,
+		TableRef:          e.TableRef,
+		RemoveEnableCache: e.RemoveEnableCache,
+	}, nil
+}
+
+// Filter selects binlogs by type and table id, the empty field matches all
+type Filter struct {
+	Types    []festruct.TBinlogType
+	TableIds []int64
+}
+
+// ParseTypes parses the comma separated binlog types, e.g. "UPSERT,ALTER_JOB"
+func ParseTypes(s string) ([]festruct.TBinlogType, error) {
+	types := make([]festruct.TBinlogType, 0)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		binlogType, err := festruct.TBinlogTypeFromString(name)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "unknown binlog type %s", name)
+		}
+		types = append(types, binlogType)
+	}
+	return types, nil
+}
+
+func (f *Filter) Match(binlog *festruct.TBinlog) bool {
+	if len(f.Types) > 0 {
+		matched := false
+		for _, binlogType := range f.Types {
+			if binlog.GetType() == binlogType {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.TableIds) > 0 {
+		for _, tableId := range binlog.GetTableIds() {
+			for _, filterTableId := range f.TableIds {
+				if tableId == filterTableId {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// MarshalEntry returns the entry as one json line without the trailing newline
+func MarshalEntry(entry *Entry) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "marshal binlog of commit seq %d failed", entry.CommitSeq)
+	}
+	return data, nil
+}
@@ -0,0 +1,123 @@
+package binlog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const upsertData = `{"commitSeq": 10, "txnId": 18019, "timeStamp": 1687676101779, "label": "insert_1", "dbId": 10116, ` +
+	`"tableRecords": {"21012": {"partitionRecords": [{"partitionId": 21011, "version": 9}]}}}`
+
+func newBinlog(commitSeq int64, binlogType festruct.TBinlogType, tableIds []int64, data string) *festruct.TBinlog {
+	dbId := int64(10116)
+	timestamp := int64(1687676101779)
+	return &festruct.TBinlog{
+		CommitSeq: &commitSeq,
+		Timestamp: &timestamp,
+		Type:      &binlogType,
+		DbId:      &dbId,
+		TableIds:  tableIds,
+		Data:      &data,
+	}
+}
+
+func TestEntryRoundTrip(t *testing.T) {
+	binlogs := []*festruct.TBinlog{
+		newBinlog(10, festruct.TBinlogType_UPSERT, []int64{21012}, upsertData),
+		newBinlog(11, festruct.TBinlogType_BARRIER, nil, "{}"),
+		newBinlog(12, festruct.TBinlogType_DROP_PARTITION, []int64{21012}, `{"tableId": 21012}`),
+	}
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	for _, binlog := range binlogs {
+		require.NoError(t, writer.Write(NewEntry(binlog, true)))
+	}
+	require.NoError(t, writer.Flush())
+
+	entries, err := ReadEntries(&buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "UPSERT", entries[0].Type)
+	assert.NotNil(t, entries[0].Record)
+	assert.Nil(t, entries[1].Record)
+	assert.Empty(t, entries[1].RecordError)
+	// drop partition without sql fails to decode, but the binlog is still dumped
+	assert.NotEmpty(t, entries[2].RecordError)
+
+	for i, entry := range entries {
+		binlog, err := entry.ToBinlog()
+		require.NoError(t, err)
+		assert.Equal(t, binlogs[i], binlog)
+	}
+
+	rec, err := record.NewRecordFromBinlog(festruct.TBinlogType_UPSERT, entries[0].Data)
+	require.NoError(t, err)
+	upsert := rec.(*record.Upsert)
+	assert.Equal(t, int64(18019), upsert.TxnID)
+	assert.Contains(t, upsert.TableRecords, int64(21012))
+}
+
+func TestFilter(t *testing.T) {
+	types, err := ParseTypes("upsert, ALTER_JOB")
+	require.NoError(t, err)
+	_, err = ParseTypes("UPSERT,NOT_A_TYPE")
+	assert.Error(t, err)
+
+	filter := &Filter{Types: types, TableIds: []int64{1}}
+	assert.True(t, filter.Match(newBinlog(1, festruct.TBinlogType_UPSERT, []int64{2, 1}, "")))
+	assert.False(t, filter.Match(newBinlog(1, festruct.TBinlogType_UPSERT, []int64{2}, "")))
+	assert.False(t, filter.Match(newBinlog(1, festruct.TBinlogType_BARRIER, []int64{1}, "")))
+	assert.True(t, (&Filter{}).Match(newBinlog(1, festruct.TBinlogType_BARRIER, nil, "")))
+}
+
+// pagedFeRpc returns at most pageSize binlogs after the prev commit seq
+type pagedFeRpc struct {
+	rpc.IFeRpc
+	binlogs  []*festruct.TBinlog
+	pageSize int
+	calls    int
+}
+
+func (r *pagedFeRpc) GetBinlog(spec *base.Spec, prevCommitSeq int64) (*festruct.TGetBinlogResult_, error) {
+	r.calls++
+	result := &festruct.TGetBinlogResult_{Status: &tstatus.TStatus{StatusCode: tstatus.TStatusCode_OK}}
+	for _, binlog := range r.binlogs {
+		if binlog.GetCommitSeq() > prevCommitSeq && len(result.Binlogs) < r.pageSize {
+			result.Binlogs = append(result.Binlogs, binlog)
+		}
+	}
+	if len(result.Binlogs) == 0 {
+		result.Status.StatusCode = tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ
+	}
+	return result, nil
+}
+
+func TestScan(t *testing.T) {
+	feRpc := &pagedFeRpc{pageSize: 2}
+	for seq := int64(1); seq <= 5; seq++ {
+		feRpc.binlogs = append(feRpc.binlogs, newBinlog(seq, festruct.TBinlogType_UPSERT, nil, upsertData))
+	}
+
+	scan := func(from, to int64, max int) []int64 {
+		seqs := make([]int64, 0)
+		err := NewScanner(feRpc, &base.Spec{}).Scan(from, to, func(binlog *festruct.TBinlog) (bool, error) {
+			seqs = append(seqs, binlog.GetCommitSeq())
+			return max == 0 || len(seqs) < max, nil
+		})
+		require.NoError(t, err)
+		return seqs
+	}
+
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, scan(0, 0, 0))
+	assert.Equal(t, []int64{3, 4}, scan(2, 4, 0))
+	assert.Equal(t, []int64{2, 3}, scan(1, 0, 2))
+}
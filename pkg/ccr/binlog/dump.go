@@ -0,0 +1,90 @@
+package binlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// the data of a binlog, e.g. create table, may be much larger than the default 64KB of bufio.Scanner
+const maxLineSize = 64 * 1024 * 1024
+
+// Writer writes entries as json lines
+type Writer struct {
+	w *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) Write(entry *Entry) error {
+	data, err := MarshalEntry(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "write binlog dump failed")
+	}
+	if err := w.w.WriteByte('\n'); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "write binlog dump failed")
+	}
+	return nil
+}
+
+func (w *Writer) Flush() error {
+	if err := w.w.Flush(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "flush binlog dump failed")
+	}
+	return nil
+}
+
+// ReadEntries reads all entries of the json lines, empty lines are skipped
+func ReadEntries(r io.Reader) ([]*Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	entries := make([]*Entry, 0)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "parse binlog dump line %d failed", line)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "read binlog dump failed")
+	}
+	return entries, nil
+}
+
+// LoadBinlogs loads the binlogs dumped in file, ordered as they are dumped
+func LoadBinlogs(path string) ([]*festruct.TBinlog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "open binlog dump %s failed", path)
+	}
+	defer file.Close()
+
+	entries, err := ReadEntries(file)
+	if err != nil {
+		return nil, err
+	}
+
+	binlogs := make([]*festruct.TBinlog, 0, len(entries))
+	for _, entry := range entries {
+		binlog, err := entry.ToBinlog()
+		if err != nil {
+			return nil, err
+		}
+		binlogs = append(binlogs, binlog)
+	}
+	return binlogs, nil
+}
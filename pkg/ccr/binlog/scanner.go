@@ -0,0 +1,68 @@
+package binlog
+
+import (
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Scanner pages through the binlogs of the db or table of spec by GetBinlog
+type Scanner struct {
+	rpc  rpc.IFeRpc
+	spec *base.Spec
+}
+
+func NewScanner(feRpc rpc.IFeRpc, spec *base.Spec) *Scanner {
+	return &Scanner{
+		rpc:  feRpc,
+		spec: spec,
+	}
+}
+
+// Scan calls fn for each binlog whose commit seq in (fromCommitSeq, toCommitSeq],
+// toCommitSeq <= 0 means to the latest binlog. It stops early if fn returns false or error.
+func (s *Scanner) Scan(fromCommitSeq, toCommitSeq int64, fn func(*festruct.TBinlog) (bool, error)) error {
+	prevCommitSeq := fromCommitSeq
+	for {
+		resp, err := s.rpc.GetBinlog(s.spec, prevCommitSeq)
+		if err != nil {
+			return err
+		}
+
+		status := resp.GetStatus()
+		switch status.GetStatusCode() {
+		case tstatus.TStatusCode_OK:
+		case tstatus.TStatusCode_BINLOG_TOO_OLD_COMMIT_SEQ:
+			// the binlogs before are gc, FE returns from the oldest one
+			log.Warnf("binlogs after commit seq %d are partially gc, start from the oldest one", prevCommitSeq)
+		case tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ:
+			return nil
+		case tstatus.TStatusCode_BINLOG_DISABLE:
+			return xerror.Errorf(xerror.Normal, "binlog is disabled")
+		case tstatus.TStatusCode_BINLOG_NOT_FOUND_DB:
+			return xerror.Errorf(xerror.Normal, "can't found db")
+		case tstatus.TStatusCode_BINLOG_NOT_FOUND_TABLE:
+			return xerror.Errorf(xerror.Normal, "can't found table")
+		default:
+			return xerror.Errorf(xerror.Normal, "invalid binlog status type: %v, msgs: %v", status.GetStatusCode(), status.GetErrorMsgs())
+		}
+
+		binlogs := resp.GetBinlogs()
+		if len(binlogs) == 0 {
+			return nil
+		}
+		for _, binlog := range binlogs {
+			if toCommitSeq > 0 && binlog.GetCommitSeq() > toCommitSeq {
+				return nil
+			}
+			if next, err := fn(binlog); err != nil || !next {
+				return err
+			}
+			prevCommitSeq = binlog.GetCommitSeq()
+		}
+	}
+}
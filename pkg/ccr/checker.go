@@ -2,6 +2,7 @@ package ccr
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/selectdb/ccr_syncer/pkg/storage"
@@ -14,6 +15,18 @@ const (
 	CHECK_TIMEOUT  = CHECK_DURATION*2 + time.Second*2
 )
 
+var (
+	checkDuration = CHECK_DURATION
+	checkTimeout  = CHECK_TIMEOUT
+)
+
+// SetCheckDuration changes the checker interval and the dead syncer timeout derived from it,
+// it must be called before checker start, and all syncers in a cluster should use the same value.
+func SetCheckDuration(duration time.Duration) {
+	checkDuration = duration
+	checkTimeout = duration*2 + time.Second*2
+}
+
 type CheckerState int
 
 const (
@@ -45,6 +58,15 @@ func (cs CheckerState) String() string {
 	}
 }
 
+// CheckerHealth is the state of checker loop, for liveness and readiness probes
+type CheckerHealth struct {
+	// the last time the loop started a check
+	LastTick time.Time
+	// the last time a check succeeded, zero if never
+	LastSuccess time.Time
+	LastError   string
+}
+
 type Checker struct {
 	lastStamp   int64
 	hostInfo    string
@@ -55,6 +77,9 @@ type Checker struct {
 	deadSyncers []string
 	err         error
 	stop        chan struct{}
+
+	healthLock sync.RWMutex
+	health     CheckerHealth
 }
 
 func NewChecker(hostInfo string, db storage.DB, jm *JobManager) *Checker {
@@ -64,6 +89,33 @@ func NewChecker(hostInfo string, db storage.DB, jm *JobManager) *Checker {
 		db:         db,
 		jobManager: jm,
 		stop:       make(chan struct{}),
+		// the loop is considered ticking during startup
+		health: CheckerHealth{LastTick: time.Now()},
+	}
+}
+
+// CheckDuration returns the interval of checker loop
+func CheckDuration() time.Duration {
+	return checkDuration
+}
+
+func (c *Checker) Health() CheckerHealth {
+	c.healthLock.RLock()
+	defer c.healthLock.RUnlock()
+
+	return c.health
+}
+
+func (c *Checker) updateHealth(start time.Time, err error) {
+	c.healthLock.Lock()
+	defer c.healthLock.Unlock()
+
+	c.health.LastTick = start
+	if err != nil {
+		c.health.LastError = err.Error()
+	} else {
+		c.health.LastSuccess = time.Now()
+		c.health.LastError = ""
 	}
 }
 
@@ -113,14 +165,15 @@ func (c *Checker) handleRefresh() {
 func (c *Checker) handleUpdate() {
 	var jobs []string
 	c.lastStamp, jobs, c.err = c.db.GetStampAndJobs(c.hostInfo)
-	if len(jobs) != 0 {
+	if c.err == nil {
+		// recover even no jobs, so job manager knows the recovery is done
 		c.err = c.jobManager.Recover(jobs)
 	}
 	log.Infof("update jobs %v", jobs)
 }
 
 func (c *Checker) handleCheck() {
-	c.deadSyncers, c.err = c.db.GetDeadSyncers(c.lastStamp - int64(CHECK_TIMEOUT.Nanoseconds()))
+	c.deadSyncers, c.err = c.db.GetDeadSyncers(c.lastStamp - int64(checkTimeout.Nanoseconds()))
 }
 
 func (c *Checker) handleRebalance() {
@@ -129,6 +182,13 @@ func (c *Checker) handleRebalance() {
 }
 
 func (c *Checker) check() error {
+	start := time.Now()
+	err := c.doCheck()
+	c.updateHealth(start, err)
+	return err
+}
+
+func (c *Checker) doCheck() error {
 	c.reset()
 
 	for {
@@ -178,7 +238,7 @@ func (c *Checker) Stop() {
 }
 
 func (c *Checker) run() error {
-	ticker := time.NewTicker(CHECK_DURATION)
+	ticker := time.NewTicker(checkDuration)
 	defer ticker.Stop()
 
 	for {
@@ -160,6 +160,21 @@ func (mr *MockIFeRpcMockRecorder) GetMasterToken(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMasterToken", reflect.TypeOf((*MockIFeRpc)(nil).GetMasterToken), arg0)
 }
 
+// GetPartitionMeta mocks base method.
+func (m *MockIFeRpc) GetPartitionMeta(arg0 *base.Spec, arg1 int64, arg2 []int64) (*frontendservice.TGetMetaResult_, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPartitionMeta", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*frontendservice.TGetMetaResult_)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPartitionMeta indicates an expected call of GetPartitionMeta.
+func (mr *MockIFeRpcMockRecorder) GetPartitionMeta(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPartitionMeta", reflect.TypeOf((*MockIFeRpc)(nil).GetPartitionMeta), arg0, arg1, arg2)
+}
+
 // GetSnapshot mocks base method.
 func (m *MockIFeRpc) GetSnapshot(arg0 *base.Spec, arg1 string) (*frontendservice.TGetSnapshotResult_, error) {
 	m.ctrl.T.Helper()
@@ -190,19 +205,49 @@ func (mr *MockIFeRpcMockRecorder) GetTableMeta(spec, tableIds any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTableMeta", reflect.TypeOf((*MockIFeRpc)(nil).GetTableMeta), spec, tableIds)
 }
 
+// GetTableNames mocks base method.
+func (m *MockIFeRpc) GetTableNames(arg0 *base.Spec) (*frontendservice.TGetMetaResult_, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTableNames", arg0)
+	ret0, _ := ret[0].(*frontendservice.TGetMetaResult_)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTableNames indicates an expected call of GetTableNames.
+func (mr *MockIFeRpcMockRecorder) GetTableNames(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTableNames", reflect.TypeOf((*MockIFeRpc)(nil).GetTableNames), arg0)
+}
+
 // RestoreSnapshot mocks base method.
-func (m *MockIFeRpc) RestoreSnapshot(arg0 *base.Spec, arg1 []*frontendservice.TTableRef, arg2 string, arg3 *frontendservice.TGetSnapshotResult_) (*frontendservice.TRestoreSnapshotResult_, error) {
+func (m *MockIFeRpc) RestoreSnapshot(arg0 *base.Spec, arg1 []*frontendservice.TTableRef, arg2 string, arg3 *frontendservice.TGetSnapshotResult_, arg4 *base.RestoreOptions) (*frontendservice.TRestoreSnapshotResult_, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RestoreSnapshot", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "RestoreSnapshot", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(*frontendservice.TRestoreSnapshotResult_)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RestoreSnapshot indicates an expected call of RestoreSnapshot.
-func (mr *MockIFeRpcMockRecorder) RestoreSnapshot(arg0, arg1, arg2, arg3 any) *gomock.Call {
+func (mr *MockIFeRpcMockRecorder) RestoreSnapshot(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSnapshot", reflect.TypeOf((*MockIFeRpc)(nil).RestoreSnapshot), arg0, arg1, arg2, arg3, arg4)
+}
+
+// RestoreSnapshotFromRepository mocks base method.
+func (m *MockIFeRpc) RestoreSnapshotFromRepository(arg0 *base.Spec, arg1 []*frontendservice.TTableRef, arg2, arg3 string, arg4 *base.RestoreOptions) (*frontendservice.TRestoreSnapshotResult_, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreSnapshotFromRepository", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*frontendservice.TRestoreSnapshotResult_)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreSnapshotFromRepository indicates an expected call of RestoreSnapshotFromRepository.
+func (mr *MockIFeRpcMockRecorder) RestoreSnapshotFromRepository(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSnapshot", reflect.TypeOf((*MockIFeRpc)(nil).RestoreSnapshot), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSnapshotFromRepository", reflect.TypeOf((*MockIFeRpc)(nil).RestoreSnapshotFromRepository), arg0, arg1, arg2, arg3, arg4)
 }
 
 // RollbackTransaction mocks base method.
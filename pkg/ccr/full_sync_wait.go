@@ -0,0 +1,106 @@
+package ccr
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	fullSyncPhaseBackup  = storage.SnapshotKindBackup
+	fullSyncPhaseRestore = storage.SnapshotKindRestore
+)
+
+var errFullSyncWaitTimeout = xerror.NewWithoutStack(xerror.Normal, "wait for full sync timeout")
+
+// isFullSyncWaitFailed returns true if the backup or restore will never finish, full sync has to begin again
+func isFullSyncWaitFailed(err error) bool {
+	return errors.Is(err, errFullSyncWaitTimeout) || errors.Is(err, base.ErrBackupRestoreCancelled)
+}
+
+// fullSyncWaitData is the persist data of WaitBackupDone and WaitRestoreDone
+type fullSyncWaitData struct {
+	// snapshot name of backup, or label of restore
+	Label     string    `json:"label"`
+	StartTime time.Time `json:"start_time"`
+}
+
+func parseFullSyncWaitData(persistData string) (*fullSyncWaitData, error) {
+	var data fullSyncWaitData
+	if err := json.Unmarshal([]byte(persistData), &data); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "unmarshal full sync wait data failed, persistData: %s", persistData)
+	}
+	return &data, nil
+}
+
+// FullSyncWait is the backup or restore the full sync is waiting for
+type FullSyncWait struct {
+	Phase string `json:"phase"`
+	*base.BackupRestoreProgress
+	StartTime time.Time `json:"start_time"`
+	Timeout   string    `json:"timeout"`
+}
+
+// waitTimeouts returns the timeouts of waiting backup and restore, the defaults of syncer if the job doesn't set them
+func (j *Job) waitTimeouts() (backup, restore time.Duration) {
+	backup, restore = base.DefaultWaitTimeouts()
+	if j.BackupTimeout > 0 {
+		backup = j.BackupTimeout
+	}
+	if j.RestoreTimeout > 0 {
+		restore = j.RestoreTimeout
+	}
+	return backup, restore
+}
+
+// checkFullSyncWait checks the backup or restore at most once per check interval, returns true if it is finished.
+// The job waits by checking it again in the next sync, so pause, stop and status are not blocked by the wait.
+func (j *Job) checkFullSyncWait(phase string, data *fullSyncWaitData, timeout, checkInterval time.Duration,
+	getProgress func(label string) (*base.BackupRestoreProgress, error)) (bool, error) {
+	if j.fullSyncWait != nil && j.fullSyncWait.Label == data.Label && time.Since(j.fullSyncWaitCheckedAt) < checkInterval {
+		return false, nil
+	}
+	j.fullSyncWaitCheckedAt = time.Now()
+
+	progress, err := getProgress(data.Label)
+	if err != nil {
+		j.fullSyncWait = nil
+		return false, err
+	}
+	if progress.Finished() {
+		j.fullSyncWait = nil
+		return true, nil
+	}
+
+	// the backends finishing tasks is a progress of the job, not stuck
+	if j.fullSyncWait == nil || j.fullSyncWait.UnfinishedTasks != progress.UnfinishedTasks || j.fullSyncWait.Progress != progress.Progress {
+		j.health.markProgress()
+	}
+	j.fullSyncWait = &FullSyncWait{
+		Phase:                 phase,
+		BackupRestoreProgress: progress,
+		StartTime:             data.StartTime,
+		Timeout:               timeout.String(),
+	}
+
+	if time.Since(data.StartTime) > timeout {
+		j.fullSyncWait = nil
+		j.cancelFullSyncWait(phase)
+		return false, xerror.XWrapf(errFullSyncWaitTimeout, "%s %s is not finished in %s, state: %s, unfinished tasks: %d",
+			phase, data.Label, timeout, progress.State, progress.UnfinishedTasks)
+	}
+	return false, nil
+}
+
+// cancelFullSyncWait cancels the backup or restore timed out, so the next full sync can begin a new one
+func (j *Job) cancelFullSyncWait(phase string) {
+	if err := j.cancelBackupRestore(phase); err != nil {
+		log.Warnf("cancel %s failed, job: %s, err: %+v", phase, j.Name, err)
+	}
+}
@@ -0,0 +1,83 @@
+package ccr
+
+import (
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PRUNE_HISTORY_DURATION = time.Minute * 10
+)
+
+// HistoryPruner removes progress histories and audit logs out of retention periodically
+type HistoryPruner struct {
+	db   storage.DB
+	stop chan struct{}
+
+	// retention and duration can be reloaded at runtime
+	lock      sync.Mutex
+	retention storage.HistoryRetention
+	duration  time.Duration
+}
+
+func NewHistoryPruner(db storage.DB, retention storage.HistoryRetention) *HistoryPruner {
+	return &HistoryPruner{
+		db:        db,
+		stop:      make(chan struct{}),
+		retention: retention,
+		duration:  PRUNE_HISTORY_DURATION,
+	}
+}
+
+// Reload changes the retention and prune interval, they take effect after the next prune
+func (p *HistoryPruner) Reload(retention storage.HistoryRetention, duration time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.retention = retention
+	p.duration = duration
+}
+
+func (p *HistoryPruner) settings() (storage.HistoryRetention, time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.retention, p.duration
+}
+
+func (p *HistoryPruner) prune() {
+	retention, _ := p.settings()
+	log.Debugf("prune histories, max age: %s, max rows per job: %d", retention.MaxAge, retention.MaxRowsPerJob)
+	if err := p.db.PruneHistories(&retention); err != nil {
+		log.Warnf("prune histories failed, err: %+v", err)
+	}
+}
+
+func (p *HistoryPruner) Start() {
+	_, duration := p.settings()
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+
+	p.prune()
+	for {
+		select {
+		case <-p.stop:
+			log.Info("history pruner stopped")
+			return
+		case <-ticker.C:
+			p.prune()
+			if _, newDuration := p.settings(); newDuration != duration {
+				duration = newDuration
+				ticker.Reset(duration)
+			}
+		}
+	}
+}
+
+func (p *HistoryPruner) Stop() {
+	log.Info("history pruner stopping")
+	close(p.stop)
+}
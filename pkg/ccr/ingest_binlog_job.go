@@ -4,12 +4,16 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/modern-go/gls"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
 	utils "github.com/selectdb/ccr_syncer/pkg/utils"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+	"github.com/selectdb/ccr_syncer/pkg/xtrace"
 
 	bestruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice"
 	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
@@ -115,13 +119,21 @@ func (h *tabletIngestBinlogHandler) handleReplica(srcReplica, destReplica *Repli
 
 		gls.ResetGls(gls.GoID(), map[interface{}]interface{}{})
 		gls.Set("job", j.ccrJob.Name)
+		fault.SetCommitSeq(j.ccrJob.progress.CommitSeq)
 		defer gls.ResetGls(gls.GoID(), map[interface{}]interface{}{})
+		xtrace.Attach(j.traceCtx)
 
+		span := xtrace.Start("IngestBinlogJob.ingestTablet", xtrace.TxnId(j.txnId), xtrace.TabletId(destTabletId))
+		var err error
+		defer func() { span.End(err) }()
+
+		ingestStart := time.Now()
 		resp, err := destRpc.IngestBinlog(req)
 		if err != nil {
 			j.setError(err)
 			return
 		}
+		xmetrics.ObserveRpc(j.ccrJob.Name, xmetrics.MethodIngestBinlog, ingestStart)
 
 		log.Debugf("ingest resp: %v", resp)
 		if !resp.IsSetStatus() {
@@ -133,6 +145,7 @@ func (h *tabletIngestBinlogHandler) handleReplica(srcReplica, destReplica *Repli
 			j.setError(err)
 			return
 		} else {
+			xmetrics.IngestTablet(j.ccrJob.Name)
 			h.appendCommitInfos(commitInfo)
 		}
 	}()
@@ -207,6 +220,9 @@ type IngestBinlogJob struct {
 	errLock sync.RWMutex
 
 	wg sync.WaitGroup
+
+	// span context of Run
+	traceCtx context.Context
 }
 
 func NewIngestBinlogJob(ctx context.Context, ccrJob *Job) (*IngestBinlogJob, error) {
@@ -517,50 +533,51 @@ func (j *IngestBinlogJob) runTabletIngestJobs() {
 
 func (j *IngestBinlogJob) prepareMeta() {
 	log.Debug("prepareMeta")
-	srcTableIds := make([]int64, 0, len(j.tableRecords))
+	span := xtrace.Start("IngestBinlogJob.prepareMeta", xtrace.TxnId(j.txnId))
+	defer func() { span.End(j.Error()) }()
 	job := j.ccrJob
-	factory := j.factory
-
-	switch job.SyncType {
-	case DBSync:
-		for _, tableRecord := range j.tableRecords {
-			srcTableIds = append(srcTableIds, tableRecord.Id)
-		}
-	case TableSync:
-		srcTableIds = append(srcTableIds, job.Src.TableId)
-	default:
-		err := xerror.Panicf(xerror.Normal, "invalid sync type: %s", job.SyncType)
-		j.setError(err)
-		return
-	}
-
-	srcMeta, err := factory.NewThriftMeta(&job.Src, j.ccrJob.factory, srcTableIds)
-	if err != nil {
-		j.setError(err)
-		return
-	}
 
-	destTableIds := make([]int64, 0, len(j.tableRecords))
-	switch job.SyncType {
-	case DBSync:
-		for _, srcTableId := range srcTableIds {
-			if destTableId, ok := j.tableMapping[srcTableId]; ok {
-				destTableIds = append(destTableIds, destTableId)
-			} else {
+	// the partitions of src older than the binlog are refreshed, the replicas of the version may be not in the cache
+	srcTables := make(map[int64]binlogVersions, len(j.tableRecords))
+	destTables := make(map[int64]binlogVersions, len(j.tableRecords))
+	for _, tableRecord := range j.tableRecords {
+		var srcTableId, destTableId int64
+		switch job.SyncType {
+		case DBSync:
+			srcTableId = tableRecord.Id
+			var ok bool
+			if destTableId, ok = j.tableMapping[srcTableId]; !ok {
 				err := xerror.XWrapf(errNotFoundDestMappingTableId, "src table id: %d", srcTableId)
 				j.setError(err)
 				return
 			}
+		case TableSync:
+			srcTableId = job.Src.TableId
+			destTableId = job.Dest.TableId
+		default:
+			err := xerror.Panicf(xerror.Normal, "invalid sync type: %s", job.SyncType)
+			j.setError(err)
+			return
 		}
-	case TableSync:
-		destTableIds = append(destTableIds, job.Dest.TableId)
-	default:
-		err := xerror.Panicf(xerror.Normal, "invalid sync type: %s", job.SyncType)
+
+		versions, ok := srcTables[srcTableId]
+		if !ok {
+			versions = make(binlogVersions)
+			srcTables[srcTableId] = versions
+		}
+		for _, partitionRecord := range tableRecord.PartitionRecords {
+			versions[partitionRecord.Id] = partitionRecord.Version
+		}
+		destTables[destTableId] = nil
+	}
+
+	srcMeta, err := job.srcMetaCache.ThriftMeta(srcTables)
+	if err != nil {
 		j.setError(err)
 		return
 	}
 
-	destMeta, err := factory.NewThriftMeta(&job.Dest, j.ccrJob.factory, destTableIds)
+	destMeta, err := job.destMetaCache.ThriftMeta(destTables)
 	if err != nil {
 		j.setError(err)
 		return
@@ -570,8 +587,54 @@ func (j *IngestBinlogJob) prepareMeta() {
 	j.destMeta = destMeta
 }
 
-// TODO(Drogon): use monad error handle
-func (j *IngestBinlogJob) Run() {
+// invalidateMetas drops the cached metas of the tables to ingest, so they are got again in the next prepare
+func (j *IngestBinlogJob) invalidateMetas() {
+	job := j.ccrJob
+	for _, tableRecord := range j.tableRecords {
+		switch job.SyncType {
+		case DBSync:
+			job.srcMetaCache.Invalidate(tableRecord.Id)
+			if destTableId, ok := j.tableMapping[tableRecord.Id]; ok {
+				job.destMetaCache.Invalidate(destTableId)
+			}
+		case TableSync:
+			job.srcMetaCache.Invalidate(job.Src.TableId)
+			job.destMetaCache.Invalidate(job.Dest.TableId)
+		}
+	}
+}
+
+// missingBackends returns the backends of the replicas to ingest, which are not in the backend maps
+func (j *IngestBinlogJob) missingBackends() []int64 {
+	missing := make([]int64, 0)
+	found := make(map[int64]bool)
+	check := func(backendId int64, backendMap map[int64]*base.Backend) {
+		if _, ok := found[backendId]; ok {
+			return
+		}
+		_, ok := backendMap[backendId]
+		found[backendId] = ok
+		if !ok {
+			missing = append(missing, backendId)
+		}
+	}
+
+	for _, h := range j.tabletIngestJobs {
+		h.srcTablet.ReplicaMetas.Scan(func(_ int64, srcReplica *ReplicaMeta) bool {
+			if srcReplica.Version >= h.binlogVersion {
+				check(srcReplica.BackendId, j.srcBackendMap)
+			}
+			return true
+		})
+		h.destTablet.ReplicaMetas.Scan(func(_ int64, destReplica *ReplicaMeta) bool {
+			check(destReplica.BackendId, j.destBackendMap)
+			return true
+		})
+	}
+	return missing
+}
+
+func (j *IngestBinlogJob) prepare() {
 	j.prepareMeta()
 	if err := j.Error(); err != nil {
 		return
@@ -583,10 +646,35 @@ func (j *IngestBinlogJob) Run() {
 	}
 
 	j.prepareTabletIngestJobs()
+}
+
+// TODO(Drogon): use monad error handle
+func (j *IngestBinlogJob) Run() {
+	// tablet ingest goroutines have their own gls, they use it as the parent span
+	j.traceCtx = xtrace.CurrentContext()
+
+	j.prepare()
 	if err := j.Error(); err != nil {
 		return
 	}
 
+	// the backends may be changed between getting backends and tablets, e.g. decommission, scale out or
+	// ip change, refresh metas once before ingesting, instead of failing with a meta error and full sync again
+	if missing := j.missingBackends(); len(missing) > 0 {
+		log.Warnf("backends %v of replicas not found, refresh metas and retry, txn id: %d", missing, j.txnId)
+		j.invalidateMetas()
+		j.ccrJob.srcMetaCache.InvalidateBackends()
+		j.ccrJob.destMetaCache.InvalidateBackends()
+		j.prepare()
+		if err := j.Error(); err != nil {
+			return
+		}
+		if missing := j.missingBackends(); len(missing) > 0 {
+			j.setError(xerror.XWrapf(errBackendNotFound, "backend ids: %v", missing))
+			return
+		}
+	}
+
 	j.runTabletIngestJobs()
 	if err := j.Error(); err != nil {
 		return
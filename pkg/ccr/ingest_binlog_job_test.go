@@ -0,0 +1,81 @@
+package ccr
+
+import (
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/btree"
+)
+
+func newTestTablet(tabletId int64, versions map[int64]int64) *TabletMeta {
+	tablet := &TabletMeta{
+		Id:           tabletId,
+		ReplicaMetas: btree.NewMap[int64, *ReplicaMeta](degree),
+	}
+	for backendId, version := range versions {
+		replicaId := tabletId*100 + backendId
+		tablet.ReplicaMetas.Set(replicaId, &ReplicaMeta{
+			TabletMeta: tablet,
+			Id:         replicaId,
+			TabletId:   tabletId,
+			BackendId:  backendId,
+			Version:    version,
+		})
+	}
+	return tablet
+}
+
+func TestMissingBackends(t *testing.T) {
+	j := &IngestBinlogJob{
+		srcBackendMap:  map[int64]*base.Backend{1: {Id: 1}, 2: {Id: 2}},
+		destBackendMap: map[int64]*base.Backend{11: {Id: 11}},
+	}
+	j.tabletIngestJobs = []*tabletIngestBinlogHandler{
+		{
+			binlogVersion: 3,
+			// the replica on backend 3 is behind, it is never used to ingest
+			srcTablet:  newTestTablet(100, map[int64]int64{1: 3, 2: 4, 3: 2}),
+			destTablet: newTestTablet(200, map[int64]int64{11: 2}),
+		},
+	}
+	assert.Empty(t, j.missingBackends())
+
+	// backend 4 is scaled out, backend 12 has a new id after decommission and re-adding
+	j.tabletIngestJobs = append(j.tabletIngestJobs, &tabletIngestBinlogHandler{
+		binlogVersion: 3,
+		srcTablet:     newTestTablet(101, map[int64]int64{4: 3}),
+		destTablet:    newTestTablet(201, map[int64]int64{11: 2, 12: 2}),
+	}, &tabletIngestBinlogHandler{
+		binlogVersion: 3,
+		srcTablet:     newTestTablet(102, map[int64]int64{4: 3}),
+		destTablet:    newTestTablet(202, map[int64]int64{12: 2}),
+	})
+	assert.ElementsMatch(t, []int64{4, 12}, j.missingBackends())
+}
+
+func TestBackendsChanged(t *testing.T) {
+	cached := map[int64]*base.Backend{
+		1: {Id: 1, Host: "10.0.0.1", BePort: 9060, HttpPort: 8040},
+		2: {Id: 2, Host: "10.0.0.2", BePort: 9060, HttpPort: 8040},
+	}
+
+	assert.False(t, backendsChanged(cached, []*base.Backend{
+		{Id: 2, Host: "10.0.0.2", BePort: 9060, HttpPort: 8040},
+		{Id: 1, Host: "10.0.0.1", BePort: 9060, HttpPort: 8040},
+	}))
+	// ip change
+	assert.True(t, backendsChanged(cached, []*base.Backend{
+		{Id: 1, Host: "10.0.0.1", BePort: 9060, HttpPort: 8040},
+		{Id: 2, Host: "10.0.0.3", BePort: 9060, HttpPort: 8040},
+	}))
+	// decommission
+	assert.True(t, backendsChanged(cached, []*base.Backend{
+		{Id: 1, Host: "10.0.0.1", BePort: 9060, HttpPort: 8040},
+	}))
+	// decommission and scale out
+	assert.True(t, backendsChanged(cached, []*base.Backend{
+		{Id: 1, Host: "10.0.0.1", BePort: 9060, HttpPort: 8040},
+		{Id: 3, Host: "10.0.0.2", BePort: 9060, HttpPort: 8040},
+	}))
+}
@@ -15,10 +15,12 @@ import (
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	utils "github.com/selectdb/ccr_syncer/pkg/utils"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+	"github.com/selectdb/ccr_syncer/pkg/xtrace"
 
 	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
 	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
@@ -31,8 +33,24 @@ import (
 
 const (
 	SYNC_DURATION = time.Second * 3
+	// lag metrics need an extra rpc to src fe, so it is updated less frequently
+	UPDATE_LAG_DURATION = time.Minute
+	// frontends are refreshed to follow the master failover, sql fails over to the known frontends meanwhile
+	UPDATE_FRONTENDS_DURATION = time.Minute
 )
 
+// syncDuration is the interval of job sync, it can be reloaded at runtime
+var syncDuration atomic.Int64
+
+func init() {
+	syncDuration.Store(int64(SYNC_DURATION))
+}
+
+// SetSyncDuration changes the sync interval of all jobs, it takes effect after their next sync
+func SetSyncDuration(duration time.Duration) {
+	syncDuration.Store(int64(duration))
+}
+
 type SyncType int
 
 const (
@@ -81,8 +99,16 @@ type Job struct {
 	destMeta  Metaer      `json:"-"`
 	SkipError bool        `json:"skip_error"`
 	State     JobState    `json:"state"`
+	// timeouts of waiting for the backup and restore of full sync, 0 means the default of syncer
+	BackupTimeout  time.Duration `json:"backup_timeout"`
+	RestoreTimeout time.Duration `json:"restore_timeout"`
+	// overrides the table properties of src on dest, nil keeps them
+	RestoreOptions *base.RestoreOptions `json:"restore_options,omitempty"`
 
 	factory *Factory `json:"-"`
+	// the metas of src and dest to ingest binlogs, they are shared by upserts
+	srcMetaCache  *MetaCache `json:"-"`
+	destMetaCache *MetaCache `json:"-"`
 
 	progress   *JobProgress `json:"-"`
 	db         storage.DB   `json:"-"`
@@ -90,6 +116,17 @@ type Job struct {
 
 	stop      chan struct{} `json:"-"`
 	isDeleted atomic.Bool   `json:"-"`
+	health    jobHealthStat `json:"-"`
+
+	// only one verify of the job runs at a time
+	verifyLock sync.Mutex                   `json:"-"`
+	lastVerify atomic.Pointer[VerifyReport] `json:"-"`
+	// the src tables to full sync again, the progress is only changed by the job goroutine, so
+	// resync is done in the next incremental sync
+	resyncRequested atomic.Pointer[[]string] `json:"-"`
+	// the backup or restore full sync is waiting for, and when it is checked
+	fullSyncWait          *FullSyncWait `json:"-"`
+	fullSyncWaitCheckedAt time.Time     `json:"-"`
 
 	lock sync.Mutex `json:"-"`
 }
@@ -127,11 +164,7 @@ func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 	job := &Job{
 		Name:      name,
 		Src:       src,
-		ISrc:      factory.NewSpecer(&src),
-		srcMeta:   factory.NewMeta(&jobContext.src),
 		Dest:      dest,
-		IDest:     factory.NewSpecer(&dest),
-		destMeta:  factory.NewMeta(&jobContext.dest),
 		SkipError: jobContext.skipError,
 		State:     JobRunning,
 
@@ -141,6 +174,13 @@ func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 		db:       jobContext.db,
 		stop:     make(chan struct{}),
 	}
+	// specers and metas share the spec of job, so the frontend switched by one is used by all and persisted
+	job.ISrc = factory.NewSpecer(&job.Src)
+	job.IDest = factory.NewSpecer(&job.Dest)
+	job.srcMeta = factory.NewMeta(&job.Src)
+	job.destMeta = factory.NewMeta(&job.Dest)
+	job.srcMetaCache = NewMetaCache(name, metaCacheSrc, &job.Src, factory)
+	job.destMetaCache = NewMetaCache(name, metaCacheDest, &job.Dest, factory)
 
 	if err := job.valid(); err != nil {
 		return nil, xerror.Wrap(err, xerror.Normal, "job is invalid")
@@ -153,6 +193,7 @@ func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 	}
 
 	job.jobFactory = NewJobFactory()
+	job.health.setState(job.State)
 
 	return job, nil
 }
@@ -161,7 +202,8 @@ func NewJobFromJson(jsonData string, db storage.DB, factory *Factory) (*Job, err
 	var job Job
 	err := json.Unmarshal([]byte(jsonData), &job)
 	if err != nil {
-		return nil, xerror.Wrapf(err, xerror.Normal, "unmarshal json failed, json: %s", jsonData)
+		// json data is not logged, it has credentials
+		return nil, xerror.Wrap(err, xerror.Normal, "unmarshal job json failed")
 	}
 
 	// recover all not json fields
@@ -170,10 +212,13 @@ func NewJobFromJson(jsonData string, db storage.DB, factory *Factory) (*Job, err
 	job.IDest = factory.NewSpecer(&job.Dest)
 	job.srcMeta = factory.NewMeta(&job.Src)
 	job.destMeta = factory.NewMeta(&job.Dest)
+	job.srcMetaCache = NewMetaCache(job.Name, metaCacheSrc, &job.Src, factory)
+	job.destMetaCache = NewMetaCache(job.Name, metaCacheDest, &job.Dest, factory)
 	job.progress = nil
 	job.db = db
 	job.stop = make(chan struct{})
 	job.jobFactory = NewJobFactory()
+	job.health.setState(job.State)
 	return &job, nil
 }
 
@@ -235,6 +280,10 @@ func (j *Job) genExtraInfo() (*base.ExtraInfo, error) {
 		return nil, err
 	}
 
+	// dest downloads the snapshot from these backends, so they must be the latest
+	if err := meta.UpdateBackends(); err != nil {
+		return nil, err
+	}
 	backends, err := meta.GetBackends()
 	if err != nil {
 		return nil, err
@@ -258,6 +307,53 @@ func (j *Job) genExtraInfo() (*base.ExtraInfo, error) {
 	}, nil
 }
 
+// invalidTokenMsg is the error of the src backends rejecting the download of the snapshot or binlog,
+// it is in the status of the cancelled restore and the failed ingest
+const invalidTokenMsg = "invalid token"
+
+// isTokenErr returns true if the restore or ingest is rejected by the master token of src,
+// e.g. the token is expired after the src master restarts or the token is rotated. The error of ingest
+// has the request with the token in it, so only the exact message is matched.
+func isTokenErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), invalidTokenMsg)
+}
+
+// extraInfoToken returns the master token in the extra info of the snapshot job info
+func extraInfoToken(jobInfo []byte) string {
+	var info struct {
+		ExtraInfo *base.ExtraInfo `json:"extra_info"`
+	}
+	if err := json.Unmarshal(jobInfo, &info); err != nil || info.ExtraInfo == nil {
+		return ""
+	}
+	return info.ExtraInfo.Token
+}
+
+// refreshTokenOnRestoreErr refreshes the master token of src if the restore fails because of it,
+// returns true if the token is changed, then restore with the new token may succeed.
+func (j *Job) refreshTokenOnRestoreErr(restoreErr error, jobInfo []byte) (bool, error) {
+	// the restore from repository doesn't use the token of src
+	if j.isRepositorySync() || !isTokenErr(restoreErr) {
+		return false, nil
+	}
+
+	log.Warnf("restore failed because of the master token of src, refresh it, err: %v", restoreErr)
+	if err := j.srcMeta.UpdateToken(j.factory); err != nil {
+		return false, err
+	}
+	token, err := j.srcMeta.GetMasterToken(j.factory)
+	if err != nil {
+		return false, err
+	}
+	// the token is not changed, restore again doesn't help
+	if token == extraInfoToken(jobInfo) {
+		return false, nil
+	}
+
+	log.Infof("master token of src is refreshed, add extra info again")
+	return true, nil
+}
+
 func (j *Job) isIncrementalSync() bool {
 	switch j.progress.SyncState {
 	case TableIncrementalSync, DBIncrementalSync, DBTablesIncrementalSync:
@@ -272,6 +368,8 @@ func (j *Job) fullSync() error {
 		SnapshotName      string                        `json:"snapshot_name"`
 		SnapshotResp      *festruct.TGetSnapshotResult_ `json:"snapshot_resp"`
 		TableCommitSeqMap map[int64]int64               `json:"table_commit_seq_map"`
+		// the timestamp of the snapshot in the repository, only for repository sync
+		BackupTimestamp string `json:"backup_timestamp,omitempty"`
 	}
 
 	switch j.progress.SubSyncState {
@@ -292,26 +390,81 @@ func (j *Job) fullSync() error {
 			if err != nil {
 				return err
 			}
+			resyncTables := make(map[string]bool, len(j.progress.ResyncTables))
+			for _, table := range j.progress.ResyncTables {
+				resyncTables[table] = true
+			}
 			for _, table := range tables {
-				backupTableList = append(backupTableList, table.Name)
+				if len(resyncTables) == 0 || resyncTables[table.Name] {
+					backupTableList = append(backupTableList, table.Name)
+				}
+			}
+			if len(resyncTables) > 0 && len(backupTableList) == 0 {
+				log.Warnf("tables %v to resync are dropped, back to incremental sync", j.progress.ResyncTables)
+				j.progress.ResyncTables = nil
+				syncState := DBIncrementalSync
+				if j.progress.TableCommitSeqMap != nil {
+					syncState = DBTablesIncrementalSync
+				}
+				j.progress.NextWithPersist(j.progress.CommitSeq, syncState, Done, "")
+				return nil
 			}
 		case TableSync:
 			backupTableList = append(backupTableList, j.Src.Table)
 		default:
 			return xerror.Errorf(xerror.Normal, "invalid sync type %s", j.SyncType)
 		}
-		snapshotName, err := j.ISrc.CreateSnapshotAndWaitForDone(backupTableList)
+		// the snapshots of the abandoned full syncs are not needed anymore
+		j.cleanSnapshots()
+
+		backupStart := time.Now()
+		snapshotName, err := j.ISrc.CreateSnapshot(backupTableList)
+		if err != nil {
+			return err
+		}
+		j.recordSnapshot(storage.SnapshotKindBackup, j.Src.Database, snapshotName)
+
+		j.progress.NextSubCheckpoint(WaitBackupDone, &fullSyncWaitData{Label: snapshotName, StartTime: backupStart})
+
+	case WaitBackupDone:
+		// Step 1.1: Wait for the backup, check it once per sync
+		waitData, err := parseFullSyncWaitData(j.progress.PersistData)
+		if err != nil {
+			return err
+		}
+
+		backupTimeout, _ := j.waitTimeouts()
+		backupCheck, _ := base.CheckDurations()
+		finished, err := j.checkFullSyncWait(fullSyncPhaseBackup, waitData, backupTimeout, backupCheck, j.ISrc.GetBackupProgress)
 		if err != nil {
+			if isFullSyncWaitFailed(err) {
+				j.progress.NextSubCheckpoint(BeginCreateSnapshot, "")
+			}
 			return err
 		}
+		if !finished {
+			return nil
+		}
+		xmetrics.ObserveFullSync(j.Name, xmetrics.PhaseBackup, waitData.StartTime)
 
-		j.progress.NextSubCheckpoint(GetSnapshotInfo, snapshotName)
+		j.progress.NextSubCheckpoint(GetSnapshotInfo, waitData.Label)
 
 	case GetSnapshotInfo:
 		// Step 2: Get snapshot info
 		log.Infof("fullsync status: get snapshot info")
 
 		snapshotName := j.progress.PersistData
+		var backupTimestamp string
+		if j.isRepositorySync() {
+			// the details of the snapshot in the repository are brief, without the table commit seqs, so the
+			// job info is still got from the src frontend and the timestamp from the repository
+			log.Debugf("begin get snapshot %s from repository %s", snapshotName, j.Src.Repository)
+			var err error
+			if backupTimestamp, err = j.getRepositorySnapshotTimestamp(snapshotName); err != nil {
+				return err
+			}
+		}
+
 		src := &j.Src
 		srcRpc, err := j.factory.NewFeRpc(src)
 		if err != nil {
@@ -338,6 +491,10 @@ func (j *Job) fullSync() error {
 		if err != nil {
 			return err
 		}
+		// the binlogs after the snapshot are synced from the commit seqs, never start from an unknown one
+		if len(tableCommitSeqMap) == 0 {
+			return xerror.Errorf(xerror.Normal, "snapshot %s has no table commit seqs, job info: %.128s", snapshotName, snapshotResp.GetJobInfo())
+		}
 
 		if j.SyncType == TableSync {
 			if _, ok := tableCommitSeqMap[j.Src.TableId]; !ok {
@@ -349,6 +506,7 @@ func (j *Job) fullSync() error {
 			SnapshotName:      snapshotName,
 			SnapshotResp:      snapshotResp,
 			TableCommitSeqMap: tableCommitSeqMap,
+			BackupTimestamp:   backupTimestamp,
 		}
 		j.progress.NextSubVolatile(AddExtraInfo, inMemoryData)
 
@@ -361,30 +519,51 @@ func (j *Job) fullSync() error {
 		jobInfo := snapshotResp.GetJobInfo()
 		tableCommitSeqMap := inMemoryData.TableCommitSeqMap
 
-		var jobInfoMap map[string]interface{}
-		err := json.Unmarshal(jobInfo, &jobInfoMap)
-		if err != nil {
-			return xerror.Wrapf(err, xerror.Normal, "unmarshal jobInfo failed, jobInfo: %s", string(jobInfo))
-		}
-		log.Debugf("jobInfoMap: %v", jobInfoMap)
+		// the dest restores from the repository, it doesn't download the snapshot from the src backends
+		if !j.isRepositorySync() {
+			var jobInfoMap map[string]interface{}
+			err := json.Unmarshal(jobInfo, &jobInfoMap)
+			if err != nil {
+				return xerror.Wrapf(err, xerror.Normal, "unmarshal jobInfo failed, jobInfo: %s", string(jobInfo))
+			}
+			log.Debugf("jobInfoMap: %v", jobInfoMap)
 
-		extraInfo, err := j.genExtraInfo()
-		if err != nil {
-			return err
-		}
-		log.Debugf("extraInfo: %v", extraInfo)
-		jobInfoMap["extra_info"] = extraInfo
+			extraInfo, err := j.genExtraInfo()
+			if err != nil {
+				return err
+			}
+			log.Debugf("extraInfo: %v", extraInfo)
+			jobInfoMap["extra_info"] = extraInfo
 
-		jobInfoBytes, err := json.Marshal(jobInfoMap)
-		if err != nil {
-			return xerror.Errorf(xerror.Normal, "marshal jobInfo failed, jobInfo: %v", jobInfoMap)
+			jobInfoBytes, err := json.Marshal(jobInfoMap)
+			if err != nil {
+				return xerror.Errorf(xerror.Normal, "marshal jobInfo failed, jobInfo: %v", jobInfoMap)
+			}
+			log.Debugf("jobInfoBytes: %s", string(jobInfoBytes))
+			snapshotResp.SetJobInfo(jobInfoBytes)
 		}
-		log.Debugf("jobInfoBytes: %s", string(jobInfoBytes))
-		snapshotResp.SetJobInfo(jobInfoBytes)
 
 		var commitSeq int64 = math.MaxInt64
 		switch j.SyncType {
 		case DBSync:
+			if len(j.progress.ResyncTables) > 0 {
+				// the tables not synced again keep syncing from the current commit seq, or from their own
+				// commit seqs if they are still behind the last full sync
+				tables, err := j.srcMeta.GetTables()
+				if err != nil {
+					return err
+				}
+				for tableId := range tables {
+					if _, ok := tableCommitSeqMap[tableId]; ok {
+						continue
+					}
+					seq := j.progress.CommitSeq
+					if prevSeq, ok := j.progress.TableCommitSeqMap[tableId]; ok && prevSeq > seq {
+						seq = prevSeq
+					}
+					tableCommitSeqMap[tableId] = seq
+				}
+			}
 			for _, seq := range tableCommitSeqMap {
 				commitSeq = utils.Min(commitSeq, seq)
 			}
@@ -411,6 +590,10 @@ func (j *Job) fullSync() error {
 		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
 		snapshotName := inMemoryData.SnapshotName
 		restoreSnapshotName := restoreSnapshotName(snapshotName)
+		if j.isRepositorySync() {
+			// the label of a restore from repository is the snapshot name
+			restoreSnapshotName = snapshotName
+		}
 		snapshotResp := inMemoryData.SnapshotResp
 
 		// Step 4.2: restore snapshot to dest
@@ -431,35 +614,78 @@ func (j *Job) fullSync() error {
 			}
 			tableRefs = append(tableRefs, tableRef)
 		}
-		restoreResp, err := destRpc.RestoreSnapshot(dest, tableRefs, restoreSnapshotName, snapshotResp)
+		restoreStart := time.Now()
+		var restoreResp *festruct.TRestoreSnapshotResult_
+		if j.isRepositorySync() {
+			restoreResp, err = destRpc.RestoreSnapshotFromRepository(dest, tableRefs, snapshotName, inMemoryData.BackupTimestamp, j.RestoreOptions)
+		} else {
+			restoreResp, err = destRpc.RestoreSnapshot(dest, tableRefs, restoreSnapshotName, snapshotResp, j.RestoreOptions)
+		}
 		if err != nil {
 			return err
 		}
 		if restoreResp.Status.GetStatusCode() != tstatus.TStatusCode_OK {
-			return xerror.Errorf(xerror.Normal, "restore snapshot failed, status: %v", restoreResp.Status)
+			err = xerror.Errorf(xerror.Normal, "restore snapshot failed, status: %v", restoreResp.Status)
+			if refreshed, refreshErr := j.refreshTokenOnRestoreErr(err, snapshotResp.GetJobInfo()); refreshErr != nil {
+				return refreshErr
+			} else if !refreshed {
+				return err
+			}
+			j.progress.NextSubVolatile(AddExtraInfo, inMemoryData)
+			break
 		}
 		log.Infof("resp: %v", restoreResp)
+		j.recordSnapshot(storage.SnapshotKindRestore, j.Dest.Database, restoreSnapshotName)
 
-		for {
-			restoreFinished, err := j.IDest.CheckRestoreFinished(restoreSnapshotName)
-			if err != nil {
+		// the snapshot is kept in memory, so a restore failed with the expired token can add extra info again
+		j.progress.NextSubCheckpoint(WaitRestoreDone, &fullSyncWaitData{Label: restoreSnapshotName, StartTime: restoreStart})
+
+	case WaitRestoreDone:
+		// Step 4.3: Wait for the restore, check it once per sync
+		waitData, err := parseFullSyncWaitData(j.progress.PersistData)
+		if err != nil {
+			return err
+		}
+
+		_, restoreTimeout := j.waitTimeouts()
+		_, restoreCheck := base.CheckDurations()
+		finished, err := j.checkFullSyncWait(fullSyncPhaseRestore, waitData, restoreTimeout, restoreCheck, j.IDest.GetRestoreProgress)
+		if err != nil {
+			if !isFullSyncWaitFailed(err) {
 				return err
 			}
-
-			if restoreFinished {
-				j.progress.NextSubCheckpoint(PersistRestoreInfo, restoreSnapshotName)
-				break
+			if inMemoryData, ok := j.progress.InMemoryData.(*inMemoryData); ok {
+				if refreshed, refreshErr := j.refreshTokenOnRestoreErr(err, inMemoryData.SnapshotResp.GetJobInfo()); refreshErr != nil {
+					return refreshErr
+				} else if refreshed {
+					j.progress.NextSubVolatile(AddExtraInfo, inMemoryData)
+					break
+				}
 			}
-			// retry for  MAX_CHECK_RETRY_TIMES, timeout, continue
+			j.progress.NextSubCheckpoint(BeginCreateSnapshot, "")
+			return err
 		}
+		if !finished {
+			return nil
+		}
+		xmetrics.ObserveFullSync(j.Name, xmetrics.PhaseRestore, waitData.StartTime)
+
+		j.progress.NextSubCheckpoint(PersistRestoreInfo, waitData.Label)
 
 	case PersistRestoreInfo:
 		// Step 5: Update job progress && dest table id
 		// update job info, only for dest table id
 		log.Infof("fullsync status: persist restore info")
 
+		// the tables of dest are replaced by restore, their ids are changed
+		j.srcMetaCache.Clear()
+		j.destMetaCache.Clear()
+
 		switch j.SyncType {
 		case DBSync:
+			if _, err := j.destMeta.GetTables(); err != nil {
+				return err
+			}
 			tableMapping := make(map[int64]int64)
 			for srcTableId := range j.progress.TableCommitSeqMap {
 				srcTableName, err := j.srcMeta.GetTableNameById(srcTableId)
@@ -476,6 +702,7 @@ func (j *Job) fullSync() error {
 			}
 
 			j.progress.TableMapping = tableMapping
+			j.progress.ResyncTables = nil
 			j.progress.NextWithPersist(j.progress.CommitSeq, DBTablesIncrementalSync, Done, "")
 		case TableSync:
 			if destTable, err := j.destMeta.UpdateTable(j.Dest.Table, 0); err != nil {
@@ -495,6 +722,8 @@ func (j *Job) fullSync() error {
 			return xerror.Errorf(xerror.Normal, "invalid sync type %d", j.SyncType)
 		}
 
+		// the restore is finished, the snapshot is not needed anymore
+		j.cleanSnapshots()
 		return nil
 	default:
 		return xerror.Errorf(xerror.Normal, "invalid job sub sync state %d", j.progress.SubSyncState)
@@ -506,7 +735,7 @@ func (j *Job) fullSync() error {
 func (j *Job) persistJob() error {
 	data, err := json.Marshal(j)
 	if err != nil {
-		return xerror.Errorf(xerror.Normal, "marshal job failed, job: %v", j)
+		return xerror.Wrapf(err, xerror.Normal, "marshal job failed, job: %s", j.Name)
 	}
 
 	if err := j.db.UpdateJob(j.Name, string(data)); err != nil {
@@ -622,16 +851,34 @@ func (j *Job) ingestBinlog(txnId int64, tableRecords []*record.TableRecord) ([]*
 		return nil, xerror.Errorf(xerror.Normal, "invalid job type, job: %+v", job)
 	}
 
+	span := xtrace.Start("IngestBinlogJob.Run", xtrace.TxnId(txnId))
 	job.Run()
+	span.End(job.Error())
 	if err := job.Error(); err != nil {
+		// the upsert is retried with the refreshed token
+		if isTokenErr(err) {
+			log.Warnf("ingest binlog failed because of the master token of src, refresh it, err: %v", err)
+			if err := j.srcMeta.UpdateToken(j.factory); err != nil {
+				log.Warnf("refresh master token of src failed, err: %+v", err)
+			}
+		} else {
+			// the cached metas may be stale, e.g. the tablets are balanced, the upsert is retried with new ones
+			ingestBinlogJob.invalidateMetas()
+		}
 		return nil, err
 	}
 	return ingestBinlogJob.CommitInfos(), nil
 }
 
-func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
+func (j *Job) handleUpsert(binlog *festruct.TBinlog) (err error) {
 	log.Infof("handle upsert binlog, sub sync state: %s", j.progress.SubSyncState)
 
+	// one span per sub state, it ends before handling the next sub state
+	span := xtrace.Start("Job.handleUpsert", xtrace.CommitSeq(j.progress.CommitSeq), xtrace.SubSyncState(j.progress.SubSyncState.String()))
+	defer func() { span.End(err) }()
+	// the upsert may be resumed from the progress without handleBinlog
+	fault.SetCommitSeq(j.progress.CommitSeq)
+
 	// inMemory will be update in state machine, but progress keep any, so progress.inMemory is also latest, well call NextSubCheckpoint don't need to upate inMemory in progress
 	type inMemoryData struct {
 		CommitSeq    int64                       `json:"commit_seq"`
@@ -649,12 +896,14 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 				return xerror.Errorf(xerror.Normal, "unmarshal persistData failed, persistData: %s", persistData)
 			}
 			j.progress.InMemoryData = inMemoryData
+			j.progress.SetTxnId(inMemoryData.TxnId)
 		}
 		return nil
 	}
 
 	rollback := func(err error, inMemoryData *inMemoryData) {
 		log.Errorf("need rollback, err: %+v", err)
+		j.progress.RecordError(err)
 		j.progress.NextSubCheckpoint(RollbackTransaction, inMemoryData)
 	}
 
@@ -739,18 +988,22 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 
 		label := j.newLabel(commitSeq)
 
+		beginTxnStart := time.Now()
 		beginTxnResp, err := destRpc.BeginTransaction(dest, label, inMemoryData.DestTableIds)
 		if err != nil {
 			return err
 		}
+		xmetrics.ObserveRpc(j.Name, xmetrics.MethodBeginTxn, beginTxnStart)
 		log.Debugf("resp: %v", beginTxnResp)
 		if beginTxnResp.GetStatus().GetStatusCode() != tstatus.TStatusCode_OK {
 			return xerror.Errorf(xerror.Normal, "begin txn failed, status: %v", beginTxnResp.GetStatus())
 		}
 		txnId := beginTxnResp.GetTxnId()
 		log.Debugf("TxnId: %d, DbId: %d", txnId, beginTxnResp.GetDbId())
+		span.SetAttributes(xtrace.TxnId(txnId), xtrace.TableIds(inMemoryData.DestTableIds))
 
 		inMemoryData.TxnId = txnId
+		j.progress.SetTxnId(txnId)
 		j.progress.NextSubCheckpoint(IngestBinlog, inMemoryData)
 
 	case IngestBinlog:
@@ -761,6 +1014,7 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
 		tableRecords := inMemoryData.TableRecords
 		txnId := inMemoryData.TxnId
+		span.SetAttributes(xtrace.TxnId(txnId))
 
 		// Step 3: ingest binlog
 		var commitInfos []*ttypes.TTabletCommitInfo
@@ -782,6 +1036,7 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
 		txnId := inMemoryData.TxnId
 		commitInfos := inMemoryData.CommitInfos
+		span.SetAttributes(xtrace.TxnId(txnId))
 
 		destRpc, err := j.factory.NewFeRpc(dest)
 		if err != nil {
@@ -789,11 +1044,13 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 			break
 		}
 
+		commitTxnStart := time.Now()
 		resp, err := destRpc.CommitTransaction(dest, txnId, commitInfos)
 		if err != nil {
 			rollback(err, inMemoryData)
 			break
 		}
+		xmetrics.ObserveRpc(j.Name, xmetrics.MethodCommitTxn, commitTxnStart)
 
 		if statusCode := resp.Status.GetStatusCode(); statusCode == tstatus.TStatusCode_PUBLISH_TIMEOUT {
 			dest.WaitTransactionDone(txnId)
@@ -848,6 +1105,7 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 		return xerror.Errorf(xerror.Normal, "invalid job sub sync state %d", j.progress.SubSyncState)
 	}
 
+	span.End(nil)
 	return j.handleUpsert(binlog)
 }
 
@@ -877,7 +1135,7 @@ func (j *Job) handleAddPartition(binlog *festruct.TBinlog) error {
 		}
 	}
 
-	addPartitionSql := addPartition.GetSql(destTableName)
+	addPartitionSql := applyRestoreOptions(addPartition.GetSql(destTableName), j.RestoreOptions)
 	log.Infof("addPartitionSql: %s", addPartitionSql)
 	return j.IDest.DbExec(addPartitionSql)
 }
@@ -929,7 +1187,7 @@ func (j *Job) handleCreateTable(binlog *festruct.TBinlog) error {
 		return err
 	}
 
-	sql := createTable.Sql
+	sql := applyRestoreOptions(createTable.Sql, j.RestoreOptions)
 	log.Infof("createTableSql: %s", sql)
 	// HACK: for drop table
 	if err := j.IDest.DbExec(sql); err != nil {
@@ -1146,16 +1404,57 @@ func (j *Job) handleBinlogs(binlogs []*festruct.TBinlog) (error, bool) {
 	return nil, false
 }
 
-func (j *Job) handleBinlog(binlog *festruct.TBinlog) error {
+// getBinlogTableIds returns the src and dest tables changed by the binlog
+func (j *Job) getBinlogTableIds(binlog *festruct.TBinlog) ([]int64, []int64) {
+	switch j.SyncType {
+	case TableSync:
+		return []int64{j.Src.TableId}, []int64{j.Dest.TableId}
+	case DBSync:
+		srcTableIds := binlog.GetTableIds()
+		destTableIds := make([]int64, 0, len(srcTableIds))
+		for _, srcTableId := range srcTableIds {
+			if destTableId, ok := j.progress.TableMapping[srcTableId]; ok {
+				destTableIds = append(destTableIds, destTableId)
+			}
+		}
+		return srcTableIds, destTableIds
+	default:
+		return nil, nil
+	}
+}
+
+func (j *Job) handleBinlog(binlog *festruct.TBinlog) (err error) {
 	if binlog == nil || !binlog.IsSetCommitSeq() {
 		return xerror.Errorf(xerror.Normal, "invalid binlog: %v", binlog)
 	}
 
+	span := xtrace.Start("Job.handleBinlog", xtrace.CommitSeq(binlog.GetCommitSeq()), xtrace.BinlogType(binlog.GetType().String()))
+	defer func() { span.End(err) }()
+	if j.SyncType == TableSync {
+		span.SetAttributes(xtrace.Table(j.Src.Table))
+	}
+
 	log.Debugf("binlog type: %s, binlog data: %s", binlog.GetType(), binlog.GetData())
 
 	// Step 2: update job progress
 	j.progress.StartHandle(binlog.GetCommitSeq())
 	xmetrics.HandlingBinlog(j.Name, binlog.GetCommitSeq())
+	fault.SetCommitSeq(binlog.GetCommitSeq())
+
+	// the partitions, indexes or tablets of the tables are changed by ddl, e.g. add partition or truncate table,
+	// the dest tables are got before handling, since the table mapping is changed by drop table
+	if binlog.GetType() != festruct.TBinlogType_UPSERT {
+		srcTableIds, destTableIds := j.getBinlogTableIds(binlog)
+		defer func() {
+			if err == nil {
+				j.srcMetaCache.Invalidate(srcTableIds...)
+				j.destMetaCache.Invalidate(destTableIds...)
+				// the metas of job cache the tables too, e.g. the partition ids got by the add partition
+				j.srcMeta.InvalidateTables(srcTableIds...)
+				j.destMeta.InvalidateTables(destTableIds...)
+			}
+		}()
+	}
 
 	switch binlog.GetType() {
 	case festruct.TBinlogType_UPSERT:
@@ -1221,10 +1520,12 @@ func (j *Job) incrementalSync() error {
 		commitSeq := j.progress.CommitSeq
 		log.Debugf("src: %s, commitSeq: %v", src, commitSeq)
 
+		getBinlogStart := time.Now()
 		getBinlogResp, err := srcRpc.GetBinlog(src, commitSeq)
 		if err != nil {
 			return err
 		}
+		xmetrics.ObserveRpc(j.Name, xmetrics.MethodGetBinlog, getBinlogStart)
 		log.Debugf("resp: %v", getBinlogResp)
 
 		// Step 2.1: check binlog status
@@ -1233,6 +1534,10 @@ func (j *Job) incrementalSync() error {
 		case tstatus.TStatusCode_OK:
 		case tstatus.TStatusCode_BINLOG_TOO_OLD_COMMIT_SEQ:
 		case tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ:
+			// all binlogs are synced
+			xmetrics.SetLag(j.Name, 0)
+			j.health.setLag(0)
+			j.health.markProgress()
 			return nil
 		case tstatus.TStatusCode_BINLOG_DISABLE:
 			return xerror.Errorf(xerror.Normal, "binlog is disabled")
@@ -1319,6 +1624,14 @@ func (j *Job) sync() error {
 	j.lock.Lock()
 	defer j.lock.Unlock()
 
+	if j.isIncrementalSync() {
+		if tables := j.resyncRequested.Swap(nil); tables != nil {
+			if err := j.newResyncSnapshot(*tables); err != nil {
+				return err
+			}
+		}
+	}
+
 	switch j.SyncType {
 	case TableSync:
 		return j.tableSync()
@@ -1350,10 +1663,12 @@ func (j *Job) handleError(err error) error {
 }
 
 func (j *Job) run() {
-	ticker := time.NewTicker(SYNC_DURATION)
+	duration := time.Duration(syncDuration.Load())
+	ticker := time.NewTicker(duration)
 	defer ticker.Stop()
 
 	var panicError error
+	var lagUpdatedAt, frontendsUpdatedAt time.Time
 
 	for {
 		// do maybeDeleted first to avoid mark job deleted after job stopped & before job run & close stop chan gap in Delete, so job will not run
@@ -1364,10 +1679,19 @@ func (j *Job) run() {
 		select {
 		case <-j.stop:
 			gls.DeleteGls(gls.GoID())
+			if j.isDeleted.Load() {
+				// the snapshots of the deleted job are not needed anymore
+				j.cleanSnapshots()
+			}
 			log.Infof("job stopped, job: %s", j.Name)
 			return
 
 		case <-ticker.C:
+			if newDuration := time.Duration(syncDuration.Load()); newDuration != duration {
+				duration = newDuration
+				ticker.Reset(duration)
+			}
+
 			// loop to print error, not panic, waiting for user to pause/stop/remove Job
 			if j.getJobState() != JobRunning {
 				break
@@ -1378,12 +1702,35 @@ func (j *Job) run() {
 				break
 			}
 
+			if time.Since(frontendsUpdatedAt) >= UPDATE_FRONTENDS_DURATION {
+				frontendsUpdatedAt = time.Now()
+				j.refreshFrontends()
+			}
+
+			point := j.progressPoint()
 			err := j.sync()
+			j.health.setSyncState(j.progress)
+			if j.progressPoint() != point {
+				j.health.markProgress()
+			}
+			if j.progress != nil && j.isIncrementalSync() && time.Since(lagUpdatedAt) >= UPDATE_LAG_DURATION {
+				lagUpdatedAt = time.Now()
+				if _, err := j.GetLag(); err != nil {
+					log.Warnf("update lag failed, job: %s, err: %+v", j.Name, err)
+				}
+			}
 			if err == nil {
 				break
 			}
 
 			log.Warnf("job sync failed, job: %s, err: %+v", j.Name, err)
+			if base.IsConnectionErr(err) {
+				// the frontend may be down, refresh frontends to fail over before the next sync
+				frontendsUpdatedAt = time.Time{}
+			}
+			if j.progress != nil {
+				j.progress.RecordError(err)
+			}
 			panicError = j.handleError(err)
 		}
 	}
@@ -1392,6 +1739,7 @@ func (j *Job) run() {
 func (j *Job) newSnapshot(commitSeq int64) error {
 	log.Infof("new snapshot, commitSeq: %d", commitSeq)
 
+	j.progress.ResyncTables = nil
 	switch j.SyncType {
 	case TableSync:
 		j.progress.NextWithPersist(commitSeq, TableFullSync, BeginCreateSnapshot, "")
@@ -1406,10 +1754,24 @@ func (j *Job) newSnapshot(commitSeq int64) error {
 	}
 }
 
+// newResyncSnapshot full syncs the tables again from the current commit seq, the other tables of
+// db sync are not restored and keep syncing from their commit seqs
+func (j *Job) newResyncSnapshot(tables []string) error {
+	if j.SyncType != DBSync {
+		return j.newSnapshot(j.progress.CommitSeq)
+	}
+
+	log.Infof("new snapshot of tables %v, commitSeq: %d", tables, j.progress.CommitSeq)
+	j.progress.ResyncTables = tables
+	j.progress.NextWithPersist(j.progress.CommitSeq, DBFullSync, BeginCreateSnapshot, "")
+	return nil
+}
+
 // run job
 func (j *Job) Run() error {
 	gls.ResetGls(gls.GoID(), map[interface{}]interface{}{})
 	gls.Set("job", j.Name)
+	j.health.markProgress()
 
 	// retry 3 times to check IsProgressExist
 	var isProgressExist bool
@@ -1514,6 +1876,29 @@ func (j *Job) UpdateSkipError(skipError bool) error {
 	}
 }
 
+// UpdateWaitTimeouts updates the timeouts of waiting backup/restore, nil means not changed, 0 means the default of syncer
+func (j *Job) UpdateWaitTimeouts(backupTimeout, restoreTimeout *time.Duration) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	originBackupTimeout, originRestoreTimeout := j.BackupTimeout, j.RestoreTimeout
+	if backupTimeout != nil {
+		j.BackupTimeout = *backupTimeout
+	}
+	if restoreTimeout != nil {
+		j.RestoreTimeout = *restoreTimeout
+	}
+	if j.BackupTimeout == originBackupTimeout && j.RestoreTimeout == originRestoreTimeout {
+		return nil
+	}
+
+	if err := j.persistJob(); err != nil {
+		j.BackupTimeout, j.RestoreTimeout = originBackupTimeout, originRestoreTimeout
+		return err
+	}
+	return nil
+}
+
 // stop job
 func (j *Job) Stop() {
 	close(j.stop)
@@ -1535,38 +1920,51 @@ func (j *Job) maybeDeleted() bool {
 	if err := j.db.RemoveJob(j.Name); err != nil {
 		log.Errorf("remove job failed, job: %s, err: %+v", j.Name, err)
 	}
+	j.cleanSnapshots()
 	return true
 }
 
-func (j *Job) updateFrontends() error {
+// updateFrontends returns true if the frontends of src or dest are changed
+func (j *Job) updateFrontends() (bool, error) {
+	var srcChanged, destChanged bool
 	if frontends, err := j.srcMeta.GetFrontends(); err != nil {
-		log.Warnf("get src frontends failed, fe: %+v", j.Src)
-		return err
+		log.Warnf("get src frontends failed, fe: %s", &j.Src)
+		return false, err
 	} else {
-		for _, frontend := range frontends {
-			j.Src.Frontends = append(j.Src.Frontends, *frontend)
-		}
+		srcChanged = j.Src.UpdateFrontends(frontends)
 	}
 	log.Debugf("src frontends %+v", j.Src.Frontends)
 
 	if frontends, err := j.destMeta.GetFrontends(); err != nil {
-		log.Warnf("get dest frontends failed, fe: %+v", j.Dest)
-		return err
+		log.Warnf("get dest frontends failed, fe: %s", &j.Dest)
+		return false, err
 	} else {
-		for _, frontend := range frontends {
-			j.Dest.Frontends = append(j.Dest.Frontends, *frontend)
-		}
+		destChanged = j.Dest.UpdateFrontends(frontends)
 	}
 	log.Debugf("dest frontends %+v", j.Dest.Frontends)
 
-	return nil
+	return srcChanged || destChanged, nil
+}
+
+// refreshFrontends follows the frontend changes of src and dest, e.g. master failover, scale in or out
+func (j *Job) refreshFrontends() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if changed, err := j.updateFrontends(); err != nil {
+		log.Warnf("update frontends failed, job: %s, err: %+v", j.Name, err)
+	} else if changed {
+		if err := j.persistJob(); err != nil {
+			log.Warnf("persist frontends failed, job: %s, err: %+v", j.Name, err)
+		}
+	}
 }
 
 func (j *Job) FirstRun() error {
 	log.Infof("first run check job, src: %s, dest: %s", &j.Src, &j.Dest)
 
 	// Step 0: get all frontends
-	if err := j.updateFrontends(); err != nil {
+	if _, err := j.updateFrontends(); err != nil {
 		return err
 	}
 
@@ -1578,6 +1976,11 @@ func (j *Job) FirstRun() error {
 		return err
 	}
 
+	// Step 1.1: check the repositories of full sync
+	if err := j.checkRepositories(); err != nil {
+		return err
+	}
+
 	// Step 2: check src database
 	if src_db_exists, err := j.ISrc.CheckDatabaseExists(); err != nil {
 		return err
@@ -1651,6 +2054,10 @@ func (j *Job) GetLag() (int64, error) {
 	j.lock.Lock()
 	defer j.lock.Unlock()
 
+	return j.getLag()
+}
+
+func (j *Job) getLag() (int64, error) {
 	srcSpec := &j.Src
 	rpc, err := j.factory.NewFeRpc(srcSpec)
 	if err != nil {
@@ -1664,6 +2071,8 @@ func (j *Job) GetLag() (int64, error) {
 	}
 
 	log.Debugf("resp: %v, lag: %d", resp, resp.GetLag())
+	xmetrics.SetLag(j.Name, resp.GetLag())
+	j.health.setLag(resp.GetLag())
 	return resp.GetLag(), nil
 }
 
@@ -1689,6 +2098,7 @@ func (j *Job) changeJobState(state JobState) error {
 		j.State = originState
 		return err
 	}
+	j.health.setState(state)
 	log.Debugf("change job %s state from %s to %s", j.Name, originState, state)
 	return nil
 }
@@ -1709,6 +2119,8 @@ type JobStatus struct {
 	Name          string `json:"name"`
 	State         string `json:"state"`
 	ProgressState string `json:"progress_state"`
+	// the backup or restore full sync is waiting for
+	FullSync *FullSyncWait `json:"full_sync,omitempty"`
 }
 
 func (j *Job) Status() *JobStatus {
@@ -1718,10 +2130,16 @@ func (j *Job) Status() *JobStatus {
 	state := j.State.String()
 	progress_state := j.progress.SyncState.String()
 
+	var fullSync *FullSyncWait
+	if j.progress.SubSyncState == WaitBackupDone || j.progress.SubSyncState == WaitRestoreDone {
+		fullSync = j.fullSyncWait
+	}
+
 	return &JobStatus{
 		Name:          j.Name,
 		State:         state,
 		ProgressState: progress_state,
+		FullSync:      fullSync,
 	}
 }
 
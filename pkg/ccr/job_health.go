@@ -0,0 +1,105 @@
+package ccr
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// JobHealthThresholds flags the jobs fall behind, zero means no limit
+type JobHealthThresholds struct {
+	MaxLag        int64
+	MaxNoProgress time.Duration
+}
+
+type JobHealth struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	SyncState string `json:"sync_state"`
+	// -1 means the lag is not known yet
+	Lag int64 `json:"lag"`
+	// seconds since the last progress, catching up with upstream is also a progress
+	SecondsSinceProgress int64    `json:"seconds_since_progress"`
+	Healthy              bool     `json:"healthy"`
+	Reasons              []string `json:"reasons,omitempty"`
+}
+
+// jobHealthStat is updated by the job goroutine and read by http service, so the health
+// of a job stuck in sync is reported without waiting for the job lock
+type jobHealthStat struct {
+	state        atomic.Int32 // JobState
+	syncState    atomic.Value // string, the sync state of progress, unset before progress is loaded
+	lag          atomic.Int64
+	lagUpdatedAt atomic.Int64 // unix milli, zero means lag is unknown
+	progressAt   atomic.Int64 // unix milli
+}
+
+func (s *jobHealthStat) setState(state JobState) {
+	s.state.Store(int32(state))
+}
+
+func (s *jobHealthStat) setSyncState(progress *JobProgress) {
+	if progress == nil {
+		return
+	}
+	s.syncState.Store(progress.SyncState.String())
+}
+
+func (s *jobHealthStat) setLag(lag int64) {
+	s.lag.Store(lag)
+	s.lagUpdatedAt.Store(time.Now().UnixMilli())
+}
+
+func (s *jobHealthStat) markProgress() {
+	s.progressAt.Store(time.Now().UnixMilli())
+}
+
+// progressPoint is used to tell whether a sync made progress
+type progressPoint struct {
+	syncState    SyncState
+	subSyncState SubSyncState
+	commitSeq    int64
+}
+
+func (j *Job) progressPoint() progressPoint {
+	if j.progress == nil {
+		return progressPoint{}
+	}
+	return progressPoint{
+		syncState:    j.progress.SyncState,
+		subSyncState: j.progress.SubSyncState,
+		commitSeq:    j.progress.CommitSeq,
+	}
+}
+
+func (j *Job) Health(thresholds *JobHealthThresholds) *JobHealth {
+	state := JobState(j.health.state.Load())
+	syncState, _ := j.health.syncState.Load().(string)
+
+	health := &JobHealth{
+		Name:      j.Name,
+		State:     state.String(),
+		SyncState: syncState,
+		Lag:       -1,
+		Healthy:   true,
+	}
+	if j.health.lagUpdatedAt.Load() != 0 {
+		health.Lag = j.health.lag.Load()
+	}
+	if progressAt := j.health.progressAt.Load(); progressAt != 0 {
+		health.SecondsSinceProgress = int64(time.Since(time.UnixMilli(progressAt)).Seconds())
+	}
+
+	// a paused job falls behind on purpose
+	if state != JobRunning {
+		return health
+	}
+	if thresholds.MaxLag > 0 && health.Lag > thresholds.MaxLag {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("lag %d exceeds %d", health.Lag, thresholds.MaxLag))
+	}
+	if noProgress := time.Duration(health.SecondsSinceProgress) * time.Second; thresholds.MaxNoProgress > 0 && noProgress > thresholds.MaxNoProgress {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("no progress for %s, exceeds %s", noProgress, thresholds.MaxNoProgress))
+	}
+	health.Healthy = len(health.Reasons) == 0
+	return health
+}
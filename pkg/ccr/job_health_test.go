@@ -0,0 +1,34 @@
+package ccr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobHealth_NotBlockedBySync(t *testing.T) {
+	job := &Job{Name: "test_job"}
+	job.health.setState(JobRunning)
+	job.health.setSyncState(&JobProgress{SyncState: DBIncrementalSync})
+	job.health.setLag(10)
+
+	// a stuck sync holds the job lock
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	done := make(chan *JobHealth)
+	go func() {
+		done <- job.Health(&JobHealthThresholds{MaxLag: 5})
+	}()
+
+	select {
+	case health := <-done:
+		assert.Equal(t, JobRunning.String(), health.State)
+		assert.Equal(t, DBIncrementalSync.String(), health.SyncState)
+		assert.Equal(t, int64(10), health.Lag)
+		assert.False(t, health.Healthy)
+	case <-time.After(time.Second):
+		t.Fatal("health is blocked by the job lock")
+	}
+}
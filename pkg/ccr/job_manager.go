@@ -3,7 +3,10 @@ package ccr
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
@@ -22,6 +25,9 @@ type JobManager struct {
 	hostInfo string
 	stop     chan struct{}
 	wg       sync.WaitGroup
+
+	// jobs of this syncer have been recovered from meta db
+	recovered atomic.Bool
 }
 
 func NewJobManager(db storage.DB, factory *Factory, hostInfo string) *JobManager {
@@ -93,6 +99,13 @@ func (jm *JobManager) Recover(jobNames []string) error {
 		} else if job, err := NewJobFromJson(jobInfo, jm.db, jm.factory); err != nil {
 			return err
 		} else {
+			// seal the credentials by the primary secret key if they are plain text or sealed by a rotated key
+			if job.Src.IsSecretStale() || job.Dest.IsSecretStale() {
+				log.Infof("reseal credentials of job: %s", jobName)
+				if err := job.persistJob(); err != nil {
+					log.Warnf("reseal credentials of job %s failed: %+v", jobName, err)
+				}
+			}
 			jobs = append(jobs, job)
 		}
 	}
@@ -101,9 +114,27 @@ func (jm *JobManager) Recover(jobNames []string) error {
 		jm.jobs[job.Name] = job
 		jm.runJob(job)
 	}
+	jm.recovered.Store(true)
 	return nil
 }
 
+func (jm *JobManager) Recovered() bool {
+	return jm.recovered.Load()
+}
+
+// JobHealths returns the health of all jobs in this syncer
+func (jm *JobManager) JobHealths(thresholds *JobHealthThresholds) []*JobHealth {
+	jm.lock.RLock()
+	defer jm.lock.RUnlock()
+
+	healths := make([]*JobHealth, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		healths = append(healths, job.Health(thresholds))
+	}
+	sort.Slice(healths, func(i, k int) bool { return healths[i].Name < healths[k].Name })
+	return healths
+}
+
 // remove job
 func (jm *JobManager) RemoveJob(name string) error {
 	log.Infof("remove job: %s", name)
@@ -121,6 +152,7 @@ func (jm *JobManager) RemoveJob(name string) error {
 	job.Delete()
 	if err := jm.db.RemoveJob(name); err == nil {
 		delete(jm.jobs, name)
+		xmetrics.RemoveJob(name)
 		log.Infof("job [%s] has been successfully deleted, but it needs to wait until an isochronous point before it will completely STOP", name)
 		return nil
 	} else {
@@ -248,3 +280,58 @@ func (jm *JobManager) UpdateJobSkipError(jobName string, skipError bool) error {
 		return xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
 	}
 }
+
+func (jm *JobManager) UpdateJobWaitTimeouts(jobName string, backupTimeout, restoreTimeout *time.Duration) error {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	if job, ok := jm.jobs[jobName]; ok {
+		return job.UpdateWaitTimeouts(backupTimeout, restoreTimeout)
+	} else {
+		return xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
+	}
+}
+
+// runningJobs returns the running jobs of this syncer
+func (jm *JobManager) runningJobs() []*Job {
+	jm.lock.RLock()
+	defer jm.lock.RUnlock()
+
+	jobs := make([]*Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		if job.getJobState() == JobRunning {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Name < jobs[k].Name })
+	return jobs
+}
+
+func (jm *JobManager) getJob(jobName string) (*Job, error) {
+	jm.lock.RLock()
+	defer jm.lock.RUnlock()
+
+	if job, ok := jm.jobs[jobName]; ok {
+		return job, nil
+	} else {
+		return nil, xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
+	}
+}
+
+// Verify compares src and dest of the job, the job manager is not locked while verifying
+func (jm *JobManager) Verify(jobName string, options *VerifyOptions) (*VerifyReport, error) {
+	job, err := jm.getJob(jobName)
+	if err != nil {
+		return nil, err
+	}
+	return job.Verify(options)
+}
+
+// GetVerifyReport returns the last verify report of the job, nil means it is never verified
+func (jm *JobManager) GetVerifyReport(jobName string) (*VerifyReport, error) {
+	job, err := jm.getJob(jobName)
+	if err != nil {
+		return nil, err
+	}
+	return job.LastVerifyReport(), nil
+}
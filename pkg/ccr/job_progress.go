@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
@@ -16,6 +17,9 @@ import (
 
 const (
 	UPDATE_JOB_PROGRESS_DURATION = time.Second * 3
+
+	// error message longer than it will be truncated in progress history
+	maxHistoryErrorMsgLength = 4096
 )
 
 type SyncState int
@@ -89,6 +93,9 @@ var (
 	AddExtraInfo        SubSyncState = SubSyncState{State: 2, BinlogType: BinlogNone}
 	RestoreSnapshot     SubSyncState = SubSyncState{State: 3, BinlogType: BinlogNone}
 	PersistRestoreInfo  SubSyncState = SubSyncState{State: 4, BinlogType: BinlogNone}
+	// the backup and restore are checked once per sync in these states, instead of blocking the job
+	WaitBackupDone  SubSyncState = SubSyncState{State: 5, BinlogType: BinlogNone}
+	WaitRestoreDone SubSyncState = SubSyncState{State: 6, BinlogType: BinlogNone}
 
 	BeginTransaction    SubSyncState = SubSyncState{State: 11, BinlogType: BinlogUpsert}
 	IngestBinlog        SubSyncState = SubSyncState{State: 12, BinlogType: BinlogUpsert}
@@ -114,6 +121,10 @@ func (s SubSyncState) String() string {
 		return "RestoreSnapshot"
 	case PersistRestoreInfo:
 		return "PersistRestoreInfo"
+	case WaitBackupDone:
+		return "WaitBackupDone"
+	case WaitRestoreDone:
+		return "WaitRestoreDone"
 	case BeginTransaction:
 		return "BeginTransaction"
 	case IngestBinlog:
@@ -142,6 +153,13 @@ type JobProgress struct {
 	TableCommitSeqMap map[int64]int64 `json:"table_commit_seq_map"` // only for DBTablesIncrementalSync
 	InMemoryData      any             `json:"-"`
 	PersistData       string          `json:"data"` // this often for binlog or snapshot info
+
+	// the src tables synced again by DBFullSync, empty means all tables
+	ResyncTables []string `json:"resync_tables,omitempty"`
+
+	// volatile, only for progress history
+	txnId       int64                    `json:"-"`
+	lastHistory *storage.ProgressHistory `json:"-"`
 }
 
 func (j *JobProgress) String() string {
@@ -272,6 +290,7 @@ func (j *JobProgress) Done() {
 	xmetrics.ConsumeBinlog(j.JobName, j.PrevCommitSeq)
 
 	j.Persist()
+	j.txnId = 0
 }
 
 func (j *JobProgress) Rollback(skipError bool) {
@@ -286,6 +305,7 @@ func (j *JobProgress) Rollback(skipError bool) {
 
 	xmetrics.Rollback(j.JobName, j.PrevCommitSeq)
 	j.Persist()
+	j.txnId = 0
 }
 
 // write progress to db, busy loop until success
@@ -314,5 +334,58 @@ func (j *JobProgress) Persist() {
 		break
 	}
 
+	j.recordHistory()
 	log.Trace("update job progress done")
 }
+
+// SetTxnId records the dest txn of the handling binlog, it only shows in progress history
+func (j *JobProgress) SetTxnId(txnId int64) {
+	j.txnId = txnId
+}
+
+func (j *JobProgress) newHistory(errMsg string) *storage.ProgressHistory {
+	if len(errMsg) > maxHistoryErrorMsgLength {
+		// cut at the rune boundary, so the message is still valid utf8
+		end := maxHistoryErrorMsgLength
+		for end > 0 && !utf8.RuneStart(errMsg[end]) {
+			end--
+		}
+		errMsg = errMsg[:end]
+	}
+
+	return &storage.ProgressHistory{
+		JobName:       j.JobName,
+		SyncState:     j.SyncState.String(),
+		SubSyncState:  j.SubSyncState.String(),
+		PrevCommitSeq: j.PrevCommitSeq,
+		CommitSeq:     j.CommitSeq,
+		TxnId:         j.txnId,
+		ErrorMsg:      errMsg,
+		CreatedAt:     time.Now().UnixMilli(),
+	}
+}
+
+// append to progress history, history is only for troubleshooting, so never block the sync
+func (j *JobProgress) appendHistory(history *storage.ProgressHistory) {
+	if err := j.db.AddProgressHistory(history); err != nil {
+		log.Warnf("add job progress history failed, job: %s, error: %+v", j.JobName, err)
+		return
+	}
+	j.lastHistory = history
+}
+
+// record the transition, persist without state change is skipped
+func (j *JobProgress) recordHistory() {
+	history := j.newHistory("")
+	if last := j.lastHistory; last != nil && last.ErrorMsg == "" &&
+		last.SyncState == history.SyncState && last.SubSyncState == history.SubSyncState &&
+		last.CommitSeq == history.CommitSeq && last.TxnId == history.TxnId {
+		return
+	}
+	j.appendHistory(history)
+}
+
+// RecordError appends the error with current state to progress history
+func (j *JobProgress) RecordError(err error) {
+	j.appendHistory(j.newHistory(err.Error()))
+}
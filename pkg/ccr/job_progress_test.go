@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	log "github.com/sirupsen/logrus"
@@ -134,3 +136,17 @@ func TestJobProgress_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestJobProgress_NewHistoryTruncate(t *testing.T) {
+	progress := NewJobProgress("test", DBSync, nil)
+
+	// the last rune crosses the length limit
+	errMsg := strings.Repeat("a", maxHistoryErrorMsgLength-1) + "错误"
+	history := progress.newHistory(errMsg)
+	if !utf8.ValidString(history.ErrorMsg) {
+		t.Errorf("truncated error msg is not valid utf8")
+	}
+	if history.ErrorMsg != strings.Repeat("a", maxHistoryErrorMsgLength-1) {
+		t.Errorf("error msg is not truncated at the rune boundary, length: %d", len(history.ErrorMsg))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
@@ -128,6 +129,13 @@ func newTestBinlog(binlogType festruct.TBinlogType, tableIds []int64) *festruct.
 	return binlog
 }
 
+// newTestMetaFactory returns the MetaFactory of the unreachable fe, so GetMeta is not probed by the tests
+func newTestMetaFactory(ctrl *gomock.Controller) MetaerFactory {
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(nil, xerror.New(xerror.RPC, "fe is unreachable")).AnyTimes()
+	return NewMetaFactory(rpcFactory)
+}
+
 func newMeta(spec *base.Spec, backends *map[int64]*base.Backend) *DatabaseMeta {
 	var tableIds []int64
 	if spec.Table == "" {
@@ -246,6 +254,37 @@ func newBackendMap(backendNum int) map[int64]*base.Backend {
 	return backendMap
 }
 
+func setReplicaVersion(dbMeta *DatabaseMeta, version int64) {
+	for _, tableMeta := range dbMeta.Tables {
+		for _, partitionMeta := range tableMeta.PartitionIdMap {
+			partitionMeta.VisibleVersion = version
+			for _, indexMeta := range partitionMeta.IndexIdMap {
+				indexMeta.ReplicaMetas.Scan(func(_ int64, replicaMeta *ReplicaMeta) bool {
+					replicaMeta.Version = version
+					return true
+				})
+			}
+		}
+	}
+}
+
+// fixedThriftMetaFactory returns the prepared thrift meta of the spec database
+type fixedThriftMetaFactory map[string]*ThriftMeta
+
+func (f fixedThriftMetaFactory) NewThriftMeta(spec *base.Spec, _ rpc.IRpcFactory, _ []int64) (*ThriftMeta, error) {
+	if thriftMeta, ok := f[spec.Database]; ok {
+		return thriftMeta, nil
+	}
+	return nil, xerror.Errorf(xerror.Meta, "thrift meta of db %s not found", spec.Database)
+}
+
+func newTestThriftMeta(spec *base.Spec, dbMeta *DatabaseMeta, backends map[int64]*base.Backend) *ThriftMeta {
+	meta := NewMeta(spec)
+	meta.DatabaseMeta = *dbMeta
+	meta.Backends = backends
+	return &ThriftMeta{meta: meta}
+}
+
 type UpsertContext struct {
 	context.Context
 	CommitSeq   int64
@@ -332,10 +371,12 @@ func TestHandleUpsertInTableSync(t *testing.T) {
 	backendMap := newBackendMap(3)
 	srcMeta := newMeta(&tblSrcSpec, &backendMap)
 	destMeta := newMeta(&tblDestSpec, &backendMap)
+	setReplicaVersion(srcMeta, version)
 
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).DoAndReturn(
 		func(_ string, progressJson string) error {
 			var jobProgress JobProgress
@@ -358,7 +399,11 @@ func TestHandleUpsertInTableSync(t *testing.T) {
 	// init factory
 	rpcFactory := NewMockIRpcFactory(ctrl)
 	metaFactory := NewMockMetaerFactory(ctrl)
-	factory := NewFactory(rpcFactory, metaFactory, base.NewSpecerFactory())
+	thriftMetaFactory := fixedThriftMetaFactory{
+		tblSrcSpec.Database:  newTestThriftMeta(&tblSrcSpec, srcMeta, backendMap),
+		tblDestSpec.Database: newTestThriftMeta(&tblDestSpec, destMeta, backendMap),
+	}
+	factory := NewFactory(rpcFactory, metaFactory, base.NewSpecerFactory(), thriftMetaFactory)
 
 	// init rpcFactory
 	rpcFactory.EXPECT().NewFeRpc(&tblDestSpec).DoAndReturn(func(_ *base.Spec) (rpc.IFeRpc, error) {
@@ -417,50 +462,12 @@ func TestHandleUpsertInTableSync(t *testing.T) {
 		return mockBeRpc, nil
 	}).Times(3)
 
-	// init metaFactory
-	metaFactory.EXPECT().NewMeta(&tblSrcSpec).DoAndReturn(func(_ *base.Spec) Metaer {
-		mockMeta := NewMockMetaer(ctrl)
-
-		mockMeta.EXPECT().GetBackendMap().Return(backendMap, nil)
-		mockMeta.EXPECT().GetPartitionRangeMap(tblSrcSpec.TableId).DoAndReturn(
-			func(tableId int64) (map[string]*PartitionMeta, error) {
-				return srcMeta.Tables[tableId].PartitionRangeMap, nil
-			})
-		mockMeta.EXPECT().GetIndexIdMap(tblSrcSpec.TableId, srcPartitionId).DoAndReturn(
-			func(tableId int64, partitionId int64) (map[int64]*IndexMeta, error) {
-				return srcMeta.Tables[tableId].PartitionIdMap[partitionId].IndexIdMap, nil
-			})
-		mockMeta.EXPECT().GetTablets(tblSrcSpec.TableId, srcPartitionId, srcIndexId).DoAndReturn(
-			func(tableId int64, partitionId int64, indexId int64) (*btree.Map[int64, *TabletMeta], error) {
-				return srcMeta.Tables[tableId].PartitionIdMap[partitionId].IndexIdMap[indexId].TabletMetas, nil
-			})
-
-		return mockMeta
-	})
-	metaFactory.EXPECT().NewMeta(&tblDestSpec).DoAndReturn(func(_ *base.Spec) Metaer {
-		mockMeta := NewMockMetaer(ctrl)
-		mockMeta.EXPECT().GetBackendMap().Return(backendMap, nil)
-		mockMeta.EXPECT().GetPartitionRangeMap(tblDestSpec.TableId).DoAndReturn(
-			func(tableId int64) (map[string]*PartitionMeta, error) {
-				return destMeta.Tables[tableId].PartitionRangeMap, nil
-			})
-		mockMeta.EXPECT().GetPartitionIdByRange(tblDestSpec.TableId, fmt.Sprint(destPartitionId)).DoAndReturn(
-			func(tableId int64, partitionRange string) (int64, error) {
-				return destMeta.Tables[tableId].PartitionRangeMap[partitionRange].Id, nil
-			})
-		mockMeta.EXPECT().GetIndexNameMap(tblDestSpec.TableId, destPartitionId).DoAndReturn(
-			func(tableId int64, partitionId int64) (map[string]*IndexMeta, error) {
-				return destMeta.Tables[tableId].PartitionIdMap[partitionId].IndexNameMap, nil
-			})
-		mockMeta.EXPECT().GetTablets(tblDestSpec.TableId, destPartitionId, destIndexId).DoAndReturn(
-			func(tableId int64, partitionId int64, indexId int64) (*btree.Map[int64, *TabletMeta], error) {
-				return destMeta.Tables[tableId].PartitionIdMap[partitionId].IndexIdMap[indexId].TabletMetas, nil
-			})
-		return mockMeta
-	})
+	// init metaFactory, ingest binlog reads the meta from thrift meta
+	metaFactory.EXPECT().NewMeta(&tblSrcSpec).Return(NewMockMetaer(ctrl))
+	metaFactory.EXPECT().NewMeta(&tblDestSpec).Return(NewMockMetaer(ctrl))
 
 	// init job
-	ctx := NewJobContext(tblSrcSpec, tblDestSpec, db, factory)
+	ctx := NewJobContext(tblSrcSpec, tblDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -513,10 +520,12 @@ func TestHandleUpsertInDbSync(t *testing.T) {
 	backendMap := newBackendMap(3)
 	srcMeta := newMeta(&dbSrcSpec, &backendMap)
 	destMeta := newMeta(&dbDestSpec, &backendMap)
+	setReplicaVersion(srcMeta, version)
 
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).DoAndReturn(
 		func(_ string, progressJson string) error {
 			var jobProgress JobProgress
@@ -539,7 +548,11 @@ func TestHandleUpsertInDbSync(t *testing.T) {
 	// init factory
 	rpcFactory := NewMockIRpcFactory(ctrl)
 	metaFactory := NewMockMetaerFactory(ctrl)
-	factory := NewFactory(rpcFactory, metaFactory, base.NewSpecerFactory())
+	thriftMetaFactory := fixedThriftMetaFactory{
+		dbSrcSpec.Database:  newTestThriftMeta(&dbSrcSpec, srcMeta, backendMap),
+		dbDestSpec.Database: newTestThriftMeta(&dbDestSpec, destMeta, backendMap),
+	}
+	factory := NewFactory(rpcFactory, metaFactory, base.NewSpecerFactory(), thriftMetaFactory)
 
 	// init rpcFactory
 	rpcFactory.EXPECT().NewFeRpc(&dbDestSpec).DoAndReturn(func(_ *base.Spec) (rpc.IFeRpc, error) {
@@ -598,52 +611,20 @@ func TestHandleUpsertInDbSync(t *testing.T) {
 		return mockBeRpc, nil
 	}).Times(3)
 
-	// init metaFactory
+	// init metaFactory, ingest binlog reads the meta from thrift meta
 	metaFactory.EXPECT().NewMeta(&dbSrcSpec).DoAndReturn(func(_ *base.Spec) Metaer {
 		mockMeta := NewMockMetaer(ctrl)
-
-		mockMeta.EXPECT().GetBackendMap().Return(backendMap, nil)
 		mockMeta.EXPECT().GetTableNameById(tableBaseId).Return(fmt.Sprint(tableBaseId), nil)
-		mockMeta.EXPECT().GetPartitionRangeMap(tableBaseId).DoAndReturn(
-			func(tableId int64) (map[string]*PartitionMeta, error) {
-				return srcMeta.Tables[tableId].PartitionRangeMap, nil
-			})
-		mockMeta.EXPECT().GetIndexIdMap(tableBaseId, srcPartitionId).DoAndReturn(
-			func(tableId int64, partitionId int64) (map[int64]*IndexMeta, error) {
-				return srcMeta.Tables[tableId].PartitionIdMap[partitionId].IndexIdMap, nil
-			})
-		mockMeta.EXPECT().GetTablets(tableBaseId, srcPartitionId, srcIndexId).DoAndReturn(
-			func(tableId int64, partitionId int64, indexId int64) (*btree.Map[int64, *TabletMeta], error) {
-				return srcMeta.Tables[tableId].PartitionIdMap[partitionId].IndexIdMap[indexId].TabletMetas, nil
-			})
-
 		return mockMeta
 	})
 	metaFactory.EXPECT().NewMeta(&dbDestSpec).DoAndReturn(func(_ *base.Spec) Metaer {
 		mockMeta := NewMockMetaer(ctrl)
-		mockMeta.EXPECT().GetBackendMap().Return(backendMap, nil)
 		mockMeta.EXPECT().GetTableId(fmt.Sprint(tableBaseId)).Return(tableBaseId, nil)
-		mockMeta.EXPECT().GetPartitionRangeMap(tblDestSpec.TableId).DoAndReturn(
-			func(tableId int64) (map[string]*PartitionMeta, error) {
-				return destMeta.Tables[tableId].PartitionRangeMap, nil
-			})
-		mockMeta.EXPECT().GetPartitionIdByRange(tblDestSpec.TableId, fmt.Sprint(destPartitionId)).DoAndReturn(
-			func(tableId int64, partitionRange string) (int64, error) {
-				return destMeta.Tables[tableId].PartitionRangeMap[partitionRange].Id, nil
-			})
-		mockMeta.EXPECT().GetIndexNameMap(tblDestSpec.TableId, destPartitionId).DoAndReturn(
-			func(tableId int64, partitionId int64) (map[string]*IndexMeta, error) {
-				return destMeta.Tables[tableId].PartitionIdMap[partitionId].IndexNameMap, nil
-			})
-		mockMeta.EXPECT().GetTablets(tblDestSpec.TableId, destPartitionId, destIndexId).DoAndReturn(
-			func(tableId int64, partitionId int64, indexId int64) (*btree.Map[int64, *TabletMeta], error) {
-				return destMeta.Tables[tableId].PartitionIdMap[partitionId].IndexIdMap[indexId].TabletMetas, nil
-			})
 		return mockMeta
 	})
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -688,10 +669,12 @@ func TestHandleAddPartitionInTableSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 
 	// init factory
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), NewMetaFactory(), iSpecFactory)
+	rpcFactory := rpc.NewRpcFactory()
+	factory := NewFactory(rpcFactory, newTestMetaFactory(ctrl), iSpecFactory, DefaultThriftMetaFactory)
 
 	// init iSpecFactory
 	iSpecFactory.EXPECT().NewSpecer(&tblSrcSpec).DoAndReturn(func(_ *base.Spec) base.Specer {
@@ -701,14 +684,15 @@ func TestHandleAddPartitionInTableSync(t *testing.T) {
 	})
 	iSpecFactory.EXPECT().NewSpecer(&tblDestSpec).DoAndReturn(func(_ *base.Spec) base.Specer {
 		mockISpec := NewMockSpecer(ctrl)
-		fullSql := fmt.Sprintf("ALTER TABLE %s.%s %s", tblDestSpec.Database, tblDestSpec.Table, testSql)
-		mockISpec.EXPECT().Exec(fullSql).Return(nil)
+		// the distribution is appended if the sql doesn't contain it
+		fullSql := fmt.Sprintf("ALTER TABLE %s %s DISTRIBUTED BY RANDOM BUCKETS 0", tblDestSpec.Table, strings.TrimRight(testSql, ";"))
+		mockISpec.EXPECT().DbExec(fullSql).Return(nil)
 		mockISpec.EXPECT().Valid().Return(nil)
 		return mockISpec
 	})
 
 	// init job
-	ctx := NewJobContext(tblSrcSpec, tblDestSpec, db, factory)
+	ctx := NewJobContext(tblSrcSpec, tblDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -744,16 +728,22 @@ func TestHandleAddPartitionInDbSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 
 	// init factory
 	metaFactory := NewMockMetaerFactory(ctrl)
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory)
+	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory, DefaultThriftMetaFactory)
 
 	// init metaFactory
-	metaFactory.EXPECT().NewMeta(&dbSrcSpec).Return(NewMockMetaer(ctrl))
+	metaFactory.EXPECT().NewMeta(&dbSrcSpec).DoAndReturn(func(_ *base.Spec) Metaer {
+		mockMeta := NewMockMetaer(ctrl)
+		mockMeta.EXPECT().GetTableNameById(tableBaseId).Return(fmt.Sprint(tableBaseId), nil)
+		return mockMeta
+	})
 	metaFactory.EXPECT().NewMeta(&dbDestSpec).DoAndReturn(func(_ *base.Spec) Metaer {
 		mockMeta := NewMockMetaer(ctrl)
+		mockMeta.EXPECT().GetTableId(fmt.Sprint(tableBaseId)).Return(tableBaseId, nil)
 		mockMeta.EXPECT().GetTableNameById(tableBaseId).Return(fmt.Sprint(tableBaseId), nil)
 		return mockMeta
 	})
@@ -766,18 +756,19 @@ func TestHandleAddPartitionInDbSync(t *testing.T) {
 	})
 	iSpecFactory.EXPECT().NewSpecer(&dbDestSpec).DoAndReturn(func(_ *base.Spec) base.Specer {
 		mockISpec := NewMockSpecer(ctrl)
-		fullSql := fmt.Sprintf("ALTER TABLE %s.%s %s", dbDestSpec.Database, fmt.Sprint(tableBaseId), testSql)
-		mockISpec.EXPECT().Exec(fullSql).Return(nil)
+		fullSql := fmt.Sprintf("ALTER TABLE %s %s DISTRIBUTED BY RANDOM BUCKETS 0", fmt.Sprint(tableBaseId), strings.TrimRight(testSql, ";"))
+		mockISpec.EXPECT().DbExec(fullSql).Return(nil)
 		mockISpec.EXPECT().Valid().Return(nil)
 		return mockISpec
 	})
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
 	}
+	job.progress = NewJobProgress(job.Name, job.SyncType, job.db)
 
 	// init binlog
 	tableIds := make([]int64, 0, 1)
@@ -809,10 +800,12 @@ func TestHandleDropPartitionInTableSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 
 	// init factory
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), NewMetaFactory(), iSpecFactory)
+	rpcFactory := rpc.NewRpcFactory()
+	factory := NewFactory(rpcFactory, newTestMetaFactory(ctrl), iSpecFactory, DefaultThriftMetaFactory)
 
 	// init iSpecFactory
 	iSpecFactory.EXPECT().NewSpecer(&tblSrcSpec).DoAndReturn(func(_ *base.Spec) base.Specer {
@@ -829,7 +822,7 @@ func TestHandleDropPartitionInTableSync(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(tblSrcSpec, tblDestSpec, db, factory)
+	ctx := NewJobContext(tblSrcSpec, tblDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -865,16 +858,22 @@ func TestHandleDropPartitionInDbSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 
 	// init factory
 	metaFactory := NewMockMetaerFactory(ctrl)
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory)
+	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory, DefaultThriftMetaFactory)
 
 	// init metaFactory
-	metaFactory.EXPECT().NewMeta(&dbSrcSpec).Return(NewMockMetaer(ctrl))
+	metaFactory.EXPECT().NewMeta(&dbSrcSpec).DoAndReturn(func(_ *base.Spec) Metaer {
+		mockMeta := NewMockMetaer(ctrl)
+		mockMeta.EXPECT().GetTableNameById(tableBaseId).Return(fmt.Sprint(tableBaseId), nil)
+		return mockMeta
+	})
 	metaFactory.EXPECT().NewMeta(&dbDestSpec).DoAndReturn(func(_ *base.Spec) Metaer {
 		mockMeta := NewMockMetaer(ctrl)
+		mockMeta.EXPECT().GetTableId(fmt.Sprint(tableBaseId)).Return(tableBaseId, nil)
 		mockMeta.EXPECT().GetTableNameById(tableBaseId).Return(fmt.Sprint(tableBaseId), nil)
 		return mockMeta
 	})
@@ -894,11 +893,12 @@ func TestHandleDropPartitionInDbSync(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
 	}
+	job.progress = NewJobProgress(job.Name, job.SyncType, job.db)
 
 	// init binlog
 	tableIds := make([]int64, 0, 1)
@@ -915,7 +915,7 @@ func TestHandleDropPartitionInDbSync(t *testing.T) {
 	}
 
 	// test begin
-	if err := job.handleAddPartition(binlog); err != nil {
+	if err := job.handleDropPartition(binlog); err != nil {
 		t.Error(err)
 	}
 }
@@ -930,12 +930,13 @@ func TestHandleCreateTable(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).Return(nil)
 
 	// init factory
 	metaFactory := NewMockMetaerFactory(ctrl)
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory)
+	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory, DefaultThriftMetaFactory)
 
 	// init metaFactory
 	metaFactory.EXPECT().NewMeta(&dbSrcSpec).DoAndReturn(func(_ *base.Spec) Metaer {
@@ -965,7 +966,7 @@ func TestHandleCreateTable(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	assert.Nil(t, err)
 
@@ -1003,11 +1004,12 @@ func TestHandleDropTable(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 
 	// init factory
 	metaFactory := NewMockMetaerFactory(ctrl)
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory)
+	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, iSpecFactory, DefaultThriftMetaFactory)
 
 	// init metaFactory
 	metaFactory.EXPECT().NewMeta(&dbSrcSpec).DoAndReturn(func(_ *base.Spec) Metaer {
@@ -1036,11 +1038,12 @@ func TestHandleDropTable(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
 	}
+	job.progress = NewJobProgress(job.Name, job.SyncType, job.db)
 
 	// init binlog
 	tableIds := make([]int64, 0, 1)
@@ -1074,6 +1077,7 @@ func TestHandleDummyInTableSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).DoAndReturn(
 		func(_ string, progressJson string) error {
 			var jobProgress JobProgress
@@ -1090,10 +1094,11 @@ func TestHandleDummyInTableSync(t *testing.T) {
 		})
 
 	// init factory
-	factory := NewFactory(rpc.NewRpcFactory(), NewMetaFactory(), base.NewSpecerFactory())
+	rpcFactory := rpc.NewRpcFactory()
+	factory := NewFactory(rpcFactory, newTestMetaFactory(ctrl), base.NewSpecerFactory(), DefaultThriftMetaFactory)
 
 	// init job
-	ctx := NewJobContext(tblSrcSpec, tblDestSpec, db, factory)
+	ctx := NewJobContext(tblSrcSpec, tblDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -1120,6 +1125,7 @@ func TestHandleDummyInDbSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).DoAndReturn(
 		func(_ string, progressJson string) error {
 			var jobProgress JobProgress
@@ -1136,10 +1142,11 @@ func TestHandleDummyInDbSync(t *testing.T) {
 		})
 
 	// init factory
-	factory := NewFactory(rpc.NewRpcFactory(), NewMetaFactory(), base.NewSpecerFactory())
+	rpcFactory := rpc.NewRpcFactory()
+	factory := NewFactory(rpcFactory, newTestMetaFactory(ctrl), base.NewSpecerFactory(), DefaultThriftMetaFactory)
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -1170,6 +1177,7 @@ func TestHandleAlterJobInTableSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).DoAndReturn(
 		func(_ string, progressJson string) error {
 			var jobProgress JobProgress
@@ -1187,7 +1195,7 @@ func TestHandleAlterJobInTableSync(t *testing.T) {
 
 	// init factory
 	metaFactory := NewMockMetaerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, base.NewSpecerFactory())
+	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, base.NewSpecerFactory(), DefaultThriftMetaFactory)
 
 	// init metaFactory
 	metaFactory.EXPECT().NewMeta(&tblSrcSpec).Return(NewMockMetaer(ctrl))
@@ -1199,7 +1207,7 @@ func TestHandleAlterJobInTableSync(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(tblSrcSpec, tblDestSpec, db, factory)
+	ctx := NewJobContext(tblSrcSpec, tblDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -1244,6 +1252,7 @@ func TestHandleAlterJobInDbSync(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 	db.EXPECT().UpdateProgress("Test", gomock.Any()).DoAndReturn(
 		func(_ string, progressJson string) error {
 			var jobProgress JobProgress
@@ -1261,7 +1270,7 @@ func TestHandleAlterJobInDbSync(t *testing.T) {
 
 	// init factory
 	metaFactory := NewMockMetaerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, base.NewSpecerFactory())
+	factory := NewFactory(rpc.NewRpcFactory(), metaFactory, base.NewSpecerFactory(), DefaultThriftMetaFactory)
 
 	// init metaFactory
 	metaFactory.EXPECT().NewMeta(&dbSrcSpec).Return(NewMockMetaer(ctrl))
@@ -1273,7 +1282,7 @@ func TestHandleAlterJobInDbSync(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(dbSrcSpec, dbDestSpec, db, factory)
+	ctx := NewJobContext(dbSrcSpec, dbDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -1314,10 +1323,12 @@ func TestHandleLightningSchemaChange(t *testing.T) {
 	// init db_mock
 	db := test_util.NewMockDB(ctrl)
 	db.EXPECT().IsJobExist("Test").Return(false, nil)
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
 
 	// init factory
 	iSpecFactory := NewMockSpecerFactory(ctrl)
-	factory := NewFactory(rpc.NewRpcFactory(), NewMetaFactory(), iSpecFactory)
+	rpcFactory := rpc.NewRpcFactory()
+	factory := NewFactory(rpcFactory, newTestMetaFactory(ctrl), iSpecFactory, DefaultThriftMetaFactory)
 
 	// init iSpecFactory
 	iSpecFactory.EXPECT().NewSpecer(&tblSrcSpec).DoAndReturn(func(_ *base.Spec) base.Specer {
@@ -1334,7 +1345,7 @@ func TestHandleLightningSchemaChange(t *testing.T) {
 	})
 
 	// init job
-	ctx := NewJobContext(tblSrcSpec, tblDestSpec, db, factory)
+	ctx := NewJobContext(tblSrcSpec, tblDestSpec, false, db, factory)
 	job, err := NewJobFromService("Test", ctx)
 	if err != nil {
 		t.Error(err)
@@ -1359,3 +1370,16 @@ func TestHandleLightningSchemaChange(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestIsTokenErr(t *testing.T) {
+	restoreErr := xerror.XWrapf(base.ErrBackupRestoreCancelled, "snapshot: %s, status: %s", "ccrs_1", "[CANCELLED] download snapshot failed, Invalid token.")
+	assert.True(t, isTokenErr(restoreErr))
+
+	// the failed ingest has the token in its request, it is not a token error
+	ingestErr := xerror.Errorf(xerror.BE, "ingest error, req %v, resp status code: %v, msg: %v",
+		"IngestBinlogRequest({RemoteToken:src_token})", status.TStatusCode_TIMEOUT, []string{"ingest timeout"})
+	assert.False(t, isTokenErr(ingestErr))
+	ingestErr = xerror.Errorf(xerror.BE, "ingest error, req %v, resp status code: %v, msg: %v",
+		"IngestBinlogRequest({RemoteToken:src_token})", status.TStatusCode_RUNTIME_ERROR, []string{"download binlog failed, invalid token"})
+	assert.True(t, isTokenErr(ingestErr))
+}
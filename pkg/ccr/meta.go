@@ -2,10 +2,12 @@ package ccr
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	"github.com/selectdb/ccr_syncer/pkg/rpc"
@@ -21,6 +23,9 @@ const (
 	degree = 128
 
 	showErrMsg = "show proc '/dbs/' failed"
+
+	// the master token changes after the master restarts or the token is rotated, so it is fetched again after ttl
+	MASTER_TOKEN_TTL = 30 * time.Minute
 )
 
 // All Update* functions force to update meta from fe
@@ -36,10 +41,16 @@ type Meta struct {
 	*base.Spec
 	DatabaseMeta
 	token                 string
+	tokenUpdatedAt        time.Time
 	Backends              map[int64]*base.Backend // backendId -> backend
 	DatabaseName2IdMap    map[string]int64
 	TableName2IdMap       map[string]int64
 	BackendHostPort2IdMap map[string]int64
+	// tableId -> the time the table is got, the table is got again after the ttl of meta cache
+	tableUpdatedAt map[int64]time.Time
+	// the metas are got by thrift GetMeta, or by show proc if rpcFactory is nil or the fe does not support it
+	rpcFactory         rpc.IRpcFactory
+	getMetaUnsupported bool
 }
 
 func NewMeta(spec *base.Spec) *Meta {
@@ -52,6 +63,7 @@ func NewMeta(spec *base.Spec) *Meta {
 		DatabaseName2IdMap:    make(map[string]int64),
 		TableName2IdMap:       make(map[string]int64),
 		BackendHostPort2IdMap: make(map[string]int64),
+		tableUpdatedAt:        make(map[int64]time.Time),
 	}
 }
 
@@ -62,6 +74,12 @@ func (m *Meta) GetDbId() (int64, error) {
 		return dbId, nil
 	}
 
+	if m.isGetMetaSupported() {
+		if dbId, err := m.getDbIdByThrift(); !errors.Is(err, errGetMetaUnsupported) {
+			return dbId, err
+		}
+	}
+
 	dbFullName := "default_cluster:" + dbName
 	// mysql> show proc '/dbs/';
 	// +-------+------------------------------------+----------+----------+-------------+--------------------------+--------------+--------------+------------------+
@@ -124,6 +142,12 @@ func (m *Meta) GetFullTableName(tableName string) string {
 func (m *Meta) UpdateTable(tableName string, tableId int64) (*TableMeta, error) {
 	log.Infof("UpdateTable tableName: %s, tableId: %d", tableName, tableId)
 
+	if m.isGetMetaSupported() {
+		if tableMeta, err := m.updateTableByThrift(tableName, tableId); !errors.Is(err, errGetMetaUnsupported) {
+			return tableMeta, err
+		}
+	}
+
 	dbId, err := m.GetDbId()
 	if err != nil {
 		return nil, err
@@ -176,6 +200,7 @@ func (m *Meta) UpdateTable(tableName string, tableId int64) (*TableMeta, error)
 				PartitionIdMap: make(map[int64]*PartitionMeta),
 			}
 			m.Tables[parsedTableId] = tableMeta
+			m.tableUpdatedAt[parsedTableId] = time.Now()
 			return tableMeta, nil
 		}
 	}
@@ -190,7 +215,7 @@ func (m *Meta) UpdateTable(tableName string, tableId int64) (*TableMeta, error)
 
 func (m *Meta) GetTable(tableId int64) (*TableMeta, error) {
 	tableMeta, ok := m.Tables[tableId]
-	if !ok {
+	if !ok || metaExpired(m.tableUpdatedAt[tableId]) {
 		var err error
 		if tableMeta, err = m.UpdateTable("", tableId); err != nil {
 			return nil, err
@@ -201,7 +226,7 @@ func (m *Meta) GetTable(tableId int64) (*TableMeta, error) {
 
 func (m *Meta) GetTableId(tableName string) (int64, error) {
 	fullTableName := m.GetFullTableName(tableName)
-	if tableId, ok := m.TableName2IdMap[fullTableName]; ok {
+	if tableId, ok := m.TableName2IdMap[fullTableName]; ok && !metaExpired(m.tableUpdatedAt[tableId]) {
 		return tableId, nil
 	}
 
@@ -213,14 +238,20 @@ func (m *Meta) GetTableId(tableName string) (int64, error) {
 }
 
 func (m *Meta) UpdatePartitions(tableId int64) error {
-	// Step 1: get dbId
-	dbId, err := m.GetDbId()
+	// Step 1: get tableId
+	table, err := m.GetTable(tableId)
 	if err != nil {
 		return err
 	}
 
-	// Step 2: get tableId
-	table, err := m.GetTable(tableId)
+	if m.isGetMetaSupported() {
+		if err := m.updatePartitionsByThrift(table); !errors.Is(err, errGetMetaUnsupported) {
+			return err
+		}
+	}
+
+	// Step 2: get dbId
+	dbId, err := m.GetDbId()
 	if err != nil {
 		return err
 	}
@@ -265,12 +296,17 @@ func (m *Meta) UpdatePartitions(tableId int64) error {
 		if err != nil {
 			return xerror.Wrapf(err, xerror.Normal, query)
 		}
+		visibleVersion, err := rowParser.GetInt64("VisibleVersion")
+		if err != nil {
+			return xerror.Wrapf(err, xerror.Normal, query)
+		}
 		log.Debugf("partitionId: %d, partitionName: %s", partitionId, partitionName)
 		partition := &PartitionMeta{
-			TableMeta: table,
-			Id:        partitionId,
-			Name:      partitionName,
-			Range:     partitionRange,
+			TableMeta:      table,
+			Id:             partitionId,
+			Name:           partitionName,
+			Range:          partitionRange,
+			VisibleVersion: visibleVersion,
 		}
 		partitions = append(partitions, partition)
 	}
@@ -503,6 +539,14 @@ func (m *Meta) UpdateBackends() error {
 		backends = append(backends, &backend)
 	}
 
+	if len(m.Backends) > 0 && backendsChanged(m.Backends, backends) {
+		frontend := m.CurrentFrontend()
+		log.Infof("backends of %s:%s changed, %v => %v", frontend.Host, frontend.Port, m.Backends, backends)
+	}
+
+	// rebuild the maps, the removed backends and the old addresses are dropped
+	m.Backends = make(map[int64]*base.Backend, len(backends))
+	m.BackendHostPort2IdMap = make(map[string]int64, len(backends))
 	for _, backend := range backends {
 		m.Backends[backend.Id] = backend
 
@@ -513,7 +557,30 @@ func (m *Meta) UpdateBackends() error {
 	return nil
 }
 
+// backendsChanged returns true if any backend is added, removed, or its host or ports are changed
+func backendsChanged(cached map[int64]*base.Backend, backends []*base.Backend) bool {
+	if len(cached) != len(backends) {
+		return true
+	}
+	for _, backend := range backends {
+		if cachedBackend, ok := cached[backend.Id]; !ok || *cachedBackend != *backend {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFrontends fails over to the other frontends, so the new master can be found after the current one is down
 func (m *Meta) GetFrontends() ([]*base.Frontend, error) {
+	var frontends []*base.Frontend
+	err := m.WithFailover(func() (err error) {
+		frontends, err = m.getFrontends()
+		return err
+	})
+	return frontends, err
+}
+
+func (m *Meta) getFrontends() ([]*base.Frontend, error) {
 	db, err := m.Connect()
 	if err != nil {
 		return nil, err
@@ -610,19 +677,13 @@ func (m *Meta) GetBackendId(host string, portStr string) (int64, error) {
 
 func (m *Meta) UpdateIndexes(tableId int64, partitionId int64) error {
 	// TODO: Optimize performance
-	// Step 1: get dbId
-	dbId, err := m.GetDbId()
-	if err != nil {
-		return err
-	}
-
-	// Step 2: get tableId
+	// Step 1: get tableId
 	table, err := m.GetTable(tableId)
 	if err != nil {
 		return err
 	}
 
-	// Step 3: get partitions
+	// Step 2: get partitions
 	partitions, err := m.GetPartitionIdMap(tableId)
 	if err != nil {
 		return err
@@ -633,6 +694,18 @@ func (m *Meta) UpdateIndexes(tableId int64, partitionId int64) error {
 		return xerror.Errorf(xerror.Normal, "partitionId: %d not found", partitionId)
 	}
 
+	if m.isGetMetaSupported() {
+		if err := m.updateIndexesByThrift(partition); !errors.Is(err, errGetMetaUnsupported) {
+			return err
+		}
+	}
+
+	// Step 3: get dbId
+	dbId, err := m.GetDbId()
+	if err != nil {
+		return err
+	}
+
 	// mysql> show proc '/dbs/10116/10118/partitions/10117';
 	// +---------+---------------+--------+--------------------------+
 	// | IndexId | IndexName     | State  | LastConsistencyCheckTime |
@@ -733,6 +806,16 @@ func (m *Meta) GetIndexNameMap(tableId int64, partitionId int64) (map[string]*In
 }
 
 func (m *Meta) updateReplica(index *IndexMeta) error {
+	if m.isGetMetaSupported() {
+		// the replicas of all indexes of the partition are got, the cached indexes are updated in place
+		if err := m.updateIndexesByThrift(index.PartitionMeta); !errors.Is(err, errGetMetaUnsupported) {
+			if err == nil && index.PartitionMeta.IndexIdMap[index.Id] != index {
+				return xerror.Errorf(xerror.Normal, "index %d not found", index.Id)
+			}
+			return err
+		}
+	}
+
 	indexId := index.Id
 	partitionId := index.PartitionMeta.Id
 	tableId := index.PartitionMeta.TableMeta.Id
@@ -824,6 +907,10 @@ func (m *Meta) UpdateReplicas(tableId int64, partitionId int64) error {
 		if err := m.updateReplica(index); err != nil {
 			return err
 		}
+		// GetMeta gets the replicas of all indexes at once
+		if m.isGetMetaSupported() {
+			break
+		}
 	}
 
 	return nil
@@ -905,12 +992,13 @@ func (m *Meta) UpdateToken(rpcFactory rpc.IRpcFactory) error {
 		return xerror.Errorf(xerror.Meta, "get master token failed, status: %s", resp.GetStatus().String())
 	} else {
 		m.token = resp.GetToken()
+		m.tokenUpdatedAt = time.Now()
 		return nil
 	}
 }
 
 func (m *Meta) GetMasterToken(rpcFactory rpc.IRpcFactory) (string, error) {
-	if m.token != "" {
+	if m.token != "" && time.Since(m.tokenUpdatedAt) < MASTER_TOKEN_TTL {
 		return m.token, nil
 	}
 
@@ -963,6 +1051,12 @@ func (m *Meta) GetTableNameById(tableId int64) (string, error) {
 
 // this method not called frequently, so it behaves like update, get and cache it every time
 func (m *Meta) GetTables() (map[int64]*TableMeta, error) {
+	if m.isGetMetaSupported() {
+		if tables, err := m.getTablesByThrift(); !errors.Is(err, errGetMetaUnsupported) {
+			return tables, err
+		}
+	}
+
 	dbId, err := m.GetDbId()
 	if err != nil {
 		return nil, err
@@ -989,6 +1083,7 @@ func (m *Meta) GetTables() (map[int64]*TableMeta, error) {
 
 	tableName2IdMap := make(map[string]int64)
 	tables := make(map[int64]*TableMeta) // tableId -> table
+	tableUpdatedAt := make(map[int64]time.Time)
 	defer rows.Close()
 	for rows.Next() {
 		rowParser := utils.NewRowParser()
@@ -1015,6 +1110,7 @@ func (m *Meta) GetTables() (map[int64]*TableMeta, error) {
 			Name:           tableName,
 			PartitionIdMap: make(map[int64]*PartitionMeta),
 		}
+		tableUpdatedAt[tableId] = time.Now()
 	}
 
 	if err := rows.Err(); err != nil {
@@ -1023,6 +1119,7 @@ func (m *Meta) GetTables() (map[int64]*TableMeta, error) {
 
 	m.TableName2IdMap = tableName2IdMap
 	m.Tables = tables
+	m.tableUpdatedAt = tableUpdatedAt
 	return tables, nil
 }
 
@@ -1031,8 +1128,9 @@ func (m *Meta) CheckBinlogFeature() error {
 	if binlogIsEnabled, err := m.isFEBinlogFeature(); err != nil {
 		return err
 	} else if !binlogIsEnabled {
+		frontend := m.CurrentFrontend()
 		return xerror.Errorf(xerror.Normal, "Fe %v:%v enable_feature_binlog=false, please set it true in fe.conf",
-			m.Spec.Host, m.Spec.Port)
+			frontend.Host, frontend.Port)
 	}
 
 	// Step 2: get be binlog feature
@@ -1123,6 +1221,7 @@ func (m *Meta) ClearDB(dbName string) {
 	// clear DatabaseMeta
 	m.DbId = 0
 	m.Tables = make(map[int64]*TableMeta)
+	m.tableUpdatedAt = make(map[int64]time.Time)
 
 	m.DatabaseName2IdMap = make(map[string]int64)
 	m.TableName2IdMap = make(map[string]int64)
@@ -1134,7 +1233,8 @@ func (m *Meta) ClearTable(dbName string, tableName string) {
 		return
 	}
 
-	tableId, ok := m.TableName2IdMap[tableName]
+	fullTableName := m.GetFullTableName(tableName)
+	tableId, ok := m.TableName2IdMap[fullTableName]
 	if !ok {
 		log.Infof("table %s not found, skip clear", tableName)
 		return
@@ -1142,6 +1242,20 @@ func (m *Meta) ClearTable(dbName string, tableName string) {
 
 	// remove TableMeta in DatabaseMeta
 	delete(m.Tables, tableId)
+	delete(m.tableUpdatedAt, tableId)
+
+	delete(m.TableName2IdMap, fullTableName)
+}
 
-	delete(m.TableName2IdMap, tableName)
+// InvalidateTables drops the tables changed by ddl, they are got again when they are used next time
+func (m *Meta) InvalidateTables(tableIds ...int64) {
+	for _, tableId := range tableIds {
+		delete(m.Tables, tableId)
+		delete(m.tableUpdatedAt, tableId)
+	}
+	for fullTableName, tableId := range m.TableName2IdMap {
+		if containsId(tableIds, tableId) {
+			delete(m.TableName2IdMap, fullTableName)
+		}
+	}
 }
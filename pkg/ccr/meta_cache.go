@@ -0,0 +1,275 @@
+package ccr
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the cached metas are got again after ttl, even if no ddl binlog invalidates them, e.g. tablets are balanced
+	META_CACHE_TTL = 10 * time.Minute
+
+	metaCacheSrc  = "src"
+	metaCacheDest = "dest"
+)
+
+// metaCacheTTL is the ttl of the cached metas of all jobs, it can be reloaded at runtime
+var metaCacheTTL atomic.Int64
+
+func init() {
+	metaCacheTTL.Store(int64(META_CACHE_TTL))
+}
+
+// SetMetaCacheTTL changes the ttl of the cached metas of all jobs, the metas already cached use it too
+func SetMetaCacheTTL(ttl time.Duration) {
+	metaCacheTTL.Store(int64(ttl))
+}
+
+func metaExpired(fetchedAt time.Time) bool {
+	return time.Since(fetchedAt) >= time.Duration(metaCacheTTL.Load())
+}
+
+// binlogVersions is the versions of the partitions in the binlog to ingest, partitionId -> version
+type binlogVersions map[int64]int64
+
+// MetaCache caches the table and backend metas got by GetMeta of one cluster of the job, they are used by
+// all upserts instead of getting them for each upsert.
+//
+// A table is got again when it is expired, invalidated by a ddl binlog or a failed ingest, or a partition
+// to ingest is missing or older than the version of the binlog, only the stale partitions are got in the
+// last case. The cached table metas are never changed, a refreshed table replaces the old one, so the
+// ThriftMeta got before keeps using the old one.
+type MetaCache struct {
+	jobName string
+	cluster string
+	spec    *base.Spec
+	factory *Factory
+
+	lock              sync.Mutex
+	db                DatabaseMeta
+	fetchedAt         map[int64]time.Time // tableId -> the time the table is got
+	backends          map[int64]*base.Backend
+	backendsFetchedAt time.Time
+}
+
+func NewMetaCache(jobName string, cluster string, spec *base.Spec, factory *Factory) *MetaCache {
+	return &MetaCache{
+		jobName: jobName,
+		cluster: cluster,
+		spec:    spec,
+		factory: factory,
+		db: DatabaseMeta{
+			Tables: make(map[int64]*TableMeta),
+		},
+		fetchedAt: make(map[int64]time.Time),
+	}
+}
+
+// ThriftMeta returns the metas of the tables to ingest, tables is tableId -> the versions of the partitions
+// to ingest, the versions are nil if they are not checked, e.g. the tables of dest
+func (c *MetaCache) ThriftMeta(tables map[int64]binlogVersions) (*ThriftMeta, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Step 1: get the missing and expired tables, with the backends
+	missingTableIds := make([]int64, 0)
+	for tableId := range tables {
+		if _, ok := c.db.Tables[tableId]; !ok || metaExpired(c.fetchedAt[tableId]) {
+			missingTableIds = append(missingTableIds, tableId)
+		}
+	}
+	if len(missingTableIds) > 0 {
+		if err := c.refreshTables(missingTableIds); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 2: get the stale partitions of the cached tables
+	for tableId, versions := range tables {
+		if containsId(missingTableIds, tableId) {
+			continue
+		}
+
+		stalePartitionIds := make([]int64, 0)
+		tableMeta := c.db.Tables[tableId]
+		for partitionId, version := range versions {
+			if partitionMeta, ok := tableMeta.PartitionIdMap[partitionId]; !ok || partitionMeta.VisibleVersion < version {
+				stalePartitionIds = append(stalePartitionIds, partitionId)
+			}
+		}
+		if len(stalePartitionIds) == 0 {
+			xmetrics.MetaCacheHit(c.jobName, c.cluster)
+			continue
+		}
+		if err := c.refreshPartitions(tableMeta, stalePartitionIds); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 3: get the expired backends, unless they are got with the tables
+	if len(missingTableIds) == 0 && (c.backends == nil || metaExpired(c.backendsFetchedAt)) {
+		if err := c.refreshBackends(); err != nil {
+			return nil, err
+		}
+	}
+
+	meta := NewMeta(c.spec)
+	meta.Id = c.db.Id
+	for tableId := range tables {
+		if tableMeta, ok := c.db.Tables[tableId]; ok {
+			meta.Tables[tableId] = tableMeta
+			meta.TableName2IdMap[tableMeta.Name] = tableId
+		}
+	}
+	meta.Backends = c.backends
+	return &ThriftMeta{
+		meta:      meta,
+		cache:     c,
+		refreshed: make(map[int64]bool),
+	}, nil
+}
+
+// Invalidate drops the cached metas of the tables, they are got again when they are used next time
+func (c *MetaCache) Invalidate(tableIds ...int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, tableId := range tableIds {
+		delete(c.db.Tables, tableId)
+		delete(c.fetchedAt, tableId)
+	}
+}
+
+// InvalidateBackends drops the cached backends, e.g. some backends of the replicas are not found
+func (c *MetaCache) InvalidateBackends() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.backends = nil
+}
+
+// Clear drops all cached metas, e.g. the tables are restored by full sync
+func (c *MetaCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.db.Tables = make(map[int64]*TableMeta)
+	c.fetchedAt = make(map[int64]time.Time)
+	c.backends = nil
+}
+
+// refreshTable gets the table again, it is used when an id is not found in the cached table
+func (c *MetaCache) refreshTable(tableId int64) (*TableMeta, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.refreshTables([]int64{tableId}); err != nil {
+		return nil, err
+	}
+	tableMeta, ok := c.db.Tables[tableId]
+	if !ok {
+		return nil, nil
+	}
+	return tableMeta, nil
+}
+
+func (c *MetaCache) refreshTables(tableIds []int64) error {
+	log.Debugf("refresh %s metas of tables %v, job: %s", c.cluster, tableIds, c.jobName)
+
+	thriftMeta, err := c.factory.NewThriftMeta(c.spec, c.factory, tableIds)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, tableId := range tableIds {
+		xmetrics.MetaCacheMiss(c.jobName, c.cluster)
+		// the table is dropped
+		delete(c.db.Tables, tableId)
+		delete(c.fetchedAt, tableId)
+	}
+	for tableId, tableMeta := range thriftMeta.meta.Tables {
+		c.db.Tables[tableId] = tableMeta
+		c.fetchedAt[tableId] = now
+	}
+	// the backends are got with the tables
+	c.backends = thriftMeta.meta.Backends
+	c.backendsFetchedAt = now
+	return nil
+}
+
+// refreshPartitions gets the partitions of the table only, and replaces them in a copy of the table
+func (c *MetaCache) refreshPartitions(tableMeta *TableMeta, partitionIds []int64) error {
+	log.Debugf("refresh %s metas of table %d partitions %v, job: %s", c.cluster, tableMeta.Id, partitionIds, c.jobName)
+	xmetrics.MetaCacheMiss(c.jobName, c.cluster)
+
+	feRpc, err := c.factory.NewFeRpc(c.spec)
+	if err != nil {
+		return err
+	}
+	resp, err := feRpc.GetPartitionMeta(c.spec, tableMeta.Id, partitionIds)
+	if err != nil {
+		return err
+	}
+	tables, err := getTableMetas(resp, &c.db)
+	if err != nil {
+		return err
+	}
+
+	refreshed := &TableMeta{
+		DatabaseMeta:      tableMeta.DatabaseMeta,
+		Id:                tableMeta.Id,
+		Name:              tableMeta.Name,
+		PartitionIdMap:    make(map[int64]*PartitionMeta),
+		PartitionRangeMap: make(map[string]*PartitionMeta),
+	}
+	// the unchanged partitions are copied too, since the cached ones are never changed
+	for partitionId, partitionMeta := range tableMeta.PartitionIdMap {
+		copied := *partitionMeta
+		copied.TableMeta = refreshed
+		refreshed.PartitionIdMap[partitionId] = &copied
+		refreshed.PartitionRangeMap[copied.Range] = &copied
+	}
+	for _, table := range tables {
+		for partitionId, partitionMeta := range table.PartitionIdMap {
+			if old, ok := refreshed.PartitionIdMap[partitionId]; ok {
+				delete(refreshed.PartitionRangeMap, old.Range)
+			}
+			partitionMeta.TableMeta = refreshed
+			refreshed.PartitionIdMap[partitionId] = partitionMeta
+			refreshed.PartitionRangeMap[partitionMeta.Range] = partitionMeta
+		}
+	}
+	c.db.Tables[tableMeta.Id] = refreshed
+	return nil
+}
+
+func (c *MetaCache) refreshBackends() error {
+	feRpc, err := c.factory.NewFeRpc(c.spec)
+	if err != nil {
+		return err
+	}
+	backends, err := getBackendMetas(feRpc, c.spec)
+	if err != nil {
+		return err
+	}
+
+	c.backends = backends
+	c.backendsFetchedAt = time.Now()
+	return nil
+}
+
+func containsId(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
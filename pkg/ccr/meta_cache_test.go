@@ -0,0 +1,104 @@
+package ccr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	rpc "github.com/selectdb/ccr_syncer/pkg/rpc"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	"github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// countingThriftMetaFactory returns the prepared thrift meta, and counts the tables got
+type countingThriftMetaFactory struct {
+	thriftMeta *ThriftMeta
+	calls      int
+}
+
+func (f *countingThriftMetaFactory) NewThriftMeta(_ *base.Spec, _ rpc.IRpcFactory, _ []int64) (*ThriftMeta, error) {
+	f.calls++
+	return f.thriftMeta, nil
+}
+
+func TestMetaCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tableId := tblSrcSpec.TableId
+	partitionId := getPartitionBaseId(tableId)
+	backendMap := newBackendMap(3)
+	dbMeta := newMeta(&tblSrcSpec, &backendMap)
+	setReplicaVersion(dbMeta, 10)
+	// a partition not changed by the binlogs
+	unchangedPartition := newPartitionMeta(partitionId + 1)
+	unchangedPartition.TableMeta = dbMeta.Tables[tableId]
+	dbMeta.Tables[tableId].PartitionIdMap[unchangedPartition.Id] = unchangedPartition
+	dbMeta.Tables[tableId].PartitionRangeMap[fmt.Sprint(unchangedPartition.Id)] = unchangedPartition
+
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	thriftMetaFactory := &countingThriftMetaFactory{thriftMeta: newTestThriftMeta(&tblSrcSpec, dbMeta, backendMap)}
+	factory := NewFactory(rpcFactory, NewMockMetaerFactory(ctrl), base.NewSpecerFactory(), thriftMetaFactory)
+	cache := NewMetaCache("Test", metaCacheSrc, &tblSrcSpec, factory)
+
+	// the table is got once, and cached for the binlogs not newer than it
+	for i := 0; i < 2; i++ {
+		thriftMeta, err := cache.ThriftMeta(map[int64]binlogVersions{tableId: {partitionId: 10}})
+		require.NoError(t, err)
+		backends, err := thriftMeta.GetBackendMap()
+		require.NoError(t, err)
+		require.Len(t, backends, 3)
+	}
+	require.Equal(t, 1, thriftMetaFactory.calls)
+
+	// only the partition older than the binlog is got again
+	feRpc := NewMockIFeRpc(ctrl)
+	rpcFactory.EXPECT().NewFeRpc(&tblSrcSpec).Return(feRpc, nil)
+	feRpc.EXPECT().GetPartitionMeta(&tblSrcSpec, tableId, []int64{partitionId}).Return(&festruct.TGetMetaResult_{
+		Status: &status.TStatus{StatusCode: status.TStatusCode_OK},
+		DbMeta: &festruct.TGetMetaDBMeta{
+			Tables: []*festruct.TGetMetaTableMeta{{
+				Id: utils.ThriftValueWrapper(tableId),
+				Partitions: []*festruct.TGetMetaPartitionMeta{{
+					Id:             utils.ThriftValueWrapper(partitionId),
+					Range:          utils.ThriftValueWrapper("11"),
+					VisibleVersion: utils.ThriftValueWrapper(int64(11)),
+				}},
+			}},
+		},
+	}, nil)
+	thriftMeta, err := cache.ThriftMeta(map[int64]binlogVersions{tableId: {partitionId: 11}})
+	require.NoError(t, err)
+	partitionRangeMap, err := thriftMeta.GetPartitionRangeMap(tableId)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), partitionRangeMap["11"].VisibleVersion)
+	require.Len(t, partitionRangeMap, 2, "the old range of the partition is removed")
+	// the unchanged partition is copied into the refreshed table, the cached one is not changed
+	refreshedTable := partitionRangeMap["11"].TableMeta
+	require.NotSame(t, unchangedPartition.TableMeta, refreshedTable)
+	require.Same(t, refreshedTable, partitionRangeMap[fmt.Sprint(unchangedPartition.Id)].TableMeta)
+	require.Equal(t, 1, thriftMetaFactory.calls)
+
+	// an id not found refreshes the table once
+	_, err = thriftMeta.GetPartitionIdByRange(tableId, "not found")
+	require.Error(t, err)
+	_, err = thriftMeta.GetPartitionIdByRange(tableId, "not found")
+	require.Error(t, err)
+	require.Equal(t, 2, thriftMetaFactory.calls)
+
+	// invalidated and expired tables are got again
+	cache.Invalidate(tableId)
+	_, err = cache.ThriftMeta(map[int64]binlogVersions{tableId: nil})
+	require.NoError(t, err)
+	require.Equal(t, 3, thriftMetaFactory.calls)
+
+	SetMetaCacheTTL(0)
+	defer SetMetaCacheTTL(META_CACHE_TTL)
+	_, err = cache.ThriftMeta(map[int64]binlogVersions{tableId: nil})
+	require.NoError(t, err)
+	require.Equal(t, 4, thriftMetaFactory.calls)
+}
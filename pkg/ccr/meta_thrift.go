@@ -0,0 +1,244 @@
+package ccr
+
+import (
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errGetMetaUnsupported means the fe is too old to support GetMeta, the metas are got by show proc instead
+var errGetMetaUnsupported = xerror.NewWithoutStack(xerror.Meta, "fe does not support GetMeta")
+
+// NewThriftBackedMeta returns the Meta which gets the dbs, tables, partitions, indexes and replicas by thrift
+// GetMeta. MetaFactory only creates it for the clusters probed to support GetMeta, it still falls back to
+// show proc if the fe does not support it, e.g. the fe is downgraded.
+func NewThriftBackedMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory) *Meta {
+	meta := NewMeta(spec)
+	meta.rpcFactory = rpcFactory
+	return meta
+}
+
+// probeGetMeta returns whether the fe of spec supports GetMeta, by getting the table names of the database
+func probeGetMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory) (bool, error) {
+	feRpc, err := rpcFactory.NewFeRpc(spec)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := feRpc.GetTableNames(spec)
+	if err != nil {
+		if rpc.IsUnknownMethod(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	// the other errors like the database not found mean GetMeta is supported
+	return resp.GetStatus().GetStatusCode() != tstatus.TStatusCode_NOT_IMPLEMENTED_ERROR, nil
+}
+
+func (m *Meta) isGetMetaSupported() bool {
+	return m.rpcFactory != nil && !m.getMetaUnsupported
+}
+
+// getMeta calls GetMeta of the fe, it returns errGetMetaUnsupported and falls back to show proc if the fe
+// does not support GetMeta
+func (m *Meta) getMeta(call func(feRpc rpc.IFeRpc) (*festruct.TGetMetaResult_, error)) (*festruct.TGetMetaResult_, error) {
+	feRpc, err := m.rpcFactory.NewFeRpc(m.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := call(feRpc)
+	if err != nil {
+		if rpc.IsUnknownMethod(err) {
+			return nil, m.fallbackToShowProc(err)
+		}
+		return nil, err
+	}
+
+	switch resp.GetStatus().GetStatusCode() {
+	case tstatus.TStatusCode_OK:
+	case tstatus.TStatusCode_NOT_IMPLEMENTED_ERROR:
+		return nil, m.fallbackToShowProc(resp.GetStatus())
+	default:
+		return nil, xerror.Errorf(xerror.Meta, "get meta failed, status: %s", resp.GetStatus())
+	}
+	if !resp.IsSetDbMeta() {
+		return nil, xerror.New(xerror.Meta, "get meta failed, db meta not set")
+	}
+	return resp, nil
+}
+
+func (m *Meta) fallbackToShowProc(cause any) error {
+	frontend := m.CurrentFrontend()
+	log.Warnf("fe %s:%s does not support GetMeta, get metas by show proc, cause: %v", frontend.Host, frontend.ThriftPort, cause)
+	m.getMetaUnsupported = true
+	return errGetMetaUnsupported
+}
+
+func (m *Meta) getTableNames() (*festruct.TGetMetaDBMeta, error) {
+	resp, err := m.getMeta(func(feRpc rpc.IFeRpc) (*festruct.TGetMetaResult_, error) {
+		return feRpc.GetTableNames(m.Spec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dbMeta := resp.GetDbMeta()
+	m.DatabaseName2IdMap["default_cluster:"+m.Database] = dbMeta.GetId()
+	m.DatabaseMeta.Id = dbMeta.GetId()
+	return dbMeta, nil
+}
+
+func (m *Meta) getDbIdByThrift() (int64, error) {
+	dbMeta, err := m.getTableNames()
+	if err != nil {
+		return 0, err
+	}
+	return dbMeta.GetId(), nil
+}
+
+func (m *Meta) updateTableByThrift(tableName string, tableId int64) (*TableMeta, error) {
+	dbMeta, err := m.getTableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range dbMeta.GetTables() {
+		if table.GetInTrash() || (table.GetName() != tableName && table.GetId() != tableId) {
+			continue
+		}
+
+		fullTableName := m.GetFullTableName(table.GetName())
+		log.Debugf("found table:%s, tableId:%d", fullTableName, table.GetId())
+		m.TableName2IdMap[fullTableName] = table.GetId()
+		tableMeta := &TableMeta{
+			DatabaseMeta:   &m.DatabaseMeta,
+			Id:             table.GetId(),
+			Name:           table.GetName(),
+			PartitionIdMap: make(map[int64]*PartitionMeta),
+		}
+		m.Tables[tableMeta.Id] = tableMeta
+		m.tableUpdatedAt[tableMeta.Id] = time.Now()
+		return tableMeta, nil
+	}
+
+	// not found
+	return nil, xerror.Errorf(xerror.Normal, "tableId %v not found table", tableId)
+}
+
+func (m *Meta) getTablesByThrift() (map[int64]*TableMeta, error) {
+	dbMeta, err := m.getTableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	tableName2IdMap := make(map[string]int64)
+	tables := make(map[int64]*TableMeta) // tableId -> table
+	tableUpdatedAt := make(map[int64]time.Time)
+	for _, table := range dbMeta.GetTables() {
+		if table.GetInTrash() {
+			continue
+		}
+
+		fullTableName := m.GetFullTableName(table.GetName())
+		log.Debugf("found table:%s, tableId:%d", fullTableName, table.GetId())
+		tableName2IdMap[fullTableName] = table.GetId()
+		tables[table.GetId()] = &TableMeta{
+			DatabaseMeta:   &m.DatabaseMeta,
+			Id:             table.GetId(),
+			Name:           table.GetName(),
+			PartitionIdMap: make(map[int64]*PartitionMeta),
+		}
+		tableUpdatedAt[table.GetId()] = time.Now()
+	}
+
+	m.TableName2IdMap = tableName2IdMap
+	m.Tables = tables
+	m.tableUpdatedAt = tableUpdatedAt
+	return tables, nil
+}
+
+// updatePartitionsByThrift gets the partitions of the table, with their indexes, tablets and replicas
+func (m *Meta) updatePartitionsByThrift(table *TableMeta) error {
+	resp, err := m.getMeta(func(feRpc rpc.IFeRpc) (*festruct.TGetMetaResult_, error) {
+		return feRpc.GetTableMeta(m.Spec, []int64{table.Id})
+	})
+	if err != nil {
+		return err
+	}
+	tables, err := getTableMetas(resp, &m.DatabaseMeta)
+	if err != nil {
+		return err
+	}
+
+	for _, tableMeta := range tables {
+		if tableMeta.Id != table.Id {
+			continue
+		}
+
+		table.PartitionIdMap = make(map[int64]*PartitionMeta)
+		table.PartitionRangeMap = make(map[string]*PartitionMeta)
+		for _, partition := range tableMeta.PartitionIdMap {
+			partition.TableMeta = table
+			table.PartitionIdMap[partition.Id] = partition
+			table.PartitionRangeMap[partition.Range] = partition
+		}
+		return nil
+	}
+	return xerror.Errorf(xerror.Normal, "tableId: %d not found", table.Id)
+}
+
+// updateIndexesByThrift gets the indexes of the partition, with their tablets and replicas. The cached indexes
+// are updated in place, since they may be held by the caller.
+func (m *Meta) updateIndexesByThrift(partition *PartitionMeta) error {
+	tableId := partition.TableMeta.Id
+	resp, err := m.getMeta(func(feRpc rpc.IFeRpc) (*festruct.TGetMetaResult_, error) {
+		return feRpc.GetPartitionMeta(m.Spec, tableId, []int64{partition.Id})
+	})
+	if err != nil {
+		return err
+	}
+	tables, err := getTableMetas(resp, &m.DatabaseMeta)
+	if err != nil {
+		return err
+	}
+
+	var fetched *PartitionMeta
+	for _, tableMeta := range tables {
+		if tableMeta.Id == tableId {
+			fetched = tableMeta.PartitionIdMap[partition.Id]
+		}
+	}
+	if fetched == nil {
+		return xerror.Errorf(xerror.Normal, "partitionId: %d not found", partition.Id)
+	}
+
+	indexIdMap := make(map[int64]*IndexMeta)
+	indexNameMap := make(map[string]*IndexMeta)
+	for _, index := range fetched.IndexIdMap {
+		if cached, ok := partition.IndexIdMap[index.Id]; ok {
+			cached.Name = index.Name
+			cached.TabletMetas = index.TabletMetas
+			cached.ReplicaMetas = index.ReplicaMetas
+			for _, tablet := range cached.TabletMetas.Values() {
+				tablet.IndexMeta = cached
+			}
+			index = cached
+		} else {
+			index.PartitionMeta = partition
+		}
+		indexIdMap[index.Id] = index
+		indexNameMap[index.Name] = index
+	}
+
+	partition.IndexIdMap = indexIdMap
+	partition.IndexNameMap = indexNameMap
+	return nil
+}
@@ -66,6 +66,7 @@ type ReplicaMeta struct {
 type MetaCleaner interface {
 	ClearDB(dbName string)
 	ClearTable(dbName string, tableName string)
+	InvalidateTables(tableIds ...int64)
 }
 
 type IngestBinlogMetaer interface {
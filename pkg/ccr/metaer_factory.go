@@ -1,19 +1,94 @@
 package ccr
 
 import (
+	"net"
+	"sync"
+	"time"
+
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type MetaerFactory interface {
 	NewMeta(tableSpec *base.Spec) Metaer
 }
 
-type MetaFactory struct{}
+// the fe unreachable when probing GetMeta is probed again after this duration
+const PROBE_GET_META_RETRY_DURATION = time.Minute
+
+type MetaFactory struct {
+	rpcFactory rpc.IRpcFactory
+
+	// whether the fe supports GetMeta, it is probed once for each cluster, fe address -> probe
+	lock          sync.Mutex
+	getMetaProbes map[string]*getMetaProbe
+}
+
+// getMetaProbe is done by the first meta of the fe, the others wait for it without the lock of factory
+type getMetaProbe struct {
+	done      chan struct{}
+	supported bool
+	failedAt  time.Time // zero means the fe is reached
+}
 
-func NewMetaFactory() MetaerFactory {
-	return &MetaFactory{}
+// NewMetaFactory returns the factory of the metas got by thrift GetMeta of rpcFactory, or by show proc if the
+// fe does not support it
+func NewMetaFactory(rpcFactory rpc.IRpcFactory) MetaerFactory {
+	return &MetaFactory{
+		rpcFactory:    rpcFactory,
+		getMetaProbes: make(map[string]*getMetaProbe),
+	}
 }
 
 func (mf *MetaFactory) NewMeta(spec *base.Spec) Metaer {
-	return NewMeta(spec)
+	if !mf.isGetMetaSupported(spec) {
+		return NewMeta(spec)
+	}
+	return NewThriftBackedMeta(spec, mf.rpcFactory)
+}
+
+// isGetMetaSupported probes the fe of spec at the first time, the metas of the cluster created later share
+// the result. The probe is retried after PROBE_GET_META_RETRY_DURATION if the fe is unreachable, the metas
+// use GetMeta and fall back to show proc by themselves meanwhile.
+func (mf *MetaFactory) isGetMetaSupported(spec *base.Spec) bool {
+	frontend := spec.CurrentFrontend()
+	addr := net.JoinHostPort(frontend.Host, frontend.ThriftPort)
+
+	mf.lock.Lock()
+	probe, ok := mf.getMetaProbes[addr]
+	if ok {
+		select {
+		case <-probe.done:
+			ok = probe.failedAt.IsZero() || time.Since(probe.failedAt) < PROBE_GET_META_RETRY_DURATION
+		default:
+		}
+	}
+	if !ok {
+		probe = &getMetaProbe{done: make(chan struct{})}
+		mf.getMetaProbes[addr] = probe
+	}
+	mf.lock.Unlock()
+
+	if !ok {
+		probe.run(spec, addr, mf.rpcFactory)
+	}
+	<-probe.done
+	return probe.supported
+}
+
+func (p *getMetaProbe) run(spec *base.Spec, addr string, rpcFactory rpc.IRpcFactory) {
+	defer close(p.done)
+
+	supported, err := probeGetMeta(spec, rpcFactory)
+	if err != nil {
+		log.Warnf("probe GetMeta of fe %s failed, probe again after %s, err: %+v", addr, PROBE_GET_META_RETRY_DURATION, err)
+		p.supported, p.failedAt = true, time.Now()
+		return
+	}
+	if !supported {
+		log.Warnf("fe %s does not support GetMeta, get metas by show proc", addr)
+	}
+	p.supported = supported
 }
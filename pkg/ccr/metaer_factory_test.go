@@ -0,0 +1,40 @@
+package ccr
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMetaFactory_ProbeFailureCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(nil, xerror.New(xerror.RPC, "fe is unreachable")).Times(1)
+	mf := NewMetaFactory(rpcFactory).(*MetaFactory)
+	spec := &base.Spec{Frontend: base.Frontend{Host: "127.0.0.1", ThriftPort: "9020"}}
+
+	// the metas created at the same time share one probe, the unreachable fe is not probed again at once
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, mf.isGetMetaSupported(spec))
+		}()
+	}
+	wg.Wait()
+	assert.True(t, mf.isGetMetaSupported(spec))
+
+	// probe again after the retry duration
+	mf.getMetaProbes["127.0.0.1:9020"].failedAt = time.Now().Add(-PROBE_GET_META_RETRY_DURATION)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(nil, xerror.New(xerror.RPC, "fe is unreachable")).Times(1)
+	assert.True(t, mf.isGetMetaSupported(spec))
+}
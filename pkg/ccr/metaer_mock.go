@@ -64,6 +64,22 @@ func (mr *MockMetaCleanerMockRecorder) ClearTable(dbName, tableName any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearTable", reflect.TypeOf((*MockMetaCleaner)(nil).ClearTable), dbName, tableName)
 }
 
+// InvalidateTables mocks base method.
+func (m *MockMetaCleaner) InvalidateTables(tableIds ...int64) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range tableIds {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "InvalidateTables", varargs...)
+}
+
+// InvalidateTables indicates an expected call of InvalidateTables.
+func (mr *MockMetaCleanerMockRecorder) InvalidateTables(tableIds ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateTables", reflect.TypeOf((*MockMetaCleaner)(nil).InvalidateTables), tableIds...)
+}
+
 // MockIngestBinlogMetaer is a mock of IngestBinlogMetaer interface.
 type MockIngestBinlogMetaer struct {
 	ctrl     *gomock.Controller
@@ -595,6 +611,22 @@ func (mr *MockMetaerMockRecorder) GetTablets(tableId, partitionId, indexId any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTablets", reflect.TypeOf((*MockMetaer)(nil).GetTablets), tableId, partitionId, indexId)
 }
 
+// InvalidateTables mocks base method.
+func (m *MockMetaer) InvalidateTables(tableIds ...int64) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range tableIds {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "InvalidateTables", varargs...)
+}
+
+// InvalidateTables indicates an expected call of InvalidateTables.
+func (mr *MockMetaerMockRecorder) InvalidateTables(tableIds ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateTables", reflect.TypeOf((*MockMetaer)(nil).InvalidateTables), tableIds...)
+}
+
 // UpdateBackends mocks base method.
 func (m *MockMetaer) UpdateBackends() error {
 	m.ctrl.T.Helper()
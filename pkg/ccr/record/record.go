@@ -0,0 +1,46 @@
+package record
+
+import (
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// NewRecordFromBinlog parses the data of binlog into the record of its type,
+// the types ignored by syncer (e.g. barrier, dummy) have no record and return nil.
+func NewRecordFromBinlog(binlogType festruct.TBinlogType, data string) (any, error) {
+	switch binlogType {
+	case festruct.TBinlogType_UPSERT:
+		return toRecord(NewUpsertFromJson(data))
+	case festruct.TBinlogType_ADD_PARTITION:
+		return toRecord(NewAddPartitionFromJson(data))
+	case festruct.TBinlogType_CREATE_TABLE:
+		return toRecord(NewCreateTableFromJson(data))
+	case festruct.TBinlogType_DROP_PARTITION:
+		return toRecord(NewDropPartitionFromJson(data))
+	case festruct.TBinlogType_DROP_TABLE:
+		return toRecord(NewDropTableFromJson(data))
+	case festruct.TBinlogType_ALTER_JOB:
+		return toRecord(NewAlterJobV2FromJson(data))
+	case festruct.TBinlogType_MODIFY_TABLE_ADD_OR_DROP_COLUMNS:
+		return toRecord(NewModifyTableAddOrDropColumnsFromJson(data))
+	case festruct.TBinlogType_TRUNCATE_TABLE:
+		return toRecord(NewTruncateTableFromJson(data))
+	case festruct.TBinlogType_DUMMY,
+		festruct.TBinlogType_ALTER_DATABASE_PROPERTY,
+		festruct.TBinlogType_MODIFY_TABLE_PROPERTY,
+		festruct.TBinlogType_BARRIER,
+		festruct.TBinlogType_MODIFY_PARTITIONS,
+		festruct.TBinlogType_REPLACE_PARTITIONS:
+		return nil, nil
+	default:
+		return nil, xerror.Errorf(xerror.Normal, "unknown binlog type: %v", binlogType)
+	}
+}
+
+// toRecord avoids returning a nil pointer in a non nil interface
+func toRecord[T any](record *T, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
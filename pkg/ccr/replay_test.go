@@ -0,0 +1,535 @@
+package ccr
+
+// The replay tests feed the binlog streams recorded under testdata/replay through
+// Job.handleBinlogs, against in memory src/dest clusters, and check the exact sql
+// executed and txns committed on dest.
+//
+// To add a case, dump the binlogs of upstream by
+//   binlog_inspector -db ccr -from <commit seq> -format json -out testdata/replay/<case>.jsonl
+// then describe the tables touched by the stream in the src/dest clusters of the case.
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/binlog"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	bestruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	ttypes "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
+	"github.com/selectdb/ccr_syncer/pkg/test_util"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type fakeIndex struct {
+	id      int64
+	name    string
+	buckets int
+}
+
+type fakePartition struct {
+	id       int64
+	name     string
+	rangeKey string
+	// all replicas are at the visible version
+	version int64
+}
+
+// the indexes are shared by all partitions, each partition has its own tablets
+type fakeTable struct {
+	id         int64
+	name       string
+	indexes    []*fakeIndex
+	partitions []*fakePartition
+}
+
+// tablet ids are partitionId*100 + (index pos+1)*10 + bucket, bucket starts from 1
+func tabletIds(partition *fakePartition, indexPos int, index *fakeIndex) []int64 {
+	tabletIds := make([]int64, 0, index.buckets)
+	for bucket := 1; bucket <= index.buckets; bucket++ {
+		tabletIds = append(tabletIds, partition.id*100+int64(indexPos+1)*10+int64(bucket))
+	}
+	return tabletIds
+}
+
+type fakeTxn struct {
+	label       string
+	tableIds    []int64
+	commitInfos []*ttypes.TTabletCommitInfo
+	ingests     []*bestruct.TIngestBinlogRequest
+	committed   bool
+	aborted     bool
+}
+
+// fakeCluster is the in memory meta of a db, it serves the FE, BE and sql of the db,
+// and records the sql executed and the txns of the db.
+type fakeCluster struct {
+	dbId     int64
+	dbName   string
+	backends []int64
+	tables   []*fakeTable
+
+	lock      sync.Mutex
+	sqls      []string
+	txns      map[int64]*fakeTxn
+	nextTxnId int64
+}
+
+func newFakeCluster(dbId int64, dbName string, backends []int64, tables ...*fakeTable) *fakeCluster {
+	return &fakeCluster{
+		dbId:      dbId,
+		dbName:    dbName,
+		backends:  backends,
+		tables:    tables,
+		txns:      make(map[int64]*fakeTxn),
+		nextTxnId: 1,
+	}
+}
+
+func (c *fakeCluster) getTable(tableId int64) (*fakeTable, bool) {
+	for _, table := range c.tables {
+		if table.id == tableId {
+			return table, true
+		}
+	}
+	return nil, false
+}
+
+func (c *fakeCluster) exec(sql string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sqls = append(c.sqls, sql)
+}
+
+func (c *fakeCluster) getTxn(txnId int64) (*fakeTxn, error) {
+	txn, ok := c.txns[txnId]
+	if !ok {
+		return nil, xerror.Errorf(xerror.Normal, "txn %d not found", txnId)
+	}
+	return txn, nil
+}
+
+func okStatus() *tstatus.TStatus {
+	return &tstatus.TStatus{StatusCode: tstatus.TStatusCode_OK}
+}
+
+// fakeFeRpc implements the rpc used by incremental sync, others panic by the nil IFeRpc
+type fakeFeRpc struct {
+	rpc.IFeRpc
+	cluster *fakeCluster
+}
+
+func (r *fakeFeRpc) GetBackends(spec *base.Spec) (*festruct.TGetBackendMetaResult_, error) {
+	backends := make([]*ttypes.TBackend, 0, len(r.cluster.backends))
+	for _, backendId := range r.cluster.backends {
+		backends = append(backends, &ttypes.TBackend{
+			Id:       utils.ThriftValueWrapper(backendId),
+			Host:     fmt.Sprintf("%s-be-%d", r.cluster.dbName, backendId),
+			BePort:   9060,
+			HttpPort: 8040,
+		})
+	}
+	return &festruct.TGetBackendMetaResult_{Status: okStatus(), Backends: backends}, nil
+}
+
+func (r *fakeFeRpc) GetTableMeta(spec *base.Spec, tableIds []int64) (*festruct.TGetMetaResult_, error) {
+	dbMeta := &festruct.TGetMetaDBMeta{
+		Id:   utils.ThriftValueWrapper(r.cluster.dbId),
+		Name: utils.ThriftValueWrapper(r.cluster.dbName),
+	}
+	for _, tableId := range tableIds {
+		table, ok := r.cluster.getTable(tableId)
+		if !ok {
+			return nil, xerror.Errorf(xerror.Meta, "table %d not found in %s", tableId, r.cluster.dbName)
+		}
+
+		tableMeta := &festruct.TGetMetaTableMeta{
+			Id:   utils.ThriftValueWrapper(table.id),
+			Name: utils.ThriftValueWrapper(table.name),
+		}
+		for _, partition := range table.partitions {
+			partitionMeta := &festruct.TGetMetaPartitionMeta{
+				Id:             utils.ThriftValueWrapper(partition.id),
+				Name:           utils.ThriftValueWrapper(partition.name),
+				Range:          utils.ThriftValueWrapper(partition.rangeKey),
+				VisibleVersion: utils.ThriftValueWrapper(partition.version),
+			}
+			for indexPos, index := range table.indexes {
+				indexMeta := &festruct.TGetMetaIndexMeta{
+					Id:   utils.ThriftValueWrapper(index.id),
+					Name: utils.ThriftValueWrapper(index.name),
+				}
+				for _, tabletId := range tabletIds(partition, indexPos, index) {
+					tabletMeta := &festruct.TGetMetaTabletMeta{Id: utils.ThriftValueWrapper(tabletId)}
+					for i, backendId := range r.cluster.backends {
+						tabletMeta.Replicas = append(tabletMeta.Replicas, &festruct.TGetMetaReplicaMeta{
+							Id:        utils.ThriftValueWrapper(tabletId*10 + int64(i)),
+							BackendId: utils.ThriftValueWrapper(backendId),
+							Version:   utils.ThriftValueWrapper(partition.version),
+						})
+					}
+					indexMeta.Tablets = append(indexMeta.Tablets, tabletMeta)
+				}
+				partitionMeta.Indexes = append(partitionMeta.Indexes, indexMeta)
+			}
+			tableMeta.Partitions = append(tableMeta.Partitions, partitionMeta)
+		}
+		dbMeta.Tables = append(dbMeta.Tables, tableMeta)
+	}
+	return &festruct.TGetMetaResult_{Status: okStatus(), DbMeta: dbMeta}, nil
+}
+
+func (r *fakeFeRpc) BeginTransaction(spec *base.Spec, label string, tableIds []int64) (*festruct.TBeginTxnResult_, error) {
+	c := r.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txnId := c.nextTxnId
+	c.nextTxnId++
+	c.txns[txnId] = &fakeTxn{label: label, tableIds: tableIds}
+	return &festruct.TBeginTxnResult_{
+		Status: okStatus(),
+		TxnId:  utils.ThriftValueWrapper(txnId),
+		DbId:   utils.ThriftValueWrapper(c.dbId),
+	}, nil
+}
+
+func (r *fakeFeRpc) CommitTransaction(spec *base.Spec, txnId int64, commitInfos []*ttypes.TTabletCommitInfo) (*festruct.TCommitTxnResult_, error) {
+	c := r.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txn, err := c.getTxn(txnId)
+	if err != nil {
+		return nil, err
+	}
+	txn.commitInfos = commitInfos
+	txn.committed = true
+	return &festruct.TCommitTxnResult_{Status: okStatus()}, nil
+}
+
+func (r *fakeFeRpc) RollbackTransaction(spec *base.Spec, txnId int64) (*festruct.TRollbackTxnResult_, error) {
+	c := r.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txn, err := c.getTxn(txnId)
+	if err != nil {
+		return nil, err
+	}
+	txn.aborted = true
+	return &festruct.TRollbackTxnResult_{Status: okStatus()}, nil
+}
+
+type fakeBeRpc struct {
+	cluster *fakeCluster
+}
+
+func (r *fakeBeRpc) IngestBinlog(req *bestruct.TIngestBinlogRequest) (*bestruct.TIngestBinlogResult_, error) {
+	c := r.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txn, err := c.getTxn(req.GetTxnId())
+	if err != nil {
+		return nil, err
+	}
+	txn.ingests = append(txn.ingests, req)
+	return &bestruct.TIngestBinlogResult_{Status: okStatus()}, nil
+}
+
+// fakeSpecer records the sql, others panic by the nil Specer
+type fakeSpecer struct {
+	base.Specer
+	cluster *fakeCluster
+}
+
+func (s *fakeSpecer) Valid() error {
+	return nil
+}
+
+func (s *fakeSpecer) Exec(sql string) error {
+	s.cluster.exec(sql)
+	return nil
+}
+
+func (s *fakeSpecer) DbExec(sql string) error {
+	s.cluster.exec(sql)
+	return nil
+}
+
+// fakeMetaer answers the table names and ids, others panic by the nil Metaer
+type fakeMetaer struct {
+	Metaer
+	cluster *fakeCluster
+}
+
+func (m *fakeMetaer) GetTables() (map[int64]*TableMeta, error) {
+	tables := make(map[int64]*TableMeta)
+	for _, table := range m.cluster.tables {
+		tables[table.id] = &TableMeta{Id: table.id, Name: table.name}
+	}
+	return tables, nil
+}
+
+func (m *fakeMetaer) DirtyGetTables() map[int64]*TableMeta {
+	tables, _ := m.GetTables()
+	return tables
+}
+
+func (m *fakeMetaer) GetTableId(tableName string) (int64, error) {
+	for _, table := range m.cluster.tables {
+		if table.name == tableName {
+			return table.id, nil
+		}
+	}
+	return 0, xerror.Errorf(xerror.Meta, "table %s not found in %s", tableName, m.cluster.dbName)
+}
+
+func (m *fakeMetaer) GetTableNameById(tableId int64) (string, error) {
+	if table, ok := m.cluster.getTable(tableId); ok {
+		return table.name, nil
+	}
+	return "", xerror.Errorf(xerror.Meta, "table %d not found in %s", tableId, m.cluster.dbName)
+}
+
+func (m *fakeMetaer) ClearTable(dbName string, tableName string) {}
+
+func (m *fakeMetaer) InvalidateTables(tableIds ...int64) {}
+
+// fakeClusters dispatches specs to the src/dest cluster by database name
+type fakeClusters struct {
+	src  *fakeCluster
+	dest *fakeCluster
+}
+
+func (f *fakeClusters) get(spec *base.Spec) *fakeCluster {
+	switch spec.Database {
+	case f.src.dbName:
+		return f.src
+	case f.dest.dbName:
+		return f.dest
+	default:
+		panic(fmt.Sprintf("unknown database %s", spec.Database))
+	}
+}
+
+func (f *fakeClusters) NewFeRpc(spec *base.Spec) (rpc.IFeRpc, error) {
+	return &fakeFeRpc{cluster: f.get(spec)}, nil
+}
+
+// only dest BEs are called by ingest binlog
+func (f *fakeClusters) NewBeRpc(be *base.Backend) (rpc.IBeRpc, error) {
+	return &fakeBeRpc{cluster: f.dest}, nil
+}
+
+func (f *fakeClusters) NewMeta(spec *base.Spec) Metaer {
+	return &fakeMetaer{cluster: f.get(spec)}
+}
+
+func (f *fakeClusters) NewSpecer(spec *base.Spec) base.Specer {
+	return &fakeSpecer{cluster: f.get(spec)}
+}
+
+type replayTxn struct {
+	TableIds []int64
+	// src tablet@binlog version -> dest tablet, sorted
+	Ingests []string
+	// dest tablet@backend, sorted
+	CommitInfos []string
+}
+
+type replayCase struct {
+	name         string
+	src          *fakeCluster
+	dest         *fakeCluster
+	srcTable     string
+	destTable    string
+	tableMapping map[int64]int64
+
+	sqls         []string
+	txns         []replayTxn
+	commitSeq    int64
+	finalMapping map[int64]int64
+}
+
+func replay(t *testing.T, c *replayCase) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := test_util.NewMockDB(ctrl)
+	db.EXPECT().IsJobExist("Replay").Return(false, nil)
+	db.EXPECT().UpdateProgress("Replay", gomock.Any()).Return(nil).AnyTimes()
+	db.EXPECT().AddProgressHistory(gomock.Any()).Return(nil).AnyTimes()
+
+	clusters := &fakeClusters{src: c.src, dest: c.dest}
+	factory := NewFactory(clusters, clusters, clusters, DefaultThriftMetaFactory)
+
+	srcSpec := base.Spec{Database: c.src.dbName, DbId: c.src.dbId, Table: c.srcTable}
+	destSpec := base.Spec{Database: c.dest.dbName, DbId: c.dest.dbId, Table: c.destTable}
+	if c.srcTable != "" {
+		srcSpec.TableId, _ = (&fakeMetaer{cluster: c.src}).GetTableId(c.srcTable)
+		destSpec.TableId, _ = (&fakeMetaer{cluster: c.dest}).GetTableId(c.destTable)
+	}
+	job, err := NewJobFromService("Replay", NewJobContext(srcSpec, destSpec, false, db, factory))
+	require.NoError(t, err)
+
+	job.progress = NewJobProgress(job.Name, job.SyncType, db)
+	if job.SyncType == TableSync {
+		job.progress.SyncState = TableIncrementalSync
+	} else {
+		job.progress.SyncState = DBIncrementalSync
+		job.progress.TableMapping = c.tableMapping
+	}
+	job.progress.SubSyncState = Done
+
+	binlogs, err := binlog.LoadBinlogs(filepath.Join("testdata", "replay", c.name+".jsonl"))
+	require.NoError(t, err)
+	err, backToRunLoop := job.handleBinlogs(binlogs)
+	require.NoError(t, err)
+	assert.False(t, backToRunLoop)
+
+	assert.Empty(t, c.src.sqls, "no sql should be executed in src")
+	assert.Empty(t, c.src.txns, "no txn should be begun in src")
+	assert.Equal(t, c.sqls, c.dest.sqls)
+
+	txns := make([]replayTxn, 0, len(c.dest.txns))
+	for txnId := int64(1); txnId < c.dest.nextTxnId; txnId++ {
+		txn := c.dest.txns[txnId]
+		assert.True(t, txn.committed, "txn %d not committed", txnId)
+		assert.False(t, txn.aborted, "txn %d aborted", txnId)
+
+		// the tables of a upsert are in map, and the tablets are ingested concurrently
+		replayTxn := replayTxn{TableIds: append([]int64(nil), txn.tableIds...)}
+		sort.Slice(replayTxn.TableIds, func(i, j int) bool { return replayTxn.TableIds[i] < replayTxn.TableIds[j] })
+		for _, req := range txn.ingests {
+			assert.Equal(t, txnId, req.GetTxnId())
+			replayTxn.Ingests = append(replayTxn.Ingests,
+				fmt.Sprintf("%d@%d -> %d", req.GetRemoteTabletId(), req.GetBinlogVersion(), req.GetLocalTabletId()))
+		}
+		sort.Strings(replayTxn.Ingests)
+		for _, commitInfo := range txn.commitInfos {
+			replayTxn.CommitInfos = append(replayTxn.CommitInfos, fmt.Sprintf("%d@%d", commitInfo.GetTabletId(), commitInfo.GetBackendId()))
+		}
+		sort.Strings(replayTxn.CommitInfos)
+		txns = append(txns, replayTxn)
+	}
+	assert.Equal(t, c.txns, txns)
+
+	assert.Equal(t, c.commitSeq, job.progress.CommitSeq)
+	assert.True(t, job.progress.IsDone())
+	if job.SyncType == DBSync {
+		assert.Equal(t, c.finalMapping, job.progress.TableMapping)
+	}
+}
+
+func newOrdersTable(tableId int64, versions ...int64) *fakeTable {
+	table := &fakeTable{
+		id:   tableId,
+		name: "orders",
+		indexes: []*fakeIndex{
+			{id: tableId + 1, name: "orders", buckets: 2},
+			{id: tableId + 2, name: "r1", buckets: 1},
+		},
+	}
+	ranges := []string{"[('2023-01-01'), ('2023-02-01'))", "[('2023-02-01'), ('2023-03-01'))"}
+	for i, version := range versions {
+		table.partitions = append(table.partitions, &fakePartition{
+			id:       tableId + int64(i+1)*10,
+			name:     fmt.Sprintf("p%d", i+1),
+			rangeKey: ranges[i],
+			version:  version,
+		})
+	}
+	return table
+}
+
+func newUsersTable(tableId int64, version int64) *fakeTable {
+	return &fakeTable{
+		id:         tableId,
+		name:       "users",
+		indexes:    []*fakeIndex{{id: tableId + 1, name: "users", buckets: 1}},
+		partitions: []*fakePartition{{id: tableId + 10, name: "users", version: version}},
+	}
+}
+
+func TestReplayTableSync(t *testing.T) {
+	replay(t, &replayCase{
+		name:      "table_sync",
+		src:       newFakeCluster(10010, "ccr", []int64{10001, 10002, 10003}, newOrdersTable(20000, 6, 2)),
+		dest:      newFakeCluster(30010, "ccr_backup", []int64{30001}, newOrdersTable(40000, 1, 1)),
+		srcTable:  "orders",
+		destTable: "orders",
+
+		sqls: []string{
+			"ALTER TABLE orders ADD PARTITION `p2` VALUES [('2023-02-01'), ('2023-03-01')) DISTRIBUTED BY HASH(id) BUCKETS 2",
+			"ALTER TABLE `orders` ADD COLUMN `note` varchar(64) NULL COMMENT \"\"",
+			"TRUNCATE TABLE orders PARTITIONS (`p1`)",
+			"ALTER TABLE ccr_backup.orders DROP PARTITION `p2`",
+		},
+		txns: []replayTxn{
+			{
+				// upsert 101: p1 at version 5, base index and rollup
+				TableIds:    []int64{40000},
+				Ingests:     []string{"2001011@5 -> 4001011", "2001012@5 -> 4001012", "2001021@5 -> 4001021"},
+				CommitInfos: []string{"4001011@30001", "4001012@30001", "4001021@30001"},
+			},
+			{
+				// upsert 103: p1 at version 6 and p2 at version 2, base index only
+				TableIds: []int64{40000},
+				Ingests: []string{
+					"2001011@6 -> 4001011", "2001012@6 -> 4001012",
+					"2002011@2 -> 4002011", "2002012@2 -> 4002012",
+				},
+				CommitInfos: []string{"4001011@30001", "4001012@30001", "4002011@30001", "4002012@30001"},
+			},
+		},
+		commitSeq: 107,
+	})
+}
+
+func TestReplayDbSync(t *testing.T) {
+	replay(t, &replayCase{
+		name: "db_sync",
+		src: newFakeCluster(10010, "ccr", []int64{10001, 10002},
+			newOrdersTable(20000, 7), newUsersTable(20100, 2)),
+		dest: newFakeCluster(30010, "ccr_backup", []int64{30001, 30002},
+			newOrdersTable(40000, 1), newUsersTable(40100, 1)),
+		tableMapping: map[int64]int64{20000: 40000},
+
+		sqls: []string{
+			"CREATE TABLE `users` (`id` int NULL, `name` varchar(64) NULL) ENGINE=OLAP " +
+				"DUPLICATE KEY(`id`) DISTRIBUTED BY HASH(`id`) BUCKETS 1 PROPERTIES (\"binlog.enable\" = \"true\")",
+			"ALTER TABLE `users` ADD COLUMN `age` int NULL",
+			"DROP TABLE orders FORCE",
+		},
+		txns: []replayTxn{
+			{
+				// upsert 202: orders p1 at version 7 and users at version 2
+				TableIds: []int64{40000, 40100},
+				Ingests: []string{
+					"2001011@7 -> 4001011", "2001011@7 -> 4001011",
+					"2001012@7 -> 4001012", "2001012@7 -> 4001012",
+					"2011011@2 -> 4011011", "2011011@2 -> 4011011",
+				},
+				CommitInfos: []string{
+					"4001011@30001", "4001011@30002",
+					"4001012@30001", "4001012@30002",
+					"4011011@30001", "4011011@30002",
+				},
+			},
+		},
+		commitSeq:    204,
+		finalMapping: map[int64]int64{20100: 40100},
+	})
+}
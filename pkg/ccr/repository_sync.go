@@ -0,0 +1,49 @@
+package ccr
+
+import (
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// isRepositorySync returns true if the full sync backs up to the repository of src and the dest restores
+// from its own repository on the same storage, so the dest backends don't download the snapshot from the
+// src backends, e.g. the clusters are in different networks.
+func (j *Job) isRepositorySync() bool {
+	return j.Src.Repository != ""
+}
+
+// checkRepositories checks the repositories of src and dest are both set or both empty, and are created
+func (j *Job) checkRepositories() error {
+	if (j.Src.Repository == "") != (j.Dest.Repository == "") {
+		return xerror.Errorf(xerror.Normal, "the repository of src and dest must be both set or both empty, src: %q, dest: %q",
+			j.Src.Repository, j.Dest.Repository)
+	}
+	if !j.isRepositorySync() {
+		return nil
+	}
+
+	if exists, err := j.ISrc.CheckRepositoryExists(); err != nil {
+		return err
+	} else if !exists {
+		return xerror.Errorf(xerror.Normal, "src repository %s not exists", j.Src.Repository)
+	}
+	if exists, err := j.IDest.CheckRepositoryExists(); err != nil {
+		return err
+	} else if !exists {
+		return xerror.Errorf(xerror.Normal, "dest repository %s not exists", j.Dest.Repository)
+	}
+	return nil
+}
+
+// getRepositorySnapshotTimestamp reads the backup timestamp of the snapshot from the repository of src, the
+// dest restores the snapshot with it and reads the meta from the repository
+func (j *Job) getRepositorySnapshotTimestamp(snapshotName string) (string, error) {
+	snapshot, err := j.ISrc.GetRepositorySnapshot(snapshotName)
+	if err != nil {
+		return "", err
+	}
+	log.Debugf("snapshot %s in repository %s, timestamp: %s", snapshotName, j.Src.Repository, snapshot.Timestamp)
+
+	return snapshot.Timestamp, nil
+}
@@ -0,0 +1,72 @@
+package ccr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// a property of create table or add partition, with the comma before it, like , "replication_num" = "3"
+	sqlPropertyPattern = regexp.MustCompile(`(,\s*)?"([\w.]+)"\s*=\s*"([^"]*)"`)
+	// the comma left by the first property removed
+	leadingCommaPattern = regexp.MustCompile(`\(\s*,\s*`)
+	// the properties clause left by all properties removed, e.g. only colocate_with is set
+	emptyPropertiesPattern = regexp.MustCompile(`(?i)\s*PROPERTIES\s*\(\s*\)`)
+)
+
+// applyRestoreOptions overrides the properties of the create table or add partition sql of src, like the
+// restore of full sync does, so the tables and partitions created by incremental sync fit dest too.
+// Nil options keep the sql unchanged.
+func applyRestoreOptions(sql string, options *base.RestoreOptions) string {
+	if options == nil {
+		return sql
+	}
+	// the options persisted before validation are not trusted either
+	replicationAllocation := options.ReplicationAllocation
+	if replicationAllocation != "" && !base.IsValidReplicationAllocation(replicationAllocation) {
+		log.Warnf("ignore invalid replication_allocation %q of restore options", replicationAllocation)
+		replicationAllocation = ""
+	}
+
+	replicationSet := false
+	rewritten := sqlPropertyPattern.ReplaceAllStringFunc(sql, func(property string) string {
+		match := sqlPropertyPattern.FindStringSubmatch(property)
+		comma, key := match[1], strings.ToLower(match[2])
+		switch key {
+		case "replication_allocation", "replication_num":
+			if replicationAllocation == "" {
+				return property
+			}
+			// only one of them is kept
+			if replicationSet {
+				return ""
+			}
+			replicationSet = true
+			return fmt.Sprintf(`%s"replication_allocation" = "%s"`, comma, replicationAllocation)
+		case "storage_medium":
+			if options.StorageMedium == "" || options.StorageMedium == base.StorageMediumSameWithUpstream {
+				return property
+			}
+			return fmt.Sprintf(`%s"storage_medium" = "%s"`, comma, options.StorageMedium)
+		case "dynamic_partition.enable":
+			if options.ReserveDynamicPartition {
+				return property
+			}
+			return fmt.Sprintf(`%s"dynamic_partition.enable" = "false"`, comma)
+		case "colocate_with":
+			if options.ReserveColocate {
+				return property
+			}
+			return ""
+		default:
+			return property
+		}
+	})
+	rewritten = leadingCommaPattern.ReplaceAllString(rewritten, "(")
+	return emptyPropertiesPattern.ReplaceAllString(rewritten, "")
+}
@@ -0,0 +1,61 @@
+package ccr
+
+import (
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyRestoreOptions(t *testing.T) {
+	createSql := `CREATE TABLE t1 (id INT) DISTRIBUTED BY HASH(id) BUCKETS 1 PROPERTIES ("colocate_with" = "g1", "replication_num" = "3", "replication_allocation" = "tag.location.default: 3", "storage_medium" = "SSD", "dynamic_partition.enable" = "true")`
+
+	tests := []struct {
+		name    string
+		options *base.RestoreOptions
+		expect  string
+	}{
+		{
+			name:   "nil options keep the sql",
+			expect: createSql,
+		},
+		{
+			name:    "override",
+			options: &base.RestoreOptions{ReplicationAllocation: "tag.location.default: 1", StorageMedium: base.StorageMediumHdd},
+			expect:  `CREATE TABLE t1 (id INT) DISTRIBUTED BY HASH(id) BUCKETS 1 PROPERTIES ("replication_allocation" = "tag.location.default: 1", "storage_medium" = "hdd", "dynamic_partition.enable" = "false")`,
+		},
+		{
+			name:    "reserve",
+			options: &base.RestoreOptions{StorageMedium: base.StorageMediumSameWithUpstream, ReserveDynamicPartition: true, ReserveColocate: true},
+			expect:  createSql,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expect, applyRestoreOptions(createSql, test.options))
+		})
+	}
+
+	addPartitionSql := `ALTER TABLE t1 ADD PARTITION p2 VALUES LESS THAN ("20") ("replication_num" = "3") DISTRIBUTED BY HASH(id) BUCKETS 1`
+	options := &base.RestoreOptions{ReplicationAllocation: "tag.location.default: 1"}
+	require.Equal(t, `ALTER TABLE t1 ADD PARTITION p2 VALUES LESS THAN ("20") ("replication_allocation" = "tag.location.default: 1") DISTRIBUTED BY HASH(id) BUCKETS 1`,
+		applyRestoreOptions(addPartitionSql, options))
+
+	// the properties clause is dropped if only colocate_with is set
+	colocateSql := `CREATE TABLE t2 (id INT) DISTRIBUTED BY HASH(id) BUCKETS 1 PROPERTIES ("colocate_with" = "g1")`
+	require.Equal(t, `CREATE TABLE t2 (id INT) DISTRIBUTED BY HASH(id) BUCKETS 1`, applyRestoreOptions(colocateSql, &base.RestoreOptions{}))
+
+	// the invalid replication allocation is never put into the sql
+	injected := &base.RestoreOptions{ReplicationAllocation: `tag.location.default: 1"); DROP DATABASE db1; --`}
+	require.Error(t, injected.Valid())
+	require.Equal(t, addPartitionSql, applyRestoreOptions(addPartitionSql, injected))
+}
+
+func TestValidReplicationAllocation(t *testing.T) {
+	for _, allocation := range []string{"tag.location.default: 1", "tag.location.default:3", "tag.location.default: 1, tag.location.g_1: 2"} {
+		require.NoError(t, (&base.RestoreOptions{ReplicationAllocation: allocation}).Valid(), allocation)
+	}
+	for _, allocation := range []string{"3", "tag.location.default: 0", "tag.location.default: 1,", "tag.location.default: 1\" ", "default: 1"} {
+		require.Error(t, (&base.RestoreOptions{ReplicationAllocation: allocation}).Valid(), allocation)
+	}
+}
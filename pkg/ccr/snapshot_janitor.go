@@ -0,0 +1,94 @@
+package ccr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recordSnapshot records the backup snapshot or restore created by the job, so it is cleaned up even if
+// the full sync is abandoned, e.g. resnapshot by a dummy binlog, or the syncer restarts.
+func (j *Job) recordSnapshot(kind string, database string, label string) {
+	snapshot := &storage.Snapshot{
+		JobName:   j.Name,
+		Kind:      kind,
+		Database:  database,
+		Label:     label,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if err := j.db.AddSnapshot(snapshot); err != nil {
+		log.Warnf("record %s %s failed, job: %s, err: %+v", kind, label, j.Name, err)
+	}
+}
+
+// cleanSnapshots cancels the backups and restores recorded by the job if they are still running,
+// and drops the backup snapshots. It is called when none of them is needed: before a new full sync begins,
+// after the restore succeeds, and after the job is deleted. The snapshots failed to clean are kept and
+// cleaned next time.
+func (j *Job) cleanSnapshots() {
+	snapshots, err := j.db.GetSnapshots(j.Name)
+	if err != nil {
+		log.Warnf("get snapshots failed, job: %s, err: %+v", j.Name, err)
+		return
+	}
+
+	for _, snapshot := range snapshots {
+		if err := j.cleanSnapshot(snapshot); err != nil {
+			log.Warnf("clean %s %s failed, job: %s, err: %+v", snapshot.Kind, snapshot.Label, j.Name, err)
+			continue
+		}
+		if err := j.db.RemoveSnapshot(snapshot.Id); err != nil {
+			log.Warnf("remove snapshot record failed, job: %s, label: %s, err: %+v", j.Name, snapshot.Label, err)
+		}
+	}
+}
+
+func (j *Job) cleanSnapshot(snapshot *storage.Snapshot) error {
+	log.Infof("clean %s %s, job: %s", snapshot.Kind, snapshot.Label, j.Name)
+
+	specer, getProgress := j.ISrc, j.ISrc.GetBackupProgress
+	if snapshot.Kind == storage.SnapshotKindRestore {
+		specer, getProgress = j.IDest, j.IDest.GetRestoreProgress
+	}
+
+	progress, err := getProgress(snapshot.Label)
+	switch {
+	case err == nil:
+		// only one backup or restore runs in a database, so the running one is of the job
+		if !progress.Finished() {
+			if err := j.cancelBackupRestore(snapshot.Kind); err != nil {
+				return err
+			}
+		}
+	case errors.Is(err, base.ErrBackupRestoreCancelled) || errors.Is(err, base.ErrBackupRestoreNotFound):
+	default:
+		return err
+	}
+
+	// the snapshot uploaded to the repository is left to the retention of the repository
+	if snapshot.Kind != storage.SnapshotKindBackup || j.isRepositorySync() {
+		return nil
+	}
+	if err := specer.DropSnapshot(snapshot.Label); errors.Is(err, base.ErrDropSnapshotUnsupported) {
+		log.Warnf("snapshot %s is kept until it is expired, err: %v", snapshot.Label, err)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// cancelBackupRestore cancels the running backup of src or restore of dest
+func (j *Job) cancelBackupRestore(kind string) error {
+	specer, database := j.ISrc, j.Src.Database
+	if kind == storage.SnapshotKindRestore {
+		specer, database = j.IDest, j.Dest.Database
+	}
+
+	return specer.Exec(fmt.Sprintf("CANCEL %s FROM `%s`", strings.ToUpper(kind), database))
+}
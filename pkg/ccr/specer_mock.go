@@ -55,19 +55,19 @@ func (mr *MockSpecerMockRecorder) CheckDatabaseExists() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckDatabaseExists", reflect.TypeOf((*MockSpecer)(nil).CheckDatabaseExists))
 }
 
-// CheckRestoreFinished mocks base method.
-func (m *MockSpecer) CheckRestoreFinished(snapshotName string) (bool, error) {
+// CheckRepositoryExists mocks base method.
+func (m *MockSpecer) CheckRepositoryExists() (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckRestoreFinished", snapshotName)
+	ret := m.ctrl.Call(m, "CheckRepositoryExists")
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CheckRestoreFinished indicates an expected call of CheckRestoreFinished.
-func (mr *MockSpecerMockRecorder) CheckRestoreFinished(snapshotName any) *gomock.Call {
+// CheckRepositoryExists indicates an expected call of CheckRepositoryExists.
+func (mr *MockSpecerMockRecorder) CheckRepositoryExists() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRestoreFinished", reflect.TypeOf((*MockSpecer)(nil).CheckRestoreFinished), snapshotName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRepositoryExists", reflect.TypeOf((*MockSpecer)(nil).CheckRepositoryExists))
 }
 
 // CheckTableExists mocks base method.
@@ -143,19 +143,19 @@ func (mr *MockSpecerMockRecorder) CreateDatabase() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDatabase", reflect.TypeOf((*MockSpecer)(nil).CreateDatabase))
 }
 
-// CreateSnapshotAndWaitForDone mocks base method.
-func (m *MockSpecer) CreateSnapshotAndWaitForDone(tables []string) (string, error) {
+// CreateSnapshot mocks base method.
+func (m *MockSpecer) CreateSnapshot(tables []string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSnapshotAndWaitForDone", tables)
+	ret := m.ctrl.Call(m, "CreateSnapshot", tables)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateSnapshotAndWaitForDone indicates an expected call of CreateSnapshotAndWaitForDone.
-func (mr *MockSpecerMockRecorder) CreateSnapshotAndWaitForDone(tables any) *gomock.Call {
+// CreateSnapshot indicates an expected call of CreateSnapshot.
+func (mr *MockSpecerMockRecorder) CreateSnapshot(tables any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSnapshotAndWaitForDone", reflect.TypeOf((*MockSpecer)(nil).CreateSnapshotAndWaitForDone), tables)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSnapshot", reflect.TypeOf((*MockSpecer)(nil).CreateSnapshot), tables)
 }
 
 // CreateTable mocks base method.
@@ -186,6 +186,20 @@ func (mr *MockSpecerMockRecorder) DbExec(sql any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DbExec", reflect.TypeOf((*MockSpecer)(nil).DbExec), sql)
 }
 
+// DropSnapshot mocks base method.
+func (m *MockSpecer) DropSnapshot(snapshotName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DropSnapshot", snapshotName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DropSnapshot indicates an expected call of DropSnapshot.
+func (mr *MockSpecerMockRecorder) DropSnapshot(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropSnapshot", reflect.TypeOf((*MockSpecer)(nil).DropSnapshot), snapshotName)
+}
+
 // Exec mocks base method.
 func (m *MockSpecer) Exec(sql string) error {
 	m.ctrl.T.Helper()
@@ -215,6 +229,51 @@ func (mr *MockSpecerMockRecorder) GetAllTables() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllTables", reflect.TypeOf((*MockSpecer)(nil).GetAllTables))
 }
 
+// GetBackupProgress mocks base method.
+func (m *MockSpecer) GetBackupProgress(snapshotName string) (*base.BackupRestoreProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBackupProgress", snapshotName)
+	ret0, _ := ret[0].(*base.BackupRestoreProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBackupProgress indicates an expected call of GetBackupProgress.
+func (mr *MockSpecerMockRecorder) GetBackupProgress(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBackupProgress", reflect.TypeOf((*MockSpecer)(nil).GetBackupProgress), snapshotName)
+}
+
+// GetRepositorySnapshot mocks base method.
+func (m *MockSpecer) GetRepositorySnapshot(snapshotName string) (*base.RepositorySnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRepositorySnapshot", snapshotName)
+	ret0, _ := ret[0].(*base.RepositorySnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRepositorySnapshot indicates an expected call of GetRepositorySnapshot.
+func (mr *MockSpecerMockRecorder) GetRepositorySnapshot(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepositorySnapshot", reflect.TypeOf((*MockSpecer)(nil).GetRepositorySnapshot), snapshotName)
+}
+
+// GetRestoreProgress mocks base method.
+func (m *MockSpecer) GetRestoreProgress(snapshotName string) (*base.BackupRestoreProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRestoreProgress", snapshotName)
+	ret0, _ := ret[0].(*base.BackupRestoreProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRestoreProgress indicates an expected call of GetRestoreProgress.
+func (mr *MockSpecerMockRecorder) GetRestoreProgress(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRestoreProgress", reflect.TypeOf((*MockSpecer)(nil).GetRestoreProgress), snapshotName)
+}
+
 // IsDatabaseEnableBinlog mocks base method.
 func (m *MockSpecer) IsDatabaseEnableBinlog() (bool, error) {
 	m.ctrl.T.Helper()
@@ -5,8 +5,10 @@ import (
 	"github.com/selectdb/ccr_syncer/pkg/rpc"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
 	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/btree"
 )
 
@@ -30,6 +32,30 @@ func NewThriftMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory, tableIds []int64
 	}
 
 	// Step 1: get backends
+	if meta.Backends, err = getBackendMetas(feRpc, spec); err != nil {
+		return nil, err
+	}
+
+	// Step 2: get table metas
+	tableMetaResp, err := feRpc.GetTableMeta(spec, tableIds)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := getTableMetas(tableMetaResp, &meta.DatabaseMeta)
+	if err != nil {
+		return nil, err
+	}
+	for _, tableMeta := range tables {
+		meta.Tables[tableMeta.Id] = tableMeta
+		meta.TableName2IdMap[tableMeta.Name] = tableMeta.Id
+	}
+
+	return &ThriftMeta{
+		meta: meta,
+	}, nil
+}
+
+func getBackendMetas(feRpc rpc.IFeRpc, spec *base.Spec) (map[int64]*base.Backend, error) {
 	backendMetaResp, err := feRpc.GetBackends(spec)
 	if err != nil {
 		return nil, err
@@ -43,6 +69,7 @@ func NewThriftMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory, tableIds []int64
 		return nil, xerror.New(xerror.Meta, "get backend meta failed, backend meta not set")
 	}
 
+	backends := make(map[int64]*base.Backend)
 	for _, backend := range backendMetaResp.GetBackends() {
 		backendMeta := &base.Backend{
 			Id:       backend.GetId(),
@@ -51,15 +78,14 @@ func NewThriftMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory, tableIds []int64
 			HttpPort: uint16(backend.GetHttpPort()),
 			BrpcPort: uint16(backend.GetBrpcPort()),
 		}
-		meta.Backends[backendMeta.Id] = backendMeta
-	}
-
-	// Step 2: get table metas
-	tableMetaResp, err := feRpc.GetTableMeta(spec, tableIds)
-	if err != nil {
-		return nil, err
+		backends[backendMeta.Id] = backendMeta
 	}
+	return backends, nil
+}
 
+// getTableMetas returns the table metas of the GetMeta result, only the requested partitions are in
+// them if the partitions are specified in the request
+func getTableMetas(tableMetaResp *festruct.TGetMetaResult_, dbMeta *DatabaseMeta) ([]*TableMeta, error) {
 	if tableMetaResp.GetStatus().GetStatusCode() != tstatus.TStatusCode_OK {
 		return nil, xerror.Errorf(xerror.Meta, "get table meta failed, status: %s", tableMetaResp.GetStatus())
 	}
@@ -68,16 +94,16 @@ func NewThriftMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory, tableIds []int64
 		return nil, xerror.New(xerror.Meta, "get table meta failed, db meta not set")
 	}
 
+	tables := make([]*TableMeta, 0, len(tableMetaResp.GetDbMeta().GetTables()))
 	for _, table := range tableMetaResp.GetDbMeta().GetTables() {
 		tableMeta := &TableMeta{
-			DatabaseMeta:      &meta.DatabaseMeta,
+			DatabaseMeta:      dbMeta,
 			Id:                table.GetId(),
 			Name:              table.GetName(),
 			PartitionIdMap:    make(map[int64]*PartitionMeta),
 			PartitionRangeMap: make(map[string]*PartitionMeta),
 		}
-		meta.Tables[tableMeta.Id] = tableMeta
-		meta.TableName2IdMap[tableMeta.Name] = tableMeta.Id
+		tables = append(tables, tableMeta)
 
 		for _, partition := range table.GetPartitions() {
 			partitionMeta := &PartitionMeta{
@@ -126,20 +152,45 @@ func NewThriftMeta(spec *base.Spec, rpcFactory rpc.IRpcFactory, tableIds []int64
 			}
 		}
 	}
-
-	return &ThriftMeta{
-		meta: meta,
-	}, nil
+	return tables, nil
 }
 
 type ThriftMeta struct {
 	meta *Meta
+	// the tables are refreshed from cache when an id is not found in them, it is nil if not cached
+	cache     *MetaCache
+	refreshed map[int64]bool
+}
+
+// getTable returns the table meta, if found is false with it, the table is refreshed once and checked again,
+// since the partitions, indexes or tablets may be changed after it is cached
+func (tm *ThriftMeta) getTable(tableId int64, found func(tableMeta *TableMeta) bool) (*TableMeta, bool) {
+	tableMeta, ok := tm.meta.Tables[tableId]
+	if (ok && found(tableMeta)) || tm.cache == nil || tm.refreshed[tableId] {
+		return tableMeta, ok
+	}
+
+	tm.refreshed[tableId] = true
+	refreshed, err := tm.cache.refreshTable(tableId)
+	if err != nil {
+		log.Warnf("refresh meta of table %d failed, err: %+v", tableId, err)
+		return tableMeta, ok
+	}
+	if refreshed == nil {
+		delete(tm.meta.Tables, tableId)
+		return nil, false
+	}
+	tm.meta.Tables[tableId] = refreshed
+	return refreshed, true
 }
 
 func (tm *ThriftMeta) GetTablets(tableId, partitionId, indexId int64) (*btree.Map[int64, *TabletMeta], error) {
 	dbId := tm.meta.Id
 
-	tableMeta, ok := tm.meta.Tables[tableId]
+	tableMeta, ok := tm.getTable(tableId, func(tableMeta *TableMeta) bool {
+		partitionMeta, ok := tableMeta.PartitionIdMap[partitionId]
+		return ok && partitionMeta.IndexIdMap[indexId] != nil
+	})
 	if !ok {
 		return nil, xerror.Errorf(xerror.Meta, "dbId: %d, tableId: %d not found", dbId, tableId)
 	}
@@ -160,7 +211,10 @@ func (tm *ThriftMeta) GetTablets(tableId, partitionId, indexId int64) (*btree.Ma
 func (tm *ThriftMeta) GetPartitionIdByRange(tableId int64, partitionRange string) (int64, error) {
 	dbId := tm.meta.Id
 
-	tableMeta, ok := tm.meta.Tables[tableId]
+	tableMeta, ok := tm.getTable(tableId, func(tableMeta *TableMeta) bool {
+		_, ok := tableMeta.PartitionRangeMap[partitionRange]
+		return ok
+	})
 	if !ok {
 		return 0, xerror.Errorf(xerror.Meta, "dbId: %d, tableId: %d not found", dbId, tableId)
 	}
@@ -176,7 +230,7 @@ func (tm *ThriftMeta) GetPartitionIdByRange(tableId int64, partitionRange string
 func (tm *ThriftMeta) GetPartitionRangeMap(tableId int64) (map[string]*PartitionMeta, error) {
 	dbId := tm.meta.Id
 
-	tableMeta, ok := tm.meta.Tables[tableId]
+	tableMeta, ok := tm.getTable(tableId, func(*TableMeta) bool { return true })
 	if !ok {
 		return nil, xerror.Errorf(xerror.Meta, "dbId: %d, tableId: %d not found", dbId, tableId)
 	}
@@ -187,7 +241,10 @@ func (tm *ThriftMeta) GetPartitionRangeMap(tableId int64) (map[string]*Partition
 func (tm *ThriftMeta) GetIndexIdMap(tableId, partitionId int64) (map[int64]*IndexMeta, error) {
 	dbId := tm.meta.Id
 
-	tableMeta, ok := tm.meta.Tables[tableId]
+	tableMeta, ok := tm.getTable(tableId, func(tableMeta *TableMeta) bool {
+		_, ok := tableMeta.PartitionIdMap[partitionId]
+		return ok
+	})
 	if !ok {
 		return nil, xerror.Errorf(xerror.Meta, "dbId: %d, tableId: %d not found", dbId, tableId)
 	}
@@ -203,7 +260,10 @@ func (tm *ThriftMeta) GetIndexIdMap(tableId, partitionId int64) (map[int64]*Inde
 func (tm *ThriftMeta) GetIndexNameMap(tableId, partitionId int64) (map[string]*IndexMeta, error) {
 	dbId := tm.meta.Id
 
-	tableMeta, ok := tm.meta.Tables[tableId]
+	tableMeta, ok := tm.getTable(tableId, func(tableMeta *TableMeta) bool {
+		_, ok := tableMeta.PartitionIdMap[partitionId]
+		return ok
+	})
 	if !ok {
 		return nil, xerror.Errorf(xerror.Meta, "dbId: %d, tableId: %d not found", dbId, tableId)
 	}
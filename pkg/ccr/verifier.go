@@ -0,0 +1,545 @@
+package ccr
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// verify states of a partition
+const (
+	VerifyConsistent = "consistent"
+	// dest is behind src, it is expected when the job is lagging
+	VerifyLagging = "lagging"
+	// src is changed while verifying, verify it again later
+	VerifyChanged  = "changed"
+	VerifyMismatch = "mismatch"
+)
+
+// columnsPlaceholder in the checksum expression is replaced by all columns of the table
+const columnsPlaceholder = "{columns}"
+
+type VerifyOptions struct {
+	// src tables to verify, empty means all tables of the job
+	Tables []string `json:"tables,omitempty"`
+	// aggregate expression of the partition checksum, such as sum(murmur_hash3_32(concat_ws('|', {columns}))),
+	// empty means comparing the visible versions and row counts only
+	Checksum string `json:"checksum,omitempty"`
+	// full sync the tables again if any of their partitions mismatches
+	Resync bool `json:"resync,omitempty"`
+}
+
+type PartitionVerify struct {
+	Name         string `json:"name"`
+	State        string `json:"state"`
+	Reason       string `json:"reason,omitempty"`
+	SrcVersion   int64  `json:"src_version"`
+	DestVersion  int64  `json:"dest_version"`
+	SrcRows      int64  `json:"src_rows"`
+	DestRows     int64  `json:"dest_rows"`
+	SrcChecksum  string `json:"src_checksum,omitempty"`
+	DestChecksum string `json:"dest_checksum,omitempty"`
+}
+
+type TableVerify struct {
+	Table         string `json:"table"`
+	DestTable     string `json:"dest_table"`
+	Error         string `json:"error,omitempty"`
+	ConsistentNum int    `json:"consistent_num"`
+	// only the partitions not consistent
+	Partitions []*PartitionVerify `json:"partitions,omitempty"`
+}
+
+type VerifyReport struct {
+	Job        string `json:"job"`
+	StartedAt  int64  `json:"started_at"` // unix milli
+	DurationMs int64  `json:"duration_ms"`
+	// all partitions of all tables are consistent
+	Consistent  bool `json:"consistent"`
+	MismatchNum int  `json:"mismatch_num"`
+	// the full sync of the mismatched tables is requested, it starts in the next incremental sync of the job
+	Resynced bool           `json:"resynced"`
+	Tables   []*TableVerify `json:"tables"`
+}
+
+// partitionStat is the visible version, row count and checksum of a partition
+type partitionStat struct {
+	name     string
+	version  int64
+	rows     int64
+	checksum string
+}
+
+// quoteIdentifier quotes the table, partition or column name, the backquotes in it are escaped
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func tableColumns(db *sql.DB, table string) ([]string, error) {
+	query := fmt.Sprintf("DESC %s", quoteIdentifier(table))
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, query)
+	}
+	defer rows.Close()
+
+	columns := make([]string, 0)
+	for rows.Next() {
+		rowParser := utils.NewRowParser()
+		if err := rowParser.Parse(rows); err != nil {
+			return nil, xerror.Wrap(err, xerror.Normal, query)
+		}
+		column, err := rowParser.GetString("Field")
+		if err != nil {
+			return nil, xerror.Wrap(err, xerror.Normal, query)
+		}
+		columns = append(columns, quoteIdentifier(column))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, query)
+	}
+	return columns, nil
+}
+
+// partitionVersions returns the visible versions of the table, keyed by partition range
+func partitionVersions(meta Metaer, tableId int64) (map[string]*PartitionMeta, error) {
+	if err := meta.UpdatePartitions(tableId); err != nil {
+		return nil, err
+	}
+	return meta.GetPartitionRangeMap(tableId)
+}
+
+// partitionStats returns the stats of all partitions of the table, keyed by partition range
+func partitionStats(specer base.Specer, meta Metaer, table string, checksum string) (map[string]*partitionStat, error) {
+	tableId, err := meta.GetTableId(table)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := partitionVersions(meta, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := specer.ConnectDB()
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]*partitionStat, len(partitions))
+	for partitionRange, partition := range partitions {
+		stat := &partitionStat{name: partition.Name, version: partition.VisibleVersion}
+		if checksum == "" {
+			query := fmt.Sprintf("SELECT count(*) FROM %s PARTITION (%s)", quoteIdentifier(table), quoteIdentifier(partition.Name))
+			if err := db.QueryRow(query).Scan(&stat.rows); err != nil {
+				return nil, xerror.Wrap(err, xerror.Normal, query)
+			}
+		} else {
+			query := fmt.Sprintf("SELECT count(*), %s FROM %s PARTITION (%s)", checksum, quoteIdentifier(table), quoteIdentifier(partition.Name))
+			var value sql.NullString
+			if err := db.QueryRow(query).Scan(&stat.rows, &value); err != nil {
+				return nil, xerror.Wrap(err, xerror.Normal, query)
+			}
+			stat.checksum = value.String
+		}
+		stats[partitionRange] = stat
+	}
+	return stats, nil
+}
+
+// comparePartitions compares the partition stats of src and dest, srcVersions and destVersions are read
+// again after dest, so the partitions not changed are at the same point. caughtUp means the job had
+// no lag and applied nothing while dest was read, then dest must be the same as src.
+func comparePartitions(src, dest map[string]*partitionStat, srcVersions, destVersions map[string]*PartitionMeta, caughtUp bool) []*PartitionVerify {
+	verifies := make([]*PartitionVerify, 0, len(src))
+	for partitionRange, srcStat := range src {
+		verify := &PartitionVerify{
+			Name:        srcStat.name,
+			SrcVersion:  srcStat.version,
+			SrcRows:     srcStat.rows,
+			SrcChecksum: srcStat.checksum,
+		}
+		verifies = append(verifies, verify)
+
+		if partition, ok := srcVersions[partitionRange]; !ok || partition.VisibleVersion != srcStat.version {
+			verify.State = VerifyChanged
+			continue
+		}
+
+		destStat, ok := dest[partitionRange]
+		if !ok {
+			verify.State = VerifyLagging
+			verify.Reason = "partition not found in dest"
+			if caughtUp {
+				verify.State = VerifyMismatch
+			}
+			continue
+		}
+		verify.DestVersion = destStat.version
+		verify.DestRows = destStat.rows
+		verify.DestChecksum = destStat.checksum
+		if partition, ok := destVersions[partitionRange]; !ok || partition.VisibleVersion != destStat.version {
+			verify.State = VerifyChanged
+			verify.Reason = "dest is changed while verifying"
+			continue
+		}
+
+		switch {
+		case destStat.version > srcStat.version:
+			verify.State = VerifyMismatch
+			verify.Reason = "dest version is newer than src"
+		case destStat.version < srcStat.version:
+			verify.State = VerifyLagging
+			if caughtUp {
+				verify.State = VerifyMismatch
+				verify.Reason = "dest version is older than src but the job has no lag"
+			}
+		case destStat.rows != srcStat.rows:
+			verify.State = VerifyMismatch
+			verify.Reason = "row count mismatch"
+		case destStat.checksum != srcStat.checksum:
+			verify.State = VerifyMismatch
+			verify.Reason = "checksum mismatch"
+		default:
+			verify.State = VerifyConsistent
+		}
+	}
+
+	for partitionRange, destStat := range dest {
+		if _, ok := src[partitionRange]; ok {
+			continue
+		}
+		verify := &PartitionVerify{
+			Name:         destStat.name,
+			State:        VerifyLagging,
+			Reason:       "partition not found in src",
+			DestVersion:  destStat.version,
+			DestRows:     destStat.rows,
+			DestChecksum: destStat.checksum,
+		}
+		if caughtUp {
+			verify.State = VerifyMismatch
+		}
+		verifies = append(verifies, verify)
+	}
+
+	sort.Slice(verifies, func(i, k int) bool { return verifies[i].Name < verifies[k].Name })
+	return verifies
+}
+
+// verifyTable compares the partitions of src table and dest table, only the inconsistent ones are reported
+func (j *Job) verifyTable(verifier *tableVerifier, table, destTable string) *TableVerify {
+	tableVerify := &TableVerify{Table: table, DestTable: destTable}
+	partitions, err := verifier.verify(j, table, destTable)
+	if err != nil {
+		log.Warnf("verify table failed, job: %s, table: %s, err: %+v", j.Name, table, err)
+		tableVerify.Error = err.Error()
+		return tableVerify
+	}
+
+	for _, partition := range partitions {
+		if partition.State == VerifyConsistent {
+			tableVerify.ConsistentNum++
+		} else {
+			tableVerify.Partitions = append(tableVerify.Partitions, partition)
+		}
+	}
+	return tableVerify
+}
+
+// tableVerifier uses its own specers and metas, the cached metas of job are not touched
+type tableVerifier struct {
+	src      base.Specer
+	srcMeta  Metaer
+	dest     base.Specer
+	destMeta Metaer
+	checksum string
+}
+
+// verify reads src, dest, then src and dest versions again in order. The job lock is only held to read
+// the commit seq and lag, the partitions changed while reading are skipped, and the job is treated as
+// lagging if it commits any binlog meanwhile.
+func (v *tableVerifier) verify(j *Job, table, destTable string) ([]*PartitionVerify, error) {
+	checksum := v.checksum
+	if strings.Contains(checksum, columnsPlaceholder) {
+		db, err := v.src.ConnectDB()
+		if err != nil {
+			return nil, err
+		}
+		columns, err := tableColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+		checksum = strings.ReplaceAll(checksum, columnsPlaceholder, strings.Join(columns, ", "))
+	}
+
+	srcStats, err := partitionStats(v.src, v.srcMeta, table, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	commitSeq, caughtUp := j.verifyPoint()
+	destStats, err := partitionStats(v.dest, v.destMeta, destTable, checksum)
+	if err != nil {
+		return nil, err
+	}
+	if caughtUp {
+		j.lock.Lock()
+		caughtUp = j.progress.CommitSeq == commitSeq
+		j.lock.Unlock()
+	}
+
+	srcTableId, err := v.srcMeta.GetTableId(table)
+	if err != nil {
+		return nil, err
+	}
+	srcVersions, err := partitionVersions(v.srcMeta, srcTableId)
+	if err != nil {
+		return nil, err
+	}
+	destTableId, err := v.destMeta.GetTableId(destTable)
+	if err != nil {
+		return nil, err
+	}
+	destVersions, err := partitionVersions(v.destMeta, destTableId)
+	if err != nil {
+		return nil, err
+	}
+	return comparePartitions(srcStats, destStats, srcVersions, destVersions, caughtUp), nil
+}
+
+// verifyPoint returns the commit seq of the job and whether the job has no lag at it
+func (j *Job) verifyPoint() (int64, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	commitSeq := j.progress.CommitSeq
+	lag, err := j.getLag()
+	if err != nil {
+		log.Warnf("get lag failed, treat the job as lagging, job: %s, err: %+v", j.Name, err)
+		return commitSeq, false
+	}
+	return commitSeq, lag == 0
+}
+
+// verifyTables returns the src and dest tables to verify
+func (j *Job) verifyTables(srcMeta Metaer, tables []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	switch j.SyncType {
+	case TableSync:
+		mapping[j.Src.Table] = j.Dest.Table
+	case DBSync:
+		srcTables, err := srcMeta.GetTables()
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range srcTables {
+			mapping[table.Name] = table.Name
+		}
+	default:
+		return nil, xerror.Errorf(xerror.Normal, "invalid sync type %s", j.SyncType)
+	}
+	if len(tables) == 0 {
+		return mapping, nil
+	}
+
+	filtered := make(map[string]string, len(tables))
+	for _, table := range tables {
+		destTable, ok := mapping[table]
+		if !ok {
+			return nil, xerror.Errorf(xerror.Normal, "table %s is not synced by job %s", table, j.Name)
+		}
+		filtered[table] = destTable
+	}
+	return filtered, nil
+}
+
+// Verify compares the visible versions, row counts and checksums of the partitions between src and dest
+func (j *Job) Verify(options *VerifyOptions) (*VerifyReport, error) {
+	if !j.verifyLock.TryLock() {
+		return nil, xerror.Errorf(xerror.Normal, "job %s is being verified", j.Name)
+	}
+	defer j.verifyLock.Unlock()
+
+	log.Infof("verify job %s, options: %+v", j.Name, options)
+	startedAt := time.Now()
+
+	j.lock.Lock()
+	if j.progress == nil || !j.isIncrementalSync() {
+		j.lock.Unlock()
+		return nil, xerror.Errorf(xerror.Normal, "job %s is not in incremental sync, verify it after full sync", j.Name)
+	}
+	src, dest := j.Src, j.Dest
+	j.lock.Unlock()
+
+	verifier := &tableVerifier{
+		src:      j.factory.NewSpecer(&src),
+		srcMeta:  j.factory.NewMeta(&src),
+		dest:     j.factory.NewSpecer(&dest),
+		destMeta: j.factory.NewMeta(&dest),
+		checksum: options.Checksum,
+	}
+	tables, err := j.verifyTables(verifier.srcMeta, options.Tables)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	report := &VerifyReport{
+		Job:        j.Name,
+		StartedAt:  startedAt.UnixMilli(),
+		Consistent: true,
+		Tables:     make([]*TableVerify, 0, len(names)),
+	}
+	for _, table := range names {
+		tableVerify := j.verifyTable(verifier, table, tables[table])
+		if tableVerify.Error != "" || len(tableVerify.Partitions) > 0 {
+			report.Consistent = false
+		}
+		for _, partition := range tableVerify.Partitions {
+			if partition.State == VerifyMismatch {
+				report.MismatchNum++
+			}
+		}
+		report.Tables = append(report.Tables, tableVerify)
+	}
+
+	if report.MismatchNum > 0 {
+		log.Warnf("job %s has %d mismatched partitions", j.Name, report.MismatchNum)
+		if options.Resync {
+			tables := make([]string, 0, len(report.Tables))
+			for _, tableVerify := range report.Tables {
+				for _, partition := range tableVerify.Partitions {
+					if partition.State == VerifyMismatch {
+						tables = append(tables, tableVerify.Table)
+						break
+					}
+				}
+			}
+			j.resync(tables)
+			report.Resynced = true
+		}
+	}
+
+	report.DurationMs = time.Since(startedAt).Milliseconds()
+	xmetrics.SetVerifyMismatches(j.Name, report.MismatchNum)
+	j.lastVerify.Store(report)
+	return report, nil
+}
+
+// resync asks the job goroutine to full sync the src tables from the current commit seq, their dest tables
+// are restored from a new snapshot of these tables only. The tables of the requests not handled yet are merged.
+func (j *Job) resync(tables []string) {
+	log.Warnf("resync tables %v of job %s for the mismatched partitions", tables, j.Name)
+	for {
+		prev := j.resyncRequested.Load()
+		next := tables
+		if prev != nil {
+			next = append(append([]string{}, *prev...), tables...)
+		}
+		if j.resyncRequested.CompareAndSwap(prev, &next) {
+			return
+		}
+	}
+}
+
+// LastVerifyReport returns the report of the last verify, nil means it is never verified
+func (j *Job) LastVerifyReport() *VerifyReport {
+	return j.lastVerify.Load()
+}
+
+// Verifier verifies all running jobs of this syncer periodically, zero interval disables it
+type Verifier struct {
+	jobManager *JobManager
+	stop       chan struct{}
+	reload     chan struct{}
+
+	// options and interval can be reloaded at runtime
+	lock     sync.Mutex
+	options  VerifyOptions
+	interval time.Duration
+}
+
+func NewVerifier(jobManager *JobManager) *Verifier {
+	return &Verifier{
+		jobManager: jobManager,
+		stop:       make(chan struct{}),
+		reload:     make(chan struct{}, 1),
+	}
+}
+
+// Reload changes the verify options and interval, the next verify is scheduled with the new interval
+func (v *Verifier) Reload(options VerifyOptions, interval time.Duration) {
+	v.lock.Lock()
+	v.options = options
+	v.interval = interval
+	v.lock.Unlock()
+
+	select {
+	case v.reload <- struct{}{}:
+	default:
+	}
+}
+
+func (v *Verifier) settings() (VerifyOptions, time.Duration) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	return v.options, v.interval
+}
+
+func (v *Verifier) verifyAll() {
+	options, _ := v.settings()
+	for _, job := range v.jobManager.runningJobs() {
+		report, err := job.Verify(&options)
+		if err != nil {
+			log.Infof("skip verify job %s: %v", job.Name, err)
+			continue
+		}
+		log.Infof("job %s verified, consistent: %t, mismatched partitions: %d, resynced: %t",
+			job.Name, report.Consistent, report.MismatchNum, report.Resynced)
+	}
+}
+
+func (v *Verifier) Start() {
+	for {
+		var tick <-chan time.Time
+		var timer *time.Timer
+		if _, interval := v.settings(); interval > 0 {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+
+		select {
+		case <-v.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			log.Info("verifier stopped")
+			return
+		case <-v.reload:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-tick:
+			v.verifyAll()
+		}
+	}
+}
+
+func (v *Verifier) Stop() {
+	log.Info("verifier stopping")
+	close(v.stop)
+}
@@ -0,0 +1,77 @@
+package ccr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparePartitions(t *testing.T) {
+	src := map[string]*partitionStat{
+		"[0, 10)":  {name: "p1", version: 3, rows: 10, checksum: "100"},
+		"[10, 20)": {name: "p2", version: 5, rows: 20, checksum: "200"},
+		"[20, 30)": {name: "p3", version: 2, rows: 30},
+		"[30, 40)": {name: "p4", version: 4, rows: 40},
+		"[40, 50)": {name: "p5", version: 2, rows: 50, checksum: "500"},
+		"[50, 60)": {name: "p6", version: 2, rows: 60},
+		"[70, 80)": {name: "p8", version: 2, rows: 80},
+	}
+	dest := map[string]*partitionStat{
+		"[0, 10)":  {name: "p1", version: 3, rows: 10, checksum: "100"},
+		"[10, 20)": {name: "p2", version: 4, rows: 18, checksum: "180"},
+		"[20, 30)": {name: "p3", version: 3, rows: 31},
+		"[30, 40)": {name: "p4", version: 4, rows: 40},
+		"[40, 50)": {name: "p5", version: 2, rows: 50, checksum: "501"},
+		"[60, 70)": {name: "p7", version: 2, rows: 70},
+		"[70, 80)": {name: "p8", version: 2, rows: 79},
+	}
+	srcVersions := map[string]*PartitionMeta{
+		"[0, 10)":  {VisibleVersion: 3},
+		"[10, 20)": {VisibleVersion: 5},
+		"[20, 30)": {VisibleVersion: 2},
+		"[30, 40)": {VisibleVersion: 5}, // changed while verifying
+		"[40, 50)": {VisibleVersion: 2},
+		"[50, 60)": {VisibleVersion: 2},
+		"[70, 80)": {VisibleVersion: 2},
+	}
+	destVersions := map[string]*PartitionMeta{
+		"[0, 10)":  {VisibleVersion: 3},
+		"[10, 20)": {VisibleVersion: 4},
+		"[20, 30)": {VisibleVersion: 3},
+		"[30, 40)": {VisibleVersion: 4},
+		"[40, 50)": {VisibleVersion: 2},
+		"[60, 70)": {VisibleVersion: 2},
+		"[70, 80)": {VisibleVersion: 3}, // changed while verifying
+	}
+
+	states := func(caughtUp bool) map[string]string {
+		result := make(map[string]string)
+		for _, verify := range comparePartitions(src, dest, srcVersions, destVersions, caughtUp) {
+			result[verify.Name] = verify.State
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string]string{
+		"p1": VerifyConsistent,
+		"p2": VerifyLagging,
+		"p3": VerifyMismatch,
+		"p4": VerifyChanged,
+		"p5": VerifyMismatch,
+		"p6": VerifyLagging,
+		"p7": VerifyLagging,
+		"p8": VerifyChanged,
+	}, states(false))
+
+	// dest must be the same as src if the job has no lag
+	assert.Equal(t, map[string]string{
+		"p1": VerifyConsistent,
+		"p2": VerifyMismatch,
+		"p3": VerifyMismatch,
+		"p4": VerifyChanged,
+		"p5": VerifyMismatch,
+		"p6": VerifyMismatch,
+		"p7": VerifyMismatch,
+		"p8": VerifyChanged,
+	}, states(true))
+}
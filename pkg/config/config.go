@@ -0,0 +1,329 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the syncer config file, fields tagged `reload:"true"` take effect on SIGHUP,
+// others need restart.
+type Config struct {
+	Storage     StorageConfig     `yaml:"storage"`
+	Http        HttpConfig        `yaml:"http"`
+	Log         LogConfig         `yaml:"log"`
+	Timeouts    TimeoutConfig     `yaml:"timeouts"`
+	Intervals   IntervalConfig    `yaml:"intervals"`
+	History     HistoryConfig     `yaml:"history"`
+	JobDefaults JobDefaultsConfig `yaml:"job_defaults"`
+	Trace       TraceConfig       `yaml:"trace"`
+	Health      HealthConfig      `yaml:"health"`
+	Verify      VerifyConfig      `yaml:"verify"`
+	Fault       FaultConfig       `yaml:"fault"`
+}
+
+type StorageConfig struct {
+	Type     string `yaml:"type"`
+	Dir      string `yaml:"dir"` // sqlite3 db file
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+}
+
+type HttpConfig struct {
+	Host            string `yaml:"host"`
+	Port            int    `yaml:"port"`
+	AuthFile        string `yaml:"auth_file"`
+	TlsCertFile     string `yaml:"tls_cert_file"`
+	TlsKeyFile      string `yaml:"tls_key_file"`
+	TlsClientCaFile string `yaml:"tls_client_ca_file"`
+}
+
+type LogConfig struct {
+	Level        string `yaml:"level" reload:"true"`
+	Filename     string `yaml:"filename"`
+	AlsoToStderr bool   `yaml:"also_to_stderr"`
+}
+
+type TimeoutConfig struct {
+	RpcConnect time.Duration `yaml:"rpc_connect"`
+	Rpc        time.Duration `yaml:"rpc"`
+	// max times to check backup/restore state before timeout
+	MaxCheckRetryTimes int `yaml:"max_check_retry_times" reload:"true"`
+	// timeout and retry policies of rpc methods, override the builtin ones
+	RpcMethods map[string]rpc.CallPolicy `yaml:"rpc_methods" reload:"true"`
+	RpcBreaker RpcBreakerConfig          `yaml:"rpc_breaker"`
+}
+
+// RpcBreakerConfig is the circuit breaker of each fe/be address, zero failures disables it
+type RpcBreakerConfig struct {
+	Failures    int           `yaml:"failures" reload:"true"`
+	OpenTimeout time.Duration `yaml:"open_timeout" reload:"true"`
+}
+
+type IntervalConfig struct {
+	Sync         time.Duration `yaml:"sync" reload:"true"`
+	BackupCheck  time.Duration `yaml:"backup_check" reload:"true"`
+	RestoreCheck time.Duration `yaml:"restore_check" reload:"true"`
+	PruneHistory time.Duration `yaml:"prune_history" reload:"true"`
+	// the cached table metas of jobs are got from fe again after it
+	MetaCacheTTL time.Duration `yaml:"meta_cache_ttl" reload:"true"`
+	// dead syncer timeout is derived from it, so all syncers must use the same value
+	Check time.Duration `yaml:"check"`
+}
+
+type HistoryConfig struct {
+	Retention time.Duration `yaml:"retention" reload:"true"`
+	MaxRows   int           `yaml:"max_rows" reload:"true"`
+}
+
+// JobDefaultsConfig is used by create_ccr when the request doesn't set it
+type JobDefaultsConfig struct {
+	SkipError bool `yaml:"skip_error" reload:"true"`
+}
+
+type TraceConfig struct {
+	// none, otlp, stdout or file
+	Exporter string `yaml:"exporter"`
+	// otlp http endpoint of the collector, host:port
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// HealthConfig is the thresholds to flag unhealthy jobs in job_health, zero means no limit
+type HealthConfig struct {
+	MaxLag        int64         `yaml:"max_lag" reload:"true"`
+	MaxNoProgress time.Duration `yaml:"max_no_progress" reload:"true"`
+}
+
+// VerifyConfig is the scheduled verify of all running jobs, zero interval disables it
+type VerifyConfig struct {
+	Interval time.Duration `yaml:"interval" reload:"true"`
+	// aggregate expression of the partition checksum, {columns} is replaced by all columns of the table
+	Checksum string `yaml:"checksum" reload:"true"`
+	// full sync the tables again if any of their partitions mismatches
+	Resync bool `yaml:"resync" reload:"true"`
+}
+
+// FaultConfig injects faults into rpc and sql calls for chaos tests, never enable it in production.
+// The rules are replaced by the debug endpoint, and set back to these ones on reload.
+type FaultConfig struct {
+	Enable bool         `yaml:"enable"`
+	Rules  []fault.Rule `yaml:"rules" reload:"true"`
+}
+
+// Default returns the config same as the compiled in defaults
+func Default() *Config {
+	return &Config{
+		Storage: StorageConfig{
+			Type: "sqlite3",
+			Dir:  "ccr.db",
+			Host: "127.0.0.1",
+			Port: 3306,
+			User: "root",
+		},
+		Http: HttpConfig{
+			Host: "127.0.0.1",
+			Port: 9190,
+		},
+		Log: LogConfig{
+			Level: "trace",
+		},
+		Timeouts: TimeoutConfig{
+			RpcConnect:         1 * time.Second,
+			Rpc:                3 * time.Second,
+			MaxCheckRetryTimes: 86400,
+			RpcBreaker: RpcBreakerConfig{
+				Failures:    rpc.BREAKER_FAILURES,
+				OpenTimeout: rpc.BREAKER_OPEN_TIMEOUT,
+			},
+		},
+		Intervals: IntervalConfig{
+			Sync:         3 * time.Second,
+			BackupCheck:  3 * time.Second,
+			RestoreCheck: 3 * time.Second,
+			PruneHistory: 10 * time.Minute,
+			MetaCacheTTL: 10 * time.Minute,
+			Check:        5 * time.Second,
+		},
+		History: HistoryConfig{
+			Retention: 7 * 24 * time.Hour,
+			MaxRows:   10000,
+		},
+		Trace: TraceConfig{
+			Exporter:    "none",
+			Endpoint:    "127.0.0.1:4318",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			MaxLag:        1000,
+			MaxNoProgress: 30 * time.Minute,
+		},
+	}
+}
+
+// Load reads the yaml config file, the missing fields keep default values
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read config file %s failed", path)
+	}
+
+	config := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// typo of a field should fail, not be ignored silently
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, xerror.Wrapf(err, xerror.Normal, "parse config file %s failed", path)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "invalid config file %s", path)
+	}
+	return config, nil
+}
+
+func (c *Config) Validate() error {
+	switch c.Storage.Type {
+	case "sqlite3", "mysql":
+	default:
+		return xerror.Errorf(xerror.Normal, "unknown storage.type %s", c.Storage.Type)
+	}
+
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "invalid log.level %s", c.Log.Level)
+	}
+
+	positives := map[string]time.Duration{
+		"timeouts.rpc_connect":     c.Timeouts.RpcConnect,
+		"timeouts.rpc":             c.Timeouts.Rpc,
+		"intervals.sync":           c.Intervals.Sync,
+		"intervals.backup_check":   c.Intervals.BackupCheck,
+		"intervals.restore_check":  c.Intervals.RestoreCheck,
+		"intervals.prune_history":  c.Intervals.PruneHistory,
+		"intervals.meta_cache_ttl": c.Intervals.MetaCacheTTL,
+		"intervals.check":          c.Intervals.Check,
+	}
+	for name, duration := range positives {
+		if duration <= 0 {
+			return xerror.Errorf(xerror.Normal, "%s must be positive, but got %s", name, duration)
+		}
+	}
+	if c.Timeouts.MaxCheckRetryTimes <= 0 {
+		return xerror.Errorf(xerror.Normal, "timeouts.max_check_retry_times must be positive")
+	}
+	if err := rpc.ValidateCallPolicies(c.Timeouts.RpcMethods); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "invalid timeouts.rpc_methods")
+	}
+	if c.Timeouts.RpcBreaker.Failures < 0 || c.Timeouts.RpcBreaker.OpenTimeout < 0 {
+		return xerror.Errorf(xerror.Normal, "timeouts.rpc_breaker.failures and timeouts.rpc_breaker.open_timeout must not be negative")
+	}
+	if c.History.Retention < 0 || c.History.MaxRows < 0 {
+		return xerror.Errorf(xerror.Normal, "history.retention and history.max_rows must not be negative")
+	}
+	switch c.Trace.Exporter {
+	case "none", "otlp", "stdout":
+	case "file":
+		if c.Trace.File == "" {
+			return xerror.Errorf(xerror.Normal, "trace.file is required by file exporter")
+		}
+	default:
+		return xerror.Errorf(xerror.Normal, "unknown trace.exporter %s", c.Trace.Exporter)
+	}
+	if c.Health.MaxLag < 0 || c.Health.MaxNoProgress < 0 {
+		return xerror.Errorf(xerror.Normal, "health.max_lag and health.max_no_progress must not be negative")
+	}
+	if c.Verify.Interval < 0 {
+		return xerror.Errorf(xerror.Normal, "verify.interval must not be negative")
+	}
+	if c.Trace.SampleRatio < 0 || c.Trace.SampleRatio > 1 {
+		return xerror.Errorf(xerror.Normal, "trace.sample_ratio must be in [0, 1]")
+	}
+	for _, rule := range c.Fault.Rules {
+		if err := rule.Validate(); err != nil {
+			return xerror.Wrap(err, xerror.Normal, "invalid fault.rules")
+		}
+	}
+	return nil
+}
+
+// Change is a changed field between two configs
+type Change struct {
+	Path       string
+	Old        any
+	New        any
+	Reloadable bool
+}
+
+func (c *Change) String() string {
+	if strings.Contains(c.Path, "password") {
+		return fmt.Sprintf("%s: ****** -> ******", c.Path)
+	}
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff returns all changed fields from c to other
+func (c *Config) Diff(other *Config) []Change {
+	changes := make([]Change, 0)
+	diffStruct("", reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem(), &changes)
+	return changes
+}
+
+func diffStruct(prefix string, oldValue, newValue reflect.Value, changes *[]Change) {
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		path := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(path, oldValue.Field(i), newValue.Field(i), changes)
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			*changes = append(*changes, Change{
+				Path:       path,
+				Old:        oldValue.Field(i).Interface(),
+				New:        newValue.Field(i).Interface(),
+				Reloadable: field.Tag.Get("reload") == "true",
+			})
+		}
+	}
+}
+
+// Reload returns the config to apply: reloadable fields from other, and the others kept from c.
+func (c *Config) Reload(other *Config) *Config {
+	reloaded := *c
+	reloadStruct(reflect.ValueOf(&reloaded).Elem(), reflect.ValueOf(other).Elem())
+	return &reloaded
+}
+
+func reloadStruct(value, newValue reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			reloadStruct(value.Field(i), newValue.Field(i))
+			continue
+		}
+		if field.Tag.Get("reload") == "true" {
+			value.Field(i).Set(newValue.Field(i))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "ccr_syncer.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	config, err := Load(writeConfig(t, `
+storage:
+  type: mysql
+  password: secret
+intervals:
+  sync: 10s
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "mysql", config.Storage.Type)
+	assert.Equal(t, 10*time.Second, config.Intervals.Sync)
+	// missing fields keep defaults
+	assert.Equal(t, Default().Http, config.Http)
+
+	_, err = Load(writeConfig(t, "intervals:\n  synk: 10s\n"))
+	assert.Error(t, err, "unknown field")
+	_, err = Load(writeConfig(t, "intervals:\n  sync: 0s\n"))
+	assert.Error(t, err, "non positive interval")
+	_, err = Load(writeConfig(t, "log:\n  level: loud\n"))
+	assert.Error(t, err, "invalid log level")
+
+	config, err = Load(writeConfig(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), config)
+}
+
+func TestDiffAndReload(t *testing.T) {
+	current := Default()
+	other := Default()
+	other.Log.Level = "info"
+	other.Http.Port = 9191
+	other.Storage.Password = "secret"
+
+	changes := current.Diff(other)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "storage.password: ****** -> ******", changes[0].String())
+	assert.False(t, changes[0].Reloadable)
+	assert.Equal(t, "http.port: 9190 -> 9191", changes[1].String())
+	assert.False(t, changes[1].Reloadable)
+	assert.Equal(t, "log.level: trace -> info", changes[2].String())
+	assert.True(t, changes[2].Reloadable)
+
+	reloaded := current.Reload(other)
+	assert.Equal(t, "info", reloaded.Log.Level)
+	assert.Equal(t, 9190, reloaded.Http.Port)
+	assert.Equal(t, "", reloaded.Storage.Password)
+	assert.Equal(t, "trace", current.Log.Level)
+}
+
+func TestLoadFaultRules(t *testing.T) {
+	config, err := Load(writeConfig(t, `
+fault:
+  enable: true
+  rules:
+    - method: CommitTransaction
+      action: status
+      status: PUBLISH_TIMEOUT
+      after_call: true
+      commit_seqs: [10, 12]
+    - method: IngestBinlog
+      action: delay
+      delay: 500ms
+      probability: 0.5
+`))
+	require.NoError(t, err)
+	assert.True(t, config.Fault.Enable)
+	require.Len(t, config.Fault.Rules, 2)
+	assert.Equal(t, []int64{10, 12}, config.Fault.Rules[0].CommitSeqs)
+	assert.True(t, config.Fault.Rules[0].AfterCall)
+	assert.Equal(t, 500*time.Millisecond, time.Duration(config.Fault.Rules[1].Delay))
+
+	_, err = Load(writeConfig(t, "fault:\n  rules:\n    - method: CommitTransaction\n      action: status\n      status: NOT_A_STATUS\n"))
+	assert.Error(t, err, "unknown status")
+	_, err = Load(writeConfig(t, "fault:\n  rules:\n    - method: Exec\n      action: status\n      status: OK\n"))
+	assert.Error(t, err, "status of sql method")
+}
+
+func TestLoadRpcPolicies(t *testing.T) {
+	config, err := Load(writeConfig(t, `
+timeouts:
+  rpc_methods:
+    GetSnapshot:
+      timeout: 2m
+      retries: 3
+      backoff: 500ms
+      max_backoff: 5s
+    CommitTransaction:
+      timeout: 1m
+  rpc_breaker:
+    failures: 10
+`))
+	require.NoError(t, err)
+	require.Len(t, config.Timeouts.RpcMethods, 2)
+	assert.Equal(t, 2*time.Minute, config.Timeouts.RpcMethods["GetSnapshot"].Timeout)
+	assert.Equal(t, 3, config.Timeouts.RpcMethods["GetSnapshot"].Retries)
+	assert.Equal(t, 10, config.Timeouts.RpcBreaker.Failures)
+	assert.Equal(t, Default().Timeouts.RpcBreaker.OpenTimeout, config.Timeouts.RpcBreaker.OpenTimeout)
+
+	_, err = Load(writeConfig(t, "timeouts:\n  rpc_methods:\n    GetSnapshots:\n      timeout: 1m\n"))
+	assert.Error(t, err, "unknown method")
+	_, err = Load(writeConfig(t, "timeouts:\n  rpc_methods:\n    CommitTransaction:\n      retries: 1\n"))
+	assert.Error(t, err, "retry method with side effects")
+	_, err = Load(writeConfig(t, "timeouts:\n  rpc_breaker:\n    failures: -1\n"))
+	assert.Error(t, err, "negative breaker failures")
+
+	other := Default()
+	other.Timeouts.RpcMethods = config.Timeouts.RpcMethods
+	other.Timeouts.RpcBreaker.Failures = 10
+	changes := Default().Diff(other)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "timeouts.rpc_methods", changes[0].Path)
+	assert.True(t, changes[0].Reloadable)
+	assert.Equal(t, "timeouts.rpc_breaker.failures", changes[1].Path)
+	assert.True(t, changes[1].Reloadable)
+}
@@ -0,0 +1,115 @@
+package fakedoris
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	bestruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+)
+
+// registry maps the http address of every fake backend to its cluster, so the backends of the
+// dest cluster can download the binlog of a remote tablet from the src cluster.
+var registry = struct {
+	lock     sync.Mutex
+	clusters map[string]*Cluster
+}{clusters: make(map[string]*Cluster)}
+
+func register(addr string, cluster *Cluster) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.clusters[addr] = cluster
+}
+
+func unregister(addr string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	delete(registry.clusters, addr)
+}
+
+func lookup(addr string) *Cluster {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	return registry.clusters[addr]
+}
+
+// backendService implements IngestBinlog of one backend, the others are left to the embedded
+// nil interface and panic if called.
+type backendService struct {
+	bestruct.BackendService
+
+	cluster   *Cluster
+	backendId int64
+}
+
+func (be *backendService) IngestBinlog(ctx context.Context, req *bestruct.TIngestBinlogRequest) (*bestruct.TIngestBinlogResult_, error) {
+	be.cluster.recordRpc("IngestBinlog")
+	result := &bestruct.TIngestBinlogResult_{}
+
+	// Step 1: download the binlog of the remote tablet, without holding the local catalog lock
+	remoteAddr := fmt.Sprintf("%s:%s", req.GetRemoteHost(), req.GetRemotePort())
+	remote := lookup(remoteAddr)
+	if remote == nil {
+		result.Status = newStatus(tstatus.TStatusCode_HTTP_ERROR, "remote be %s not found", remoteAddr)
+		return result, nil
+	}
+	remoteIndexPos, remoteBucket, rows, err := remote.catalog.binlogRows(req.GetRemoteTabletId(), req.GetBinlogVersion())
+	if err != nil {
+		result.Status = newStatus(tstatus.TStatusCode_RUNTIME_ERROR, "%v", err)
+		return result, nil
+	}
+
+	// Step 2: check the txn and the local tablet, which must be paired with the remote one
+	c := be.cluster.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txn, ok := c.txns[req.GetTxnId()]
+	if !ok || txn.Status != TxnStatusPrepare {
+		result.Status = newStatus(tstatus.TStatusCode_RUNTIME_ERROR, "transaction [%d] not found", req.GetTxnId())
+		return result, nil
+	}
+
+	_, partition, indexPos, bucket, tablet := c.findTablet(req.GetLocalTabletId())
+	if tablet == nil {
+		result.Status = newStatus(tstatus.TStatusCode_TABLET_MISSING, "local tablet %d not found", req.GetLocalTabletId())
+		return result, nil
+	}
+	if partition.Id != req.GetPartitionId() {
+		result.Status = newStatus(tstatus.TStatusCode_RUNTIME_ERROR, "local tablet %d is not in partition %d", tablet.Id, req.GetPartitionId())
+		return result, nil
+	}
+	if indexPos != remoteIndexPos || bucket != remoteBucket {
+		result.Status = newStatus(tstatus.TStatusCode_RUNTIME_ERROR, "local tablet %d (index %d, bucket %d) is not paired with remote tablet %d (index %d, bucket %d)",
+			tablet.Id, indexPos, bucket, req.GetRemoteTabletId(), remoteIndexPos, remoteBucket)
+		return result, nil
+	}
+	hasReplica := false
+	for _, replica := range tablet.Replicas {
+		hasReplica = hasReplica || replica.BackendId == be.backendId
+	}
+	if !hasReplica {
+		result.Status = newStatus(tstatus.TStatusCode_TABLET_MISSING, "local tablet %d has no replica on backend %d", tablet.Id, be.backendId)
+		return result, nil
+	}
+
+	txn.ingested[partition.Id] = rows
+	result.Status = okStatus()
+	return result, nil
+}
+
+// showConfig serves the be config api used by the binlog feature check
+func showConfig(w http.ResponseWriter, r *http.Request) {
+	configs := [][]string{}
+	if item := r.URL.Query().Get("conf_item"); item == "" || item == "enable_feature_binlog" {
+		configs = append(configs, []string{"enable_feature_binlog", "bool", "true", "true"})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(configs)
+}
@@ -0,0 +1,909 @@
+package fakedoris
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+)
+
+const (
+	binlogEnableProperty = `"binlog.enable" = "true"`
+
+	// the max binlogs returned by one GetBinlog call, small enough to make the syncer page
+	maxBinlogsPerPage = 64
+
+	// at most 3 replicas per tablet, like the default replication_num
+	maxReplicationNum = 3
+
+	TxnStatusPrepare = "PREPARE"
+	TxnStatusVisible = "VISIBLE"
+	TxnStatusAborted = "ABORTED"
+
+	JobStatePending   = "PENDING"
+	JobStateFinished  = "FINISHED"
+	JobStateCancelled = "CANCELLED"
+)
+
+type Backend struct {
+	Id       int64
+	Host     string
+	BePort   int
+	HttpPort int
+	BrpcPort int
+}
+
+type Replica struct {
+	Id        int64
+	BackendId int64
+	Version   int64
+}
+
+type Tablet struct {
+	Id       int64
+	Replicas []*Replica
+}
+
+type Index struct {
+	Id   int64
+	Name string
+}
+
+type Partition struct {
+	Id             int64
+	Name           string
+	Range          string
+	Buckets        int
+	VisibleVersion int64
+	// tablets of each index, the syncer pairs the tablets of two clusters by their position
+	Tablets map[int64][]*Tablet
+	// the row count of each visible version, loads only append rows
+	RowsAt map[int64]int64
+}
+
+func (p *Partition) Rows() int64 {
+	return p.RowsAt[p.VisibleVersion]
+}
+
+type Table struct {
+	Id   int64
+	Name string
+	// Body is the create table statement after the table name
+	Body                string
+	Columns             []string
+	DistributionColumns []string // empty means DISTRIBUTED BY RANDOM
+	Buckets             int
+	BinlogEnabled       bool
+	Indexes             []*Index
+	Partitions          []*Partition
+}
+
+func (t *Table) CreateSql() string {
+	sql := fmt.Sprintf("CREATE TABLE `%s` %s", t.Name, t.Body)
+	if t.BinlogEnabled && !strings.Contains(sql, binlogEnableProperty) {
+		sql += "\nPROPERTIES (\n" + binlogEnableProperty + "\n)"
+	}
+	return sql
+}
+
+func (t *Table) Rows() int64 {
+	var rows int64
+	for _, partition := range t.Partitions {
+		rows += partition.Rows()
+	}
+	return rows
+}
+
+func (t *Table) partition(name string) *Partition {
+	for _, partition := range t.Partitions {
+		if partition.Name == name {
+			return partition
+		}
+	}
+	return nil
+}
+
+type Database struct {
+	Id            int64
+	Name          string
+	BinlogEnabled bool
+	Tables        []*Table
+	Binlogs       []*festruct.TBinlog
+}
+
+func (db *Database) CreateSql() string {
+	if !db.BinlogEnabled {
+		return fmt.Sprintf("CREATE DATABASE `%s`", db.Name)
+	}
+	return fmt.Sprintf("CREATE DATABASE `%s`\nPROPERTIES (\n%s\n)", db.Name, binlogEnableProperty)
+}
+
+func (db *Database) table(name string) *Table {
+	for _, table := range db.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+func (db *Database) tableById(tableId int64) *Table {
+	for _, table := range db.Tables {
+		if table.Id == tableId {
+			return table
+		}
+	}
+	return nil
+}
+
+type Txn struct {
+	Id       int64
+	DbId     int64
+	Label    string
+	TableIds []int64
+	Status   string
+	// the rows ingested into each partition, set by the BackendService
+	ingested map[int64]int64
+}
+
+type snapshotPartition struct {
+	Name    string `json:"name"`
+	Range   string `json:"range"`
+	Buckets int    `json:"buckets"`
+	Version int64  `json:"version"`
+	Rows    int64  `json:"rows"`
+}
+
+type snapshotTable struct {
+	Name                string              `json:"name"`
+	Body                string              `json:"body"`
+	Columns             []string            `json:"columns"`
+	DistributionColumns []string            `json:"distribution_columns"`
+	Buckets             int                 `json:"buckets"`
+	Partitions          []snapshotPartition `json:"partitions"`
+}
+
+type backupJob struct {
+	Database string
+	// the repository the snapshot is uploaded to, empty if it is kept on local
+	Repository string
+	State      string
+	Meta       []byte
+	JobInfo    []byte
+	// tasks of the pending job, like [taskId=backendId, ...]
+	UnfinishedTasks string
+}
+
+type restoreJob struct {
+	Database        string
+	State           string
+	Status          string
+	UnfinishedTasks string
+	// the properties of the restore request
+	Properties map[string]string
+}
+
+// Catalog is the in-memory metadata and data versions of a fake cluster.
+// Every mutation that Doris writes into the binlog appends a binlog to its database.
+type Catalog struct {
+	lock sync.Mutex
+
+	nextId    int64
+	commitSeq int64
+	backends  []*Backend
+	dbs       []*Database
+	txns      map[int64]*Txn
+	backups   map[string]*backupJob
+	restores  map[string]*restoreJob
+	// the locations of the repositories by name
+	repositories map[string]string
+	// the new backup and restore jobs are pending until released, e.g. to test waiting for them
+	holdJobs bool
+
+	// the master token is read by the restores of other clusters, it has its own lock
+	tokenLock sync.Mutex
+	token     string
+}
+
+func newCatalog(idStart int64, token string) *Catalog {
+	return &Catalog{
+		nextId:   idStart,
+		token:    token,
+		txns:     make(map[int64]*Txn),
+		backups:  make(map[string]*backupJob),
+		restores: make(map[string]*restoreJob),
+
+		repositories: make(map[string]string),
+	}
+}
+
+func (c *Catalog) masterToken() string {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+
+	return c.token
+}
+
+func (c *Catalog) allocId() int64 {
+	id := c.nextId
+	c.nextId++
+	return id
+}
+
+func (c *Catalog) database(name string) (*Database, error) {
+	name = strings.TrimPrefix(name, "default_cluster:")
+	for _, db := range c.dbs {
+		if db.Name == name {
+			return db, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown database '%s'", name)
+}
+
+func (c *Catalog) databaseById(dbId int64) (*Database, error) {
+	for _, db := range c.dbs {
+		if db.Id == dbId {
+			return db, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown database id %d", dbId)
+}
+
+func (c *Catalog) table(dbName, tableName string) (*Database, *Table, error) {
+	db, err := c.database(dbName)
+	if err != nil {
+		return nil, nil, err
+	}
+	table := db.table(tableName)
+	if table == nil {
+		return nil, nil, fmt.Errorf("Unknown table '%s'", tableName)
+	}
+	return db, table, nil
+}
+
+func (c *Catalog) createDatabase(name string, binlogEnabled, ifNotExists bool) error {
+	if _, err := c.database(name); err == nil {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("Can't create database '%s'; database exists", name)
+	}
+
+	c.dbs = append(c.dbs, &Database{
+		Id:            c.allocId(),
+		Name:          name,
+		BinlogEnabled: binlogEnabled,
+	})
+	return nil
+}
+
+func (c *Catalog) dropDatabase(name string, ifExists bool) error {
+	for i, db := range c.dbs {
+		if db.Name == name {
+			c.dbs = append(c.dbs[:i], c.dbs[i+1:]...)
+			return nil
+		}
+	}
+	if ifExists {
+		return nil
+	}
+	return fmt.Errorf("Can't drop database '%s'; database doesn't exist", name)
+}
+
+func (c *Catalog) alterDatabaseBinlog(db *Database, enabled bool) {
+	if db.BinlogEnabled == enabled {
+		return
+	}
+	// the property change itself is recorded in the binlog
+	if enabled {
+		db.BinlogEnabled = true
+	}
+	c.appendBinlog(db, nil, festruct.TBinlogType_ALTER_DATABASE_PROPERTY, nil, map[string]any{
+		"dbId":       db.Id,
+		"dbName":     db.Name,
+		"properties": map[string]string{"binlog.enable": fmt.Sprint(enabled)},
+	})
+	db.BinlogEnabled = enabled
+}
+
+func (c *Catalog) newPartition(table *Table, name, partitionRange string, buckets int) *Partition {
+	partition := &Partition{
+		Id:             c.allocId(),
+		Name:           name,
+		Range:          partitionRange,
+		Buckets:        buckets,
+		VisibleVersion: 1,
+		Tablets:        make(map[int64][]*Tablet),
+		RowsAt:         map[int64]int64{1: 0},
+	}
+
+	replicationNum := len(c.backends)
+	if replicationNum > maxReplicationNum {
+		replicationNum = maxReplicationNum
+	}
+	for _, index := range table.Indexes {
+		tablets := make([]*Tablet, 0, buckets)
+		for bucket := 0; bucket < buckets; bucket++ {
+			tablet := &Tablet{Id: c.allocId()}
+			for i := 0; i < replicationNum; i++ {
+				backend := c.backends[(bucket+i)%len(c.backends)]
+				tablet.Replicas = append(tablet.Replicas, &Replica{
+					Id:        c.allocId(),
+					BackendId: backend.Id,
+					Version:   1,
+				})
+			}
+			tablets = append(tablets, tablet)
+		}
+		partition.Tablets[index.Id] = tablets
+	}
+	return partition
+}
+
+// newTable builds a table with its base index and one partition named after the table,
+// the partitions of a range partitioned table are added by ALTER TABLE ADD PARTITION.
+func (c *Catalog) newTable(db *Database, name string, def *tableDef) *Table {
+	table := &Table{
+		Id:                  c.allocId(),
+		Name:                name,
+		Body:                def.body,
+		Columns:             def.columns,
+		DistributionColumns: def.distributionColumns,
+		Buckets:             def.buckets,
+		BinlogEnabled:       db.BinlogEnabled || strings.Contains(def.body, binlogEnableProperty),
+	}
+	table.Indexes = []*Index{{Id: c.allocId(), Name: name}}
+	if !def.partitioned {
+		table.Partitions = []*Partition{c.newPartition(table, name, "", def.buckets)}
+	}
+	for _, partition := range def.partitions {
+		table.Partitions = append(table.Partitions, c.newPartition(table, partition.name, partition.partitionRange, def.buckets))
+	}
+	return table
+}
+
+func (c *Catalog) createTable(db *Database, name string, def *tableDef, ifNotExists bool) error {
+	if db.table(name) != nil {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("Table '%s' already exists", name)
+	}
+
+	table := c.newTable(db, name, def)
+	db.Tables = append(db.Tables, table)
+	c.appendBinlog(db, table, festruct.TBinlogType_CREATE_TABLE, []int64{table.Id}, map[string]any{
+		"dbId":    db.Id,
+		"tableId": table.Id,
+		"sql":     fmt.Sprintf("CREATE TABLE `%s` %s", name, def.body),
+	})
+	return nil
+}
+
+func (c *Catalog) dropTable(db *Database, table *Table, rawSql string) {
+	for i, t := range db.Tables {
+		if t == table {
+			db.Tables = append(db.Tables[:i], db.Tables[i+1:]...)
+			break
+		}
+	}
+	c.appendBinlog(db, table, festruct.TBinlogType_DROP_TABLE, []int64{table.Id}, map[string]any{
+		"dbId":      db.Id,
+		"tableId":   table.Id,
+		"tableName": table.Name,
+		"rawSql":    rawSql,
+	})
+}
+
+func (c *Catalog) addPartition(db *Database, table *Table, name, partitionRange string, buckets int, sql string) error {
+	if table.partition(name) != nil {
+		return fmt.Errorf("Duplicate partition name %s", name)
+	}
+	if buckets == 0 {
+		buckets = table.Buckets
+	}
+
+	table.Partitions = append(table.Partitions, c.newPartition(table, name, partitionRange, buckets))
+
+	distributionType := "RANDOM"
+	distributionColumns := make([]map[string]string, 0, len(table.DistributionColumns))
+	if len(table.DistributionColumns) > 0 {
+		distributionType = "HASH"
+		for _, column := range table.DistributionColumns {
+			distributionColumns = append(distributionColumns, map[string]string{"name": column})
+		}
+	}
+	c.appendBinlog(db, table, festruct.TBinlogType_ADD_PARTITION, []int64{table.Id}, map[string]any{
+		"dbId":    db.Id,
+		"tableId": table.Id,
+		"sql":     sql,
+		"partition": map[string]any{
+			"distributionInfo": map[string]any{
+				"bucketNum":           buckets,
+				"type":                distributionType,
+				"distributionColumns": distributionColumns,
+			},
+		},
+	})
+	return nil
+}
+
+func (c *Catalog) dropPartition(db *Database, table *Table, name string) error {
+	for i, partition := range table.Partitions {
+		if partition.Name == name {
+			table.Partitions = append(table.Partitions[:i], table.Partitions[i+1:]...)
+			c.appendBinlog(db, table, festruct.TBinlogType_DROP_PARTITION, []int64{table.Id}, map[string]any{
+				"tableId": table.Id,
+				"sql":     fmt.Sprintf("DROP PARTITION %s", name),
+			})
+			return nil
+		}
+	}
+	return fmt.Errorf("Error in list of partitions to %s", name)
+}
+
+func (c *Catalog) modifyColumns(db *Database, table *Table, add []string, drop []string, rawSql string) error {
+	for _, column := range add {
+		for _, existed := range table.Columns {
+			if existed == column {
+				return fmt.Errorf("Can not add column which already exists in base table: %s", column)
+			}
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	for _, column := range drop {
+		found := false
+		for i, existed := range table.Columns {
+			if existed == column {
+				table.Columns = append(table.Columns[:i], table.Columns[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Column does not exists: %s", column)
+		}
+	}
+
+	c.appendBinlog(db, table, festruct.TBinlogType_MODIFY_TABLE_ADD_OR_DROP_COLUMNS, []int64{table.Id}, map[string]any{
+		"dbId":    db.Id,
+		"tableId": table.Id,
+		"rawSql":  rawSql,
+	})
+	return nil
+}
+
+// truncateTable replaces the truncated partitions by empty ones with new ids, like Doris does
+func (c *Catalog) truncateTable(db *Database, table *Table, partitionNames []string, rawSql string) error {
+	truncated := make(map[string]bool)
+	for _, name := range partitionNames {
+		if table.partition(name) == nil {
+			return fmt.Errorf("Partition %s does not exist", name)
+		}
+		truncated[name] = true
+	}
+
+	for i, partition := range table.Partitions {
+		if len(truncated) == 0 || truncated[partition.Name] {
+			table.Partitions[i] = c.newPartition(table, partition.Name, partition.Range, partition.Buckets)
+		}
+	}
+
+	c.appendBinlog(db, table, festruct.TBinlogType_TRUNCATE_TABLE, []int64{table.Id}, map[string]any{
+		"dbId":          db.Id,
+		"db":            db.Name,
+		"tblId":         table.Id,
+		"table":         table.Name,
+		"isEntireTable": len(truncated) == 0,
+		"rawSql":        rawSql,
+	})
+	return nil
+}
+
+// publish makes the loaded rows of a txn visible, one new version per loaded partition
+func (c *Catalog) publish(db *Database, txnId int64, label string, loaded map[int64]int64) error {
+	type partitionRecord struct {
+		PartitionId int64  `json:"partitionId"`
+		Range       string `json:"range"`
+		Version     int64  `json:"version"`
+	}
+	type tableRecord struct {
+		PartitionRecords []partitionRecord `json:"partitionRecords"`
+		IndexIds         []int64           `json:"indexIds"`
+	}
+
+	tableRecords := make(map[int64]*tableRecord)
+	tableIds := make([]int64, 0)
+	published := 0
+	var binlogTable *Table
+	for _, table := range db.Tables {
+		for _, partition := range table.Partitions {
+			rows, ok := loaded[partition.Id]
+			if !ok {
+				continue
+			}
+
+			version := partition.VisibleVersion + 1
+			partition.RowsAt[version] = partition.Rows() + rows
+			partition.VisibleVersion = version
+			for _, tablets := range partition.Tablets {
+				for _, tablet := range tablets {
+					for _, replica := range tablet.Replicas {
+						replica.Version = version
+					}
+				}
+			}
+
+			record, ok := tableRecords[table.Id]
+			if !ok {
+				record = &tableRecord{}
+				for _, index := range table.Indexes {
+					record.IndexIds = append(record.IndexIds, index.Id)
+				}
+				tableRecords[table.Id] = record
+				tableIds = append(tableIds, table.Id)
+				binlogTable = table
+			}
+			record.PartitionRecords = append(record.PartitionRecords, partitionRecord{
+				PartitionId: partition.Id,
+				Range:       partition.Range,
+				Version:     version,
+			})
+			published++
+		}
+	}
+	if published != len(loaded) {
+		return fmt.Errorf("some partitions of %v not found", loaded)
+	}
+
+	if len(tableIds) > 1 {
+		binlogTable = nil
+	}
+	c.appendBinlog(db, binlogTable, festruct.TBinlogType_UPSERT, tableIds, map[string]any{
+		"txnId":        txnId,
+		"timeStamp":    time.Now().UnixMilli(),
+		"label":        label,
+		"dbId":         db.Id,
+		"tableRecords": tableRecords,
+	})
+	return nil
+}
+
+// load is a committed and published insert into one partition
+func (c *Catalog) load(db *Database, partition *Partition, rows int64) error {
+	txnId := c.allocId()
+	label := fmt.Sprintf("insert_%d", txnId)
+	c.txns[txnId] = &Txn{Id: txnId, DbId: db.Id, Label: label, Status: TxnStatusVisible}
+	return c.publish(db, txnId, label, map[int64]int64{partition.Id: rows})
+}
+
+// appendBinlog records a binlog if the binlog of the db, or of the table for table level binlogs, is enabled
+func (c *Catalog) appendBinlog(db *Database, table *Table, binlogType festruct.TBinlogType, tableIds []int64, data map[string]any) {
+	if !db.BinlogEnabled && (table == nil || !table.BinlogEnabled) {
+		return
+	}
+
+	c.commitSeq++
+	commitSeq := c.commitSeq
+	if binlogType == festruct.TBinlogType_UPSERT {
+		data["commitSeq"] = commitSeq
+	}
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+
+	timestamp := time.Now().UnixMilli()
+	dbId := db.Id
+	belong := int64(-1)
+	if table != nil {
+		belong = table.Id
+	}
+	dataStr := string(bytes)
+	db.Binlogs = append(db.Binlogs, &festruct.TBinlog{
+		CommitSeq: &commitSeq,
+		Timestamp: &timestamp,
+		Type:      &binlogType,
+		DbId:      &dbId,
+		TableIds:  tableIds,
+		Data:      &dataStr,
+		Belong:    &belong,
+	})
+}
+
+// binlogsAfter returns the binlogs of the db, or of a table if tableId is not 0, after prevCommitSeq
+func (c *Catalog) binlogsAfter(db *Database, tableId int64, prevCommitSeq int64) []*festruct.TBinlog {
+	binlogs := make([]*festruct.TBinlog, 0)
+	for _, binlog := range db.Binlogs {
+		if binlog.GetCommitSeq() <= prevCommitSeq {
+			continue
+		}
+		if tableId != 0 && !containsId(binlog.GetTableIds(), tableId) {
+			continue
+		}
+		binlogs = append(binlogs, binlog)
+	}
+	return binlogs
+}
+
+func (c *Catalog) backup(db *Database, snapshotName string, repository string, tableNames []string) error {
+	if repository != localRepository {
+		if _, ok := c.repositories[repository]; !ok {
+			return fmt.Errorf("Repository %s does not exist", repository)
+		}
+	} else {
+		repository = ""
+	}
+	if _, ok := c.backups[snapshotName]; ok {
+		return fmt.Errorf("Label %s already exists", snapshotName)
+	}
+	for _, job := range c.backups {
+		if job.Database == db.Name && job.State == JobStatePending {
+			return fmt.Errorf("Can only run one backup or restore job of a database at same time")
+		}
+	}
+
+	tables := make([]snapshotTable, 0, len(tableNames))
+	tableCommitSeqMap := make(map[int64]int64)
+	for _, name := range tableNames {
+		table := db.table(name)
+		if table == nil {
+			return fmt.Errorf("Unknown table '%s'", name)
+		}
+
+		snapshot := snapshotTable{
+			Name:                table.Name,
+			Body:                table.Body,
+			Columns:             table.Columns,
+			DistributionColumns: table.DistributionColumns,
+			Buckets:             table.Buckets,
+		}
+		for _, partition := range table.Partitions {
+			snapshot.Partitions = append(snapshot.Partitions, snapshotPartition{
+				Name:    partition.Name,
+				Range:   partition.Range,
+				Buckets: partition.Buckets,
+				Version: partition.VisibleVersion,
+				Rows:    partition.Rows(),
+			})
+		}
+		tables = append(tables, snapshot)
+		tableCommitSeqMap[table.Id] = c.commitSeq
+	}
+
+	meta, err := json.Marshal(tables)
+	if err != nil {
+		return err
+	}
+	jobInfo, err := json.Marshal(map[string]any{
+		"name":                 snapshotName,
+		"database":             db.Name,
+		"backup_time":          time.Now().UnixMilli(),
+		"table_commit_seq_map": tableCommitSeqMap,
+	})
+	if err != nil {
+		return err
+	}
+
+	state, unfinishedTasks := c.newJobState()
+	job := &backupJob{
+		Database:        db.Name,
+		Repository:      repository,
+		State:           state,
+		Meta:            meta,
+		JobInfo:         jobInfo,
+		UnfinishedTasks: unfinishedTasks,
+	}
+	c.backups[snapshotName] = job
+	if state == JobStateFinished {
+		c.finishBackup(snapshotName, job)
+	}
+	return nil
+}
+
+// restore downloads the snapshot from the src backends by the extra info of job info, and restores its tables
+func (c *Catalog) restore(db *Database, label string, tableRefs []*festruct.TTableRef, meta, jobInfo []byte) error {
+	var info struct {
+		ExtraInfo *struct {
+			BeNetworkMap map[int64]struct {
+				Ip   string `json:"ip"`
+				Port int    `json:"port"`
+			} `json:"be_network_map"`
+			Token string `json:"token"`
+		} `json:"extra_info"`
+	}
+	if err := json.Unmarshal(jobInfo, &info); err != nil {
+		return fmt.Errorf("invalid job info: %v", err)
+	}
+	if info.ExtraInfo == nil || info.ExtraInfo.Token == "" {
+		return fmt.Errorf("job info has no extra info to download the snapshot")
+	}
+
+	// the src backends reject the download with an expired token, the restore job is cancelled
+	for _, addr := range info.ExtraInfo.BeNetworkMap {
+		remote := lookup(net.JoinHostPort(addr.Ip, strconv.Itoa(addr.Port)))
+		if remote != nil && remote.catalog.masterToken() != info.ExtraInfo.Token {
+			c.restores[label] = &restoreJob{
+				Database: db.Name,
+				State:    JobStateCancelled,
+				Status:   "[CANCELLED] download snapshot failed, invalid token",
+			}
+			return nil
+		}
+	}
+
+	return c.restoreTables(db, label, tableRefs, meta)
+}
+
+// restoreFromRepository restores the tables of the snapshot in the repository, the src backends are not reached
+func (c *Catalog) restoreFromRepository(db *Database, label string, repository string, timestamp string, tableRefs []*festruct.TTableRef) error {
+	snapshot, err := c.findRepositorySnapshot(repository, label, timestamp)
+	if err != nil {
+		return err
+	}
+	return c.restoreTables(db, label, tableRefs, snapshot.Meta)
+}
+
+// restoreTables replaces or creates the tables of the snapshot, the versions and rows are kept like
+// a restore with reserve_replica.
+func (c *Catalog) restoreTables(db *Database, label string, tableRefs []*festruct.TTableRef, meta []byte) error {
+	var tables []snapshotTable
+	if err := json.Unmarshal(meta, &tables); err != nil {
+		return fmt.Errorf("invalid snapshot meta: %v", err)
+	}
+
+	aliases := make(map[string]string)
+	for _, tableRef := range tableRefs {
+		aliases[tableRef.GetTable()] = tableRef.GetAliasName()
+	}
+
+	for _, snapshot := range tables {
+		name := snapshot.Name
+		if alias, ok := aliases[name]; ok && alias != "" {
+			name = alias
+		} else if len(aliases) > 0 && !ok {
+			continue
+		}
+
+		def := &tableDef{
+			body:                snapshot.Body,
+			columns:             snapshot.Columns,
+			distributionColumns: snapshot.DistributionColumns,
+			buckets:             snapshot.Buckets,
+			partitioned:         true,
+		}
+		table := db.table(name)
+		if table == nil {
+			table = c.newTable(db, name, def)
+			db.Tables = append(db.Tables, table)
+		} else {
+			table.Columns = snapshot.Columns
+			table.Partitions = nil
+		}
+
+		for _, partitionSnapshot := range snapshot.Partitions {
+			partition := c.newPartition(table, partitionSnapshot.Name, partitionSnapshot.Range, partitionSnapshot.Buckets)
+			partition.VisibleVersion = partitionSnapshot.Version
+			partition.RowsAt = map[int64]int64{partitionSnapshot.Version: partitionSnapshot.Rows}
+			for _, tablets := range partition.Tablets {
+				for _, tablet := range tablets {
+					for _, replica := range tablet.Replicas {
+						replica.Version = partitionSnapshot.Version
+					}
+				}
+			}
+			table.Partitions = append(table.Partitions, partition)
+		}
+	}
+
+	state, unfinishedTasks := c.newJobState()
+	c.restores[label] = &restoreJob{Database: db.Name, State: state, UnfinishedTasks: unfinishedTasks}
+	return nil
+}
+
+// newJobState returns the state of a new backup or restore job, the held job has a task on each backend
+func (c *Catalog) newJobState() (string, string) {
+	if !c.holdJobs {
+		return JobStateFinished, "[]"
+	}
+
+	tasks := make([]string, 0, len(c.backends))
+	for _, backend := range c.backends {
+		tasks = append(tasks, fmt.Sprintf("%d=%d", c.allocId(), backend.Id))
+	}
+	return JobStatePending, "[" + strings.Join(tasks, ", ") + "]"
+}
+
+// dropSnapshot removes the snapshot of a finished or cancelled backup
+func (c *Catalog) dropSnapshot(db *Database, snapshotName string) error {
+	job, ok := c.backups[snapshotName]
+	if !ok || job.Database != db.Name {
+		return fmt.Errorf("snapshot %s does not exist", snapshotName)
+	}
+	if job.State == JobStatePending {
+		return fmt.Errorf("snapshot %s is running", snapshotName)
+	}
+	delete(c.backups, snapshotName)
+	return nil
+}
+
+// cancelJobs cancels the pending backup or restore jobs of the database
+func (c *Catalog) cancelJobs(db *Database, restore bool) {
+	if restore {
+		for _, job := range c.restores {
+			if job.Database == db.Name && job.State == JobStatePending {
+				job.State, job.Status, job.UnfinishedTasks = JobStateCancelled, "[CANCELLED] user cancelled", "[]"
+			}
+		}
+		return
+	}
+	for _, job := range c.backups {
+		if job.Database == db.Name && job.State == JobStatePending {
+			job.State, job.UnfinishedTasks = JobStateCancelled, "[]"
+		}
+	}
+}
+
+// findTablet returns the partition, index position and bucket of a tablet
+func (c *Catalog) findTablet(tabletId int64) (*Table, *Partition, int, int, *Tablet) {
+	for _, db := range c.dbs {
+		for _, table := range db.Tables {
+			for _, partition := range table.Partitions {
+				for indexPos, index := range table.Indexes {
+					for bucket, tablet := range partition.Tablets[index.Id] {
+						if tablet.Id == tabletId {
+							return table, partition, indexPos, bucket, tablet
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil, nil, 0, 0, nil
+}
+
+// binlogRows returns the rows loaded by the binlog version of a tablet
+func (c *Catalog) binlogRows(tabletId, version int64) (int, int, int64, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, partition, indexPos, bucket, tablet := c.findTablet(tabletId)
+	if tablet == nil {
+		return 0, 0, 0, fmt.Errorf("remote tablet %d not found", tabletId)
+	}
+	for _, replica := range tablet.Replicas {
+		if replica.Version < version {
+			return 0, 0, 0, fmt.Errorf("remote tablet %d replica %d version %d is less than binlog version %d",
+				tabletId, replica.Id, replica.Version, version)
+		}
+	}
+	rows, ok := partition.RowsAt[version]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("remote tablet %d binlog version %d not found", tabletId, version)
+	}
+	return indexPos, bucket, rows - partition.RowsAt[version-1], nil
+}
+
+func containsId(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedTableNames(db *Database) []string {
+	names := make([]string, 0, len(db.Tables))
+	for _, table := range db.Tables {
+		names = append(names, table.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,53 @@
+package fakedoris
+
+import (
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChaos checks the rows are synced exactly once, whatever fault the upsert recovers from
+func TestChaos(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2 PROPERTIES ("binlog.enable" = "true")`,
+		`INSERT INTO db1.t1 VALUES (1, 'a')`)
+
+	injector := fault.NewInjector()
+	startJob(t, "chaos", src.Spec("db1", "t1"), dest.Spec("db1", "t1"), injector)
+	requireSynced(t, src, dest, "db1.t1")
+
+	cases := []struct {
+		name string
+		rule fault.Rule
+	}{
+		{"ingest failed", fault.Rule{Method: "IngestBinlog", Action: fault.ActionStatus, Status: "TABLET_MISSING"}},
+		{"commit request lost", fault.Rule{Method: "CommitTransaction", Action: fault.ActionDrop}},
+		{"commit response lost", fault.Rule{Method: "CommitTransaction", Action: fault.ActionDrop, AfterCall: true}},
+		{"commit failed", fault.Rule{Method: "CommitTransaction", Action: fault.ActionError, Error: "connection reset"}},
+		{"publish timeout", fault.Rule{Method: "CommitTransaction", Action: fault.ActionStatus, Status: "PUBLISH_TIMEOUT", AfterCall: true}},
+		{"rollback response lost", fault.Rule{Method: "RollbackTransaction", Action: fault.ActionDrop, AfterCall: true}},
+		{"begin failed", fault.Rule{Method: "BeginTransaction", Action: fault.ActionStatus, Status: "INTERNAL_ERROR"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.rule.Times = 1
+			rules := []fault.Rule{c.rule}
+			// rollback needs a failed commit first
+			if c.rule.Method == "RollbackTransaction" {
+				rules = append(rules, fault.Rule{Method: "CommitTransaction", Action: fault.ActionDrop, Times: 1})
+			}
+			require.NoError(t, injector.SetRules(rules))
+
+			mustExec(t, src, `INSERT INTO db1.t1 VALUES (2, 'b'), (3, 'c')`)
+			requireSynced(t, src, dest, "db1.t1")
+			for _, state := range injector.Rules() {
+				require.Equal(t, 1, state.Injected, "rule %s is not injected", &state.Rule)
+			}
+		})
+	}
+}
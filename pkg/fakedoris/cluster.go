@@ -0,0 +1,359 @@
+// Package fakedoris is an in-process fake Doris cluster: a FrontendService and BackendService speaking
+// thrift, and a mysql protocol endpoint, backed by an in-memory catalog. It implements just enough for
+// the syncer to run full sync and incremental sync end-to-end, without a real cluster.
+package fakedoris
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/kitex/server"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	beservice "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice/backendservice"
+	feservice "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice/frontendservice"
+)
+
+// the thrift servers are stopped without waiting for the idle connections of the syncer
+const exitWaitTime = 10 * time.Millisecond
+
+type Config struct {
+	// Name is part of the master token, defaults to fake_doris
+	Name string
+	// Host of all listeners, defaults to 127.0.0.1
+	Host string
+	// QueryPort and RpcPort of the frontend, 0 picks a free port
+	QueryPort int
+	RpcPort   int
+	// Followers is the number of follower frontends, their query ports follow QueryPort, they share the catalog
+	// and the thrift endpoint with the master, and reject the master-only statements
+	Followers int
+	// BePortBase and HttpPortBase are the ports of the first backend, the others follow; 0 picks free ports
+	BePortBase   int
+	HttpPortBase int
+	// Backends is the number of backends, defaults to 3
+	Backends int
+	// IdStart is the first id of dbs, tables, partitions, ... use distinct ranges for src and dest
+	IdStart int64
+	// WithoutGetMeta makes the frontend an old version without the GetMeta rpc
+	WithoutGetMeta bool
+}
+
+// queryFrontend is the mysql endpoint of a frontend
+type queryFrontend struct {
+	port    int
+	mysql   *mysqlServer
+	master  bool
+	stopped bool
+}
+
+type Cluster struct {
+	name    string
+	host    string
+	rpcPort int
+	catalog *Catalog
+
+	withoutGetMeta bool
+
+	feLock    sync.Mutex
+	frontends []*queryFrontend
+
+	servers     []server.Server
+	httpServers []*http.Server
+	httpAddrs   []string
+
+	statsLock sync.Mutex
+	sqls      []string
+	rpcs      map[string]int
+}
+
+func listen(host string, port int) (net.Listener, int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, 0, xerror.Wrapf(err, xerror.Normal, "listen on %s:%d failed", host, port)
+	}
+	return listener, listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func nextPort(base, i int) int {
+	if base == 0 {
+		return 0
+	}
+	return base + i
+}
+
+// Start starts the frontend, the backends and the mysql endpoint of a fake cluster
+func Start(cfg Config) (*Cluster, error) {
+	if cfg.Name == "" {
+		cfg.Name = "fake_doris"
+	}
+	if cfg.Host == "" {
+		cfg.Host = "127.0.0.1"
+	}
+	if cfg.Backends <= 0 {
+		cfg.Backends = 3
+	}
+	if cfg.IdStart <= 0 {
+		cfg.IdStart = 10000
+	}
+
+	c := &Cluster{
+		name:    cfg.Name,
+		host:    cfg.Host,
+		catalog: newCatalog(cfg.IdStart, fmt.Sprintf("%s_token_%d", cfg.Name, cfg.IdStart)),
+		rpcs:    make(map[string]int),
+
+		withoutGetMeta: cfg.WithoutGetMeta,
+	}
+	if err := c.start(cfg); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cluster) start(cfg Config) error {
+	// Step 1: frontend thrift and mysql endpoints
+	rpcListener, rpcPort, err := listen(c.host, cfg.RpcPort)
+	if err != nil {
+		return err
+	}
+	c.rpcPort = rpcPort
+	c.serve(feservice.NewServer(&frontendService{cluster: c, catalog: c.catalog}, server.WithListener(rpcListener), server.WithExitWaitTime(exitWaitTime)))
+
+	for i := 0; i <= cfg.Followers; i++ {
+		queryListener, queryPort, err := listen(c.host, nextPort(cfg.QueryPort, i))
+		if err != nil {
+			return err
+		}
+		frontend := &queryFrontend{port: queryPort, master: i == 0}
+		frontend.mysql = newMysqlServer(c, frontend, queryListener)
+		c.frontends = append(c.frontends, frontend)
+		go frontend.mysql.serve()
+	}
+
+	// Step 2: backends, each with a thrift and a http endpoint
+	for i := 0; i < cfg.Backends; i++ {
+		backend := &Backend{Id: c.catalog.allocId(), Host: c.host}
+
+		beListener, bePort, err := listen(c.host, nextPort(cfg.BePortBase, i))
+		if err != nil {
+			return err
+		}
+		backend.BePort = bePort
+		c.serve(beservice.NewServer(&backendService{cluster: c, backendId: backend.Id}, server.WithListener(beListener), server.WithExitWaitTime(exitWaitTime)))
+
+		httpListener, httpPort, err := listen(c.host, nextPort(cfg.HttpPortBase, i))
+		if err != nil {
+			return err
+		}
+		backend.HttpPort = httpPort
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/show_config", showConfig)
+		httpServer := &http.Server{Handler: mux}
+		c.httpServers = append(c.httpServers, httpServer)
+		go httpServer.Serve(httpListener)
+
+		httpAddr := net.JoinHostPort(c.host, strconv.Itoa(httpPort))
+		register(httpAddr, c)
+		c.httpAddrs = append(c.httpAddrs, httpAddr)
+
+		c.catalog.backends = append(c.catalog.backends, backend)
+	}
+	return nil
+}
+
+func (c *Cluster) serve(svr server.Server) {
+	c.servers = append(c.servers, svr)
+	go svr.Run()
+}
+
+// Close stops all listeners of the cluster
+func (c *Cluster) Close() {
+	for _, addr := range c.httpAddrs {
+		unregister(addr)
+	}
+	for _, httpServer := range c.httpServers {
+		httpServer.Close()
+	}
+	for _, svr := range c.servers {
+		svr.Stop()
+	}
+	for _, frontend := range c.frontends {
+		if !frontend.stopped {
+			frontend.mysql.close()
+		}
+	}
+}
+
+// master returns the master frontend
+func (c *Cluster) master() *queryFrontend {
+	c.feLock.Lock()
+	defer c.feLock.Unlock()
+
+	for _, frontend := range c.frontends {
+		if frontend.master {
+			return frontend
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) isMaster(frontend *queryFrontend) bool {
+	c.feLock.Lock()
+	defer c.feLock.Unlock()
+
+	return frontend.master
+}
+
+// FailoverMaster stops the mysql endpoint of the master, and elects the first alive follower as the master
+func (c *Cluster) FailoverMaster() error {
+	c.feLock.Lock()
+	var master, next *queryFrontend
+	for _, frontend := range c.frontends {
+		if frontend.master {
+			master = frontend
+		} else if !frontend.stopped && next == nil {
+			next = frontend
+		}
+	}
+	if next == nil {
+		c.feLock.Unlock()
+		return xerror.Errorf(xerror.Normal, "no alive follower to be the master")
+	}
+	master.master, master.stopped = false, true
+	next.master = true
+	c.feLock.Unlock()
+
+	master.mysql.close()
+	return nil
+}
+
+// HoldJobs keeps the new backup and restore jobs pending, until it is called with false, then the
+// pending jobs are finished
+func (c *Cluster) HoldJobs(hold bool) {
+	c.catalog.lock.Lock()
+	defer c.catalog.lock.Unlock()
+
+	c.catalog.holdJobs = hold
+	if hold {
+		return
+	}
+	for name, job := range c.catalog.backups {
+		if job.State == JobStatePending {
+			c.catalog.finishBackup(name, job)
+		}
+	}
+	for _, job := range c.catalog.restores {
+		if job.State == JobStatePending {
+			job.State, job.UnfinishedTasks = JobStateFinished, "[]"
+		}
+	}
+}
+
+// RotateToken changes the master token, like the token of a restarted master, the restores with
+// the old token are cancelled
+func (c *Cluster) RotateToken() string {
+	c.catalog.tokenLock.Lock()
+	defer c.catalog.tokenLock.Unlock()
+
+	c.catalog.token += "_rotated"
+	return c.catalog.token
+}
+
+func (c *Cluster) recordSql(query string) {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	c.sqls = append(c.sqls, query)
+}
+
+func (c *Cluster) recordRpc(method string) {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	c.rpcs[method]++
+}
+
+// Sqls returns all sql executed by the cluster, in order
+func (c *Cluster) Sqls() []string {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	return append([]string(nil), c.sqls...)
+}
+
+// RpcCount returns how many times the thrift method is called
+func (c *Cluster) RpcCount(method string) int {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	return c.rpcs[method]
+}
+
+// Spec returns the spec to connect the syncer to the master of this cluster
+func (c *Cluster) Spec(db, table string) base.Spec {
+	c.feLock.Lock()
+	var master base.Frontend
+	frontends := make([]base.Frontend, 0, len(c.frontends))
+	for _, queryFrontend := range c.frontends {
+		if queryFrontend.stopped {
+			continue
+		}
+		frontend := base.Frontend{
+			Host:       c.host,
+			Port:       strconv.Itoa(queryFrontend.port),
+			ThriftPort: strconv.Itoa(c.rpcPort),
+			IsMaster:   queryFrontend.master,
+		}
+		if frontend.IsMaster {
+			master = frontend
+		}
+		frontends = append(frontends, frontend)
+	}
+	c.feLock.Unlock()
+
+	return base.Spec{
+		Frontend:  master,
+		Frontends: frontends,
+		User:      "root",
+		Cluster:   "",
+		Database:  db,
+		Table:     table,
+	}
+}
+
+// Exec runs the sql statements in one session, like a mysql client
+func (c *Cluster) Exec(queries ...string) (*Result, error) {
+	s := &session{}
+	var result *Result
+	for _, query := range queries {
+		var err error
+		if result, err = c.execute(s, query); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Addr returns the host and the query, rpc ports of the master frontend
+func (c *Cluster) Addr() (string, int, int) {
+	return c.host, c.master().port, c.rpcPort
+}
+
+// Columns returns the column names of the table
+func (c *Cluster) Columns(dbName, tableName string) ([]string, error) {
+	c.catalog.lock.Lock()
+	defer c.catalog.lock.Unlock()
+
+	_, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), table.Columns...), nil
+}
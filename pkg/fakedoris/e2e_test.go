@@ -0,0 +1,183 @@
+package fakedoris
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	ccr.SetSyncDuration(50 * time.Millisecond)
+	base.SetCheckSettings(10*time.Millisecond, 10*time.Millisecond, 100)
+}
+
+func startClusters(t *testing.T) (*Cluster, *Cluster) {
+	src, err := Start(Config{Name: "src", IdStart: 10000})
+	require.NoError(t, err)
+	t.Cleanup(src.Close)
+
+	dest, err := Start(Config{Name: "dest", IdStart: 90000})
+	require.NoError(t, err)
+	t.Cleanup(dest.Close)
+	return src, dest
+}
+
+func mustExec(t *testing.T, c *Cluster, queries ...string) {
+	_, err := c.Exec(queries...)
+	require.NoError(t, err)
+}
+
+func count(t *testing.T, c *Cluster, table string) string {
+	result, err := c.Exec("SELECT COUNT(*) FROM " + table)
+	if err != nil {
+		return err.Error()
+	}
+	return result.Rows[0][0]
+}
+
+// startJob runs the job like the JobManager, until the test ends, a nil injector means no fault
+func startJob(t *testing.T, name string, src, dest base.Spec, injector *fault.Injector) *ccr.Job {
+	return startJobWith(t, name, src, dest, injector, nil)
+}
+
+// startJobWith is startJob with the settings of the job changed by setup before it runs
+func startJobWith(t *testing.T, name string, src, dest base.Spec, injector *fault.Injector, setup func(job *ccr.Job)) *ccr.Job {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	rpcFactory, specerFactory := rpc.NewRpcFactory(), base.NewSpecerFactory()
+	if injector != nil {
+		rpcFactory = fault.NewRpcFactory(rpcFactory, injector)
+		specerFactory = fault.NewSpecerFactory(specerFactory, injector)
+	}
+	factory := ccr.NewFactory(rpcFactory, ccr.NewMetaFactory(rpcFactory), specerFactory, ccr.DefaultThriftMetaFactory)
+	job, err := ccr.NewJobFromService(name, ccr.NewJobContext(src, dest, false, db, factory))
+	require.NoError(t, err)
+	if setup != nil {
+		setup(job)
+	}
+	require.NoError(t, job.FirstRun())
+
+	data, err := json.Marshal(job)
+	require.NoError(t, err)
+	require.NoError(t, db.AddJob(name, string(data), "127.0.0.1:9190"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run()
+	}()
+	t.Cleanup(func() {
+		job.Stop()
+		<-done
+	})
+	return job
+}
+
+func requireSynced(t *testing.T, src, dest *Cluster, tables ...string) {
+	for _, table := range tables {
+		expect := count(t, src, table)
+		require.Eventually(t, func() bool { return count(t, dest, table) == expect },
+			10*time.Second, 20*time.Millisecond, "table %s is not synced, expect %s rows", table, expect)
+	}
+}
+
+func TestMysqlProtocol(t *testing.T) {
+	src, err := Start(Config{Backends: 1})
+	require.NoError(t, err)
+	defer src.Close()
+
+	mustExec(t, src, `CREATE DATABASE db1`, `CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`)
+
+	spec := src.Spec("db1", "")
+	db, err := spec.ConnectDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO t1 VALUES (1, 'a'), (2, 'b')")
+	require.NoError(t, err)
+
+	var rows int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM t1").Scan(&rows))
+	require.Equal(t, 2, rows)
+
+	var table, createSql string
+	require.NoError(t, db.QueryRow("SHOW CREATE TABLE t1").Scan(&table, &createSql))
+	require.Equal(t, "t1", table)
+	require.Contains(t, createSql, "BUCKETS 2")
+
+	_, err = db.Exec("SELECT unknown FROM t1")
+	require.ErrorContains(t, err, "does not support")
+
+	var name string
+	require.ErrorIs(t, db.QueryRow("SHOW DATABASES LIKE 'db2'").Scan(&name), sql.ErrNoRows)
+}
+
+func TestTableSync(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2 PROPERTIES ("binlog.enable" = "true")`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b'), (3, 'c')`)
+
+	startJob(t, "table_sync", src.Spec("db1", "t1"), dest.Spec("db1", "t1"), nil)
+	requireSynced(t, src, dest, "db1.t1")
+
+	// incremental sync
+	mustExec(t, src,
+		`INSERT INTO db1.t1 VALUES (4, 'd')`,
+		`INSERT INTO db1.t1 VALUES (5, 'e'), (6, 'f')`)
+	requireSynced(t, src, dest, "db1.t1")
+	require.Positive(t, dest.RpcCount("CommitTxn"))
+	require.Positive(t, dest.RpcCount("IngestBinlog"))
+}
+
+func TestDbSync(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`)
+
+	startJob(t, "db_sync", src.Spec("db1", ""), dest.Spec("db1", ""), nil)
+	requireSynced(t, src, dest, "db1.t1")
+
+	// create table, then load into the new table
+	mustExec(t, src,
+		`CREATE TABLE db1.t2 (id INT, k INT) PARTITION BY RANGE(k) (PARTITION p1 VALUES LESS THAN ("10")) DISTRIBUTED BY HASH(id) BUCKETS 3`,
+		`INSERT INTO db1.t2 PARTITION (p1) VALUES (1, 1), (2, 2)`,
+		`INSERT INTO db1.t1 VALUES (3, 'c')`)
+	requireSynced(t, src, dest, "db1.t1", "db1.t2")
+
+	// partition and schema changes
+	mustExec(t, src,
+		`ALTER TABLE db1.t2 ADD PARTITION p2 VALUES LESS THAN ("20") DISTRIBUTED BY HASH(id) BUCKETS 3`,
+		`INSERT INTO db1.t2 PARTITION (p2) VALUES (11, 11)`,
+		`ALTER TABLE db1.t1 ADD COLUMN c INT`,
+		`INSERT INTO db1.t1 VALUES (4, 'd', 4)`)
+	requireSynced(t, src, dest, "db1.t1", "db1.t2")
+
+	columns, err := dest.Columns("db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "v", "c"}, columns)
+
+	// truncate and drop
+	mustExec(t, src, `TRUNCATE TABLE db1.t1`, `DROP TABLE db1.t2`)
+	requireSynced(t, src, dest, "db1.t1")
+	require.Eventually(t, func() bool {
+		_, err := dest.Exec("SHOW CREATE TABLE db1.t2")
+		return err != nil
+	}, 10*time.Second, 20*time.Millisecond)
+}
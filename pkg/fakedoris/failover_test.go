@@ -0,0 +1,53 @@
+package fakedoris
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontendFailover(t *testing.T) {
+	src, err := Start(Config{Name: "src", IdStart: 10000, Followers: 1})
+	require.NoError(t, err)
+	t.Cleanup(src.Close)
+
+	dest, err := Start(Config{Name: "dest", IdStart: 90000, Followers: 1})
+	require.NoError(t, err)
+	t.Cleanup(dest.Close)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`)
+
+	// the follower rejects backup, so full sync has to switch to the master
+	srcSpec := src.Spec("db1", "")
+	require.Len(t, srcSpec.Frontends, 2)
+	srcSpec.Frontend = srcSpec.Frontends[1]
+	require.False(t, srcSpec.IsMaster)
+	startJob(t, "failover", srcSpec, dest.Spec("db1", ""), nil)
+	requireSynced(t, src, dest, "db1.t1")
+
+	// the dest master is down, ddl and loads go to the new master
+	_, oldMaster, _ := dest.Addr()
+	require.NoError(t, dest.FailoverMaster())
+	_, newMaster, _ := dest.Addr()
+	require.NotEqual(t, oldMaster, newMaster)
+
+	mustExec(t, src,
+		`CREATE TABLE db1.t2 (id INT, k INT) DISTRIBUTED BY HASH(id) BUCKETS 3`,
+		`INSERT INTO db1.t2 VALUES (1, 1), (2, 2)`,
+		`ALTER TABLE db1.t1 ADD COLUMN c INT`,
+		`INSERT INTO db1.t1 VALUES (3, 'c', 3)`)
+	requireSynced(t, src, dest, "db1.t1", "db1.t2")
+
+	columns, err := dest.Columns("db1", "t1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "v", "c"}, columns)
+
+	// the stopped frontend is not listed any more
+	result, err := dest.Exec("select Host, QueryPort, RpcPort, IsMaster from frontends()")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"127.0.0.1", strconv.Itoa(newMaster), dest.Spec("", "").ThriftPort, "true"}}, result.Rows)
+}
@@ -0,0 +1,380 @@
+package fakedoris
+
+import (
+	"context"
+	"fmt"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+
+	"github.com/cloudwego/kitex/pkg/remote"
+)
+
+func newStatus(code tstatus.TStatusCode, format string, args ...any) *tstatus.TStatus {
+	status := &tstatus.TStatus{StatusCode: code}
+	if format != "" {
+		status.ErrorMsgs = []string{fmt.Sprintf(format, args...)}
+	}
+	return status
+}
+
+func okStatus() *tstatus.TStatus {
+	return newStatus(tstatus.TStatusCode_OK, "")
+}
+
+// frontendService implements the FrontendService methods used by pkg/rpc, the others are
+// left to the embedded nil interface and panic if called.
+type frontendService struct {
+	festruct.FrontendService
+
+	cluster *Cluster
+	catalog *Catalog
+}
+
+func (fe *frontendService) BeginTxn(ctx context.Context, req *festruct.TBeginTxnRequest) (*festruct.TBeginTxnResult_, error) {
+	fe.cluster.recordRpc("BeginTxn")
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	db, err := c.database(req.GetDb())
+	if err != nil {
+		return &festruct.TBeginTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "%v", err)}, nil
+	}
+	for _, tableId := range req.GetTableIds() {
+		if db.tableById(tableId) == nil {
+			return &festruct.TBeginTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "unknown table id %d", tableId)}, nil
+		}
+	}
+	for _, txn := range c.txns {
+		if txn.DbId == db.Id && txn.Label == req.GetLabel() && txn.Status != TxnStatusAborted {
+			return &festruct.TBeginTxnResult_{Status: newStatus(tstatus.TStatusCode_LABEL_ALREADY_EXISTS, "label %s already exists", req.GetLabel())}, nil
+		}
+	}
+
+	txn := &Txn{
+		Id:       c.allocId(),
+		DbId:     db.Id,
+		Label:    req.GetLabel(),
+		TableIds: req.GetTableIds(),
+		Status:   TxnStatusPrepare,
+		ingested: make(map[int64]int64),
+	}
+	c.txns[txn.Id] = txn
+	return &festruct.TBeginTxnResult_{
+		Status: okStatus(),
+		TxnId:  utils.ThriftValueWrapper(txn.Id),
+		DbId:   utils.ThriftValueWrapper(db.Id),
+	}, nil
+}
+
+func (fe *frontendService) CommitTxn(ctx context.Context, req *festruct.TCommitTxnRequest) (*festruct.TCommitTxnResult_, error) {
+	fe.cluster.recordRpc("CommitTxn")
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txn, ok := c.txns[req.GetTxnId()]
+	if !ok {
+		return &festruct.TCommitTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "transaction [%d] not found", req.GetTxnId())}, nil
+	}
+	if txn.Status != TxnStatusPrepare {
+		return &festruct.TCommitTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "transaction [%d] is already %s", txn.Id, txn.Status)}, nil
+	}
+
+	// every ingested tablet must be committed
+	committed := make(map[int64]bool)
+	for _, commitInfo := range req.GetCommitInfos() {
+		if _, _, _, _, tablet := c.findTablet(commitInfo.GetTabletId()); tablet == nil {
+			return &festruct.TCommitTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "tablet %d not found", commitInfo.GetTabletId())}, nil
+		}
+		committed[commitInfo.GetTabletId()] = true
+	}
+	for partitionId := range txn.ingested {
+		for _, db := range c.dbs {
+			for _, table := range db.Tables {
+				for _, partition := range table.Partitions {
+					if partition.Id != partitionId {
+						continue
+					}
+					for _, tablets := range partition.Tablets {
+						for _, tablet := range tablets {
+							if !committed[tablet.Id] {
+								return &festruct.TCommitTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "tablet %d has no commit info", tablet.Id)}, nil
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	db, err := c.databaseById(txn.DbId)
+	if err != nil {
+		return &festruct.TCommitTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "%v", err)}, nil
+	}
+	if err := c.publish(db, txn.Id, txn.Label, txn.ingested); err != nil {
+		return &festruct.TCommitTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "%v", err)}, nil
+	}
+	txn.Status = TxnStatusVisible
+	return &festruct.TCommitTxnResult_{Status: okStatus()}, nil
+}
+
+func (fe *frontendService) RollbackTxn(ctx context.Context, req *festruct.TRollbackTxnRequest) (*festruct.TRollbackTxnResult_, error) {
+	fe.cluster.recordRpc("RollbackTxn")
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	txn, ok := c.txns[req.GetTxnId()]
+	switch {
+	case !ok:
+		return &festruct.TRollbackTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "transaction [%d] not found", req.GetTxnId())}, nil
+	case txn.Status == TxnStatusVisible:
+		return &festruct.TRollbackTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "transaction [%d] is already COMMITTED", txn.Id)}, nil
+	case txn.Status == TxnStatusAborted:
+		return &festruct.TRollbackTxnResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "transaction [%d] is already aborted", txn.Id)}, nil
+	}
+
+	txn.Status = TxnStatusAborted
+	return &festruct.TRollbackTxnResult_{Status: okStatus()}, nil
+}
+
+// binlogs finds the binlogs after prevCommitSeq, the status is not OK if there is none
+func (fe *frontendService) binlogs(req *festruct.TGetBinlogRequest) ([]*festruct.TBinlog, *tstatus.TStatus) {
+	c := fe.catalog
+	db, err := c.database(req.GetDb())
+	if err != nil {
+		return nil, newStatus(tstatus.TStatusCode_BINLOG_NOT_FOUND_DB, "%v", err)
+	}
+
+	var tableId int64
+	binlogEnabled := db.BinlogEnabled
+	if req.IsSetTable() && req.GetTable() != "" {
+		var table *Table
+		if req.GetTableId() != 0 {
+			table = db.tableById(req.GetTableId())
+		} else {
+			table = db.table(req.GetTable())
+		}
+		if table == nil {
+			return nil, newStatus(tstatus.TStatusCode_BINLOG_NOT_FOUND_TABLE, "table %s not found", req.GetTable())
+		}
+		tableId = table.Id
+		binlogEnabled = binlogEnabled || table.BinlogEnabled
+	}
+	if !binlogEnabled {
+		return nil, newStatus(tstatus.TStatusCode_BINLOG_DISABLE, "binlog is disabled")
+	}
+
+	binlogs := c.binlogsAfter(db, tableId, req.GetPrevCommitSeq())
+	if len(binlogs) == 0 {
+		return nil, newStatus(tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ, "")
+	}
+	return binlogs, okStatus()
+}
+
+func (fe *frontendService) GetBinlog(ctx context.Context, req *festruct.TGetBinlogRequest) (*festruct.TGetBinlogResult_, error) {
+	fe.cluster.recordRpc("GetBinlog")
+	fe.catalog.lock.Lock()
+	defer fe.catalog.lock.Unlock()
+
+	binlogs, status := fe.binlogs(req)
+	if len(binlogs) > maxBinlogsPerPage {
+		binlogs = binlogs[:maxBinlogsPerPage]
+	}
+	result := &festruct.TGetBinlogResult_{Status: status, Binlogs: binlogs}
+	if len(binlogs) > 0 {
+		result.NextCommitSeq = utils.ThriftValueWrapper(binlogs[len(binlogs)-1].GetCommitSeq())
+	}
+	return result, nil
+}
+
+func (fe *frontendService) GetBinlogLag(ctx context.Context, req *festruct.TGetBinlogLagRequest) (*festruct.TGetBinlogLagResult_, error) {
+	fe.cluster.recordRpc("GetBinlogLag")
+	fe.catalog.lock.Lock()
+	defer fe.catalog.lock.Unlock()
+
+	binlogs, status := fe.binlogs(req)
+	if status.GetStatusCode() == tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ {
+		status = okStatus()
+	}
+	return &festruct.TGetBinlogLagResult_{
+		Status: status,
+		Lag:    utils.ThriftValueWrapper(int64(len(binlogs))),
+	}, nil
+}
+
+func (fe *frontendService) GetSnapshot(ctx context.Context, req *festruct.TGetSnapshotRequest) (*festruct.TGetSnapshotResult_, error) {
+	fe.cluster.recordRpc("GetSnapshot")
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	job, ok := c.backups[req.GetLabelName()]
+	if !ok || job.Database != req.GetDb() {
+		return &festruct.TGetSnapshotResult_{Status: newStatus(tstatus.TStatusCode_SNAPSHOT_NOT_EXIST, "snapshot %s not exist", req.GetLabelName())}, nil
+	}
+	return &festruct.TGetSnapshotResult_{
+		Status:  okStatus(),
+		Meta:    job.Meta,
+		JobInfo: job.JobInfo,
+	}, nil
+}
+
+func (fe *frontendService) RestoreSnapshot(ctx context.Context, req *festruct.TRestoreSnapshotRequest) (*festruct.TRestoreSnapshotResult_, error) {
+	fe.cluster.recordRpc("RestoreSnapshot")
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	db, err := c.database(req.GetDb())
+	if err != nil {
+		return &festruct.TRestoreSnapshotResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "%v", err)}, nil
+	}
+	if job, ok := c.restores[req.GetLabelName()]; ok && job.State != JobStateCancelled {
+		return &festruct.TRestoreSnapshotResult_{Status: newStatus(tstatus.TStatusCode_LABEL_ALREADY_EXISTS, "label %s already exists", req.GetLabelName())}, nil
+	}
+	if repository := req.GetRepoName(); repository != localRepository {
+		err = c.restoreFromRepository(db, req.GetLabelName(), repository, req.GetProperties()["backup_timestamp"], req.GetTableRefs())
+	} else {
+		err = c.restore(db, req.GetLabelName(), req.GetTableRefs(), req.GetMeta(), req.GetJobInfo())
+	}
+	if err != nil {
+		return &festruct.TRestoreSnapshotResult_{Status: newStatus(tstatus.TStatusCode_ANALYSIS_ERROR, "%v", err)}, nil
+	}
+	c.restores[req.GetLabelName()].Properties = req.GetProperties()
+	return &festruct.TRestoreSnapshotResult_{Status: okStatus()}, nil
+}
+
+func (fe *frontendService) GetMasterToken(ctx context.Context, req *festruct.TGetMasterTokenRequest) (*festruct.TGetMasterTokenResult_, error) {
+	fe.cluster.recordRpc("GetMasterToken")
+	return &festruct.TGetMasterTokenResult_{
+		Status: okStatus(),
+		Token:  utils.ThriftValueWrapper(fe.catalog.masterToken()),
+	}, nil
+}
+
+func (fe *frontendService) GetMeta(ctx context.Context, req *festruct.TGetMetaRequest) (*festruct.TGetMetaResult_, error) {
+	fe.cluster.recordRpc("GetMeta")
+	if fe.cluster.withoutGetMeta {
+		return nil, remote.NewTransErrorWithMsg(remote.UnknownMethod, "Invalid method name: 'getMeta'")
+	}
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	reqDb := req.GetDb()
+	var db *Database
+	var err error
+	if reqDb.IsSetId() {
+		db, err = c.databaseById(reqDb.GetId())
+	} else {
+		db, err = c.database(reqDb.GetName())
+	}
+	if err != nil {
+		return &festruct.TGetMetaResult_{Status: newStatus(tstatus.TStatusCode_NOT_FOUND, "%v", err)}, nil
+	}
+
+	tables := db.Tables
+	// partition ids of the requested tables, only the partitions are returned if they are requested
+	partitionIds := make(map[int64][]int64)
+	if len(reqDb.GetTables()) > 0 {
+		tables = make([]*Table, 0, len(reqDb.GetTables()))
+		for _, reqTable := range reqDb.GetTables() {
+			var table *Table
+			if reqTable.IsSetId() {
+				table = db.tableById(reqTable.GetId())
+			} else {
+				table = db.table(reqTable.GetName())
+			}
+			if table == nil {
+				return &festruct.TGetMetaResult_{Status: newStatus(tstatus.TStatusCode_NOT_FOUND, "table %d %s not found", reqTable.GetId(), reqTable.GetName())}, nil
+			}
+			tables = append(tables, table)
+			for _, reqPartition := range reqTable.GetPartitions() {
+				partitionIds[table.Id] = append(partitionIds[table.Id], reqPartition.GetId())
+			}
+		}
+	}
+
+	dbMeta := &festruct.TGetMetaDBMeta{
+		Id:   utils.ThriftValueWrapper(db.Id),
+		Name: utils.ThriftValueWrapper(db.Name),
+	}
+	for _, table := range tables {
+		dbMeta.Tables = append(dbMeta.Tables, tableMeta(table, !reqDb.GetOnlyTableNames(), partitionIds[table.Id]))
+	}
+	return &festruct.TGetMetaResult_{Status: okStatus(), DbMeta: dbMeta}, nil
+}
+
+// tableMeta returns the meta of the table, with the partitions of partitionIds, or all if it is empty
+func tableMeta(table *Table, withPartitions bool, partitionIds []int64) *festruct.TGetMetaTableMeta {
+	meta := &festruct.TGetMetaTableMeta{
+		Id:      utils.ThriftValueWrapper(table.Id),
+		Name:    utils.ThriftValueWrapper(table.Name),
+		InTrash: ptr(false),
+	}
+	if !withPartitions {
+		return meta
+	}
+
+	for _, partition := range table.Partitions {
+		if len(partitionIds) > 0 && !containsId(partitionIds, partition.Id) {
+			continue
+		}
+		partitionMeta := &festruct.TGetMetaPartitionMeta{
+			Id:             utils.ThriftValueWrapper(partition.Id),
+			Name:           utils.ThriftValueWrapper(partition.Name),
+			Range:          utils.ThriftValueWrapper(partition.Range),
+			VisibleVersion: utils.ThriftValueWrapper(partition.VisibleVersion),
+			IsTemp:         ptr(false),
+		}
+		for _, index := range table.Indexes {
+			indexMeta := &festruct.TGetMetaIndexMeta{
+				Id:   utils.ThriftValueWrapper(index.Id),
+				Name: utils.ThriftValueWrapper(index.Name),
+			}
+			for _, tablet := range partition.Tablets[index.Id] {
+				tabletMeta := &festruct.TGetMetaTabletMeta{Id: utils.ThriftValueWrapper(tablet.Id)}
+				for _, replica := range tablet.Replicas {
+					tabletMeta.Replicas = append(tabletMeta.Replicas, &festruct.TGetMetaReplicaMeta{
+						Id:        utils.ThriftValueWrapper(replica.Id),
+						BackendId: utils.ThriftValueWrapper(replica.BackendId),
+						Version:   utils.ThriftValueWrapper(replica.Version),
+					})
+				}
+				indexMeta.Tablets = append(indexMeta.Tablets, tabletMeta)
+			}
+			partitionMeta.Indexes = append(partitionMeta.Indexes, indexMeta)
+		}
+		meta.Partitions = append(meta.Partitions, partitionMeta)
+	}
+	return meta
+}
+
+func (fe *frontendService) GetBackendMeta(ctx context.Context, req *festruct.TGetBackendMetaRequest) (*festruct.TGetBackendMetaResult_, error) {
+	fe.cluster.recordRpc("GetBackendMeta")
+	c := fe.catalog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	backends := make([]*types.TBackend, 0, len(c.backends))
+	for _, backend := range c.backends {
+		backends = append(backends, &types.TBackend{
+			Host:     backend.Host,
+			BePort:   types.TPort(backend.BePort),
+			HttpPort: types.TPort(backend.HttpPort),
+			BrpcPort: ptr(types.TPort(backend.BrpcPort)),
+			IsAlive:  ptr(true),
+			Id:       utils.ThriftValueWrapper(backend.Id),
+		})
+	}
+	return &festruct.TGetBackendMetaResult_{Status: okStatus(), Backends: backends}, nil
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,59 @@
+package fakedoris
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFullSyncWait(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`)
+
+	// the restore is not finished until the jobs are released
+	dest.HoldJobs(true)
+	job := startJob(t, "full_sync_wait", src.Spec("db1", ""), dest.Spec("db1", ""), nil)
+	restoreTimeout := time.Minute
+	require.NoError(t, job.UpdateWaitTimeouts(nil, &restoreTimeout))
+
+	require.Eventually(t, func() bool { return dest.RpcCount("RestoreSnapshot") >= 1 },
+		10*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		fullSync := job.Status().FullSync
+		return fullSync != nil && fullSync.Phase == "restore"
+	}, 10*time.Second, 10*time.Millisecond)
+	fullSync := job.Status().FullSync
+	require.Equal(t, JobStatePending, fullSync.State)
+	require.Positive(t, fullSync.UnfinishedTasks)
+	require.Equal(t, "1m0s", fullSync.Timeout)
+
+	// the wait doesn't hold the job, pause takes effect at once
+	begin := time.Now()
+	require.NoError(t, job.Pause())
+	require.Less(t, time.Since(begin), time.Second)
+	require.Equal(t, "paused", job.Status().State)
+	require.NoError(t, job.Resume())
+
+	// the restore timed out is cancelled, and full sync begins again
+	restoreTimeout = 50 * time.Millisecond
+	require.NoError(t, job.UpdateWaitTimeouts(nil, &restoreTimeout))
+	require.Eventually(t, func() bool { return dest.RpcCount("RestoreSnapshot") >= 2 },
+		10*time.Second, 10*time.Millisecond)
+	require.Contains(t, dest.Sqls(), "CANCEL RESTORE FROM `db1`")
+
+	restoreTimeout = 0
+	require.NoError(t, job.UpdateWaitTimeouts(nil, &restoreTimeout))
+	dest.HoldJobs(false)
+	require.Eventually(t, func() bool { return strings.HasSuffix(job.Status().ProgressState, "IncrementalSync") },
+		10*time.Second, 10*time.Millisecond)
+	require.Nil(t, job.Status().FullSync)
+
+	mustExec(t, src, `INSERT INTO db1.t1 VALUES (3, 'c')`)
+	requireSynced(t, src, dest, "db1.t1")
+}
@@ -0,0 +1,42 @@
+package fakedoris
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaCache(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, k INT) PARTITION BY RANGE(k) (PARTITION p1 VALUES LESS THAN ("10")) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (1, 1)`)
+
+	startJob(t, "meta_cache", src.Spec("db1", ""), dest.Spec("db1", ""), nil)
+	requireSynced(t, src, dest, "db1.t1")
+	mustExec(t, src, `INSERT INTO db1.t1 VALUES (2, 2)`)
+	requireSynced(t, src, dest, "db1.t1")
+
+	// the metas are cached, only the partitions of src older than the binlogs are got again
+	srcGetMeta, destGetMeta := src.RpcCount("GetMeta"), dest.RpcCount("GetMeta")
+	destGetBackends := dest.RpcCount("GetBackendMeta")
+	mustExec(t, src,
+		`INSERT INTO db1.t1 VALUES (3, 3)`,
+		`INSERT INTO db1.t1 VALUES (4, 4)`,
+		`INSERT INTO db1.t1 VALUES (5, 5)`)
+	requireSynced(t, src, dest, "db1.t1")
+	require.Equal(t, destGetMeta, dest.RpcCount("GetMeta"))
+	require.Equal(t, destGetBackends, dest.RpcCount("GetBackendMeta"))
+	require.LessOrEqual(t, src.RpcCount("GetMeta")-srcGetMeta, 3)
+
+	// the partitions are changed by ddl, the cached metas are invalidated
+	mustExec(t, src,
+		`ALTER TABLE db1.t1 ADD PARTITION p2 VALUES LESS THAN ("20") DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (11, 11)`,
+		`TRUNCATE TABLE db1.t1 PARTITIONS (p1)`,
+		`INSERT INTO db1.t1 VALUES (6, 6)`)
+	requireSynced(t, src, dest, "db1.t1")
+	require.Greater(t, dest.RpcCount("GetMeta"), destGetMeta)
+}
@@ -0,0 +1,112 @@
+package fakedoris
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+
+	"github.com/stretchr/testify/require"
+)
+
+// metaSnapshot is what the syncer reads from Meta, to compare the metas got by GetMeta and by show proc
+type metaSnapshot struct {
+	DbId         int64
+	TableIds     map[int64]string
+	PartitionIds []int64
+	Partitions   map[int64]string // partitionId -> name@range
+	Indexes      map[string]int64
+	Replicas     map[int64]int64 // replicaId -> backendId
+}
+
+func getMetaSnapshot(t *testing.T, meta ccr.Metaer) *metaSnapshot {
+	dbId, err := meta.GetDbId()
+	require.NoError(t, err)
+	tables, err := meta.GetTables()
+	require.NoError(t, err)
+	tableId, err := meta.GetTableId("t1")
+	require.NoError(t, err)
+	partitionIds, err := meta.GetPartitionIds("t1")
+	require.NoError(t, err)
+
+	snapshot := &metaSnapshot{
+		DbId:         dbId,
+		TableIds:     make(map[int64]string),
+		PartitionIds: partitionIds,
+		Partitions:   make(map[int64]string),
+		Replicas:     make(map[int64]int64),
+	}
+	for tableId, table := range tables {
+		snapshot.TableIds[tableId] = table.Name
+	}
+	for _, partitionId := range partitionIds {
+		name, err := meta.GetPartitionName(tableId, partitionId)
+		require.NoError(t, err)
+		partitionRange, err := meta.GetPartitionRange(tableId, partitionId)
+		require.NoError(t, err)
+		snapshot.Partitions[partitionId] = name + "@" + partitionRange
+	}
+
+	indexes, err := meta.GetIndexNameMap(tableId, partitionIds[0])
+	require.NoError(t, err)
+	snapshot.Indexes = make(map[string]int64)
+	for name, index := range indexes {
+		snapshot.Indexes[name] = index.Id
+	}
+	require.NoError(t, meta.UpdateReplicas(tableId, partitionIds[0]))
+	replicas, err := meta.GetReplicas(tableId, partitionIds[0])
+	require.NoError(t, err)
+	for _, replica := range replicas.Values() {
+		snapshot.Replicas[replica.Id] = replica.BackendId
+	}
+	return snapshot
+}
+
+func showProcs(c *Cluster) int {
+	count := 0
+	for _, sql := range c.Sqls() {
+		if strings.HasPrefix(strings.ToLower(sql), "show proc") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestThriftBackedMeta(t *testing.T) {
+	// the same ids are allocated by the same statements
+	current, err := Start(Config{Name: "current", IdStart: 10000})
+	require.NoError(t, err)
+	t.Cleanup(current.Close)
+	old, err := Start(Config{Name: "old", IdStart: 10000, WithoutGetMeta: true})
+	require.NoError(t, err)
+	t.Cleanup(old.Close)
+
+	for _, c := range []*Cluster{current, old} {
+		mustExec(t, c,
+			`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+			`CREATE TABLE db1.t1 (id INT, k INT) PARTITION BY RANGE(k) (PARTITION p1 VALUES LESS THAN ("10"), PARTITION p2 VALUES LESS THAN ("20")) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+			`CREATE TABLE db1.t2 (id INT) DISTRIBUTED BY HASH(id) BUCKETS 1`,
+			`INSERT INTO db1.t1 VALUES (1, 1), (11, 11)`)
+	}
+
+	// the metas of the current fe are got by GetMeta only
+	currentSpec := current.Spec("db1", "")
+	currentSnapshot := getMetaSnapshot(t, ccr.NewMetaFactory(rpc.NewRpcFactory()).NewMeta(&currentSpec))
+	require.Zero(t, showProcs(current))
+	require.Positive(t, current.RpcCount("GetMeta"))
+
+	// the old fe does not support GetMeta, it is probed once by the factory, the metas are got by show proc
+	metaFactory := ccr.NewMetaFactory(rpc.NewRpcFactory())
+	oldSpec := old.Spec("db1", "")
+	oldSnapshot := getMetaSnapshot(t, metaFactory.NewMeta(&oldSpec))
+	require.Positive(t, showProcs(old))
+	require.Equal(t, 1, old.RpcCount("GetMeta"))
+	getMetaSnapshot(t, metaFactory.NewMeta(&oldSpec))
+	require.Equal(t, 1, old.RpcCount("GetMeta"), "the other metas of the cluster share the probe")
+
+	require.Equal(t, oldSnapshot, currentSnapshot)
+	require.Len(t, currentSnapshot.TableIds, 2)
+	require.Len(t, currentSnapshot.Partitions, 2)
+	require.Len(t, currentSnapshot.Replicas, 2*3)
+}
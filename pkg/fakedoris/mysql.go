@@ -0,0 +1,367 @@
+package fakedoris
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the subset of the mysql protocol spoken by go-sql-driver/mysql with the text protocol
+const (
+	clientLongPassword     uint32 = 0x00000001
+	clientFoundRows        uint32 = 0x00000002
+	clientLongFlag         uint32 = 0x00000004
+	clientConnectWithDB    uint32 = 0x00000008
+	clientProtocol41       uint32 = 0x00000200
+	clientTransactions     uint32 = 0x00002000
+	clientSecureConn       uint32 = 0x00008000
+	clientMultiResults     uint32 = 0x00020000
+	clientPluginAuth       uint32 = 0x00080000
+	clientPluginAuthLenenc uint32 = 0x00200000
+
+	// DEPRECATE_EOF is not advertised, so result sets end with EOF packets
+	serverCapabilities = clientLongPassword | clientFoundRows | clientLongFlag | clientConnectWithDB |
+		clientProtocol41 | clientTransactions | clientSecureConn | clientMultiResults |
+		clientPluginAuth | clientPluginAuthLenenc
+
+	comQuit   byte = 0x01
+	comInitDB byte = 0x02
+	comQuery  byte = 0x03
+	comPing   byte = 0x0e
+
+	serverStatusAutocommit uint16 = 0x0002
+	charsetUtf8            byte   = 33
+	fieldTypeVarString     byte   = 0xfd
+
+	serverVersion = "5.7.99-fake-doris"
+	authPlugin    = "mysql_native_password"
+)
+
+// mysqlServer accepts any user and password, every connection is a session of the cluster
+type mysqlServer struct {
+	cluster  *Cluster
+	frontend *queryFrontend
+	listener net.Listener
+
+	lock   sync.Mutex
+	conns  map[net.Conn]struct{}
+	nextId uint32
+	wg     sync.WaitGroup
+}
+
+func newMysqlServer(cluster *Cluster, frontend *queryFrontend, listener net.Listener) *mysqlServer {
+	return &mysqlServer{
+		cluster:  cluster,
+		frontend: frontend,
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+func (m *mysqlServer) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		m.lock.Lock()
+		m.conns[conn] = struct{}{}
+		m.nextId++
+		connId := m.nextId
+		m.lock.Unlock()
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			defer m.closeConn(conn)
+
+			if err := m.handle(conn, connId); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Debugf("fake doris mysql connection %d closed: %+v", connId, err)
+			}
+		}()
+	}
+}
+
+func (m *mysqlServer) closeConn(conn net.Conn) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	conn.Close()
+	delete(m.conns, conn)
+}
+
+func (m *mysqlServer) close() {
+	m.listener.Close()
+
+	m.lock.Lock()
+	for conn := range m.conns {
+		conn.Close()
+	}
+	m.lock.Unlock()
+
+	m.wg.Wait()
+}
+
+type packetConn struct {
+	reader *bufio.Reader
+	writer io.Writer
+	seq    byte
+}
+
+func (p *packetConn) readPacket() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(p.reader, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	p.seq = header[3] + 1
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(p.reader, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (p *packetConn) writePacket(payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), p.seq}
+	p.seq++
+	if _, err := p.writer.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *mysqlServer) handle(conn net.Conn, connId uint32) error {
+	p := &packetConn{reader: bufio.NewReader(conn), writer: conn}
+	s := &session{frontend: m.frontend}
+
+	// Step 1: handshake, the scramble is never checked
+	if err := p.writePacket(handshakePacket(connId)); err != nil {
+		return err
+	}
+	response, err := p.readPacket()
+	if err != nil {
+		return err
+	}
+	s.db = parseHandshakeResponse(response)
+	if s.db != "" {
+		if _, err := m.cluster.execute(s, "USE "+s.db); err != nil {
+			return p.writeError(err)
+		}
+	}
+	if err := p.writeOk(0); err != nil {
+		return err
+	}
+
+	// Step 2: serve commands until the client quits
+	for {
+		packet, err := p.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(packet) == 0 {
+			continue
+		}
+
+		switch packet[0] {
+		case comQuit:
+			return nil
+		case comPing:
+			err = p.writeOk(0)
+		case comInitDB:
+			if _, execErr := m.cluster.execute(s, "USE "+string(packet[1:])); execErr != nil {
+				err = p.writeError(execErr)
+			} else {
+				err = p.writeOk(0)
+			}
+		case comQuery:
+			result, execErr := m.cluster.execute(s, string(packet[1:]))
+			if execErr != nil {
+				err = p.writeError(execErr)
+			} else {
+				err = p.writeResult(result)
+			}
+		default:
+			err = p.writeError(errors.New("fake doris does not support the command"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func handshakePacket(connId uint32) []byte {
+	var buf bytes.Buffer
+	scramble := []byte("0123456789abcdefghij")
+
+	buf.WriteByte(10)
+	buf.WriteString(serverVersion)
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.LittleEndian, connId)
+	buf.Write(scramble[:8])
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.LittleEndian, uint16(serverCapabilities&0xffff))
+	buf.WriteByte(charsetUtf8)
+	binary.Write(&buf, binary.LittleEndian, serverStatusAutocommit)
+	binary.Write(&buf, binary.LittleEndian, uint16(serverCapabilities>>16))
+	buf.WriteByte(byte(len(scramble) + 1))
+	buf.Write(make([]byte, 10))
+	buf.Write(scramble[8:])
+	buf.WriteByte(0)
+	buf.WriteString(authPlugin)
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+// parseHandshakeResponse returns the database of the connection, if any
+func parseHandshakeResponse(packet []byte) string {
+	if len(packet) < 32 {
+		return ""
+	}
+	capabilities := binary.LittleEndian.Uint32(packet)
+	rest := packet[32:]
+
+	// user
+	if i := bytes.IndexByte(rest, 0); i >= 0 {
+		rest = rest[i+1:]
+	} else {
+		return ""
+	}
+
+	// auth response
+	switch {
+	case capabilities&clientPluginAuthLenenc != 0:
+		length, n := readLenencInt(rest)
+		rest = skip(rest, n+int(length))
+	case capabilities&clientSecureConn != 0 && len(rest) > 0:
+		rest = skip(rest, 1+int(rest[0]))
+	default:
+		if i := bytes.IndexByte(rest, 0); i >= 0 {
+			rest = rest[i+1:]
+		}
+	}
+
+	if capabilities&clientConnectWithDB == 0 {
+		return ""
+	}
+	if i := bytes.IndexByte(rest, 0); i >= 0 {
+		return string(rest[:i])
+	}
+	return string(rest)
+}
+
+func skip(b []byte, n int) []byte {
+	if n > len(b) {
+		return nil
+	}
+	return b[n:]
+}
+
+func readLenencInt(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	switch b[0] {
+	case 0xfc:
+		return uint64(b[1]) | uint64(b[2])<<8, 3
+	case 0xfd:
+		return uint64(b[1]) | uint64(b[2])<<8 | uint64(b[3])<<16, 4
+	case 0xfe:
+		return binary.LittleEndian.Uint64(b[1:]), 9
+	default:
+		return uint64(b[0]), 1
+	}
+}
+
+func appendLenencInt(buf []byte, n uint64) []byte {
+	switch {
+	case n < 251:
+		return append(buf, byte(n))
+	case n < 1<<16:
+		return append(buf, 0xfc, byte(n), byte(n>>8))
+	case n < 1<<24:
+		return append(buf, 0xfd, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		buf = append(buf, 0xfe)
+		return binary.LittleEndian.AppendUint64(buf, n)
+	}
+}
+
+func appendLenencString(buf []byte, s string) []byte {
+	buf = appendLenencInt(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func (p *packetConn) writeOk(affected int64) error {
+	payload := []byte{0x00}
+	payload = appendLenencInt(payload, uint64(affected))
+	payload = appendLenencInt(payload, 0)
+	payload = binary.LittleEndian.AppendUint16(payload, serverStatusAutocommit)
+	payload = binary.LittleEndian.AppendUint16(payload, 0)
+	return p.writePacket(payload)
+}
+
+func (p *packetConn) writeEof() error {
+	payload := []byte{0xfe, 0, 0}
+	payload = binary.LittleEndian.AppendUint16(payload, serverStatusAutocommit)
+	return p.writePacket(payload)
+}
+
+func (p *packetConn) writeError(err error) error {
+	payload := []byte{0xff}
+	payload = binary.LittleEndian.AppendUint16(payload, 1105)
+	payload = append(payload, "#HY000"...)
+	payload = append(payload, strings.ReplaceAll(err.Error(), "\n", " ")...)
+	return p.writePacket(payload)
+}
+
+func (p *packetConn) writeResult(result *Result) error {
+	if len(result.Columns) == 0 {
+		return p.writeOk(result.Affected)
+	}
+
+	if err := p.writePacket(appendLenencInt(nil, uint64(len(result.Columns)))); err != nil {
+		return err
+	}
+	for _, column := range result.Columns {
+		var payload []byte
+		payload = appendLenencString(payload, "def")
+		payload = appendLenencString(payload, "")
+		payload = appendLenencString(payload, "")
+		payload = appendLenencString(payload, "")
+		payload = appendLenencString(payload, column)
+		payload = appendLenencString(payload, column)
+		payload = append(payload, 0x0c)
+		payload = binary.LittleEndian.AppendUint16(payload, uint16(charsetUtf8))
+		payload = binary.LittleEndian.AppendUint32(payload, 65535)
+		payload = append(payload, fieldTypeVarString)
+		payload = binary.LittleEndian.AppendUint16(payload, 0)
+		payload = append(payload, 0, 0, 0)
+		if err := p.writePacket(payload); err != nil {
+			return err
+		}
+	}
+	if err := p.writeEof(); err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows {
+		var payload []byte
+		for _, value := range row {
+			payload = appendLenencString(payload, value)
+		}
+		if err := p.writePacket(payload); err != nil {
+			return err
+		}
+	}
+	return p.writeEof()
+}
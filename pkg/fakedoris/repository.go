@@ -0,0 +1,76 @@
+package fakedoris
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// localRepository keeps the snapshots on the backends of the cluster
+const localRepository = "__keep_on_local__"
+
+// objectStore is the storage shared by the repositories of all fake clusters, like a MinIO bucket
+// both the src and dest clusters can reach. The snapshots are keyed by the location of the repository.
+var objectStore = struct {
+	lock      sync.Mutex
+	snapshots map[string][]*repositorySnapshot
+}{snapshots: make(map[string][]*repositorySnapshot)}
+
+type repositorySnapshot struct {
+	Name      string
+	Timestamp string
+	Meta      []byte
+	JobInfo   []byte
+}
+
+// upload saves the snapshot of a finished backup to the location of its repository
+func upload(location string, name string, job *backupJob) {
+	objectStore.lock.Lock()
+	defer objectStore.lock.Unlock()
+
+	objectStore.snapshots[location] = append(objectStore.snapshots[location], &repositorySnapshot{
+		Name:      name,
+		Timestamp: time.Now().Format("2006-01-02-15-04-05.000"),
+		Meta:      job.Meta,
+		JobInfo:   job.JobInfo,
+	})
+}
+
+// repositorySnapshots returns the snapshots in the location with the name, oldest first
+func repositorySnapshots(location string, name string) []*repositorySnapshot {
+	objectStore.lock.Lock()
+	defer objectStore.lock.Unlock()
+
+	var snapshots []*repositorySnapshot
+	for _, snapshot := range objectStore.snapshots[location] {
+		if snapshot.Name == name {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots
+}
+
+// findRepositorySnapshot returns the snapshot in the repository with the name and timestamp
+func (c *Catalog) findRepositorySnapshot(repository string, name string, timestamp string) (*repositorySnapshot, error) {
+	location, ok := c.repositories[repository]
+	if !ok {
+		return nil, fmt.Errorf("Repository %s does not exist", repository)
+	}
+	for _, snapshot := range repositorySnapshots(location, name) {
+		if snapshot.Timestamp == timestamp {
+			return snapshot, nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %s with timestamp %s does not exist in repository %s", name, timestamp, repository)
+}
+
+// finishBackup finishes the backup job, the snapshot of a backup to a repository is uploaded
+func (c *Catalog) finishBackup(name string, job *backupJob) {
+	job.State, job.UnfinishedTasks = JobStateFinished, "[]"
+	if job.Repository == "" {
+		return
+	}
+	if location, ok := c.repositories[job.Repository]; ok {
+		upload(location, name, job)
+	}
+}
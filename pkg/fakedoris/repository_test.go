@@ -0,0 +1,48 @@
+package fakedoris
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositorySync(t *testing.T) {
+	src, dest := startClusters(t)
+
+	// the repositories of both clusters are on the same storage
+	createRepository := fmt.Sprintf(`CREATE REPOSITORY ccr_repo WITH S3 ON LOCATION "s3://ccr/%s" PROPERTIES ("s3.endpoint" = "http://127.0.0.1:9000")`, t.Name())
+	mustExec(t, src,
+		createRepository,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`CREATE TABLE db1.t2 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 1`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`,
+		`INSERT INTO db1.t2 VALUES (1, 'a')`)
+	mustExec(t, dest, createRepository)
+
+	srcSpec, destSpec := src.Spec("db1", ""), dest.Spec("db1", "")
+	srcSpec.Repository, destSpec.Repository = "ccr_repo", "ccr_repo"
+	startJob(t, "repository_sync", srcSpec, destSpec, nil)
+	requireSynced(t, src, dest, "db1.t1", "db1.t2")
+
+	// the table commit seqs are got from the src frontend, the details in the repository are brief
+	require.Equal(t, 1, src.RpcCount("GetSnapshot"))
+	var snapshotName string
+	for _, sql := range src.Sqls() {
+		if strings.Contains(sql, "TO `ccr_repo`") {
+			snapshotName = strings.TrimPrefix(strings.Fields(sql)[2], "db1.")
+		}
+	}
+	require.NotEmpty(t, snapshotName)
+	result, err := src.Exec(fmt.Sprintf("SHOW SNAPSHOT ON `ccr_repo` WHERE SNAPSHOT = \"%s\"", snapshotName))
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	result, err = src.Exec(fmt.Sprintf("SHOW SNAPSHOT ON `ccr_repo` WHERE SNAPSHOT = \"%s\" AND TIMESTAMP = \"%s\"", snapshotName, result.Rows[0][1]))
+	require.NoError(t, err)
+	require.NotContains(t, result.Rows[0][3], "table_commit_seq_map")
+
+	mustExec(t, src, `INSERT INTO db1.t1 VALUES (3, 'c')`)
+	requireSynced(t, src, dest, "db1.t1")
+}
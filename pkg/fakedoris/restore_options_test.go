@@ -0,0 +1,67 @@
+package fakedoris
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreOptions(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, k INT) PARTITION BY RANGE(k) (PARTITION p1 VALUES LESS THAN ("10")) DISTRIBUTED BY HASH(id) BUCKETS 2 PROPERTIES ("replication_allocation" = "tag.location.default: 3", "colocate_with" = "g1")`,
+		`INSERT INTO db1.t1 VALUES (1, 1), (2, 2)`)
+
+	options := &base.RestoreOptions{ReplicationAllocation: "tag.location.default: 1", StorageMedium: base.StorageMediumHdd}
+	startJobWith(t, "restore_options", src.Spec("db1", ""), dest.Spec("db1", ""), nil, func(job *ccr.Job) {
+		job.RestoreOptions = options
+	})
+	requireSynced(t, src, dest, "db1.t1")
+
+	// the full sync restores with the options instead of the replicas of src
+	properties := dest.restoreProperties("db1")
+	require.Equal(t, "tag.location.default: 1", properties["replication_allocation"])
+	require.Equal(t, "hdd", properties["storage_medium"])
+	require.Equal(t, "false", properties["reserve_colocate"])
+	require.Equal(t, "false", properties["reserve_dynamic_partition_enable"])
+	require.NotContains(t, properties, "reserve_replica")
+
+	// the tables and partitions created by incremental sync are overridden too
+	mustExec(t, src,
+		`CREATE TABLE db1.t2 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 1 PROPERTIES ("colocate_with" = "g1", "replication_num" = "3", "storage_medium" = "SSD", "dynamic_partition.enable" = "true")`,
+		`ALTER TABLE db1.t1 ADD PARTITION p2 VALUES LESS THAN ("20") ("replication_num" = "3")`,
+		`INSERT INTO db1.t2 VALUES (1, 'a')`,
+		`INSERT INTO db1.t1 VALUES (3, 15)`)
+	requireSynced(t, src, dest, "db1.t1", "db1.t2")
+
+	result, err := dest.Exec("SHOW CREATE TABLE db1.t2")
+	require.NoError(t, err)
+	createSql := result.Rows[0][1]
+	require.Contains(t, createSql, `PROPERTIES ("replication_allocation" = "tag.location.default: 1", "storage_medium" = "hdd", "dynamic_partition.enable" = "false")`)
+
+	addPartition := ""
+	for _, sql := range dest.Sqls() {
+		if strings.Contains(sql, "ADD PARTITION p2") {
+			addPartition = sql
+		}
+	}
+	require.Contains(t, addPartition, `("replication_allocation" = "tag.location.default: 1")`)
+}
+
+// restoreProperties returns the properties of a restore of the database
+func (c *Cluster) restoreProperties(dbName string) map[string]string {
+	c.catalog.lock.Lock()
+	defer c.catalog.lock.Unlock()
+
+	for _, job := range c.catalog.restores {
+		if job.Database == dbName {
+			return job.Properties
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package fakedoris
+
+import (
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotJanitor(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`)
+
+	// the backup is not finished until the jobs are released
+	src.HoldJobs(true)
+	injector := fault.NewInjector()
+	job := startJob(t, "snapshot_janitor", src.Spec("db1", ""), dest.Spec("db1", ""), injector)
+
+	var abandoned string
+	require.Eventually(t, func() bool {
+		for label, state := range src.backupStates() {
+			abandoned = label
+			return state == JobStatePending
+		}
+		return false
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the backup timed out is not cancelled, the janitor cancels and drops it before the next backup
+	require.NoError(t, injector.SetRules([]fault.Rule{{Method: "Exec", Action: fault.ActionError, Error: "connection reset", Times: 1}}))
+	backupTimeout := 50 * time.Millisecond
+	require.NoError(t, job.UpdateWaitTimeouts(&backupTimeout, nil))
+	require.Eventually(t, func() bool {
+		states := src.backupStates()
+		_, ok := states[abandoned]
+		return !ok && len(states) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	backupTimeout = 0
+	require.NoError(t, job.UpdateWaitTimeouts(&backupTimeout, nil))
+	src.HoldJobs(false)
+	requireSynced(t, src, dest, "db1.t1")
+
+	// the snapshot is dropped after the restore succeeds
+	require.Eventually(t, func() bool { return len(src.backupStates()) == 0 }, 10*time.Second, 10*time.Millisecond)
+}
+
+// backupStates returns the state of backups by snapshot name
+func (c *Cluster) backupStates() map[string]string {
+	c.catalog.lock.Lock()
+	defer c.catalog.lock.Unlock()
+
+	states := make(map[string]string)
+	for label, job := range c.catalog.backups {
+		states[label] = job.State
+	}
+	return states
+}
@@ -0,0 +1,791 @@
+package fakedoris
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Result is the result set of a query, or the affected rows of a statement without columns.
+type Result struct {
+	Columns  []string
+	Rows     [][]string
+	Affected int64
+}
+
+func (r *Result) addRow(values ...any) {
+	row := make([]string, 0, len(values))
+	for _, value := range values {
+		row = append(row, fmt.Sprint(value))
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+type session struct {
+	db string
+	// frontend the session connects to, nil means the master
+	frontend *queryFrontend
+}
+
+type partitionDef struct {
+	name           string
+	partitionRange string
+}
+
+type tableDef struct {
+	body                string
+	columns             []string
+	distributionColumns []string
+	buckets             int
+	partitioned         bool
+	partitions          []partitionDef
+}
+
+type statement struct {
+	pattern    *regexp.Regexp
+	handle     func(c *Cluster, s *session, match []string) (*Result, error)
+	masterOnly bool
+}
+
+func stmt(pattern string, handle func(c *Cluster, s *session, match []string) (*Result, error)) statement {
+	return statement{
+		pattern: regexp.MustCompile(`(?is)^\s*` + pattern + `\s*$`),
+		handle:  handle,
+	}
+}
+
+// masterStmt is a statement rejected by the followers
+func masterStmt(pattern string, handle func(c *Cluster, s *session, match []string) (*Result, error)) statement {
+	statement := stmt(pattern, handle)
+	statement.masterOnly = true
+	return statement
+}
+
+// statements are the sql used by the syncer and the e2e tests, anything else is rejected
+var statements = []statement{
+	stmt(`show\s+proc\s+'/dbs/?'`, (*Cluster).showProcDbs),
+	stmt(`show\s+proc\s+'/dbs/(\d+)/?'`, (*Cluster).showProcTables),
+	stmt(`show\s+proc\s+'/dbs/(\d+)/(\d+)/partitions/?'`, (*Cluster).showProcPartitions),
+	stmt(`show\s+proc\s+'/dbs/(\d+)/(\d+)/partitions/(\d+)/?'`, (*Cluster).showProcIndexes),
+	stmt(`show\s+proc\s+'/dbs/(\d+)/(\d+)/partitions/(\d+)/(\d+)/?'`, (*Cluster).showProcTablets),
+	stmt(`show\s+backends`, (*Cluster).showBackends),
+	stmt(`select\s+.*\s+from\s+frontends\(\)`, (*Cluster).showFrontends),
+	stmt(`show\s+table\s+(\d+)`, (*Cluster).showTableById),
+	stmt(`admin\s+show\s+frontend\s+config(?:\s+like\s+["']([^"']*)["'])?`, (*Cluster).showFrontendConfig),
+	stmt(`show\s+create\s+database\s+(\S+)`, (*Cluster).showCreateDatabase),
+	stmt(`show\s+create\s+table\s+(\S+)`, (*Cluster).showCreateTable),
+	stmt(`show\s+tables(?:\s+from\s+(\S+))?(?:\s+like\s+'([^']*)')?`, (*Cluster).showTables),
+	stmt(`show\s+databases(?:\s+like\s+'([^']*)')?`, (*Cluster).showDatabases),
+	masterStmt(`show\s+backup\s+from\s+(\S+)\s+where\s+snapshotname\s*=\s*"([^"]*)"`, (*Cluster).showBackup),
+	masterStmt(`show\s+restore\s+from\s+(\S+)\s+where\s+label\s*=\s*"([^"]*)"`, (*Cluster).showRestore),
+	masterStmt(`show\s+transaction\s+from\s+(\S+)\s+where\s+id\s*=\s*(\d+)`, (*Cluster).showTransaction),
+	stmt(`select\s+count\(\*\)(\s*,.*?)?\s+from\s+(\S+?)(?:\s+partition\s*\(([^)]*)\))?`, (*Cluster).selectCount),
+	stmt(`desc(?:ribe)?\s+(\S+)`, (*Cluster).describe),
+	stmt(`create\s+database\s+(if\s+not\s+exists\s+)?([^\s(]+)(?:\s+properties\s*\((.*)\))?`, (*Cluster).createDatabase),
+	stmt(`drop\s+database\s+(if\s+exists\s+)?(\S+?)(?:\s+force)?`, (*Cluster).dropDatabase),
+	stmt(`alter\s+database\s+(\S+)\s+set\s+properties\s*\((.*)\)`, (*Cluster).alterDatabase),
+	stmt(`create\s+table\s+(if\s+not\s+exists\s+)?([^\s(]+)\s*(\(.*)`, (*Cluster).createTable),
+	stmt(`drop\s+table\s+(if\s+exists\s+)?(\S+?)(?:\s+force)?`, (*Cluster).dropTable),
+	stmt(`alter\s+table\s+(\S+)\s+(.*)`, (*Cluster).alterTable),
+	stmt(`truncate\s+table\s+(\S+?)(?:\s+(partitions?\s*\((.*)\)))?`, (*Cluster).truncateTable),
+	stmt(`insert\s+into\s+(\S+?)(?:\s+partition\s*\(([^)]*)\))?\s+values\s*(.*)`, (*Cluster).insert),
+	masterStmt(`cancel\s+(backup|restore)\s+from\s+(\S+)`, (*Cluster).cancelJobs),
+	masterStmt(`drop\s+snapshot\s+(\S+)\s+from\s+(\S+)`, (*Cluster).dropSnapshot),
+	masterStmt(`create\s+repository\s+(\S+)\s+with\s+(?:s3|hdfs)\s+on\s+location\s+"([^"]*)"(?:\s+properties\s*\(.*\))?`, (*Cluster).createRepository),
+	stmt(`show\s+repositories`, (*Cluster).showRepositories),
+	masterStmt(`show\s+snapshot\s+on\s+(\S+)\s+where\s+snapshot\s*=\s*"([^"]*)"(?:\s+and\s+timestamp\s*=\s*"([^"]*)")?`, (*Cluster).showSnapshot),
+	masterStmt(`backup\s+snapshot\s+(\S+)\s+to\s+(\S+)\s+on\s*\((.*?)\)(?:\s+properties\s*\(.*\))?`, (*Cluster).backup),
+	stmt(`use\s+(\S+)`, (*Cluster).use),
+	stmt(`(?:set\s+.*|select\s+1|begin|commit|rollback)`, (*Cluster).noop),
+}
+
+// execute runs one sql statement of a mysql session
+func (c *Cluster) execute(s *session, query string) (*Result, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	c.recordSql(query)
+
+	for _, statement := range statements {
+		if match := statement.pattern.FindStringSubmatch(query); match != nil {
+			if statement.masterOnly && s.frontend != nil && !c.isMaster(s.frontend) {
+				return nil, fmt.Errorf("errCode = 2, detailMessage = this frontend is not master, statement: %s", query)
+			}
+
+			c.catalog.lock.Lock()
+			result, err := statement.handle(c, s, match)
+			c.catalog.lock.Unlock()
+			if err != nil {
+				return nil, fmt.Errorf("errCode = 2, detailMessage = %s", err.Error())
+			}
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("errCode = 2, detailMessage = fake doris does not support the statement: %s", query)
+}
+
+func (c *Cluster) showProcDbs(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"DbId", "DbName", "TableNum"}}
+	for _, db := range c.catalog.dbs {
+		result.addRow(db.Id, db.Name, len(db.Tables))
+	}
+	return result, nil
+}
+
+func (c *Cluster) showProcTables(s *session, match []string) (*Result, error) {
+	db, err := c.catalog.databaseById(parseId(match[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"TableId", "TableName", "IndexNum", "Type", "State"}}
+	for _, table := range db.Tables {
+		result.addRow(table.Id, table.Name, len(table.Indexes), "OLAP", "NORMAL")
+	}
+	return result, nil
+}
+
+func (c *Cluster) procTable(dbId, tableId string) (*Table, error) {
+	db, err := c.catalog.databaseById(parseId(dbId))
+	if err != nil {
+		return nil, err
+	}
+	table := db.tableById(parseId(tableId))
+	if table == nil {
+		return nil, fmt.Errorf("Unknown table id %s", tableId)
+	}
+	return table, nil
+}
+
+func (c *Cluster) procPartition(dbId, tableId, partitionId string) (*Table, *Partition, error) {
+	table, err := c.procTable(dbId, tableId)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, partition := range table.Partitions {
+		if partition.Id == parseId(partitionId) {
+			return table, partition, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("Unknown partition id %s", partitionId)
+}
+
+func (c *Cluster) showProcPartitions(s *session, match []string) (*Result, error) {
+	table, err := c.procTable(match[1], match[2])
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"PartitionId", "PartitionName", "VisibleVersion", "State", "Range", "Buckets"}}
+	for _, partition := range table.Partitions {
+		result.addRow(partition.Id, partition.Name, partition.VisibleVersion, "NORMAL", partition.Range, partition.Buckets)
+	}
+	return result, nil
+}
+
+func (c *Cluster) showProcIndexes(s *session, match []string) (*Result, error) {
+	table, _, err := c.procPartition(match[1], match[2], match[3])
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"IndexId", "IndexName", "State"}}
+	for _, index := range table.Indexes {
+		result.addRow(index.Id, index.Name, "NORMAL")
+	}
+	return result, nil
+}
+
+func (c *Cluster) showProcTablets(s *session, match []string) (*Result, error) {
+	_, partition, err := c.procPartition(match[1], match[2], match[3])
+	if err != nil {
+		return nil, err
+	}
+	tablets, ok := partition.Tablets[parseId(match[4])]
+	if !ok {
+		return nil, fmt.Errorf("Unknown index id %s", match[4])
+	}
+
+	result := &Result{Columns: []string{"TabletId", "ReplicaId", "BackendId", "Version", "State"}}
+	for _, tablet := range tablets {
+		for _, replica := range tablet.Replicas {
+			result.addRow(tablet.Id, replica.Id, replica.BackendId, replica.Version, "NORMAL")
+		}
+	}
+	return result, nil
+}
+
+func (c *Cluster) showBackends(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"BackendId", "Cluster", "Host", "HeartbeatPort", "BePort", "HttpPort", "BrpcPort", "Alive"}}
+	for _, backend := range c.catalog.backends {
+		result.addRow(backend.Id, "default_cluster", backend.Host, 0, backend.BePort, backend.HttpPort, backend.BrpcPort, true)
+	}
+	return result, nil
+}
+
+func (c *Cluster) showFrontends(s *session, match []string) (*Result, error) {
+	c.feLock.Lock()
+	defer c.feLock.Unlock()
+
+	result := &Result{Columns: []string{"Host", "QueryPort", "RpcPort", "IsMaster"}}
+	for _, frontend := range c.frontends {
+		if !frontend.stopped {
+			result.addRow(c.host, frontend.port, c.rpcPort, frontend.master)
+		}
+	}
+	return result, nil
+}
+
+func (c *Cluster) showTableById(s *session, match []string) (*Result, error) {
+	tableId := parseId(match[1])
+	for _, db := range c.catalog.dbs {
+		if table := db.tableById(tableId); table != nil {
+			result := &Result{Columns: []string{"DbName", "TableName", "DbId"}}
+			result.addRow(db.Name, table.Name, db.Id)
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown table id %d", tableId)
+}
+
+func (c *Cluster) showFrontendConfig(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"Key", "Value", "Type", "IsMutable", "MasterOnly", "Comment"}}
+	if match[1] == "" || likeToRegexp(match[1]).MatchString("enable_feature_binlog") {
+		result.addRow("enable_feature_binlog", true, "boolean", false, false, "")
+	}
+	return result, nil
+}
+
+func (c *Cluster) showCreateDatabase(s *session, match []string) (*Result, error) {
+	db, err := c.catalog.database(unquote(match[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"Database", "Create Database"}}
+	result.addRow(db.Name, db.CreateSql())
+	return result, nil
+}
+
+func (c *Cluster) showCreateTable(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[1])
+	if err != nil {
+		return nil, err
+	}
+	_, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"Table", "Create Table"}}
+	result.addRow(table.Name, table.CreateSql())
+	return result, nil
+}
+
+func (c *Cluster) showTables(s *session, match []string) (*Result, error) {
+	dbName := s.db
+	if match[1] != "" {
+		dbName = unquote(match[1])
+	}
+	if dbName == "" {
+		return nil, fmt.Errorf("No database selected")
+	}
+	db, err := c.catalog.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"Tables_in_" + db.Name}}
+	for _, name := range sortedTableNames(db) {
+		if match[2] == "" || likeToRegexp(match[2]).MatchString(name) {
+			result.addRow(name)
+		}
+	}
+	return result, nil
+}
+
+func (c *Cluster) showDatabases(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"Database"}}
+	for _, db := range c.catalog.dbs {
+		if match[1] == "" || likeToRegexp(match[1]).MatchString(db.Name) {
+			result.addRow(db.Name)
+		}
+	}
+	return result, nil
+}
+
+func (c *Cluster) showBackup(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"JobId", "SnapshotName", "DbName", "State", "UnfinishedTasks", "Progress", "Status"}}
+	if job, ok := c.catalog.backups[match[2]]; ok && job.Database == unquote(match[1]) {
+		result.addRow(0, match[2], job.Database, job.State, job.UnfinishedTasks, "", "")
+	}
+	return result, nil
+}
+
+func (c *Cluster) showRestore(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"JobId", "Label", "DbName", "State", "UnfinishedTasks", "Progress", "Status"}}
+	if job, ok := c.catalog.restores[match[2]]; ok && job.Database == unquote(match[1]) {
+		result.addRow(0, match[2], job.Database, job.State, job.UnfinishedTasks, "", job.Status)
+	}
+	return result, nil
+}
+
+func (c *Cluster) showTransaction(s *session, match []string) (*Result, error) {
+	txn, ok := c.catalog.txns[parseId(match[2])]
+	if !ok {
+		return nil, fmt.Errorf("transaction [%s] not found", match[2])
+	}
+
+	result := &Result{Columns: []string{"TransactionId", "Label", "TransactionStatus"}}
+	result.addRow(txn.Id, txn.Label, txn.Status)
+	return result, nil
+}
+
+// selectCount counts the rows of the table or a partition, the fake table has no column data,
+// so the extra aggregates, such as checksums, are the row count too
+func (c *Cluster) selectCount(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[2])
+	if err != nil {
+		return nil, err
+	}
+	_, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := table.Rows()
+	if match[3] != "" {
+		partitionName := unquote(strings.TrimSpace(match[3]))
+		partition := table.partition(partitionName)
+		if partition == nil {
+			return nil, fmt.Errorf("Unknown partition '%s' in table '%s'", partitionName, tableName)
+		}
+		rows = partition.Rows()
+	}
+
+	result := &Result{Columns: []string{"count(*)"}}
+	values := []any{rows}
+	for _, aggregate := range splitItems(strings.TrimPrefix(strings.TrimSpace(match[1]), ",")) {
+		result.Columns = append(result.Columns, aggregate)
+		values = append(values, rows)
+	}
+	result.addRow(values...)
+	return result, nil
+}
+
+func (c *Cluster) describe(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[1])
+	if err != nil {
+		return nil, err
+	}
+	_, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Columns: []string{"Field", "Type", "Null", "Key", "Default", "Extra"}}
+	for _, column := range table.Columns {
+		result.addRow(column, "", "", "", "", "")
+	}
+	return result, nil
+}
+
+func (c *Cluster) createDatabase(s *session, match []string) (*Result, error) {
+	binlogEnabled := strings.Contains(match[3], binlogEnableProperty)
+	return &Result{}, c.catalog.createDatabase(unquote(match[2]), binlogEnabled, match[1] != "")
+}
+
+func (c *Cluster) dropDatabase(s *session, match []string) (*Result, error) {
+	return &Result{}, c.catalog.dropDatabase(unquote(match[2]), match[1] != "")
+}
+
+func (c *Cluster) alterDatabase(s *session, match []string) (*Result, error) {
+	db, err := c.catalog.database(unquote(match[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	properties := strings.ReplaceAll(match[2], " ", "")
+	switch {
+	case strings.Contains(properties, `"binlog.enable"="true"`):
+		c.catalog.alterDatabaseBinlog(db, true)
+	case strings.Contains(properties, `"binlog.enable"="false"`):
+		c.catalog.alterDatabaseBinlog(db, false)
+	}
+	return &Result{}, nil
+}
+
+func (c *Cluster) createTable(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[2])
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.catalog.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := parseTableDef(match[3])
+	if err != nil {
+		return nil, err
+	}
+	return &Result{}, c.catalog.createTable(db, tableName, def, match[1] != "")
+}
+
+func (c *Cluster) dropTable(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[2])
+	if err != nil {
+		return nil, err
+	}
+	db, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		if match[1] != "" {
+			return &Result{}, nil
+		}
+		return nil, err
+	}
+
+	c.catalog.dropTable(db, table, match[0])
+	return &Result{}, nil
+}
+
+var (
+	addPartitionPattern  = regexp.MustCompile(`(?is)^add\s+(?:temporary\s+)?partition\s+(?:if\s+not\s+exists\s+)?(\S+)\s+(values\s+.*?)(?:\s+distributed\s+by\s+.*)?$`)
+	dropPartitionPattern = regexp.MustCompile(`(?is)^drop\s+partition\s+(if\s+exists\s+)?(\S+?)(?:\s+force)?$`)
+	addColumnPattern     = regexp.MustCompile(`(?is)^add\s+column\s+(\S+)`)
+	dropColumnPattern    = regexp.MustCompile(`(?is)^drop\s+column\s+(\S+)`)
+	setPropertyPattern   = regexp.MustCompile(`(?is)^set\s*\(.*\)$`)
+	bucketsPattern       = regexp.MustCompile(`(?is)\bbuckets\s+(\d+)`)
+)
+
+func (c *Cluster) alterTable(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[1])
+	if err != nil {
+		return nil, err
+	}
+	db, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	clause := strings.TrimSpace(match[2])
+	rawSql := fmt.Sprintf("ALTER TABLE `%s`.`%s` %s", db.Name, table.Name, clause)
+	if m := addPartitionPattern.FindStringSubmatch(clause); m != nil {
+		buckets := 0
+		if b := bucketsPattern.FindStringSubmatch(clause); b != nil {
+			buckets, _ = strconv.Atoi(b[1])
+		}
+		sql := fmt.Sprintf("ADD PARTITION %s %s", unquote(m[1]), strings.TrimSpace(m[2]))
+		return &Result{}, c.catalog.addPartition(db, table, unquote(m[1]), strings.TrimSpace(m[2]), buckets, sql)
+	} else if m := dropPartitionPattern.FindStringSubmatch(clause); m != nil {
+		if table.partition(unquote(m[2])) == nil && m[1] != "" {
+			return &Result{}, nil
+		}
+		return &Result{}, c.catalog.dropPartition(db, table, unquote(m[2]))
+	} else if m := addColumnPattern.FindStringSubmatch(clause); m != nil {
+		return &Result{}, c.catalog.modifyColumns(db, table, []string{unquote(m[1])}, nil, rawSql)
+	} else if m := dropColumnPattern.FindStringSubmatch(clause); m != nil {
+		return &Result{}, c.catalog.modifyColumns(db, table, nil, []string{unquote(m[1])}, rawSql)
+	} else if setPropertyPattern.MatchString(clause) {
+		return &Result{}, nil
+	}
+	return nil, fmt.Errorf("fake doris does not support the alter clause: %s", clause)
+}
+
+func (c *Cluster) truncateTable(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[1])
+	if err != nil {
+		return nil, err
+	}
+	db, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitionNames []string
+	if match[3] != "" {
+		for _, name := range strings.Split(match[3], ",") {
+			partitionNames = append(partitionNames, unquote(strings.TrimSpace(name)))
+		}
+	}
+	return &Result{}, c.catalog.truncateTable(db, table, partitionNames, match[2])
+}
+
+func (c *Cluster) insert(s *session, match []string) (*Result, error) {
+	dbName, tableName, err := s.resolve(match[1])
+	if err != nil {
+		return nil, err
+	}
+	db, table, err := c.catalog.table(dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(table.Partitions) == 0 {
+		return nil, fmt.Errorf("table %s has no partition", tableName)
+	}
+
+	partition := table.Partitions[0]
+	if match[2] != "" {
+		if partition = table.partition(unquote(strings.TrimSpace(match[2]))); partition == nil {
+			return nil, fmt.Errorf("Unknown partition '%s' in table '%s'", match[2], tableName)
+		}
+	}
+
+	rows := int64(countGroups(match[3]))
+	if err := c.catalog.load(db, partition, rows); err != nil {
+		return nil, err
+	}
+	return &Result{Affected: rows}, nil
+}
+
+func (c *Cluster) backup(s *session, match []string) (*Result, error) {
+	dbName, snapshotName, err := s.resolve(match[1])
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.catalog.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	var tableNames []string
+	for _, name := range strings.Split(match[3], ",") {
+		tableNames = append(tableNames, unquote(strings.TrimSpace(name)))
+	}
+	return &Result{}, c.catalog.backup(db, snapshotName, unquote(match[2]), tableNames)
+}
+
+func (c *Cluster) createRepository(s *session, match []string) (*Result, error) {
+	name := unquote(match[1])
+	if _, ok := c.catalog.repositories[name]; ok {
+		return nil, fmt.Errorf("Repository %s already exist", name)
+	}
+	c.catalog.repositories[name] = match[2]
+	return &Result{}, nil
+}
+
+func (c *Cluster) showRepositories(s *session, match []string) (*Result, error) {
+	result := &Result{Columns: []string{"RepoId", "RepoName", "CreateTime", "IsReadOnly", "Location", "Broker", "ErrMsg"}}
+	for name, location := range c.catalog.repositories {
+		result.addRow(0, name, "", false, location, "-", "NULL")
+	}
+	return result, nil
+}
+
+// showSnapshot lists the snapshots in the repository, the brief job info is shown as details with the timestamp
+// like a real frontend, the table commit seqs are only in the job info of GetSnapshot
+func (c *Cluster) showSnapshot(s *session, match []string) (*Result, error) {
+	repository := unquote(match[1])
+	location, ok := c.catalog.repositories[repository]
+	if !ok {
+		return nil, fmt.Errorf("Repository %s does not exist", repository)
+	}
+
+	result := &Result{Columns: []string{"Snapshot", "Timestamp", "Status", "Details"}}
+	for _, snapshot := range repositorySnapshots(location, match[2]) {
+		switch {
+		case match[3] == "":
+			result.addRow(snapshot.Name, snapshot.Timestamp, "OK", "")
+		case snapshot.Timestamp == match[3]:
+			var brief map[string]any
+			if err := json.Unmarshal(snapshot.JobInfo, &brief); err != nil {
+				return nil, err
+			}
+			delete(brief, "table_commit_seq_map")
+			details, err := json.Marshal(brief)
+			if err != nil {
+				return nil, err
+			}
+			result.addRow(snapshot.Name, snapshot.Timestamp, "OK", string(details))
+		}
+	}
+	return result, nil
+}
+
+func (c *Cluster) cancelJobs(s *session, match []string) (*Result, error) {
+	db, err := c.catalog.database(unquote(match[2]))
+	if err != nil {
+		return nil, err
+	}
+	c.catalog.cancelJobs(db, strings.EqualFold(match[1], "restore"))
+	return &Result{}, nil
+}
+
+func (c *Cluster) dropSnapshot(s *session, match []string) (*Result, error) {
+	db, err := c.catalog.database(unquote(match[2]))
+	if err != nil {
+		return nil, err
+	}
+	return &Result{}, c.catalog.dropSnapshot(db, unquote(match[1]))
+}
+
+func (c *Cluster) use(s *session, match []string) (*Result, error) {
+	db, err := c.catalog.database(unquote(match[1]))
+	if err != nil {
+		return nil, err
+	}
+	s.db = db.Name
+	return &Result{}, nil
+}
+
+func (c *Cluster) noop(s *session, match []string) (*Result, error) {
+	return &Result{}, nil
+}
+
+// resolve splits `db`.`table` or table of the current db
+func (s *session) resolve(ref string) (string, string, error) {
+	ref = unquote(ref)
+	if dbName, tableName, ok := strings.Cut(ref, "."); ok {
+		return dbName, tableName, nil
+	}
+	if s.db == "" {
+		return "", "", fmt.Errorf("No database selected")
+	}
+	return s.db, ref, nil
+}
+
+func parseTableDef(body string) (*tableDef, error) {
+	def := &tableDef{body: strings.TrimSpace(body), buckets: 1}
+
+	columns, rest, err := cutParens(def.body)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range splitItems(columns) {
+		fields := strings.Fields(column)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "INDEX", "KEY", "PRIMARY", "UNIQUE", "CONSTRAINT":
+			continue
+		}
+		def.columns = append(def.columns, unquote(fields[0]))
+	}
+
+	if m := regexp.MustCompile(`(?is)distributed\s+by\s+hash\s*\(([^)]*)\)`).FindStringSubmatch(rest); m != nil {
+		for _, column := range strings.Split(m[1], ",") {
+			def.distributionColumns = append(def.distributionColumns, unquote(strings.TrimSpace(column)))
+		}
+	}
+	if m := bucketsPattern.FindStringSubmatch(rest); m != nil {
+		def.buckets, _ = strconv.Atoi(m[1])
+	}
+
+	loc := regexp.MustCompile(`(?is)partition\s+by\s+\w+\s*\([^)]*\)\s*`).FindStringIndex(rest)
+	if loc == nil {
+		return def, nil
+	}
+	def.partitioned = true
+	partitions, _, err := cutParens(rest[loc[1]:])
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range splitItems(partitions) {
+		m := regexp.MustCompile(`(?is)^partition\s+(\S+)\s+(values\s+.*)$`).FindStringSubmatch(partition)
+		if m == nil {
+			return nil, fmt.Errorf("invalid partition desc: %s", partition)
+		}
+		def.partitions = append(def.partitions, partitionDef{name: unquote(m[1]), partitionRange: strings.TrimSpace(m[2])})
+	}
+	return def, nil
+}
+
+// cutParens returns the content of the leading parenthesized group and the rest after it
+func cutParens(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		return "", "", fmt.Errorf("expect '(' at: %.32s", s)
+	}
+
+	depth := 0
+	var quote rune
+	for i, ch := range s {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:], nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unbalanced parentheses: %.32s", s)
+}
+
+// splitItems splits the items separated by top level commas, e.g. the column defs of a create table
+func splitItems(s string) []string {
+	items := make([]string, 0)
+	depth := 0
+	start := 0
+	var quote rune
+	for i, ch := range s {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if item := strings.TrimSpace(s[start:]); item != "" {
+		items = append(items, item)
+	}
+	return items
+}
+
+// countGroups counts the top level parenthesized groups, e.g. the rows of insert values
+func countGroups(s string) int {
+	groups := 0
+	depth := 0
+	var quote rune
+	for _, ch := range s {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			if depth == 0 {
+				groups++
+			}
+			depth++
+		case ch == ')':
+			depth--
+		}
+	}
+	return groups
+}
+
+func unquote(name string) string {
+	return strings.ReplaceAll(strings.TrimSpace(name), "`", "")
+}
+
+func parseId(s string) int64 {
+	id, _ := strconv.ParseInt(s, 10, 64)
+	return id
+}
+
+func likeToRegexp(pattern string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, "%", ".*")
+	expr = strings.ReplaceAll(expr, "_", ".")
+	return regexp.MustCompile("(?i)^" + expr + "$")
+}
@@ -0,0 +1,37 @@
+package fakedoris
+
+import (
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMasterTokenRefresh(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`)
+
+	job := startJob(t, "token", src.Spec("db1", ""), dest.Spec("db1", ""), nil)
+	requireSynced(t, src, dest, "db1.t1")
+	require.Equal(t, 1, src.RpcCount("GetMasterToken"))
+	require.Equal(t, 1, dest.RpcCount("RestoreSnapshot"))
+
+	// the src master restarts with a new token, the cached one is rejected by the restore
+	src.RotateToken()
+	mustExec(t, dest, `INSERT INTO db1.t1 VALUES (3, 'c')`)
+	// the snapshot and restore labels are named by seconds, the first ones are not reused
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	report, err := job.Verify(&ccr.VerifyOptions{Resync: true})
+	require.NoError(t, err)
+	require.True(t, report.Resynced)
+
+	verifyConsistent(t, job, &ccr.VerifyOptions{})
+	require.Equal(t, count(t, src, "db1.t1"), count(t, dest, "db1.t1"))
+	require.Equal(t, 2, src.RpcCount("GetMasterToken"))
+	require.Equal(t, 3, dest.RpcCount("RestoreSnapshot"))
+}
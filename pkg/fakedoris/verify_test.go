@@ -0,0 +1,78 @@
+package fakedoris
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/stretchr/testify/require"
+)
+
+// verifyConsistent waits until the job is verified consistent, it may be in full sync or lagging
+func verifyConsistent(t *testing.T, job *ccr.Job, options *ccr.VerifyOptions) *ccr.VerifyReport {
+	var report *ccr.VerifyReport
+	require.Eventually(t, func() bool {
+		var err error
+		report, err = job.Verify(options)
+		return err == nil && report.Consistent
+	}, 10*time.Second, 20*time.Millisecond)
+	return report
+}
+
+func TestVerify(t *testing.T) {
+	src, dest := startClusters(t)
+
+	mustExec(t, src,
+		`CREATE DATABASE db1 PROPERTIES ("binlog.enable" = "true")`,
+		`CREATE TABLE db1.t1 (id INT, v STRING) DISTRIBUTED BY HASH(id) BUCKETS 2`,
+		`CREATE TABLE db1.t2 (id INT, k INT) PARTITION BY RANGE(k) (PARTITION p1 VALUES LESS THAN ("10"), PARTITION p2 VALUES LESS THAN ("20")) DISTRIBUTED BY HASH(id) BUCKETS 3`,
+		`INSERT INTO db1.t1 VALUES (1, 'a'), (2, 'b')`,
+		`INSERT INTO db1.t2 PARTITION (p1) VALUES (1, 1)`)
+
+	job := startJob(t, "verify", src.Spec("db1", ""), dest.Spec("db1", ""), nil)
+	requireSynced(t, src, dest, "db1.t1", "db1.t2")
+	mustExec(t, src, `INSERT INTO db1.t2 PARTITION (p2) VALUES (11, 11), (12, 12)`)
+	requireSynced(t, src, dest, "db1.t2")
+
+	options := &ccr.VerifyOptions{Checksum: "sum(murmur_hash3_32(concat_ws('|', {columns})))"}
+	report := verifyConsistent(t, job, options)
+	require.Equal(t, report, job.LastVerifyReport())
+	require.Len(t, report.Tables, 2)
+	require.Equal(t, 1, report.Tables[0].ConsistentNum)
+	require.Equal(t, 2, report.Tables[1].ConsistentNum)
+	require.Contains(t, src.Sqls(), "SELECT count(*), sum(murmur_hash3_32(concat_ws('|', `id`, `k`))) FROM `t2` PARTITION (`p2`)")
+
+	// rows written to dest directly
+	mustExec(t, dest, `INSERT INTO db1.t2 PARTITION (p1) VALUES (2, 2)`)
+	report, err := job.Verify(&ccr.VerifyOptions{Tables: []string{"t2"}})
+	require.NoError(t, err)
+	require.False(t, report.Consistent)
+	require.Equal(t, 1, report.MismatchNum)
+	require.Len(t, report.Tables, 1)
+	mismatch := report.Tables[0].Partitions[0]
+	require.Equal(t, "p1", mismatch.Name)
+	require.Equal(t, ccr.VerifyMismatch, mismatch.State)
+	require.Equal(t, int64(1), mismatch.SrcRows)
+	require.Equal(t, int64(2), mismatch.DestRows)
+
+	_, err = job.Verify(&ccr.VerifyOptions{Tables: []string{"t3"}})
+	require.ErrorContains(t, err, "not synced")
+
+	// resync restores the mismatched table from a new snapshot, the others keep syncing
+	report, err = job.Verify(&ccr.VerifyOptions{Resync: true})
+	require.NoError(t, err)
+	require.True(t, report.Resynced)
+	mustExec(t, src, `INSERT INTO db1.t1 VALUES (3, 'c')`)
+	verifyConsistent(t, job, options)
+	require.Equal(t, count(t, src, "db1.t1"), count(t, dest, "db1.t1"))
+	require.Equal(t, count(t, src, "db1.t2"), count(t, dest, "db1.t2"))
+	backups := make([]string, 0)
+	for _, sql := range src.Sqls() {
+		if strings.HasPrefix(sql, "BACKUP SNAPSHOT") {
+			backups = append(backups, sql)
+		}
+	}
+	require.Len(t, backups, 2)
+	require.Contains(t, backups[1], "ON ( t2 )")
+}
@@ -0,0 +1,262 @@
+// Package fault injects faults into the rpc and sql calls of the syncer, to chaos test the recovery
+// paths of jobs, e.g. the rollback, isTxnCommitted and PUBLISH_TIMEOUT handling of upsert binlogs.
+// It must never be enabled in production.
+package fault
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/modern-go/gls"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the gls key of the commit seq handled by the job goroutine
+	glsCommitSeqKey = "fault_commit_seq"
+
+	// AnyMethod matches all methods
+	AnyMethod = "*"
+)
+
+type Action string
+
+const (
+	// ActionDelay sleeps before the call
+	ActionDelay Action = "delay"
+	// ActionDrop fails the call as if the request is lost, or the response if after_call is set
+	ActionDrop Action = "drop"
+	// ActionStatus returns a result of the status code, only for rpc methods
+	ActionStatus Action = "status"
+	// ActionError fails the call with the error message
+	ActionError Action = "error"
+)
+
+// Duration is a time.Duration in text, e.g. 500ms, both in yaml and json
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Rule injects a fault into the calls of a method
+type Rule struct {
+	// the rpc method of IFeRpc/IBeRpc or the sql method of Specer, * means all
+	Method string `yaml:"method" json:"method"`
+	Action Action `yaml:"action" json:"action"`
+	// sleep duration of delay action
+	Delay Duration `yaml:"delay,omitempty" json:"delay,omitempty"`
+	// TStatusCode name of status action, e.g. PUBLISH_TIMEOUT
+	Status string `yaml:"status,omitempty" json:"status,omitempty"`
+	// error message of error action
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+	// make the real call before the fault, so its effect is kept, e.g. a committed txn with PUBLISH_TIMEOUT
+	AfterCall bool `yaml:"after_call,omitempty" json:"after_call,omitempty"`
+
+	// inject in this probability, 0 means always
+	Probability float64 `yaml:"probability,omitempty" json:"probability,omitempty"`
+	// only inject when the job handles these commit seqs, empty means any
+	CommitSeqs []int64 `yaml:"commit_seqs,omitempty" json:"commit_seqs,omitempty"`
+	// only inject into the calls of this job, empty means any
+	Job string `yaml:"job,omitempty" json:"job,omitempty"`
+	// max injected times, 0 means no limit
+	Times int `yaml:"times,omitempty" json:"times,omitempty"`
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s %s", r.Method, r.Action)
+}
+
+func (r *Rule) Validate() error {
+	if r.Method == "" {
+		return xerror.Errorf(xerror.Normal, "fault rule method is empty")
+	}
+	if r.Method != AnyMethod && !isRpcMethod(r.Method) && !isSqlMethod(r.Method) {
+		return xerror.Errorf(xerror.Normal, "fault rule method %s is unknown", r.Method)
+	}
+
+	switch r.Action {
+	case ActionDelay:
+		if r.Delay <= 0 {
+			return xerror.Errorf(xerror.Normal, "fault rule %s: delay must be positive", r)
+		}
+	case ActionDrop:
+	case ActionStatus:
+		if _, err := tstatus.TStatusCodeFromString(r.Status); err != nil {
+			return xerror.Errorf(xerror.Normal, "fault rule %s: unknown status %s", r, r.Status)
+		}
+		if r.Method != AnyMethod && !isRpcMethod(r.Method) {
+			return xerror.Errorf(xerror.Normal, "fault rule %s: status is only for rpc methods", r)
+		}
+	case ActionError:
+		if r.Error == "" {
+			return xerror.Errorf(xerror.Normal, "fault rule %s: error is empty", r)
+		}
+	default:
+		return xerror.Errorf(xerror.Normal, "fault rule %s: unknown action, want delay, drop, status or error", r)
+	}
+
+	if r.Probability < 0 || r.Probability > 1 {
+		return xerror.Errorf(xerror.Normal, "fault rule %s: probability must be in [0, 1]", r)
+	}
+	if r.Times < 0 {
+		return xerror.Errorf(xerror.Normal, "fault rule %s: times must not be negative", r)
+	}
+	return nil
+}
+
+func (r *Rule) match(method string, commitSeq int64, hasCommitSeq bool, job string) bool {
+	if r.Method != AnyMethod && r.Method != method {
+		return false
+	}
+	// status needs a rpc result
+	if r.Action == ActionStatus && !isRpcMethod(method) {
+		return false
+	}
+	if r.Job != "" && r.Job != job {
+		return false
+	}
+	if len(r.CommitSeqs) > 0 {
+		if !hasCommitSeq {
+			return false
+		}
+		for _, seq := range r.CommitSeqs {
+			if seq == commitSeq {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// RuleState is a rule and how many times it is injected
+type RuleState struct {
+	Rule
+	Injected int `json:"injected"`
+}
+
+// Injector decides the fault of each call by its rules, the first matched rule wins
+type Injector struct {
+	lock  sync.Mutex
+	rules []*RuleState
+	rand  *rand.Rand
+}
+
+func NewInjector() *Injector {
+	return &Injector{
+		rules: make([]*RuleState, 0),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetRules replaces all rules and resets the injected times
+func (i *Injector) SetRules(rules []Rule) error {
+	states := make([]*RuleState, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		states = append(states, &RuleState{Rule: rule})
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.rules = states
+	log.Infof("fault rules are set, rules: %v", rules)
+	return nil
+}
+
+func (i *Injector) Rules() []RuleState {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	states := make([]RuleState, 0, len(i.rules))
+	for _, state := range i.rules {
+		states = append(states, *state)
+	}
+	return states
+}
+
+// fault returns the rule to inject into this call, nil means no fault
+func (i *Injector) fault(method string) *Rule {
+	commitSeq, hasCommitSeq := gls.Get(glsCommitSeqKey).(int64)
+	job, _ := gls.Get("job").(string)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for _, state := range i.rules {
+		if state.Times > 0 && state.Injected >= state.Times {
+			continue
+		}
+		if !state.match(method, commitSeq, hasCommitSeq, job) {
+			continue
+		}
+		if state.Probability > 0 && i.rand.Float64() >= state.Probability {
+			continue
+		}
+
+		state.Injected++
+		rule := state.Rule
+		log.Warnf("fault injected, rule: %s, method: %s, job: %s, commitSeq: %d", &rule, method, job, commitSeq)
+		return &rule
+	}
+	return nil
+}
+
+// SetCommitSeq marks the commit seq handled by the current goroutine, the gls must be enabled
+func SetCommitSeq(commitSeq int64) {
+	if gls.IsGlsEnabled(gls.GoID()) {
+		gls.Set(glsCommitSeqKey, commitSeq)
+	}
+}
+
+// inject runs call with the fault of rule, newResult builds the result of status action
+func inject[T any](rule *Rule, category xerror.ErrorCategory, method string, newResult func(*tstatus.TStatus) T, call func() (T, error)) (T, error) {
+	var zero T
+	if rule == nil {
+		return call()
+	}
+
+	if rule.Action == ActionDelay {
+		time.Sleep(time.Duration(rule.Delay))
+		return call()
+	}
+
+	if rule.AfterCall {
+		if _, err := call(); err != nil {
+			return zero, err
+		}
+	}
+	switch rule.Action {
+	case ActionStatus:
+		code, _ := tstatus.TStatusCodeFromString(rule.Status)
+		return newResult(&tstatus.TStatus{
+			StatusCode: code,
+			ErrorMsgs:  []string{fmt.Sprintf("fault injected: %s", rule.Status)},
+		}), nil
+	case ActionError:
+		return zero, xerror.Errorf(category, "fault injected: %s", rule.Error)
+	default:
+		if rule.AfterCall {
+			return zero, xerror.Errorf(category, "fault injected: the response of %s is dropped", method)
+		}
+		return zero, xerror.Errorf(category, "fault injected: the request of %s is dropped", method)
+	}
+}
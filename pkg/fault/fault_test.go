@@ -0,0 +1,170 @@
+package fault
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modern-go/gls"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	bestruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	festruct_types "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingFeRpc counts the real calls, all of them succeed
+type countingFeRpc struct {
+	commits int
+}
+
+func (r *countingFeRpc) CommitTransaction(spec *base.Spec, txnId int64, commitInfos []*festruct_types.TTabletCommitInfo) (*festruct.TCommitTxnResult_, error) {
+	r.commits++
+	return &festruct.TCommitTxnResult_{Status: &tstatus.TStatus{StatusCode: tstatus.TStatusCode_OK}}, nil
+}
+
+func commit(injector *Injector, real *countingFeRpc) (*festruct.TCommitTxnResult_, error) {
+	return injectRpc(injector, "CommitTransaction",
+		func(status *tstatus.TStatus) *festruct.TCommitTxnResult_ {
+			return &festruct.TCommitTxnResult_{Status: status}
+		},
+		func() (*festruct.TCommitTxnResult_, error) {
+			return real.CommitTransaction(nil, 1, nil)
+		})
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := []Rule{
+		{Method: "CommitTransaction", Action: ActionStatus, Status: "PUBLISH_TIMEOUT"},
+		{Method: "*", Action: ActionDrop, Probability: 0.1},
+		{Method: "Exec", Action: ActionError, Error: "Lost connection"},
+		{Method: "IngestBinlog", Action: ActionDelay, Delay: Duration(time.Second)},
+	}
+	for _, rule := range valid {
+		assert.NoError(t, rule.Validate(), "rule %s", &rule)
+	}
+
+	invalid := []Rule{
+		{Action: ActionDrop},
+		{Method: "Commit", Action: ActionDrop},
+		{Method: "CommitTransaction", Action: "crash"},
+		{Method: "CommitTransaction", Action: ActionStatus, Status: "UNKNOWN_STATUS"},
+		{Method: "DbExec", Action: ActionStatus, Status: "OK"},
+		{Method: "DbExec", Action: ActionError},
+		{Method: "IngestBinlog", Action: ActionDelay},
+		{Method: "IngestBinlog", Action: ActionDrop, Probability: 2},
+		{Method: "IngestBinlog", Action: ActionDrop, Times: -1},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, rule.Validate(), "rule %s", &rule)
+	}
+}
+
+func TestInject(t *testing.T) {
+	injector := NewInjector()
+	real := &countingFeRpc{}
+
+	// no rule
+	resp, err := commit(injector, real)
+	require.NoError(t, err)
+	assert.Equal(t, tstatus.TStatusCode_OK, resp.GetStatus().GetStatusCode())
+	assert.Equal(t, 1, real.commits)
+
+	// drop the request once
+	require.NoError(t, injector.SetRules([]Rule{{Method: "CommitTransaction", Action: ActionDrop, Times: 1}}))
+	_, err = commit(injector, real)
+	assert.ErrorContains(t, err, "request of CommitTransaction is dropped")
+	assert.Equal(t, 1, real.commits)
+	_, err = commit(injector, real)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, real.commits)
+	assert.Equal(t, 1, injector.Rules()[0].Injected)
+
+	// drop the response, the txn is committed
+	require.NoError(t, injector.SetRules([]Rule{{Method: "CommitTransaction", Action: ActionDrop, AfterCall: true}}))
+	_, err = commit(injector, real)
+	assert.ErrorContains(t, err, "response of CommitTransaction is dropped")
+	assert.Equal(t, 3, real.commits)
+
+	// status after call
+	require.NoError(t, injector.SetRules([]Rule{{Method: "CommitTransaction", Action: ActionStatus, Status: "PUBLISH_TIMEOUT", AfterCall: true}}))
+	resp, err = commit(injector, real)
+	require.NoError(t, err)
+	assert.Equal(t, tstatus.TStatusCode_PUBLISH_TIMEOUT, resp.GetStatus().GetStatusCode())
+	assert.Equal(t, 4, real.commits)
+
+	// error, and the rule of other method doesn't match
+	require.NoError(t, injector.SetRules([]Rule{
+		{Method: "IngestBinlog", Action: ActionDrop},
+		{Method: "*", Action: ActionError, Error: "connection reset"},
+	}))
+	_, err = commit(injector, real)
+	assert.ErrorContains(t, err, "connection reset")
+	assert.Equal(t, 4, real.commits)
+
+	// a tiny probability almost never injects
+	require.NoError(t, injector.SetRules([]Rule{{Method: "CommitTransaction", Action: ActionDrop, Probability: 1e-9}}))
+	for i := 0; i < 10; i++ {
+		_, err = commit(injector, real)
+		assert.NoError(t, err)
+	}
+
+	require.NoError(t, injector.SetRules(nil))
+	assert.Empty(t, injector.Rules())
+	assert.Error(t, injector.SetRules([]Rule{{Method: "CommitTransaction"}}))
+}
+
+func TestInjectAtCommitSeqs(t *testing.T) {
+	injector := NewInjector()
+	require.NoError(t, injector.SetRules([]Rule{{Method: "CommitTransaction", Action: ActionDrop, CommitSeqs: []int64{12}, Job: "job1"}}))
+	real := &countingFeRpc{}
+
+	// no commit seq in gls
+	_, err := commit(injector, real)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		gls.ResetGls(gls.GoID(), map[interface{}]interface{}{})
+		defer gls.DeleteGls(gls.GoID())
+		gls.Set("job", "job1")
+
+		var errs []error
+		for _, commitSeq := range []int64{11, 12, 13} {
+			SetCommitSeq(commitSeq)
+			if _, err := commit(injector, real); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) != 1 {
+			done <- errors.New("expect exactly one fault at commit seq 12")
+			return
+		}
+		done <- nil
+	}()
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, injector.Rules()[0].Injected)
+}
+
+func TestRuleJson(t *testing.T) {
+	var rule Rule
+	require.NoError(t, json.Unmarshal([]byte(`{"method": "IngestBinlog", "action": "delay", "delay": "1.5s"}`), &rule))
+	assert.Equal(t, Duration(1500*time.Millisecond), rule.Delay)
+
+	data, err := json.Marshal(&RuleState{Rule: rule, Injected: 2})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"method": "IngestBinlog", "action": "delay", "delay": "1.5s", "injected": 2}`, string(data))
+}
+
+func TestBeRpc(t *testing.T) {
+	injector := NewInjector()
+	require.NoError(t, injector.SetRules([]Rule{{Method: "IngestBinlog", Action: ActionStatus, Status: "TABLET_MISSING"}}))
+
+	r := &beRpc{injector: injector}
+	resp, err := r.IngestBinlog(&bestruct.TIngestBinlogRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, tstatus.TStatusCode_TABLET_MISSING, resp.GetStatus().GetStatusCode())
+}
@@ -0,0 +1,229 @@
+package fault
+
+import (
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	bestruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice"
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	festruct_types "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+var rpcMethods = map[string]bool{
+	"BeginTransaction":    true,
+	"CommitTransaction":   true,
+	"RollbackTransaction": true,
+	"GetBinlog":           true,
+	"GetBinlogLag":        true,
+	"GetSnapshot":         true,
+	"RestoreSnapshot":     true,
+	"GetMasterToken":      true,
+	"GetDbMeta":           true,
+	"GetTableNames":       true,
+	"GetTableMeta":        true,
+	"GetPartitionMeta":    true,
+	"GetBackends":         true,
+	"IngestBinlog":        true,
+}
+
+func isRpcMethod(method string) bool {
+	return rpcMethods[method]
+}
+
+// rpcFactory wraps the fe and be rpcs of factory with the injector
+type rpcFactory struct {
+	factory  rpc.IRpcFactory
+	injector *Injector
+}
+
+func NewRpcFactory(factory rpc.IRpcFactory, injector *Injector) rpc.IRpcFactory {
+	return &rpcFactory{
+		factory:  factory,
+		injector: injector,
+	}
+}
+
+func (f *rpcFactory) NewFeRpc(spec *base.Spec) (rpc.IFeRpc, error) {
+	client, err := f.factory.NewFeRpc(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &feRpc{rpc: client, injector: f.injector}, nil
+}
+
+func (f *rpcFactory) NewBeRpc(be *base.Backend) (rpc.IBeRpc, error) {
+	client, err := f.factory.NewBeRpc(be)
+	if err != nil {
+		return nil, err
+	}
+	return &beRpc{rpc: client, injector: f.injector}, nil
+}
+
+type feRpc struct {
+	rpc      rpc.IFeRpc
+	injector *Injector
+}
+
+func injectRpc[T any](injector *Injector, method string, newResult func(*tstatus.TStatus) T, call func() (T, error)) (T, error) {
+	return inject(injector.fault(method), xerror.RPC, method, newResult, call)
+}
+
+func (r *feRpc) BeginTransaction(spec *base.Spec, label string, tableIds []int64) (*festruct.TBeginTxnResult_, error) {
+	return injectRpc(r.injector, "BeginTransaction",
+		func(status *tstatus.TStatus) *festruct.TBeginTxnResult_ {
+			return &festruct.TBeginTxnResult_{Status: status}
+		},
+		func() (*festruct.TBeginTxnResult_, error) {
+			return r.rpc.BeginTransaction(spec, label, tableIds)
+		})
+}
+
+func (r *feRpc) CommitTransaction(spec *base.Spec, txnId int64, commitInfos []*festruct_types.TTabletCommitInfo) (*festruct.TCommitTxnResult_, error) {
+	return injectRpc(r.injector, "CommitTransaction",
+		func(status *tstatus.TStatus) *festruct.TCommitTxnResult_ {
+			return &festruct.TCommitTxnResult_{Status: status}
+		},
+		func() (*festruct.TCommitTxnResult_, error) {
+			return r.rpc.CommitTransaction(spec, txnId, commitInfos)
+		})
+}
+
+func (r *feRpc) RollbackTransaction(spec *base.Spec, txnId int64) (*festruct.TRollbackTxnResult_, error) {
+	return injectRpc(r.injector, "RollbackTransaction",
+		func(status *tstatus.TStatus) *festruct.TRollbackTxnResult_ {
+			return &festruct.TRollbackTxnResult_{Status: status}
+		},
+		func() (*festruct.TRollbackTxnResult_, error) {
+			return r.rpc.RollbackTransaction(spec, txnId)
+		})
+}
+
+func (r *feRpc) GetBinlog(spec *base.Spec, commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+	return injectRpc(r.injector, "GetBinlog",
+		func(status *tstatus.TStatus) *festruct.TGetBinlogResult_ {
+			return &festruct.TGetBinlogResult_{Status: status}
+		},
+		func() (*festruct.TGetBinlogResult_, error) {
+			return r.rpc.GetBinlog(spec, commitSeq)
+		})
+}
+
+func (r *feRpc) GetBinlogLag(spec *base.Spec, commitSeq int64) (*festruct.TGetBinlogLagResult_, error) {
+	return injectRpc(r.injector, "GetBinlogLag",
+		func(status *tstatus.TStatus) *festruct.TGetBinlogLagResult_ {
+			return &festruct.TGetBinlogLagResult_{Status: status}
+		},
+		func() (*festruct.TGetBinlogLagResult_, error) {
+			return r.rpc.GetBinlogLag(spec, commitSeq)
+		})
+}
+
+func (r *feRpc) GetSnapshot(spec *base.Spec, labelName string) (*festruct.TGetSnapshotResult_, error) {
+	return injectRpc(r.injector, "GetSnapshot",
+		func(status *tstatus.TStatus) *festruct.TGetSnapshotResult_ {
+			return &festruct.TGetSnapshotResult_{Status: status}
+		},
+		func() (*festruct.TGetSnapshotResult_, error) {
+			return r.rpc.GetSnapshot(spec, labelName)
+		})
+}
+
+func (r *feRpc) RestoreSnapshot(spec *base.Spec, tableRefs []*festruct.TTableRef, label string, snapshotResult *festruct.TGetSnapshotResult_, options *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error) {
+	return injectRpc(r.injector, "RestoreSnapshot",
+		func(status *tstatus.TStatus) *festruct.TRestoreSnapshotResult_ {
+			return &festruct.TRestoreSnapshotResult_{Status: status}
+		},
+		func() (*festruct.TRestoreSnapshotResult_, error) {
+			return r.rpc.RestoreSnapshot(spec, tableRefs, label, snapshotResult, options)
+		})
+}
+
+func (r *feRpc) RestoreSnapshotFromRepository(spec *base.Spec, tableRefs []*festruct.TTableRef, snapshotName string, backupTimestamp string, options *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error) {
+	return injectRpc(r.injector, "RestoreSnapshot",
+		func(status *tstatus.TStatus) *festruct.TRestoreSnapshotResult_ {
+			return &festruct.TRestoreSnapshotResult_{Status: status}
+		},
+		func() (*festruct.TRestoreSnapshotResult_, error) {
+			return r.rpc.RestoreSnapshotFromRepository(spec, tableRefs, snapshotName, backupTimestamp, options)
+		})
+}
+
+func (r *feRpc) GetMasterToken(spec *base.Spec) (*festruct.TGetMasterTokenResult_, error) {
+	return injectRpc(r.injector, "GetMasterToken",
+		func(status *tstatus.TStatus) *festruct.TGetMasterTokenResult_ {
+			return &festruct.TGetMasterTokenResult_{Status: status}
+		},
+		func() (*festruct.TGetMasterTokenResult_, error) {
+			return r.rpc.GetMasterToken(spec)
+		})
+}
+
+func (r *feRpc) GetDbMeta(spec *base.Spec) (*festruct.TGetMetaResult_, error) {
+	return injectRpc(r.injector, "GetDbMeta",
+		func(status *tstatus.TStatus) *festruct.TGetMetaResult_ {
+			return &festruct.TGetMetaResult_{Status: status}
+		},
+		func() (*festruct.TGetMetaResult_, error) {
+			return r.rpc.GetDbMeta(spec)
+		})
+}
+
+func (r *feRpc) GetTableMeta(spec *base.Spec, tableIds []int64) (*festruct.TGetMetaResult_, error) {
+	return injectRpc(r.injector, "GetTableMeta",
+		func(status *tstatus.TStatus) *festruct.TGetMetaResult_ {
+			return &festruct.TGetMetaResult_{Status: status}
+		},
+		func() (*festruct.TGetMetaResult_, error) {
+			return r.rpc.GetTableMeta(spec, tableIds)
+		})
+}
+
+func (r *feRpc) GetTableNames(spec *base.Spec) (*festruct.TGetMetaResult_, error) {
+	return injectRpc(r.injector, "GetTableNames",
+		func(status *tstatus.TStatus) *festruct.TGetMetaResult_ {
+			return &festruct.TGetMetaResult_{Status: status}
+		},
+		func() (*festruct.TGetMetaResult_, error) {
+			return r.rpc.GetTableNames(spec)
+		})
+}
+
+func (r *feRpc) GetPartitionMeta(spec *base.Spec, tableId int64, partitionIds []int64) (*festruct.TGetMetaResult_, error) {
+	return injectRpc(r.injector, "GetPartitionMeta",
+		func(status *tstatus.TStatus) *festruct.TGetMetaResult_ {
+			return &festruct.TGetMetaResult_{Status: status}
+		},
+		func() (*festruct.TGetMetaResult_, error) {
+			return r.rpc.GetPartitionMeta(spec, tableId, partitionIds)
+		})
+}
+
+func (r *feRpc) GetBackends(spec *base.Spec) (*festruct.TGetBackendMetaResult_, error) {
+	return injectRpc(r.injector, "GetBackends",
+		func(status *tstatus.TStatus) *festruct.TGetBackendMetaResult_ {
+			return &festruct.TGetBackendMetaResult_{Status: status}
+		},
+		func() (*festruct.TGetBackendMetaResult_, error) {
+			return r.rpc.GetBackends(spec)
+		})
+}
+
+func (r *feRpc) Address() string {
+	return r.rpc.Address()
+}
+
+type beRpc struct {
+	rpc      rpc.IBeRpc
+	injector *Injector
+}
+
+func (r *beRpc) IngestBinlog(req *bestruct.TIngestBinlogRequest) (*bestruct.TIngestBinlogResult_, error) {
+	return injectRpc(r.injector, "IngestBinlog",
+		func(status *tstatus.TStatus) *bestruct.TIngestBinlogResult_ {
+			return &bestruct.TIngestBinlogResult_{Status: status}
+		},
+		func() (*bestruct.TIngestBinlogResult_, error) {
+			return r.rpc.IngestBinlog(req)
+		})
+}
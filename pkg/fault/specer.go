@@ -0,0 +1,147 @@
+package fault
+
+import (
+	"database/sql"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+var sqlMethods = map[string]bool{
+	"Connect":                true,
+	"ConnectDB":              true,
+	"IsDatabaseEnableBinlog": true,
+	"IsTableEnableBinlog":    true,
+	"GetAllTables":           true,
+	"ClearDB":                true,
+	"CreateDatabase":         true,
+	"CreateTable":            true,
+	"CheckDatabaseExists":    true,
+	"CheckTableExists":       true,
+	"CreateSnapshot":         true,
+	"GetBackupProgress":      true,
+	"GetRestoreProgress":     true,
+	"DropSnapshot":           true,
+	"CheckRepositoryExists":  true,
+	"GetRepositorySnapshot":  true,
+	"Exec":                   true,
+	"DbExec":                 true,
+}
+
+func isSqlMethod(method string) bool {
+	return sqlMethods[method]
+}
+
+// specerFactory wraps the specers of factory with the injector
+type specerFactory struct {
+	factory  base.SpecerFactory
+	injector *Injector
+}
+
+func NewSpecerFactory(factory base.SpecerFactory, injector *Injector) base.SpecerFactory {
+	return &specerFactory{
+		factory:  factory,
+		injector: injector,
+	}
+}
+
+func (f *specerFactory) NewSpecer(spec *base.Spec) base.Specer {
+	return &specer{Specer: f.factory.NewSpecer(spec), injector: f.injector}
+}
+
+// specer injects faults into the sql methods, the others are passed through
+type specer struct {
+	base.Specer
+	injector *Injector
+}
+
+func injectSql[T any](injector *Injector, method string, call func() (T, error)) (T, error) {
+	return inject(injector.fault(method), xerror.Normal, method, nil, call)
+}
+
+func (s *specer) injectExec(method string, call func() error) error {
+	_, err := injectSql(s.injector, method, func() (struct{}, error) {
+		return struct{}{}, call()
+	})
+	return err
+}
+
+func (s *specer) Connect() (*sql.DB, error) {
+	return injectSql(s.injector, "Connect", s.Specer.Connect)
+}
+
+func (s *specer) ConnectDB() (*sql.DB, error) {
+	return injectSql(s.injector, "ConnectDB", s.Specer.ConnectDB)
+}
+
+func (s *specer) IsDatabaseEnableBinlog() (bool, error) {
+	return injectSql(s.injector, "IsDatabaseEnableBinlog", s.Specer.IsDatabaseEnableBinlog)
+}
+
+func (s *specer) IsTableEnableBinlog() (bool, error) {
+	return injectSql(s.injector, "IsTableEnableBinlog", s.Specer.IsTableEnableBinlog)
+}
+
+func (s *specer) GetAllTables() ([]string, error) {
+	return injectSql(s.injector, "GetAllTables", s.Specer.GetAllTables)
+}
+
+func (s *specer) ClearDB() error {
+	return s.injectExec("ClearDB", s.Specer.ClearDB)
+}
+
+func (s *specer) CreateDatabase() error {
+	return s.injectExec("CreateDatabase", s.Specer.CreateDatabase)
+}
+
+func (s *specer) CreateTable(stmt string) error {
+	return s.injectExec("CreateTable", func() error { return s.Specer.CreateTable(stmt) })
+}
+
+func (s *specer) CheckDatabaseExists() (bool, error) {
+	return injectSql(s.injector, "CheckDatabaseExists", s.Specer.CheckDatabaseExists)
+}
+
+func (s *specer) CheckTableExists() (bool, error) {
+	return injectSql(s.injector, "CheckTableExists", s.Specer.CheckTableExists)
+}
+
+func (s *specer) CreateSnapshot(tables []string) (string, error) {
+	return injectSql(s.injector, "CreateSnapshot", func() (string, error) {
+		return s.Specer.CreateSnapshot(tables)
+	})
+}
+
+func (s *specer) GetBackupProgress(snapshotName string) (*base.BackupRestoreProgress, error) {
+	return injectSql(s.injector, "GetBackupProgress", func() (*base.BackupRestoreProgress, error) {
+		return s.Specer.GetBackupProgress(snapshotName)
+	})
+}
+
+func (s *specer) GetRestoreProgress(snapshotName string) (*base.BackupRestoreProgress, error) {
+	return injectSql(s.injector, "GetRestoreProgress", func() (*base.BackupRestoreProgress, error) {
+		return s.Specer.GetRestoreProgress(snapshotName)
+	})
+}
+
+func (s *specer) DropSnapshot(snapshotName string) error {
+	return s.injectExec("DropSnapshot", func() error { return s.Specer.DropSnapshot(snapshotName) })
+}
+
+func (s *specer) CheckRepositoryExists() (bool, error) {
+	return injectSql(s.injector, "CheckRepositoryExists", s.Specer.CheckRepositoryExists)
+}
+
+func (s *specer) GetRepositorySnapshot(snapshotName string) (*base.RepositorySnapshot, error) {
+	return injectSql(s.injector, "GetRepositorySnapshot", func() (*base.RepositorySnapshot, error) {
+		return s.Specer.GetRepositorySnapshot(snapshotName)
+	})
+}
+
+func (s *specer) Exec(sql string) error {
+	return s.injectExec("Exec", func() error { return s.Specer.Exec(sql) })
+}
+
+func (s *specer) DbExec(sql string) error {
+	return s.injectExec("DbExec", func() error { return s.Specer.DbExec(sql) })
+}
@@ -2,13 +2,17 @@ package rpc
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xtrace"
 
 	bestruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice"
 	beservice "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice/backendservice"
 
+	"github.com/cloudwego/kitex/client/callopt"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -24,10 +28,15 @@ type BeRpc struct {
 func (beRpc *BeRpc) IngestBinlog(req *bestruct.TIngestBinlogRequest) (*bestruct.TIngestBinlogResult_, error) {
 	log.Debugf("IngestBinlog req: %+v, txnId: %d, be: %v", req, req.GetTxnId(), beRpc.backend)
 
+	addr := fmt.Sprintf("%s:%d", beRpc.backend.Host, beRpc.backend.BePort)
+	span := xtrace.Start("BeRpc.IngestBinlog", xtrace.Address(addr), xtrace.TabletId(req.GetLocalTabletId()))
 	client := beRpc.client
-	if result, err := client.IngestBinlog(context.Background(), req); err != nil {
+	result, err := invoke("IngestBinlog", addr, func(timeout callopt.Option) (*bestruct.TIngestBinlogResult_, error) {
+		return client.IngestBinlog(context.Background(), req, timeout)
+	})
+	span.End(err)
+	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.Normal, "IngestBinlog error: %v", err)
-	} else {
-		return result, nil
 	}
+	return result, nil
 }
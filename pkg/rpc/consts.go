@@ -5,4 +5,21 @@ import "time"
 const (
 	CONNECT_TIMEOUT = 1 * time.Second
 	RPC_TIMEOUT     = 3 * time.Second
+
+	// be clients are cached by address, the least recently used one is evicted if there are too many
+	MAX_CACHED_BE_RPCS = 256
+	// be clients idle for a long time are evicted, e.g. clients of decommissioned backends or old addresses
+	BE_RPC_IDLE_TIMEOUT = 10 * time.Minute
+)
+
+// timeouts of new clients, the defaults are the consts above
+var (
+	connectTimeout = CONNECT_TIMEOUT
+	rpcTimeout     = RPC_TIMEOUT
 )
+
+// SetTimeouts changes the timeouts of rpc clients, it must be called before any client is created
+func SetTimeouts(connect, rpc time.Duration) {
+	connectTimeout = connect
+	rpcTimeout = rpc
+}
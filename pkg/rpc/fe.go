@@ -14,10 +14,12 @@ import (
 	festruct_types "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
 	"github.com/selectdb/ccr_syncer/pkg/utils"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xtrace"
 
 	"github.com/cloudwego/kitex/client"
 	"github.com/cloudwego/kitex/client/callopt"
 	"github.com/cloudwego/kitex/pkg/kerrors"
+	"github.com/cloudwego/kitex/pkg/remote"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	log "github.com/sirupsen/logrus"
 )
@@ -31,8 +33,20 @@ const (
 
 var ErrFeNotMasterCompatible = xerror.NewWithoutStack(xerror.FE, "not master compatible")
 
+// IsUnknownMethod returns true if the server does not have the method, e.g. an old fe without GetMeta
+func IsUnknownMethod(err error) bool {
+	var transErr *remote.TransError
+	return errors.As(err, &transErr) && transErr.TypeID() == remote.UnknownMethod
+}
+
 // canUseNextAddr means can try next addr, err is a connection error, not a method not found or other error
 func canUseNextAddr(err error) bool {
+	if IsUnknownMethod(err) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
 	if errors.Is(err, kerrors.ErrNoConnection) {
 		return true
 	}
@@ -70,10 +84,13 @@ type IFeRpc interface {
 	GetBinlog(*base.Spec, int64) (*festruct.TGetBinlogResult_, error)
 	GetBinlogLag(*base.Spec, int64) (*festruct.TGetBinlogLagResult_, error)
 	GetSnapshot(*base.Spec, string) (*festruct.TGetSnapshotResult_, error)
-	RestoreSnapshot(*base.Spec, []*festruct.TTableRef, string, *festruct.TGetSnapshotResult_) (*festruct.TRestoreSnapshotResult_, error)
+	RestoreSnapshot(*base.Spec, []*festruct.TTableRef, string, *festruct.TGetSnapshotResult_, *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error)
+	RestoreSnapshotFromRepository(*base.Spec, []*festruct.TTableRef, string, string, *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error)
 	GetMasterToken(*base.Spec) (*festruct.TGetMasterTokenResult_, error)
 	GetDbMeta(spec *base.Spec) (*festruct.TGetMetaResult_, error)
+	GetTableNames(spec *base.Spec) (*festruct.TGetMetaResult_, error)
 	GetTableMeta(spec *base.Spec, tableIds []int64) (*festruct.TGetMetaResult_, error)
+	GetPartitionMeta(spec *base.Spec, tableId int64, partitionIds []int64) (*festruct.TGetMetaResult_, error)
 	GetBackends(spec *base.Spec) (*festruct.TGetBackendMetaResult_, error)
 
 	Address() string
@@ -88,7 +105,8 @@ type FeRpc struct {
 }
 
 func NewFeRpc(spec *base.Spec) (*FeRpc, error) {
-	addr := fmt.Sprintf("%s:%s", spec.Host, spec.ThriftPort)
+	frontend := spec.CurrentFrontend()
+	addr := fmt.Sprintf("%s:%s", frontend.Host, frontend.ThriftPort)
 	client, err := newSingleFeClient(addr)
 	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "NewFeClient error: %v", err)
@@ -97,7 +115,7 @@ func NewFeRpc(spec *base.Spec) (*FeRpc, error) {
 	clients := make(map[string]IFeRpc)
 	clients[client.Address()] = client
 	cachedFeAddrs := make(map[string]bool)
-	for _, fe := range spec.Frontends {
+	for _, fe := range spec.KnownFrontends() {
 		addr := fmt.Sprintf("%s:%s", fe.Host, fe.ThriftPort)
 
 		if _, ok := cachedFeAddrs[addr]; ok {
@@ -306,12 +324,15 @@ func (r *retryWithMasterRedirectAndCachedClientsRpc) call() (resultType, error)
 	return r.call()
 }
 
-func (rpc *FeRpc) callWithMasterRedirect(caller callerType) (resultType, error) {
+func (rpc *FeRpc) callWithMasterRedirect(method string, caller callerType) (resultType, error) {
+	span := xtrace.Start("FeRpc."+method, xtrace.Address(rpc.getMasterClient().Address()))
 	r := &retryWithMasterRedirectAndCachedClientsRpc{
 		rpc:    rpc,
 		caller: caller,
 	}
-	return r.call()
+	result, err := withRetry(method, r.call)
+	span.End(err)
+	return result, err
 }
 
 func convertResult[T any](result any, err error) (*T, error) {
@@ -327,7 +348,7 @@ func (rpc *FeRpc) BeginTransaction(spec *base.Spec, label string, tableIds []int
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.BeginTransaction(spec, label, tableIds)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("BeginTransaction", caller)
 	return convertResult[festruct.TBeginTxnResult_](result, err)
 }
 
@@ -336,7 +357,7 @@ func (rpc *FeRpc) CommitTransaction(spec *base.Spec, txnId int64, commitInfos []
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.CommitTransaction(spec, txnId, commitInfos)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("CommitTransaction", caller)
 	return convertResult[festruct.TCommitTxnResult_](result, err)
 }
 
@@ -345,7 +366,7 @@ func (rpc *FeRpc) RollbackTransaction(spec *base.Spec, txnId int64) (*festruct.T
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.RollbackTransaction(spec, txnId)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("RollbackTransaction", caller)
 	return convertResult[festruct.TRollbackTxnResult_](result, err)
 }
 
@@ -354,7 +375,7 @@ func (rpc *FeRpc) GetBinlog(spec *base.Spec, commitSeq int64) (*festruct.TGetBin
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetBinlog(spec, commitSeq)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetBinlog", caller)
 	return convertResult[festruct.TGetBinlogResult_](result, err)
 }
 
@@ -363,7 +384,7 @@ func (rpc *FeRpc) GetBinlogLag(spec *base.Spec, commitSeq int64) (*festruct.TGet
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetBinlogLag(spec, commitSeq)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetBinlogLag", caller)
 	return convertResult[festruct.TGetBinlogLagResult_](result, err)
 }
 
@@ -372,16 +393,24 @@ func (rpc *FeRpc) GetSnapshot(spec *base.Spec, labelName string) (*festruct.TGet
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetSnapshot(spec, labelName)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetSnapshot", caller)
 	return convertResult[festruct.TGetSnapshotResult_](result, err)
 }
 
-func (rpc *FeRpc) RestoreSnapshot(spec *base.Spec, tableRefs []*festruct.TTableRef, label string, snapshotResult *festruct.TGetSnapshotResult_) (*festruct.TRestoreSnapshotResult_, error) {
+func (rpc *FeRpc) RestoreSnapshot(spec *base.Spec, tableRefs []*festruct.TTableRef, label string, snapshotResult *festruct.TGetSnapshotResult_, options *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error) {
 	// return rpc.masterClient.RestoreSnapshot(spec, tableRefs, label, snapshotResult)
 	caller := func(client IFeRpc) (resultType, error) {
-		return client.RestoreSnapshot(spec, tableRefs, label, snapshotResult)
+		return client.RestoreSnapshot(spec, tableRefs, label, snapshotResult, options)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("RestoreSnapshot", caller)
+	return convertResult[festruct.TRestoreSnapshotResult_](result, err)
+}
+
+func (rpc *FeRpc) RestoreSnapshotFromRepository(spec *base.Spec, tableRefs []*festruct.TTableRef, snapshotName string, backupTimestamp string, options *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error) {
+	caller := func(client IFeRpc) (resultType, error) {
+		return client.RestoreSnapshotFromRepository(spec, tableRefs, snapshotName, backupTimestamp, options)
+	}
+	result, err := rpc.callWithMasterRedirect("RestoreSnapshot", caller)
 	return convertResult[festruct.TRestoreSnapshotResult_](result, err)
 }
 
@@ -390,7 +419,7 @@ func (rpc *FeRpc) GetMasterToken(spec *base.Spec) (*festruct.TGetMasterTokenResu
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetMasterToken(spec)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetMasterToken", caller)
 	return convertResult[festruct.TGetMasterTokenResult_](result, err)
 }
 
@@ -398,7 +427,15 @@ func (rpc *FeRpc) GetDbMeta(spec *base.Spec) (*festruct.TGetMetaResult_, error)
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetDbMeta(spec)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetDbMeta", caller)
+	return convertResult[festruct.TGetMetaResult_](result, err)
+}
+
+func (rpc *FeRpc) GetTableNames(spec *base.Spec) (*festruct.TGetMetaResult_, error) {
+	caller := func(client IFeRpc) (resultType, error) {
+		return client.GetTableNames(spec)
+	}
+	result, err := rpc.callWithMasterRedirect("GetTableNames", caller)
 	return convertResult[festruct.TGetMetaResult_](result, err)
 }
 
@@ -406,7 +443,15 @@ func (rpc *FeRpc) GetTableMeta(spec *base.Spec, tableIds []int64) (*festruct.TGe
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetTableMeta(spec, tableIds)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetTableMeta", caller)
+	return convertResult[festruct.TGetMetaResult_](result, err)
+}
+
+func (rpc *FeRpc) GetPartitionMeta(spec *base.Spec, tableId int64, partitionIds []int64) (*festruct.TGetMetaResult_, error) {
+	caller := func(client IFeRpc) (resultType, error) {
+		return client.GetPartitionMeta(spec, tableId, partitionIds)
+	}
+	result, err := rpc.callWithMasterRedirect("GetPartitionMeta", caller)
 	return convertResult[festruct.TGetMetaResult_](result, err)
 }
 
@@ -414,7 +459,7 @@ func (rpc *FeRpc) GetBackends(spec *base.Spec) (*festruct.TGetBackendMetaResult_
 	caller := func(client IFeRpc) (resultType, error) {
 		return client.GetBackends(spec)
 	}
-	result, err := rpc.callWithMasterRedirect(caller)
+	result, err := rpc.callWithMasterRedirect("GetBackends", caller)
 	return convertResult[festruct.TGetBackendMetaResult_](result, err)
 }
 
@@ -439,7 +484,7 @@ type singleFeClient struct {
 
 func newSingleFeClient(addr string) (*singleFeClient, error) {
 	// create kitex FrontendService client
-	if fe_client, err := feservice.NewClient("FrontendService", client.WithHostPorts(addr), client.WithConnectTimeout(CONNECT_TIMEOUT), client.WithRPCTimeout(RPC_TIMEOUT)); err != nil {
+	if fe_client, err := feservice.NewClient("FrontendService", client.WithHostPorts(addr), client.WithConnectTimeout(connectTimeout), client.WithRPCTimeout(rpcTimeout)); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "NewFeClient error: %v, addr: %s", err, addr)
 	} else {
 		return &singleFeClient{
@@ -480,7 +525,9 @@ func (rpc *singleFeClient) BeginTransaction(spec *base.Spec, label string, table
 	req.TableIds = tableIds
 
 	log.Debugf("BeginTransaction user %s, label: %s, tableIds: %v", req.GetUser(), label, tableIds)
-	if result, err := client.BeginTxn(context.Background(), req); err != nil {
+	if result, err := invoke("BeginTransaction", rpc.Address(), func(timeout callopt.Option) (*festruct.TBeginTxnResult_, error) {
+		return client.BeginTxn(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "BeginTransaction error: %v, req: %+v", err, req)
 	} else {
 		return result, nil
@@ -510,7 +557,9 @@ func (rpc *singleFeClient) CommitTransaction(spec *base.Spec, txnId int64, commi
 	req.TxnId = &txnId
 	req.CommitInfos = commitInfos
 
-	if result, err := client.CommitTxn(context.Background(), req, callopt.WithRPCTimeout(COMMIT_TXN_TIMEOUT)); err != nil {
+	if result, err := invoke("CommitTransaction", rpc.Address(), func(timeout callopt.Option) (*festruct.TCommitTxnResult_, error) {
+		return client.CommitTxn(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "CommitTransaction error: %v, req: %+v", err, req)
 	} else {
 		return result, nil
@@ -538,7 +587,9 @@ func (rpc *singleFeClient) RollbackTransaction(spec *base.Spec, txnId int64) (*f
 	setAuthInfo(req, spec)
 	req.TxnId = &txnId
 
-	if result, err := client.RollbackTxn(context.Background(), req); err != nil {
+	if result, err := invoke("RollbackTransaction", rpc.Address(), func(timeout callopt.Option) (*festruct.TRollbackTxnResult_, error) {
+		return client.RollbackTxn(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "RollbackTransaction error: %v, req: %+v", err, req)
 	} else {
 		return result, nil
@@ -573,7 +624,9 @@ func (rpc *singleFeClient) GetBinlog(spec *base.Spec, commitSeq int64) (*festruc
 
 	log.Debugf("GetBinlog user %s, db %s, tableId %d, prev seq: %d", req.GetUser(), req.GetDb(),
 		req.GetTableId(), req.GetPrevCommitSeq())
-	if resp, err := client.GetBinlog(context.Background(), req); err != nil {
+	if resp, err := invoke("GetBinlog", rpc.Address(), func(timeout callopt.Option) (*festruct.TGetBinlogResult_, error) {
+		return client.GetBinlog(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "GetBinlog error: %v, req: %+v", err, req)
 	} else {
 		return resp, nil
@@ -599,7 +652,9 @@ func (rpc *singleFeClient) GetBinlogLag(spec *base.Spec, commitSeq int64) (*fest
 
 	log.Debugf("GetBinlog user %s, db %s, tableId %d, prev seq: %d", req.GetUser(), req.GetDb(),
 		req.GetTableId(), req.GetPrevCommitSeq())
-	if resp, err := client.GetBinlogLag(context.Background(), req); err != nil {
+	if resp, err := invoke("GetBinlogLag", rpc.Address(), func(timeout callopt.Option) (*festruct.TGetBinlogLagResult_, error) {
+		return client.GetBinlogLag(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "GetBinlogLag error: %v, req: %+v", err, req)
 	} else {
 		return resp, nil
@@ -622,6 +677,9 @@ func (rpc *singleFeClient) GetSnapshot(spec *base.Spec, labelName string) (*fest
 
 	client := rpc.client
 	snapshotType := festruct.TSnapshotType_LOCAL
+	if spec.Repository != "" {
+		snapshotType = festruct.TSnapshotType_REMOTE
+	}
 	snapshotName := ""
 	req := &festruct.TGetSnapshotRequest{
 		Table:        &spec.Table,
@@ -633,7 +691,9 @@ func (rpc *singleFeClient) GetSnapshot(spec *base.Spec, labelName string) (*fest
 
 	log.Debugf("GetSnapshotRequest user %s, db %s, table %s, label name %s, snapshot name %s, snapshot type %d",
 		req.GetUser(), req.GetDb(), req.GetTable(), req.GetLabelName(), req.GetSnapshotName(), req.GetSnapshotType())
-	if resp, err := client.GetSnapshot(context.Background(), req); err != nil {
+	if resp, err := invoke("GetSnapshot", rpc.Address(), func(timeout callopt.Option) (*festruct.TGetSnapshotResult_, error) {
+		return client.GetSnapshot(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "GetSnapshot error: %v, req: %+v", err, req)
 	} else {
 		return resp, nil
@@ -656,14 +716,13 @@ func (rpc *singleFeClient) GetSnapshot(spec *base.Spec, labelName string) (*fest
 //	}
 //
 // Restore Snapshot rpc
-func (rpc *singleFeClient) RestoreSnapshot(spec *base.Spec, tableRefs []*festruct.TTableRef, label string, snapshotResult *festruct.TGetSnapshotResult_) (*festruct.TRestoreSnapshotResult_, error) {
+func (rpc *singleFeClient) RestoreSnapshot(spec *base.Spec, tableRefs []*festruct.TTableRef, label string, snapshotResult *festruct.TGetSnapshotResult_, options *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error) {
 	// NOTE: ignore meta, because it's too large
 	log.Debugf("Call RestoreSnapshot, addr: %s, spec: %s", rpc.Address(), spec)
 
 	client := rpc.client
-	repoName := "__keep_on_local__"
-	properties := make(map[string]string)
-	properties["reserve_replica"] = "true"
+	repoName := base.LocalRepository
+	properties := options.Properties()
 	req := &festruct.TRestoreSnapshotRequest{
 		Table:      &spec.Table,
 		LabelName:  &label,
@@ -678,13 +737,43 @@ func (rpc *singleFeClient) RestoreSnapshot(spec *base.Spec, tableRefs []*festruc
 	// NOTE: ignore meta, because it's too large
 	log.Debugf("RestoreSnapshotRequest user %s, db %s, table %s, label name %s, properties %v",
 		req.GetUser(), req.GetDb(), req.GetTable(), req.GetLabelName(), properties)
-	if resp, err := client.RestoreSnapshot(context.Background(), req); err != nil {
+	if resp, err := invoke("RestoreSnapshot", rpc.Address(), func(timeout callopt.Option) (*festruct.TRestoreSnapshotResult_, error) {
+		return client.RestoreSnapshot(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "RestoreSnapshot failed")
 	} else {
 		return resp, nil
 	}
 }
 
+// Restore the snapshot in the repository of spec, the restore label is the snapshot name, and the
+// meta and job info are read from the repository by the frontend
+func (rpc *singleFeClient) RestoreSnapshotFromRepository(spec *base.Spec, tableRefs []*festruct.TTableRef, snapshotName string, backupTimestamp string, options *base.RestoreOptions) (*festruct.TRestoreSnapshotResult_, error) {
+	log.Debugf("Call RestoreSnapshotFromRepository, addr: %s, spec: %s", rpc.Address(), spec)
+
+	client := rpc.client
+	properties := options.Properties()
+	properties["backup_timestamp"] = backupTimestamp
+	req := &festruct.TRestoreSnapshotRequest{
+		Table:      &spec.Table,
+		LabelName:  &snapshotName,
+		RepoName:   &spec.Repository,
+		TableRefs:  tableRefs,
+		Properties: properties,
+	}
+	setAuthInfo(req, spec)
+
+	log.Debugf("RestoreSnapshotRequest user %s, db %s, table %s, label name %s, repo name %s, properties %v",
+		req.GetUser(), req.GetDb(), req.GetTable(), req.GetLabelName(), req.GetRepoName(), properties)
+	if resp, err := invoke("RestoreSnapshot", rpc.Address(), func(timeout callopt.Option) (*festruct.TRestoreSnapshotResult_, error) {
+		return client.RestoreSnapshot(context.Background(), req, timeout)
+	}); err != nil {
+		return nil, xerror.Wrapf(err, xerror.RPC, "RestoreSnapshot from repository failed")
+	} else {
+		return resp, nil
+	}
+}
+
 func (rpc *singleFeClient) GetMasterToken(spec *base.Spec) (*festruct.TGetMasterTokenResult_, error) {
 	log.Debugf("Call GetMasterToken, addr: %s, spec: %s", rpc.Address(), spec)
 
@@ -696,19 +785,28 @@ func (rpc *singleFeClient) GetMasterToken(spec *base.Spec) (*festruct.TGetMaster
 	}
 
 	log.Debugf("GetMasterToken user: %s", *req.User)
-	if resp, err := client.GetMasterToken(context.Background(), req); err != nil {
+	if resp, err := invoke("GetMasterToken", rpc.Address(), func(timeout callopt.Option) (*festruct.TGetMasterTokenResult_, error) {
+		return client.GetMasterToken(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "GetMasterToken failed, req: %+v", req)
 	} else {
 		return resp, nil
 	}
 }
 
-func (rpc *singleFeClient) getMeta(spec *base.Spec, reqTables []*festruct.TGetMetaTable) (*festruct.TGetMetaResult_, error) {
-	client := rpc.client
+// newGetMetaDB returns the db of GetMeta request, the db is got by name before the job gets its id
+func newGetMetaDB(spec *base.Spec) *festruct.TGetMetaDB {
+	reqDb := festruct.NewTGetMetaDB()
+	if spec.DbId != 0 {
+		reqDb.Id = &spec.DbId
+	} else {
+		reqDb.Name = &spec.Database
+	}
+	return reqDb
+}
 
-	reqDb := festruct.NewTGetMetaDB() // festruct.NewTGetMetaTable()
-	reqDb.Id = &spec.DbId
-	reqDb.SetTables(reqTables)
+func (rpc *singleFeClient) getMeta(method string, spec *base.Spec, reqDb *festruct.TGetMetaDB) (*festruct.TGetMetaResult_, error) {
+	client := rpc.client
 
 	req := &festruct.TGetMetaRequest{
 		User:   &spec.User,
@@ -716,7 +814,9 @@ func (rpc *singleFeClient) getMeta(spec *base.Spec, reqTables []*festruct.TGetMe
 		Db:     reqDb,
 	}
 
-	if resp, err := client.GetMeta(context.Background(), req); err != nil {
+	if resp, err := invoke(method, rpc.Address(), func(timeout callopt.Option) (*festruct.TGetMetaResult_, error) {
+		return client.GetMeta(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "GetMeta failed, req: %+v", req)
 	} else {
 		return resp, nil
@@ -726,7 +826,17 @@ func (rpc *singleFeClient) getMeta(spec *base.Spec, reqTables []*festruct.TGetMe
 func (rpc *singleFeClient) GetDbMeta(spec *base.Spec) (*festruct.TGetMetaResult_, error) {
 	log.Debugf("GetMetaDb, addr: %s, spec: %s", rpc.Address(), spec)
 
-	return rpc.getMeta(spec, nil)
+	return rpc.getMeta("GetDbMeta", spec, newGetMetaDB(spec))
+}
+
+// GetTableNames gets the ids and names of the db and its tables, without the partitions
+func (rpc *singleFeClient) GetTableNames(spec *base.Spec) (*festruct.TGetMetaResult_, error) {
+	log.Debugf("GetMetaTableNames, addr: %s, spec: %s", rpc.Address(), spec)
+
+	onlyTableNames := true
+	reqDb := newGetMetaDB(spec)
+	reqDb.SetOnlyTableNames(&onlyTableNames)
+	return rpc.getMeta("GetTableNames", spec, reqDb)
 }
 
 func (rpc *singleFeClient) GetTableMeta(spec *base.Spec, tableIds []int64) (*festruct.TGetMetaResult_, error) {
@@ -735,11 +845,30 @@ func (rpc *singleFeClient) GetTableMeta(spec *base.Spec, tableIds []int64) (*fes
 	reqTables := make([]*festruct.TGetMetaTable, 0, len(tableIds))
 	for _, tableId := range tableIds {
 		reqTable := festruct.NewTGetMetaTable()
-		reqTable.Id = &tableId
+		reqTable.Id = utils.ThriftValueWrapper(tableId)
 		reqTables = append(reqTables, reqTable)
 	}
 
-	return rpc.getMeta(spec, reqTables)
+	reqDb := newGetMetaDB(spec)
+	reqDb.SetTables(reqTables)
+	return rpc.getMeta("GetTableMeta", spec, reqDb)
+}
+
+// GetPartitionMeta gets the metas of the partitions of the table only
+func (rpc *singleFeClient) GetPartitionMeta(spec *base.Spec, tableId int64, partitionIds []int64) (*festruct.TGetMetaResult_, error) {
+	log.Debugf("GetMetaPartition, addr: %s, tableId: %d, partitionIds: %v", rpc.Address(), tableId, partitionIds)
+
+	reqTable := festruct.NewTGetMetaTable()
+	reqTable.Id = utils.ThriftValueWrapper(tableId)
+	for _, partitionId := range partitionIds {
+		reqPartition := festruct.NewTGetMetaPartition()
+		reqPartition.Id = utils.ThriftValueWrapper(partitionId)
+		reqTable.Partitions = append(reqTable.Partitions, reqPartition)
+	}
+
+	reqDb := newGetMetaDB(spec)
+	reqDb.SetTables([]*festruct.TGetMetaTable{reqTable})
+	return rpc.getMeta("GetPartitionMeta", spec, reqDb)
 }
 
 func (rpc *singleFeClient) GetBackends(spec *base.Spec) (*festruct.TGetBackendMetaResult_, error) {
@@ -752,7 +881,9 @@ func (rpc *singleFeClient) GetBackends(spec *base.Spec) (*festruct.TGetBackendMe
 		Passwd:  &spec.Password,
 	}
 
-	if resp, err := client.GetBackendMeta(context.Background(), req); err != nil {
+	if resp, err := invoke("GetBackends", rpc.Address(), func(timeout callopt.Option) (*festruct.TGetBackendMetaResult_, error) {
+		return client.GetBackendMeta(context.Background(), req, timeout)
+	}); err != nil {
 		return nil, xerror.Wrapf(err, xerror.RPC, "GetBackendMeta failed, req: %+v", req)
 	} else {
 		return resp, nil
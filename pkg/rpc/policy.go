@@ -0,0 +1,237 @@
+package rpc
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	"github.com/cloudwego/kitex/client/callopt"
+	"github.com/cloudwego/kitex/pkg/kerrors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// large snapshots and tablets take a long time to transfer
+	SNAPSHOT_TIMEOUT      = 60 * time.Second
+	INGEST_BINLOG_TIMEOUT = 60 * time.Second
+
+	READ_RETRIES     = 2
+	READ_BACKOFF     = 200 * time.Millisecond
+	READ_MAX_BACKOFF = 2 * time.Second
+
+	BREAKER_FAILURES     = 5
+	BREAKER_OPEN_TIMEOUT = 10 * time.Second
+)
+
+var ErrCircuitOpen = xerror.NewWithoutStack(xerror.RPC, "circuit breaker is open")
+
+// CallPolicy is the timeout and retry policy of a FeRpc or BeRpc method
+type CallPolicy struct {
+	// 0 means the default rpc timeout
+	Timeout time.Duration `yaml:"timeout"`
+	// retry times on connection errors and timeouts, only the methods without side effects can retry
+	Retries int `yaml:"retries"`
+	// backoff of the first retry, it doubles for each retry until max backoff, with jitter
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// idempotentMethods are all methods of FeRpc and BeRpc, true means it is safe to retry
+var idempotentMethods = map[string]bool{
+	"BeginTransaction":    false,
+	"CommitTransaction":   false,
+	"RollbackTransaction": false,
+	"GetBinlog":           true,
+	"GetBinlogLag":        true,
+	"GetSnapshot":         true,
+	"RestoreSnapshot":     false,
+	"GetMasterToken":      true,
+	"GetDbMeta":           true,
+	"GetTableNames":       true,
+	"GetTableMeta":        true,
+	"GetPartitionMeta":    true,
+	"GetBackends":         true,
+	"IngestBinlog":        false,
+}
+
+func defaultCallPolicies() map[string]CallPolicy {
+	policies := map[string]CallPolicy{
+		"CommitTransaction": {Timeout: COMMIT_TXN_TIMEOUT},
+		"RestoreSnapshot":   {Timeout: SNAPSHOT_TIMEOUT},
+		"IngestBinlog":      {Timeout: INGEST_BINLOG_TIMEOUT},
+	}
+	for method, idempotent := range idempotentMethods {
+		if idempotent {
+			policies[method] = CallPolicy{Retries: READ_RETRIES, Backoff: READ_BACKOFF, MaxBackoff: READ_MAX_BACKOFF}
+		}
+	}
+	getSnapshot := policies["GetSnapshot"]
+	getSnapshot.Timeout = SNAPSHOT_TIMEOUT
+	policies["GetSnapshot"] = getSnapshot
+	return policies
+}
+
+var callPolicies atomic.Pointer[map[string]CallPolicy]
+
+func init() {
+	policies := defaultCallPolicies()
+	callPolicies.Store(&policies)
+	SetBreaker(BREAKER_FAILURES, BREAKER_OPEN_TIMEOUT)
+}
+
+// ValidateCallPolicies checks the methods are known, and only the idempotent ones retry
+func ValidateCallPolicies(policies map[string]CallPolicy) error {
+	for method, policy := range policies {
+		idempotent, ok := idempotentMethods[method]
+		if !ok {
+			methods := make([]string, 0, len(idempotentMethods))
+			for method := range idempotentMethods {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+			return xerror.Errorf(xerror.Normal, "unknown rpc method %s, methods: %s", method, strings.Join(methods, ", "))
+		}
+		if policy.Timeout < 0 || policy.Retries < 0 || policy.Backoff < 0 || policy.MaxBackoff < 0 {
+			return xerror.Errorf(xerror.Normal, "policy of rpc method %s must not be negative", method)
+		}
+		if policy.Retries > 0 && !idempotent {
+			return xerror.Errorf(xerror.Normal, "rpc method %s has side effects, it can not retry", method)
+		}
+	}
+	return nil
+}
+
+// SetCallPolicies overrides the default policies of the methods, the calls after it use the new policies
+func SetCallPolicies(policies map[string]CallPolicy) error {
+	if err := ValidateCallPolicies(policies); err != nil {
+		return err
+	}
+
+	merged := defaultCallPolicies()
+	for method, policy := range policies {
+		merged[method] = policy
+	}
+	callPolicies.Store(&merged)
+	return nil
+}
+
+func getCallPolicy(method string) CallPolicy {
+	policy := (*callPolicies.Load())[method]
+	if policy.Timeout == 0 {
+		policy.Timeout = rpcTimeout
+	}
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = policy.Backoff
+	}
+	return policy
+}
+
+// isRetryable returns true if the call may not reach the server, or the server is too slow to respond
+func isRetryable(err error) bool {
+	return canUseNextAddr(err) || errors.Is(err, kerrors.ErrRPCTimeout)
+}
+
+// withRetry calls the method until it succeeds, the error is not retryable, or the retries are used up
+func withRetry[T any](method string, call func() (T, error)) (T, error) {
+	policy := getCallPolicy(method)
+	backoff := policy.Backoff
+	for i := 0; ; i++ {
+		result, err := call()
+		if err == nil || i >= policy.Retries || !isRetryable(err) {
+			return result, err
+		}
+
+		// equal jitter, avoid all jobs retrying at the same time
+		sleep := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		log.Warnf("call %s failed, retry %d/%d after %s, err: %v", method, i+1, policy.Retries, sleep, err)
+		time.Sleep(sleep)
+		if backoff *= 2; backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// breakerSettings is shared by all breakers, 0 failures means breakers are disabled
+type breakerSettings struct {
+	failures    int
+	openTimeout time.Duration
+}
+
+var currentBreakerSettings atomic.Pointer[breakerSettings]
+
+// SetBreaker changes the settings of all circuit breakers, a breaker opens after the consecutive
+// failures of an address, and lets a call through to probe after the open timeout.
+func SetBreaker(failures int, openTimeout time.Duration) {
+	currentBreakerSettings.Store(&breakerSettings{failures: failures, openTimeout: openTimeout})
+}
+
+// breaker is the circuit breaker of a fe or be address, so a dead server is not called by every job
+type breaker struct {
+	lock     sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var breakers sync.Map // addr -> *breaker
+
+func getBreaker(addr string) *breaker {
+	b, _ := breakers.LoadOrStore(addr, &breaker{})
+	return b.(*breaker)
+}
+
+// allow returns ErrCircuitOpen if the breaker is open, or the probe of the half open breaker is running
+func (b *breaker) allow(addr string, settings *breakerSettings) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if settings.failures <= 0 || b.failures < settings.failures {
+		return nil
+	}
+	if time.Since(b.openedAt) < settings.openTimeout || b.probing {
+		return xerror.XWrapf(ErrCircuitOpen, "addr: %s, failures: %d", addr, b.failures)
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the consecutive failures, only the connection errors and timeouts are failures
+func (b *breaker) record(addr string, settings *breakerSettings, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.probing = false
+	if err == nil || !isRetryable(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if settings.failures > 0 && b.failures >= settings.failures {
+		if b.failures == settings.failures {
+			log.Warnf("circuit breaker of %s is open, consecutive failures: %d, err: %v", addr, b.failures, err)
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+// invoke calls the method of addr with its timeout, guarded by the circuit breaker of addr
+func invoke[T any](method, addr string, call func(timeout callopt.Option) (T, error)) (T, error) {
+	settings := currentBreakerSettings.Load()
+	b := getBreaker(addr)
+	if err := b.allow(addr, settings); err != nil {
+		var result T
+		return result, err
+	}
+
+	result, err := call(callopt.WithRPCTimeout(getCallPolicy(method).Timeout))
+	b.record(addr, settings, err)
+	return result, err
+}
@@ -0,0 +1,132 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/kitex/client/callopt"
+	"github.com/cloudwego/kitex/pkg/kerrors"
+	"github.com/cloudwego/kitex/pkg/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCallPolicies(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, SetCallPolicies(nil)) })
+
+	assert.Equal(t, COMMIT_TXN_TIMEOUT, getCallPolicy("CommitTransaction").Timeout)
+	assert.Equal(t, rpcTimeout, getCallPolicy("BeginTransaction").Timeout)
+	assert.Equal(t, READ_RETRIES, getCallPolicy("GetBinlog").Retries)
+
+	assert.Error(t, SetCallPolicies(map[string]CallPolicy{"GetBinlogs": {Timeout: time.Second}}))
+	assert.Error(t, SetCallPolicies(map[string]CallPolicy{"IngestBinlog": {Retries: 1}}))
+	assert.Error(t, SetCallPolicies(map[string]CallPolicy{"GetBinlog": {Timeout: -time.Second}}))
+
+	require.NoError(t, SetCallPolicies(map[string]CallPolicy{"GetSnapshot": {Timeout: 2 * time.Minute}}))
+	assert.Equal(t, 2*time.Minute, getCallPolicy("GetSnapshot").Timeout)
+	assert.Equal(t, 0, getCallPolicy("GetSnapshot").Retries)
+	// the others keep defaults
+	assert.Equal(t, COMMIT_TXN_TIMEOUT, getCallPolicy("CommitTransaction").Timeout)
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, SetCallPolicies(nil)) })
+	require.NoError(t, SetCallPolicies(map[string]CallPolicy{
+		"GetBinlog": {Retries: 2, Backoff: time.Millisecond},
+	}))
+
+	calls := 0
+	_, err := withRetry("GetBinlog", func() (int, error) {
+		calls++
+		return 0, kerrors.ErrRPCTimeout
+	})
+	assert.True(t, errors.Is(err, kerrors.ErrRPCTimeout))
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	result, err := withRetry("GetBinlog", func() (int, error) {
+		if calls++; calls < 2 {
+			return 0, kerrors.ErrNoConnection
+		}
+		return 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result)
+	assert.Equal(t, 2, calls)
+
+	// not retryable error
+	calls = 0
+	_, err = withRetry("GetBinlog", func() (int, error) {
+		calls++
+		return 0, errors.New("table not found")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	// the old fe does not have the method
+	calls = 0
+	_, err = withRetry("GetTableNames", func() (int, error) {
+		calls++
+		return 0, kerrors.ErrRemoteOrNetwork.WithCause(remote.NewTransErrorWithMsg(remote.UnknownMethod, "unknown method getMeta"))
+	})
+	assert.True(t, IsUnknownMethod(err))
+	assert.Equal(t, 1, calls)
+
+	// method with side effects
+	calls = 0
+	_, err = withRetry("CommitTransaction", func() (int, error) {
+		calls++
+		return 0, kerrors.ErrRPCTimeout
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestBreaker(t *testing.T) {
+	t.Cleanup(func() { SetBreaker(BREAKER_FAILURES, BREAKER_OPEN_TIMEOUT) })
+	SetBreaker(2, 50*time.Millisecond)
+
+	addr := "127.0.0.1:1"
+	calls := 0
+	call := func(err error) error {
+		_, err = invoke("GetBinlog", addr, func(timeout callopt.Option) (int, error) {
+			calls++
+			return 0, err
+		})
+		return err
+	}
+
+	// open after consecutive failures
+	assert.Error(t, call(kerrors.ErrNoConnection))
+	assert.Error(t, call(kerrors.ErrRPCTimeout))
+	err := call(nil)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.True(t, canUseNextAddr(err))
+	assert.Equal(t, 2, calls)
+
+	// half open, the failed probe opens it again
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, errors.Is(call(kerrors.ErrNoConnection), kerrors.ErrNoConnection))
+	assert.True(t, errors.Is(call(nil), ErrCircuitOpen))
+	assert.Equal(t, 3, calls)
+
+	// the succeeded probe closes it
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, call(nil))
+	assert.NoError(t, call(nil))
+	assert.Equal(t, 5, calls)
+
+	// the errors returned by the server are not failures of the address
+	for i := 0; i < 3; i++ {
+		assert.Error(t, call(errors.New("table not found")))
+	}
+	assert.NoError(t, call(nil))
+
+	// disabled
+	SetBreaker(0, 0)
+	for i := 0; i < 3; i++ {
+		assert.Error(t, call(kerrors.ErrNoConnection))
+	}
+	assert.NoError(t, call(nil))
+}
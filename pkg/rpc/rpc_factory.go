@@ -3,12 +3,15 @@ package rpc
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	beservice "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/backendservice/backendservice"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 
 	"github.com/cloudwego/kitex/client"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type IRpcFactory interface {
@@ -16,18 +19,25 @@ type IRpcFactory interface {
 	NewBeRpc(be *base.Backend) (IBeRpc, error)
 }
 
+// cachedBeRpc is the client of the backend on an address, it is replaced if another backend uses the address
+type cachedBeRpc struct {
+	backendId int64
+	rpc       IBeRpc
+	lastUsed  time.Time
+}
+
 type RpcFactory struct {
 	feRpcs     map[*base.Spec]IFeRpc
 	feRpcsLock sync.Mutex
 
-	beRpcs     map[*base.Backend]IBeRpc
+	beRpcs     map[string]*cachedBeRpc // host:be_port -> client
 	beRpcsLock sync.Mutex
 }
 
 func NewRpcFactory() IRpcFactory {
 	return &RpcFactory{
 		feRpcs: make(map[*base.Spec]IFeRpc),
-		beRpcs: make(map[*base.Backend]IBeRpc),
+		beRpcs: make(map[string]*cachedBeRpc),
 	}
 }
 
@@ -55,28 +65,63 @@ func (rf *RpcFactory) NewFeRpc(spec *base.Spec) (IFeRpc, error) {
 	return feRpc, nil
 }
 
+// NewBeRpc returns the cached client of the backend, the backends are got from the latest metas, so a client
+// is created if the backend is new or its address changes, and the stale clients are evicted meanwhile.
 func (rf *RpcFactory) NewBeRpc(be *base.Backend) (IBeRpc, error) {
+	addr := fmt.Sprintf("%s:%d", be.Host, be.BePort)
+	now := time.Now()
+
 	rf.beRpcsLock.Lock()
-	if beRpc, ok := rf.beRpcs[be]; ok {
-		rf.beRpcsLock.Unlock()
-		return beRpc, nil
+	defer rf.beRpcsLock.Unlock()
+
+	if cached, ok := rf.beRpcs[addr]; ok && cached.backendId == be.Id {
+		cached.lastUsed = now
+		return cached.rpc, nil
+	} else if ok {
+		log.Infof("backend on %s changed from %d to %d, replace the cached client", addr, cached.backendId, be.Id)
 	}
-	rf.beRpcsLock.Unlock()
 
 	// create kitex BackendService client
-	addr := fmt.Sprintf("%s:%d", be.Host, be.BePort)
-	client, err := beservice.NewClient("BackendService", client.WithHostPorts(addr), client.WithConnectTimeout(CONNECT_TIMEOUT), client.WithRPCTimeout(RPC_TIMEOUT))
+	client, err := beservice.NewClient("BackendService", client.WithHostPorts(addr), client.WithConnectTimeout(connectTimeout), client.WithRPCTimeout(rpcTimeout))
 	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.Normal, "NewBeClient error: %v", err)
 	}
 
+	// the backend is copied, the metas own theirs and drop them after refresh
+	backend := *be
 	beRpc := &BeRpc{
-		backend: be,
+		backend: &backend,
 		client:  client,
 	}
 
-	rf.beRpcsLock.Lock()
-	defer rf.beRpcsLock.Unlock()
-	rf.beRpcs[be] = beRpc
+	rf.evictBeRpcs(now)
+	rf.beRpcs[addr] = &cachedBeRpc{
+		backendId: be.Id,
+		rpc:       beRpc,
+		lastUsed:  now,
+	}
 	return beRpc, nil
 }
+
+// evictBeRpcs evicts the idle clients, and the least recently used ones to make room for a new client,
+// the evicted kitex clients are closed when they are garbage collected
+func (rf *RpcFactory) evictBeRpcs(now time.Time) {
+	for addr, cached := range rf.beRpcs {
+		if now.Sub(cached.lastUsed) >= BE_RPC_IDLE_TIMEOUT {
+			log.Infof("evict idle be client of %s, backend id: %d", addr, cached.backendId)
+			delete(rf.beRpcs, addr)
+		}
+	}
+
+	for len(rf.beRpcs) >= MAX_CACHED_BE_RPCS {
+		var lruAddr string
+		var lru *cachedBeRpc
+		for addr, cached := range rf.beRpcs {
+			if lru == nil || cached.lastUsed.Before(lru.lastUsed) {
+				lruAddr, lru = addr, cached
+			}
+		}
+		log.Infof("too many be clients, evict the least recently used one of %s, backend id: %d", lruAddr, lru.backendId)
+		delete(rf.beRpcs, lruAddr)
+	}
+}
@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBeRpc(t *testing.T) {
+	rf := NewRpcFactory().(*RpcFactory)
+
+	be := &base.Backend{Id: 1, Host: "127.0.0.1", BePort: 9060}
+	client, err := rf.NewBeRpc(be)
+	require.NoError(t, err)
+
+	// the backends of each refreshed meta are new objects, the client is cached by address
+	cached, err := rf.NewBeRpc(&base.Backend{Id: 1, Host: "127.0.0.1", BePort: 9060})
+	require.NoError(t, err)
+	assert.Same(t, client, cached)
+
+	// another backend on the address
+	replaced, err := rf.NewBeRpc(&base.Backend{Id: 2, Host: "127.0.0.1", BePort: 9060})
+	require.NoError(t, err)
+	assert.NotSame(t, client, replaced)
+	assert.Len(t, rf.beRpcs, 1)
+
+	// ip change, the old client is evicted after idle timeout
+	_, err = rf.NewBeRpc(&base.Backend{Id: 2, Host: "127.0.0.2", BePort: 9060})
+	require.NoError(t, err)
+	assert.Len(t, rf.beRpcs, 2)
+	rf.beRpcs["127.0.0.1:9060"].lastUsed = time.Now().Add(-BE_RPC_IDLE_TIMEOUT)
+	_, err = rf.NewBeRpc(&base.Backend{Id: 3, Host: "127.0.0.3", BePort: 9060})
+	require.NoError(t, err)
+	assert.NotContains(t, rf.beRpcs, "127.0.0.1:9060")
+	assert.Len(t, rf.beRpcs, 2)
+}
+
+func TestNewBeRpcBounded(t *testing.T) {
+	rf := NewRpcFactory().(*RpcFactory)
+
+	for i := 0; i < MAX_CACHED_BE_RPCS+10; i++ {
+		_, err := rf.NewBeRpc(&base.Backend{Id: int64(i), Host: "127.0.0.1", BePort: uint16(10000 + i)})
+		require.NoError(t, err)
+		if i == 0 {
+			// keep the first one recently used
+			rf.beRpcs["127.0.0.1:10000"].lastUsed = time.Now().Add(time.Hour)
+		}
+	}
+	assert.Len(t, rf.beRpcs, MAX_CACHED_BE_RPCS)
+	assert.Contains(t, rf.beRpcs, "127.0.0.1:10000")
+	assert.NotContains(t, rf.beRpcs, fmt.Sprintf("127.0.0.1:%d", 10001))
+}
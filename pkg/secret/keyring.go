@@ -0,0 +1,144 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const (
+	// encrypted value format: enc:v1:<key id>:<base64(nonce + ciphertext)>
+	encryptedPrefix = "enc:v1:"
+	keySize         = 32 // AES-256
+)
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring encrypts with the primary key and decrypts with any known key, so keys can be rotated
+// by putting the new key first and keeping the old ones until all values are re-encrypted.
+type Keyring struct {
+	primary *key
+	keys    map[string]*key
+}
+
+// ParseKeyring parses keys separated by newline or comma, each key is `<key id>:<base64 of 32 bytes>`,
+// the first one is the primary key.
+func ParseKeyring(data string) (*Keyring, error) {
+	keyring := &Keyring{
+		keys: make(map[string]*key),
+	}
+
+	fields := strings.FieldsFunc(data, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ','
+	})
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || strings.HasPrefix(field, "#") {
+			continue
+		}
+
+		id, encodedKey, found := strings.Cut(field, ":")
+		if !found || id == "" {
+			return nil, xerror.Errorf(xerror.Normal, "invalid secret key, want <key id>:<base64 key>")
+		}
+		if _, ok := keyring.keys[id]; ok {
+			return nil, xerror.Errorf(xerror.Normal, "duplicated secret key id %s", id)
+		}
+
+		rawKey, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "decode secret key %s failed", id)
+		}
+		if len(rawKey) != keySize {
+			return nil, xerror.Errorf(xerror.Normal, "secret key %s must be %d bytes, but got %d", id, keySize, len(rawKey))
+		}
+
+		block, err := aes.NewCipher(rawKey)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "new cipher of secret key %s failed", id)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "new gcm of secret key %s failed", id)
+		}
+
+		k := &key{id: id, aead: aead}
+		keyring.keys[id] = k
+		if keyring.primary == nil {
+			keyring.primary = k
+		}
+	}
+
+	if keyring.primary == nil {
+		return nil, xerror.Errorf(xerror.Normal, "no secret key found")
+	}
+	return keyring, nil
+}
+
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read secret key file %s failed", path)
+	}
+	return ParseKeyring(string(data))
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// PrimaryKeyId returns the id of the key used to encrypt
+func (k *Keyring) PrimaryKeyId() string {
+	return k.primary.id
+}
+
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, k.primary.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", xerror.Wrap(err, xerror.Normal, "generate nonce failed")
+	}
+
+	// key id is authenticated as additional data, so a value can't be moved to another key
+	sealed := k.primary.aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.primary.id))
+	return encryptedPrefix + k.primary.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext and the id of the key encrypted it
+func (k *Keyring) Decrypt(value string) (string, string, error) {
+	if !IsEncrypted(value) {
+		return "", "", xerror.Errorf(xerror.Normal, "value is not encrypted")
+	}
+
+	id, encoded, found := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !found {
+		return "", "", xerror.Errorf(xerror.Normal, "invalid encrypted value")
+	}
+	key, ok := k.keys[id]
+	if !ok {
+		return "", "", xerror.Errorf(xerror.Normal, "secret key %s not found, it may be removed by key rotation too early", id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", xerror.Wrap(err, xerror.Normal, "decode encrypted value failed")
+	}
+	nonceSize := key.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", "", xerror.Errorf(xerror.Normal, "encrypted value too short")
+	}
+
+	plaintext, err := key.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(id))
+	if err != nil {
+		return "", "", xerror.Wrapf(err, xerror.Normal, "decrypt with secret key %s failed", id)
+	}
+	return string(plaintext), id, nil
+}
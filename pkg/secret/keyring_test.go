@@ -0,0 +1,86 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T, id string) string {
+	rawKey := make([]byte, keySize)
+	_, err := rand.Read(rawKey)
+	require.NoError(t, err)
+	return id + ":" + base64.StdEncoding.EncodeToString(rawKey)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := newKey(t, "k1")
+	primaryKey := newKey(t, "k2")
+
+	oldKeyring, err := ParseKeyring(oldKey)
+	require.NoError(t, err)
+	sealed, err := oldKeyring.Encrypt("p@ss")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "p@ss")
+
+	// new key is primary, old key is kept for decrypt
+	rotated, err := ParseKeyring(primaryKey + "\n" + oldKey)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", rotated.PrimaryKeyId())
+
+	password, keyId, err := rotated.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "p@ss", password)
+	assert.Equal(t, "k1", keyId)
+
+	resealed, err := rotated.Encrypt(password)
+	require.NoError(t, err)
+	_, keyId, err = rotated.Decrypt(resealed)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", keyId)
+
+	// old key removed too early
+	_, _, err = oldKeyring.Decrypt(resealed)
+	assert.Error(t, err)
+}
+
+func TestParseKeyringInvalid(t *testing.T) {
+	_, err := ParseKeyring("")
+	assert.Error(t, err)
+
+	_, err = ParseKeyring("k1:" + base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+
+	key := newKey(t, "k1")
+	_, err = ParseKeyring(key + "," + key)
+	assert.Error(t, err)
+}
+
+func TestOpenPassword(t *testing.T) {
+	keyring = nil
+	defer func() { keyring = nil }()
+
+	// no key configured, plain text is kept
+	sealed, err := SealPassword("p@ss")
+	require.NoError(t, err)
+	assert.Equal(t, "p@ss", sealed)
+
+	keyring, err = ParseKeyring(newKey(t, "k1"))
+	require.NoError(t, err)
+
+	password, stale, err := OpenPassword("p@ss")
+	require.NoError(t, err)
+	assert.Equal(t, "p@ss", password)
+	assert.True(t, stale)
+
+	sealed, err = SealPassword("p@ss")
+	require.NoError(t, err)
+	password, stale, err = OpenPassword(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "p@ss", password)
+	assert.False(t, stale)
+}
@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SecretKeyEnv holds the keyring if secret_key_file is not set
+const SecretKeyEnv = "CCR_SYNCER_SECRET_KEY"
+
+var (
+	secretKeyFile string
+	secretsFile   string
+
+	lock    sync.RWMutex
+	keyring *Keyring
+	secrets map[string]string
+)
+
+func init() {
+	flag.StringVar(&secretKeyFile, "secret_key_file", "", "file of keys to encrypt credentials in meta db, one <key id>:<base64 key> per line, the first is primary")
+	flag.StringVar(&secretsFile, "secrets_file", "", "json file of named credentials, jobs can refer them by password_secret")
+}
+
+// Init loads the keyring and named secrets, it must be called after flag parsed
+func Init() error {
+	var newKeyring *Keyring
+	var err error
+	if secretKeyFile != "" {
+		newKeyring, err = LoadKeyringFile(secretKeyFile)
+	} else if keys := os.Getenv(SecretKeyEnv); keys != "" {
+		newKeyring, err = ParseKeyring(keys)
+	}
+	if err != nil {
+		return err
+	}
+
+	var newSecrets map[string]string
+	if secretsFile != "" {
+		data, err := os.ReadFile(secretsFile)
+		if err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "read secrets file %s failed", secretsFile)
+		}
+		if err := json.Unmarshal(data, &newSecrets); err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "parse secrets file %s failed", secretsFile)
+		}
+	}
+
+	if newKeyring == nil {
+		log.Warnf("no secret key configured by -secret_key_file or %s, credentials are stored in plain text", SecretKeyEnv)
+	} else {
+		log.Infof("credentials are encrypted by secret key %s", newKeyring.PrimaryKeyId())
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	keyring = newKeyring
+	secrets = newSecrets
+	return nil
+}
+
+// SealPassword encrypts the password before persisted, it keeps plain text if no key configured
+func SealPassword(password string) (string, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if keyring == nil || password == "" {
+		return password, nil
+	}
+	return keyring.Encrypt(password)
+}
+
+// OpenPassword decrypts the persisted password.
+// stale is true if the password should be sealed again: plain text with a key configured,
+// or encrypted by a rotated key.
+func OpenPassword(value string) (string, bool, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if !IsEncrypted(value) {
+		return value, keyring != nil && value != "", nil
+	}
+	if keyring == nil {
+		return "", false, xerror.Errorf(xerror.Normal, "password is encrypted, but no secret key configured by -secret_key_file or %s", SecretKeyEnv)
+	}
+
+	password, keyId, err := keyring.Decrypt(value)
+	if err != nil {
+		return "", false, err
+	}
+	return password, keyId != keyring.PrimaryKeyId(), nil
+}
+
+// LookupSecret returns the named credential in secrets file
+func LookupSecret(name string) (string, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if secret, ok := secrets[name]; ok {
+		return secret, nil
+	}
+	return "", xerror.Errorf(xerror.Normal, "secret %s not found in secrets file", name)
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Role int
+
+const (
+	RoleNone     Role = 0
+	RoleReadOnly Role = 1
+	// operator can also do everything read only can do
+	RoleOperator Role = 2
+)
+
+// Role Stringer
+func (r Role) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read_only"
+	case RoleOperator:
+		return "operator"
+	default:
+		return "none"
+	}
+}
+
+func ParseRole(role string) (Role, error) {
+	switch role {
+	case "read_only":
+		return RoleReadOnly, nil
+	case "operator":
+		return RoleOperator, nil
+	default:
+		return RoleNone, xerror.Errorf(xerror.Normal, "unknown role %s, want read_only or operator", role)
+	}
+}
+
+// Principal is the authenticated caller
+type Principal struct {
+	Name string
+	Role Role
+}
+
+func (p *Principal) String() string {
+	return fmt.Sprintf("%s(%s)", p.Name, p.Role)
+}
+
+// Authenticator checks one kind of credential.
+// It returns nil principal and nil error if the request doesn't carry its kind of credential.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+var (
+	errInvalidToken      = xerror.NewWithoutStack(xerror.Normal, "invalid bearer token")
+	errUnknownClientCert = xerror.NewWithoutStack(xerror.Normal, "unknown client certificate")
+)
+
+// TokenAuthenticator authenticates static bearer tokens
+type TokenAuthenticator struct {
+	tokens []*tokenEntry
+}
+
+type tokenEntry struct {
+	token     []byte
+	principal *Principal
+}
+
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return nil, nil
+	}
+
+	token, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found {
+		return nil, errInvalidToken
+	}
+
+	// compare all tokens in constant time, not leak which one is close
+	var principal *Principal
+	for _, entry := range a.tokens {
+		if subtle.ConstantTimeCompare(entry.token, []byte(token)) == 1 {
+			principal = entry.principal
+		}
+	}
+	if principal == nil {
+		return nil, errInvalidToken
+	}
+	return principal, nil
+}
+
+// CertAuthenticator authenticates verified mTLS client certificates by common name
+type CertAuthenticator struct {
+	principals map[string]*Principal
+}
+
+func (a *CertAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if principal, ok := a.principals[commonName]; ok {
+		return principal, nil
+	}
+	return nil, xerror.XWrapf(errUnknownClientCert, "common name: %s", commonName)
+}
+
+// AuthConfig is the json content of auth file, for example:
+//
+//	{
+//	  "tokens": [{"name": "ops", "token": "xxx", "role": "operator"}],
+//	  "client_certs": [{"common_name": "dashboard", "role": "read_only"}]
+//	}
+type AuthConfig struct {
+	Tokens []struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+		Role  string `json:"role"`
+	} `json:"tokens"`
+	ClientCerts []struct {
+		CommonName string `json:"common_name"`
+		Role       string `json:"role"`
+	} `json:"client_certs"`
+}
+
+// LoadAuthenticators loads token and client cert authenticators from auth file
+func LoadAuthenticators(authFile string) ([]Authenticator, error) {
+	data, err := os.ReadFile(authFile)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read auth file %s failed", authFile)
+	}
+
+	var config AuthConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "parse auth file %s failed", authFile)
+	}
+
+	tokenAuthenticator := &TokenAuthenticator{}
+	for _, token := range config.Tokens {
+		if token.Name == "" || token.Token == "" {
+			return nil, xerror.Errorf(xerror.Normal, "token name and token must not be empty")
+		}
+		role, err := ParseRole(token.Role)
+		if err != nil {
+			return nil, err
+		}
+		tokenAuthenticator.tokens = append(tokenAuthenticator.tokens, &tokenEntry{
+			token:     []byte(token.Token),
+			principal: &Principal{Name: token.Name, Role: role},
+		})
+	}
+
+	certAuthenticator := &CertAuthenticator{principals: make(map[string]*Principal)}
+	for _, cert := range config.ClientCerts {
+		if cert.CommonName == "" {
+			return nil, xerror.Errorf(xerror.Normal, "client cert common name must not be empty")
+		}
+		role, err := ParseRole(cert.Role)
+		if err != nil {
+			return nil, err
+		}
+		certAuthenticator.principals[cert.CommonName] = &Principal{Name: cert.CommonName, Role: role}
+	}
+
+	authenticators := make([]Authenticator, 0)
+	if len(tokenAuthenticator.tokens) > 0 {
+		authenticators = append(authenticators, tokenAuthenticator)
+	}
+	if len(certAuthenticator.principals) > 0 {
+		authenticators = append(authenticators, certAuthenticator)
+	}
+	if len(authenticators) == 0 {
+		return nil, xerror.Errorf(xerror.Normal, "no token or client cert in auth file %s", authFile)
+	}
+	return authenticators, nil
+}
+
+type principalKey struct{}
+
+// requestPrincipal returns the authenticated caller, nil if auth is disabled
+func requestPrincipal(r *http.Request) *Principal {
+	if principal, ok := r.Context().Value(principalKey{}).(*Principal); ok {
+		return principal
+	}
+	return nil
+}
+
+func (s *HttpService) authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range s.authenticators {
+		if principal, err := authenticator.Authenticate(r); err != nil {
+			return nil, err
+		} else if principal != nil {
+			return principal, nil
+		}
+	}
+	return nil, nil
+}
+
+// withAuth checks the caller has the role before calling handler, it is a no-op if auth is disabled
+func (s *HttpService) withAuth(role Role, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.authenticators) == 0 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := s.authenticate(r)
+		if err != nil {
+			log.Warnf("auth failed, path: %s, remote: %s, err: %+v", r.URL.Path, r.RemoteAddr, err)
+			xmetrics.AuthFailed("invalid_credential")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if principal == nil {
+			log.Warnf("auth failed, path: %s, remote: %s, no credential", r.URL.Path, r.RemoteAddr)
+			xmetrics.AuthFailed("no_credential")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if principal.Role < role {
+			log.Warnf("auth failed, path: %s, remote: %s, principal: %s, need role: %s", r.URL.Path, r.RemoteAddr, principal, role)
+			xmetrics.AuthFailed("forbidden")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAuth(t *testing.T) {
+	authFile := filepath.Join(t.TempDir(), "auth.json")
+	require.NoError(t, os.WriteFile(authFile, []byte(`{
+		"tokens": [
+			{"name": "ops", "token": "ops-token", "role": "operator"},
+			{"name": "viewer", "token": "viewer-token", "role": "read_only"}
+		]
+	}`), 0600))
+
+	authenticators, err := LoadAuthenticators(authFile)
+	require.NoError(t, err)
+
+	s := &HttpService{}
+	s.SetAuthenticators(authenticators)
+
+	var caller string
+	handler := s.withAuth(RoleOperator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = requestCaller(r)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"no credential", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer nope", http.StatusUnauthorized},
+		{"not bearer", "Basic b3BzOm9wcw==", http.StatusUnauthorized},
+		{"read only", "Bearer viewer-token", http.StatusForbidden},
+		{"operator", "Bearer ops-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/pause", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+	assert.Equal(t, "ops@192.0.2.1:1234", caller)
+}
+
+func TestLoadAuthenticatorsInvalidRole(t *testing.T) {
+	authFile := filepath.Join(t.TempDir(), "auth.json")
+	require.NoError(t, os.WriteFile(authFile, []byte(`{"tokens": [{"name": "ops", "token": "t", "role": "admin"}]}`), 0600))
+
+	_, err := LoadAuthenticators(authFile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetFaultInjector enables the /debug/fault endpoint, it must be called before Start
+func (s *HttpService) SetFaultInjector(injector *fault.Injector) {
+	s.faultInjector = injector
+}
+
+type FaultRequest struct {
+	Rules []fault.Rule `json:"rules"`
+}
+
+// faultHandler lists the fault rules by GET, replaces them by POST and clears them by DELETE
+func (s *HttpService) faultHandler(w http.ResponseWriter, r *http.Request) {
+	type result struct {
+		*defaultResult
+		Rules []fault.RuleState `json:"rules,omitempty"`
+	}
+	var faultResult *result
+	defer func() { writeJson(w, faultResult) }()
+
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var request FaultRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err == nil {
+			err = s.faultInjector.SetRules(request.Rules)
+		}
+	case http.MethodDelete:
+		err = s.faultInjector.SetRules(nil)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		faultResult = &result{defaultResult: newErrorResult("method not allowed, want GET, POST or DELETE")}
+		return
+	}
+	if err != nil {
+		log.Warnf("set fault rules failed: %+v", err)
+		faultResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	faultResult = &result{
+		defaultResult: newSuccessResult(),
+		Rules:         s.faultInjector.Rules(),
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultHandler(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	s := NewHttpServer("127.0.0.1", 9190, db, ccr.NewJobManager(db, nil, "127.0.0.1:9190"))
+	injector := fault.NewInjector()
+	s.SetFaultInjector(injector)
+
+	type result struct {
+		Success  bool              `json:"success"`
+		ErrorMsg string            `json:"error_msg"`
+		Rules    []fault.RuleState `json:"rules"`
+	}
+	call := func(method, body string) (int, result) {
+		w := httptest.NewRecorder()
+		s.faultHandler(w, httptest.NewRequest(method, "/debug/fault", strings.NewReader(body)))
+		var r result
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return w.Code, r
+	}
+
+	_, r := call(http.MethodPost, `{"rules": [{"method": "CommitTransaction", "action": "drop", "after_call": true, "times": 1}]}`)
+	require.True(t, r.Success, r.ErrorMsg)
+	require.Len(t, r.Rules, 1)
+	assert.True(t, r.Rules[0].AfterCall)
+
+	_, r = call(http.MethodGet, "")
+	assert.True(t, r.Success)
+	assert.Len(t, r.Rules, 1)
+
+	// invalid rules keep the current ones
+	_, r = call(http.MethodPost, `{"rules": [{"method": "Commit", "action": "drop"}]}`)
+	assert.False(t, r.Success)
+	assert.Len(t, injector.Rules(), 1)
+
+	_, r = call(http.MethodDelete, "")
+	assert.True(t, r.Success)
+	assert.Empty(t, injector.Rules())
+
+	code, r := call(http.MethodPut, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	assert.False(t, r.Success)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the process is not alive if checker loop doesn't tick in these check durations
+	livenessStaleChecks = 6
+	// the syncer is not ready if no check succeeded in these check durations
+	readinessStaleChecks = 3
+)
+
+// SetChecker enables the checker state in health probes, it must be called before Start
+func (s *HttpService) SetChecker(checker *ccr.Checker) {
+	s.checker = checker
+}
+
+// SetJobHealthThresholds sets the thresholds of job_health, it can be reloaded
+func (s *HttpService) SetJobHealthThresholds(thresholds ccr.JobHealthThresholds) {
+	s.jobHealthThresholds.Store(&thresholds)
+}
+
+func writeProbe(w http.ResponseWriter, ok bool, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Warnf("write probe result failed: %+v", err)
+	}
+}
+
+// healthzHandler is the liveness probe, the process is alive if checker loop is ticking
+func (s *HttpService) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	type result struct {
+		Alive    bool   `json:"alive"`
+		LastTick string `json:"last_tick,omitempty"`
+	}
+
+	if s.checker == nil {
+		writeProbe(w, true, &result{Alive: true})
+		return
+	}
+
+	health := s.checker.Health()
+	alive := time.Since(health.LastTick) <= ccr.CheckDuration()*livenessStaleChecks
+	if !alive {
+		log.Warnf("liveness probe failed, checker last tick: %s", health.LastTick)
+	}
+	writeProbe(w, alive, &result{Alive: alive, LastTick: health.LastTick.Format(time.RFC3339)})
+}
+
+// readyzHandler is the readiness probe, the syncer is ready if meta db is reachable,
+// checker succeeded recently and the jobs have been recovered
+func (s *HttpService) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	type result struct {
+		Ready  bool              `json:"ready"`
+		Checks map[string]string `json:"checks"`
+	}
+
+	ready := true
+	checks := make(map[string]string)
+	fail := func(name string, reason string) {
+		ready = false
+		checks[name] = reason
+	}
+
+	if err := s.db.Ping(); err != nil {
+		fail("meta_db", err.Error())
+	} else {
+		checks["meta_db"] = "ok"
+	}
+
+	if s.checker != nil {
+		health := s.checker.Health()
+		if health.LastSuccess.IsZero() {
+			fail("checker", fmt.Sprintf("never succeeded, last error: %s", health.LastError))
+		} else if since := time.Since(health.LastSuccess); since > ccr.CheckDuration()*readinessStaleChecks {
+			fail("checker", fmt.Sprintf("last succeeded %s ago, last error: %s", since.Truncate(time.Second), health.LastError))
+		} else {
+			checks["checker"] = "ok"
+		}
+	}
+
+	if !s.jobManager.Recovered() {
+		fail("jobs_recovered", "jobs are not recovered yet")
+	} else {
+		checks["jobs_recovered"] = "ok"
+	}
+
+	if !ready {
+		log.Warnf("readiness probe failed, checks: %v", checks)
+	}
+	writeProbe(w, ready, &result{Ready: ready, Checks: checks})
+}
+
+// jobHealthHandler summarizes the health of jobs in this syncer
+func (s *HttpService) jobHealthHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get job health")
+
+	type result struct {
+		*defaultResult
+		UnhealthyNum int              `json:"unhealthy_num"`
+		Jobs         []*ccr.JobHealth `json:"jobs"`
+	}
+
+	thresholds := s.jobHealthThresholds.Load()
+	if thresholds == nil {
+		thresholds = &ccr.JobHealthThresholds{}
+	}
+
+	jobs := s.jobManager.JobHealths(thresholds)
+	unhealthyNum := 0
+	for _, job := range jobs {
+		if !job.Healthy {
+			unhealthyNum++
+		}
+	}
+	writeJson(w, &result{
+		defaultResult: newSuccessResult(),
+		UnhealthyNum:  unhealthyNum,
+		Jobs:          jobs,
+	})
+}
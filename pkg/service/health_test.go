@@ -0,0 +1,45 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyz(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	jobManager := ccr.NewJobManager(db, nil, "127.0.0.1:9190")
+	s := NewHttpServer("127.0.0.1", 9190, db, jobManager)
+
+	probe := func() (int, map[string]string) {
+		w := httptest.NewRecorder()
+		s.readyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var result struct {
+			Checks map[string]string `json:"checks"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return w.Code, result.Checks
+	}
+
+	code, checks := probe()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "ok", checks["meta_db"])
+	assert.NotEqual(t, "ok", checks["jobs_recovered"])
+
+	require.NoError(t, jobManager.Recover(nil))
+	code, _ = probe()
+	assert.Equal(t, http.StatusOK, code)
+
+	w := httptest.NewRecorder()
+	s.healthzHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/selectdb/ccr_syncer/pkg/ccr"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/fault"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	"github.com/selectdb/ccr_syncer/pkg/version"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
@@ -58,6 +62,21 @@ type HttpService struct {
 
 	db         storage.DB
 	jobManager *ccr.JobManager
+
+	// empty means auth is disabled
+	authenticators []Authenticator
+	// nil means serving plain http
+	tls *tlsReloader
+
+	// job defaults of create_ccr, it can be reloaded
+	defaultSkipError atomic.Bool
+
+	// nil means the probes skip checker state
+	checker             *ccr.Checker
+	jobHealthThresholds atomic.Pointer[ccr.JobHealthThresholds]
+
+	// nil means fault injection is disabled
+	faultInjector *fault.Injector
 }
 
 func NewHttpServer(host string, port int, db storage.DB, jobManager *ccr.JobManager) *HttpService {
@@ -71,17 +90,60 @@ func NewHttpServer(host string, port int, db storage.DB, jobManager *ccr.JobMana
 	}
 }
 
+// SetAuthenticators enables auth of all handlers, it must be called before Start
+func (s *HttpService) SetAuthenticators(authenticators []Authenticator) {
+	s.authenticators = authenticators
+}
+
+// EnableTLS serves https with the cert, it must be called before Start
+func (s *HttpService) EnableTLS(config *TLSConfig) error {
+	reloader, err := newTLSReloader(config)
+	if err != nil {
+		return err
+	}
+	s.tls = reloader
+	return nil
+}
+
+// SetJobDefaults sets the defaults used by create_ccr if the request doesn't set them
+func (s *HttpService) SetJobDefaults(skipError bool) {
+	s.defaultSkipError.Store(skipError)
+}
+
+// ReloadTLS reloads the cert and client CA, it is a no-op if tls is disabled
+func (s *HttpService) ReloadTLS() error {
+	if s.tls == nil {
+		return nil
+	}
+	return s.tls.Reload()
+}
+
+func (s *HttpService) scheme() string {
+	if s.tls != nil {
+		return "https"
+	}
+	return "http"
+}
+
 type CreateCcrRequest struct {
 	// must need all fields required
-	Name      string    `json:"name,required"`
-	Src       base.Spec `json:"src,required"`
-	Dest      base.Spec `json:"dest,required"`
-	SkipError bool      `json:"skip_error"`
+	Name string    `json:"name,required"`
+	Src  base.Spec `json:"src,required"`
+	Dest base.Spec `json:"dest,required"`
+	// nil means using job_defaults.skip_error of config
+	SkipError *bool `json:"skip_error"`
+	// the max duration of waiting backup/restore in full sync, e.g. "2h",
+	// empty means the default of syncer: check interval * max_check_retry_times
+	BackupTimeout  string `json:"backup_timeout"`
+	RestoreTimeout string `json:"restore_timeout"`
+	// overrides the table properties of src on dest, nil keeps them
+	RestoreOptions *base.RestoreOptions `json:"restore_options"`
 }
 
 // Stringer
 func (r *CreateCcrRequest) String() string {
-	return fmt.Sprintf("name: %s, src: %v, dest: %v", r.Name, r.Src, r.Dest)
+	// use spec Stringer, it never prints the password
+	return fmt.Sprintf("name: %s, src: %s, dest: %s", r.Name, &r.Src, &r.Dest)
 }
 
 // version Handler
@@ -101,16 +163,64 @@ func (s *HttpService) versionHandler(w http.ResponseWriter, r *http.Request) {
 	writeJson(w, result)
 }
 
+// requestCaller returns who sends the request, it is recorded in audit logs
+func requestCaller(r *http.Request) string {
+	caller := r.RemoteAddr
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		caller = fmt.Sprintf("%s (forwarded for %s)", caller, forwardedFor)
+	}
+	if principal := requestPrincipal(r); principal != nil {
+		caller = fmt.Sprintf("%s@%s", principal.Name, caller)
+	}
+	return caller
+}
+
+// audit records an operator action on the job, the action result is kept in detail.
+// audit failure only be logged, it never fails the action.
+func (s *HttpService) audit(r *http.Request, jobName string, action string, detail string, actionErr error) {
+	if actionErr != nil {
+		detail = fmt.Sprintf("%s failed: %s", detail, actionErr.Error())
+	} else {
+		detail = fmt.Sprintf("%s success", detail)
+	}
+
+	auditLog := &storage.AuditLog{
+		JobName:   jobName,
+		Action:    action,
+		Caller:    requestCaller(r),
+		Detail:    strings.TrimSpace(detail),
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if err := s.db.AddAuditLog(auditLog); err != nil {
+		log.Warnf("add audit log failed, job: %s, action: %s, err: %+v", jobName, action, err)
+	}
+}
+
 // createCcr creates a new CCR job and adds it to the job manager.
 // It takes a CreateCcrRequest as input and returns an error if there was a problem creating the job or adding it to the job manager.
 func createCcr(request *CreateCcrRequest, db storage.DB, jobManager *ccr.JobManager) error {
 	log.Infof("create ccr %s", request)
 
-	ctx := ccr.NewJobContext(request.Src, request.Dest, request.SkipError, db, jobManager.GetFactory())
+	backupTimeout, err := parseWaitTimeout("backup_timeout", request.BackupTimeout)
+	if err != nil {
+		return err
+	}
+	restoreTimeout, err := parseWaitTimeout("restore_timeout", request.RestoreTimeout)
+	if err != nil {
+		return err
+	}
+	if err := request.RestoreOptions.Valid(); err != nil {
+		return err
+	}
+
+	ctx := ccr.NewJobContext(request.Src, request.Dest, *request.SkipError, db, jobManager.GetFactory())
 	job, err := ccr.NewJobFromService(request.Name, ctx)
 	if err != nil {
 		return err
 	}
+	job.BackupTimeout = backupTimeout
+	job.RestoreTimeout = restoreTimeout
+	job.RestoreOptions = request.RestoreOptions
 
 	// add to job manager
 	err = jobManager.AddJob(job)
@@ -121,6 +231,21 @@ func createCcr(request *CreateCcrRequest, db storage.DB, jobManager *ccr.JobMana
 	return nil
 }
 
+// parseWaitTimeout parses the timeout of waiting backup/restore, empty means 0, using the default of syncer
+func parseWaitTimeout(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, xerror.Wrapf(err, xerror.Normal, "invalid %s %s", name, value)
+	}
+	if timeout < 0 {
+		return 0, xerror.Errorf(xerror.Normal, "invalid %s %s, it must not be negative", name, value)
+	}
+	return timeout, nil
+}
+
 // return exit(bool)
 func (s *HttpService) redirect(jobName string, w http.ResponseWriter, r *http.Request) bool {
 	if jobExist, err := s.db.IsJobExist(jobName); err != nil {
@@ -148,7 +273,9 @@ func (s *HttpService) redirect(jobName string, w http.ResponseWriter, r *http.Re
 	}
 
 	log.Infof("%s is located in syncer %s, please redirect to %s", jobName, belongHost, belongHost)
-	http.Redirect(w, r, fmt.Sprintf("http://%s/job_status", belongHost), http.StatusSeeOther)
+	// all syncers in a cluster share the same tls setting,
+	// 307 keeps the method and body, so the client can resend the request to the same path
+	http.Redirect(w, r, fmt.Sprintf("%s://%s%s", s.scheme(), belongHost, r.URL.Path), http.StatusTemporaryRedirect)
 	return true
 }
 
@@ -168,8 +295,15 @@ func (s *HttpService) createHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if request.SkipError == nil {
+		skipError := s.defaultSkipError.Load()
+		request.SkipError = &skipError
+	}
+
 	// Call the createCcr function to create the CCR
-	if err = createCcr(&request, s.db, s.jobManager); err != nil {
+	err = createCcr(&request, s.db, s.jobManager)
+	s.audit(r, request.Name, "create", fmt.Sprintf("src: %s.%s, dest: %s.%s,", request.Src.Database, request.Src.Table, request.Dest.Database, request.Dest.Table), err)
+	if err != nil {
 		log.Warnf("create ccr failed: %+v", err)
 		createResult = newErrorResult(err.Error())
 	} else {
@@ -260,7 +394,9 @@ func (s *HttpService) pauseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = s.jobManager.Pause(request.Name); err != nil {
+	err = s.jobManager.Pause(request.Name)
+	s.audit(r, request.Name, "pause", "", err)
+	if err != nil {
 		log.Warnf("pause job failed: %+v", err)
 
 		pauseResult = newErrorResult(err.Error())
@@ -298,7 +434,9 @@ func (s *HttpService) resumeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = s.jobManager.Resume(request.Name); err != nil {
+	err = s.jobManager.Resume(request.Name)
+	s.audit(r, request.Name, "resume", "", err)
+	if err != nil {
 		log.Warnf("resume job failed: %+v", err)
 
 		resumeResult = newErrorResult(err.Error())
@@ -335,7 +473,9 @@ func (s *HttpService) deleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = s.jobManager.RemoveJob(request.Name); err != nil {
+	err = s.jobManager.RemoveJob(request.Name)
+	s.audit(r, request.Name, "delete", "", err)
+	if err != nil {
 		log.Warnf("delete job failed: %+v", err)
 
 		deleteResult = newErrorResult(err.Error())
@@ -421,7 +561,9 @@ func (s *HttpService) desyncHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.jobManager.Desync(request.Name); err != nil {
+	err = s.jobManager.Desync(request.Name)
+	s.audit(r, request.Name, "desync", "", err)
+	if err != nil {
 		log.Warnf("desync job failed: %+v", err)
 
 		desyncResult = newErrorResult(err.Error())
@@ -431,8 +573,12 @@ func (s *HttpService) desyncHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdateJobRequest struct {
-	Name      string `json:"name,required"`
-	SkipError bool   `json:"skip_error"`
+	Name string `json:"name,required"`
+	// nil means not changed
+	SkipError *bool `json:"skip_error,omitempty"`
+	// the timeouts of waiting backup/restore in full sync, "0s" means the default of syncer, empty means not changed
+	BackupTimeout  string `json:"backup_timeout,omitempty"`
+	RestoreTimeout string `json:"restore_timeout,omitempty"`
 }
 
 func (s *HttpService) updateJobHandler(w http.ResponseWriter, r *http.Request) {
@@ -462,7 +608,16 @@ func (s *HttpService) updateJobHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.jobManager.UpdateJobSkipError(request.Name, request.SkipError); err != nil {
+	if request.SkipError == nil && request.BackupTimeout == "" && request.RestoreTimeout == "" {
+		log.Warnf("update job failed: nothing to update")
+
+		updateJobResult = newErrorResult("nothing to update")
+		return
+	}
+
+	err = s.updateJob(&request)
+	s.audit(r, request.Name, "update", request.String(), err)
+	if err != nil {
 		log.Warnf("desync job failed: %+v", err)
 
 		updateJobResult = newErrorResult(err.Error())
@@ -471,6 +626,50 @@ func (s *HttpService) updateJobHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Stringer, only the updated settings are printed
+func (r *UpdateJobRequest) String() string {
+	var settings []string
+	if r.SkipError != nil {
+		settings = append(settings, fmt.Sprintf("skip_error: %t,", *r.SkipError))
+	}
+	if r.BackupTimeout != "" {
+		settings = append(settings, fmt.Sprintf("backup_timeout: %s,", r.BackupTimeout))
+	}
+	if r.RestoreTimeout != "" {
+		settings = append(settings, fmt.Sprintf("restore_timeout: %s,", r.RestoreTimeout))
+	}
+	return strings.Join(settings, " ")
+}
+
+func (s *HttpService) updateJob(request *UpdateJobRequest) error {
+	if request.BackupTimeout != "" || request.RestoreTimeout != "" {
+		// nil means not changed
+		var backupTimeout, restoreTimeout *time.Duration
+		if request.BackupTimeout != "" {
+			timeout, err := parseWaitTimeout("backup_timeout", request.BackupTimeout)
+			if err != nil {
+				return err
+			}
+			backupTimeout = &timeout
+		}
+		if request.RestoreTimeout != "" {
+			timeout, err := parseWaitTimeout("restore_timeout", request.RestoreTimeout)
+			if err != nil {
+				return err
+			}
+			restoreTimeout = &timeout
+		}
+		if err := s.jobManager.UpdateJobWaitTimeouts(request.Name, backupTimeout, restoreTimeout); err != nil {
+			return err
+		}
+	}
+
+	if request.SkipError != nil {
+		return s.jobManager.UpdateJobSkipError(request.Name, *request.SkipError)
+	}
+	return nil
+}
+
 // ListJobs service
 func (s *HttpService) listJobsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Infof("list jobs")
@@ -497,18 +696,118 @@ func (s *HttpService) listJobsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type HistoryRequest struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+// progress history of job, newest first
+func (s *HttpService) progressHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get job progress history")
+
+	type result struct {
+		*defaultResult
+		Histories []*storage.ProgressHistory `json:"histories,omitempty"`
+	}
+	var historyResult *result
+	defer func() { writeJson(w, historyResult) }()
+
+	// Parse the JSON request body
+	var request HistoryRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get job progress history failed: %+v", err)
+
+		historyResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("get job progress history failed: name is empty")
+
+		historyResult = &result{
+			defaultResult: newErrorResult("name is empty"),
+		}
+		return
+	}
+
+	// history is kept after job deleted, so no redirect
+	if histories, err := s.db.GetProgressHistories(request.Name, request.Limit); err != nil {
+		log.Warnf("get job progress history failed: %+v", err)
+
+		historyResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+	} else {
+		historyResult = &result{
+			defaultResult: newSuccessResult(),
+			Histories:     histories,
+		}
+	}
+}
+
+// audit logs of job, newest first, empty name means all jobs
+func (s *HttpService) auditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get audit logs")
+
+	type result struct {
+		*defaultResult
+		AuditLogs []*storage.AuditLog `json:"audit_logs,omitempty"`
+	}
+	var auditLogsResult *result
+	defer func() { writeJson(w, auditLogsResult) }()
+
+	// Parse the JSON request body
+	var request HistoryRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get audit logs failed: %+v", err)
+
+		auditLogsResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+		return
+	}
+
+	if auditLogs, err := s.db.GetAuditLogs(request.Name, request.Limit); err != nil {
+		log.Warnf("get audit logs failed: %+v", err)
+
+		auditLogsResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+	} else {
+		auditLogsResult = &result{
+			defaultResult: newSuccessResult(),
+			AuditLogs:     auditLogs,
+		}
+	}
+}
+
 func (s *HttpService) RegisterHandlers() {
-	s.mux.HandleFunc("/version", s.versionHandler)
-	s.mux.HandleFunc("/create_ccr", s.createHandler)
-	s.mux.HandleFunc("/get_lag", s.getLagHandler)
-	s.mux.HandleFunc("/pause", s.pauseHandler)
-	s.mux.HandleFunc("/resume", s.resumeHandler)
-	s.mux.HandleFunc("/delete", s.deleteHandler)
-	s.mux.HandleFunc("/job_status", s.statusHandler)
-	s.mux.HandleFunc("/desync", s.desyncHandler)
-	s.mux.HandleFunc("/update_job", s.updateJobHandler)
-	s.mux.HandleFunc("/list_jobs", s.listJobsHandler)
-	s.mux.Handle("/metrics", promhttp.Handler())
+	s.mux.Handle("/version", s.withAuth(RoleReadOnly, http.HandlerFunc(s.versionHandler)))
+	s.mux.Handle("/create_ccr", s.withAuth(RoleOperator, http.HandlerFunc(s.createHandler)))
+	s.mux.Handle("/get_lag", s.withAuth(RoleReadOnly, http.HandlerFunc(s.getLagHandler)))
+	s.mux.Handle("/pause", s.withAuth(RoleOperator, http.HandlerFunc(s.pauseHandler)))
+	s.mux.Handle("/resume", s.withAuth(RoleOperator, http.HandlerFunc(s.resumeHandler)))
+	s.mux.Handle("/delete", s.withAuth(RoleOperator, http.HandlerFunc(s.deleteHandler)))
+	s.mux.Handle("/job_status", s.withAuth(RoleReadOnly, http.HandlerFunc(s.statusHandler)))
+	s.mux.Handle("/desync", s.withAuth(RoleOperator, http.HandlerFunc(s.desyncHandler)))
+	s.mux.Handle("/update_job", s.withAuth(RoleOperator, http.HandlerFunc(s.updateJobHandler)))
+	s.mux.Handle("/list_jobs", s.withAuth(RoleReadOnly, http.HandlerFunc(s.listJobsHandler)))
+	s.mux.Handle("/job_progress_history", s.withAuth(RoleReadOnly, http.HandlerFunc(s.progressHistoryHandler)))
+	s.mux.Handle("/audit_logs", s.withAuth(RoleReadOnly, http.HandlerFunc(s.auditLogsHandler)))
+	s.mux.Handle("/metrics", s.withAuth(RoleReadOnly, promhttp.Handler()))
+	s.mux.Handle("/job_health", s.withAuth(RoleReadOnly, http.HandlerFunc(s.jobHealthHandler)))
+	s.mux.Handle("/verify", s.withAuth(RoleOperator, http.HandlerFunc(s.verifyHandler)))
+	s.mux.Handle("/verify_report", s.withAuth(RoleReadOnly, http.HandlerFunc(s.verifyReportHandler)))
+	if s.faultInjector != nil {
+		s.mux.Handle("/debug/fault", s.withAuth(RoleOperator, http.HandlerFunc(s.faultHandler)))
+	}
+	// probes of orchestrators carry no credential
+	s.mux.HandleFunc("/healthz", s.healthzHandler)
+	s.mux.HandleFunc("/readyz", s.readyzHandler)
 }
 
 func (s *HttpService) Start() error {
@@ -518,7 +817,14 @@ func (s *HttpService) Start() error {
 	s.RegisterHandlers()
 
 	s.server = &http.Server{Addr: addr, Handler: s.mux}
-	err := s.server.ListenAndServe()
+	var err error
+	if s.tls != nil {
+		// cert and key are provided by tls config, so they can be reloaded
+		s.server.TLSConfig = s.tls.tlsConfig()
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
 	if err == nil {
 		return nil
 	} else if err == http.ErrServerClosed {
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectKeepsPath(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AddJob("ccr_test", "{}", "10.0.0.2:9190"))
+
+	jobManager := ccr.NewJobManager(db, nil, "127.0.0.1:9190")
+	s := NewHttpServer("127.0.0.1", 9190, db, jobManager)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/pause", strings.NewReader(`{"name": "ccr_test"}`))
+	s.pauseHandler(w, r)
+
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "http://10.0.0.2:9190/pause", w.Header().Get("Location"))
+}
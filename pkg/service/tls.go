@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// optional, client certs signed by it are verified and can be used by auth
+	ClientCAFile string
+}
+
+// tlsReloader keeps the current certificate and client CA, they can be reloaded without restart
+type tlsReloader struct {
+	config *TLSConfig
+
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+}
+
+func newTLSReloader(config *TLSConfig) (*tlsReloader, error) {
+	reloader := &tlsReloader{config: config}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload reads cert, key and client CA files again, the old ones are kept if any is invalid
+func (t *tlsReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(t.config.CertFile, t.config.KeyFile)
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "load tls cert %s and key %s failed", t.config.CertFile, t.config.KeyFile)
+	}
+
+	var clientCA *x509.CertPool
+	if t.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(t.config.ClientCAFile)
+		if err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "read tls client ca %s failed", t.config.ClientCAFile)
+		}
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(pem) {
+			return xerror.Errorf(xerror.Normal, "no cert found in tls client ca %s", t.config.ClientCAFile)
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.cert = &cert
+	t.clientCA = clientCA
+	log.Infof("tls cert %s loaded", t.config.CertFile)
+	return nil
+}
+
+func (t *tlsReloader) serverConfig() *tls.Config {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*t.cert},
+	}
+	if t.clientCA != nil {
+		// client cert is optional, caller without cert can still use bearer token
+		config.ClientCAs = t.clientCA
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config
+}
+
+// tlsConfig builds a config per handshake, so reloaded cert and client CA take effect on new connections
+func (t *tlsReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.serverConfig(), nil
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSelfSignedCert(t *testing.T, dir string, commonName string) *TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	config := &TLSConfig{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	}
+	require.NoError(t, os.WriteFile(config.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(config.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return config
+}
+
+func currentCommonName(t *testing.T, reloader *tlsReloader) string {
+	config, err := reloader.tlsConfig().GetConfigForClient(nil)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return cert.Subject.CommonName
+}
+
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	config := writeSelfSignedCert(t, dir, "old")
+
+	reloader, err := newTLSReloader(config)
+	require.NoError(t, err)
+	assert.Equal(t, "old", currentCommonName(t, reloader))
+
+	writeSelfSignedCert(t, dir, "new")
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, "new", currentCommonName(t, reloader))
+
+	// broken cert keeps the old one
+	require.NoError(t, os.WriteFile(config.CertFile, []byte("broken"), 0600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "new", currentCommonName(t, reloader))
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type VerifyRequest struct {
+	Name string `json:"name,required"`
+	ccr.VerifyOptions
+}
+
+// verify src and dest of the job, and full sync the mismatched tables again if resync is set
+func (s *HttpService) verifyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("verify job")
+
+	type result struct {
+		*defaultResult
+		Report *ccr.VerifyReport `json:"report,omitempty"`
+	}
+	var verifyResult *result
+	defer func() { writeJson(w, verifyResult) }()
+
+	// Parse the JSON request body
+	var request VerifyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("verify job failed: %+v", err)
+
+		verifyResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("verify job failed: name is empty")
+
+		verifyResult = &result{
+			defaultResult: newErrorResult("name is empty"),
+		}
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	report, err := s.jobManager.Verify(request.Name, &request.VerifyOptions)
+	if err != nil {
+		log.Warnf("verify job failed: %+v", err)
+
+		verifyResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+		return
+	}
+
+	if request.Resync {
+		detail := fmt.Sprintf("mismatch_num: %d, resynced: %t,", report.MismatchNum, report.Resynced)
+		s.audit(r, request.Name, "verify", detail, nil)
+	}
+	verifyResult = &result{
+		defaultResult: newSuccessResult(),
+		Report:        report,
+	}
+}
+
+// the last verify report of the job, by request or by schedule
+func (s *HttpService) verifyReportHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get verify report")
+
+	type result struct {
+		*defaultResult
+		Report *ccr.VerifyReport `json:"report,omitempty"`
+	}
+	var reportResult *result
+	defer func() { writeJson(w, reportResult) }()
+
+	// Parse the JSON request body
+	var request CcrCommonRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get verify report failed: %+v", err)
+
+		reportResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("get verify report failed: name is empty")
+
+		reportResult = &result{
+			defaultResult: newErrorResult("name is empty"),
+		}
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	report, err := s.jobManager.GetVerifyReport(request.Name)
+	if err != nil {
+		log.Warnf("get verify report failed: %+v", err)
+
+		reportResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+	} else if report == nil {
+		reportResult = &result{
+			defaultResult: newErrorResult(fmt.Sprintf("job %s is never verified", request.Name)),
+		}
+	} else {
+		reportResult = &result{
+			defaultResult: newSuccessResult(),
+			Report:        report,
+		}
+	}
+}
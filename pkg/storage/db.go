@@ -44,6 +44,27 @@ type DB interface {
 	// rebalance load
 	RebalanceLoadFromDeadSyncers(syncers []string) error
 
+	// Append a progress transition to history
+	AddProgressHistory(history *ProgressHistory) error
+	// Get the latest progress histories of job, newest first
+	GetProgressHistories(jobName string, limit int) ([]*ProgressHistory, error)
+	// Append an operator action to audit logs
+	AddAuditLog(auditLog *AuditLog) error
+	// Get the latest audit logs, newest first, empty jobName means all jobs
+	GetAuditLogs(jobName string, limit int) ([]*AuditLog, error)
+	// Remove progress histories and audit logs out of retention
+	PruneHistories(retention *HistoryRetention) error
+
+	// Record a snapshot created by job
+	AddSnapshot(snapshot *Snapshot) error
+	// Get the recorded snapshots of job, oldest first
+	GetSnapshots(jobName string) ([]*Snapshot, error)
+	// Remove the record of a cleaned snapshot
+	RemoveSnapshot(id int64) error
+
+	// Check the meta db is reachable
+	Ping() error
+
 	// GetAllData
 	GetAllData() (map[string][]string, error)
 }
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"time"
+)
+
+const (
+	// default limit of a history query
+	DefaultHistoryLimit = 100
+	// max limit of a history query
+	MaxHistoryLimit = 10000
+)
+
+// ProgressHistory is one transition of a job progress, only appended, never updated
+type ProgressHistory struct {
+	Id            int64  `json:"id"`
+	JobName       string `json:"job_name"`
+	SyncState     string `json:"sync_state"`
+	SubSyncState  string `json:"sub_sync_state"`
+	PrevCommitSeq int64  `json:"prev_commit_seq"`
+	CommitSeq     int64  `json:"commit_seq"`
+	TxnId         int64  `json:"txn_id,omitempty"`
+	ErrorMsg      string `json:"error_msg,omitempty"`
+	CreatedAt     int64  `json:"created_at"` // unix milli
+}
+
+// AuditLog records one operator action on a job
+type AuditLog struct {
+	Id        int64  `json:"id"`
+	JobName   string `json:"job_name"`
+	Action    string `json:"action"`
+	Caller    string `json:"caller"`
+	Detail    string `json:"detail,omitempty"`
+	CreatedAt int64  `json:"created_at"` // unix milli
+}
+
+// HistoryRetention bounds both progress histories and audit logs,
+// rows created before now - MaxAge are removed and each job keeps at most MaxRowsPerJob rows.
+// zero value of a field means no limit.
+type HistoryRetention struct {
+	MaxAge        time.Duration
+	MaxRowsPerJob int
+}
+
+func (r *HistoryRetention) expiredTime() int64 {
+	if r.MaxAge <= 0 {
+		return 0
+	}
+	return time.Now().Add(-r.MaxAge).UnixMilli()
+}
+
+func normalizeHistoryLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultHistoryLimit
+	}
+	if limit > MaxHistoryLimit {
+		return MaxHistoryLimit
+	}
+	return limit
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteProgressHistories(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	now := time.Now().UnixMilli()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.AddProgressHistory(&ProgressHistory{
+			JobName:   "job",
+			SyncState: "TableIncrementalSync",
+			CommitSeq: int64(i),
+			ErrorMsg:  "it's failed",
+			CreatedAt: now,
+		}))
+	}
+	require.NoError(t, db.AddProgressHistory(&ProgressHistory{JobName: "other", CreatedAt: now}))
+
+	histories, err := db.GetProgressHistories("job", 2)
+	require.NoError(t, err)
+	require.Len(t, histories, 2)
+	assert.Equal(t, int64(4), histories[0].CommitSeq)
+	assert.Equal(t, int64(3), histories[1].CommitSeq)
+	assert.Equal(t, "it's failed", histories[0].ErrorMsg)
+
+	require.NoError(t, db.PruneHistories(&HistoryRetention{MaxRowsPerJob: 3}))
+	histories, err = db.GetProgressHistories("job", 0)
+	require.NoError(t, err)
+	require.Len(t, histories, 3)
+	assert.Equal(t, int64(2), histories[2].CommitSeq)
+
+	histories, err = db.GetProgressHistories("other", 0)
+	require.NoError(t, err)
+	assert.Len(t, histories, 1)
+}
+
+func TestSQLiteAuditLogs(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-2 * time.Hour).UnixMilli()
+	require.NoError(t, db.AddAuditLog(&AuditLog{JobName: "job", Action: "create", Caller: "127.0.0.1", CreatedAt: expired}))
+	require.NoError(t, db.AddAuditLog(&AuditLog{JobName: "job", Action: "pause", Caller: "127.0.0.1", CreatedAt: time.Now().UnixMilli()}))
+	require.NoError(t, db.AddAuditLog(&AuditLog{JobName: "other", Action: "delete", Caller: "127.0.0.1", CreatedAt: time.Now().UnixMilli()}))
+
+	auditLogs, err := db.GetAuditLogs("", 0)
+	require.NoError(t, err)
+	assert.Len(t, auditLogs, 3)
+
+	require.NoError(t, db.PruneHistories(&HistoryRetention{MaxAge: time.Hour}))
+	auditLogs, err = db.GetAuditLogs("job", 0)
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 1)
+	assert.Equal(t, "pause", auditLogs[0].Action)
+}
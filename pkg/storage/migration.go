@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrSchemaTooNew = errors.New("meta db schema is newer than this syncer")
+
+const (
+	dialectSQLite = "sqlite"
+	dialectMysql  = "mysql"
+
+	// the syncers sharing a mysql meta db wait for each other to migrate
+	migrationLockName    = "ccr_schema_migration"
+	migrationLockTimeout = 60 // seconds
+)
+
+// Migration is one ordered schema change of the syncer meta db.
+// Each dialect has its own statements, they are applied in order inside one version.
+type Migration struct {
+	Version     int
+	Description string
+	SQLite      []string
+	Mysql       []string
+}
+
+func (m *Migration) statements(dialect string) []string {
+	switch dialect {
+	case dialectSQLite:
+		return m.SQLite
+	case dialectMysql:
+		return m.Mysql
+	default:
+		return nil
+	}
+}
+
+// migrations must be append only, never modify a released one.
+// version 1 is the schema before versioning, so it uses IF NOT EXISTS to adopt old deployments.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create jobs, progresses and syncers",
+		SQLite: []string{
+			"CREATE TABLE IF NOT EXISTS jobs (job_name TEXT PRIMARY KEY, job_info TEXT, belong_to TEXT)",
+			"CREATE TABLE IF NOT EXISTS progresses (job_name TEXT PRIMARY KEY, progress TEXT)",
+			"CREATE TABLE IF NOT EXISTS syncers (host_info TEXT PRIMARY KEY, timestamp INTEGER)",
+		},
+		Mysql: []string{
+			"CREATE TABLE IF NOT EXISTS jobs (`job_name` VARCHAR(512) PRIMARY KEY, `job_info` TEXT, `belong_to` VARCHAR(96))",
+			"CREATE TABLE IF NOT EXISTS progresses (`job_name` VARCHAR(512) PRIMARY KEY, `progress` LONGTEXT)",
+			"CREATE TABLE IF NOT EXISTS syncers (`host_info` VARCHAR(96) PRIMARY KEY, `timestamp` BIGINT)",
+		},
+	},
+	{
+		Version:     2,
+		Description: "create progress_histories and audit_logs",
+		SQLite: []string{
+			"CREATE TABLE IF NOT EXISTS progress_histories (id INTEGER PRIMARY KEY AUTOINCREMENT, job_name TEXT, sync_state TEXT, sub_sync_state TEXT, prev_commit_seq INTEGER, commit_seq INTEGER, txn_id INTEGER, error_msg TEXT, created_at INTEGER)",
+			"CREATE INDEX IF NOT EXISTS idx_progress_histories_job ON progress_histories (job_name, id)",
+			"CREATE TABLE IF NOT EXISTS audit_logs (id INTEGER PRIMARY KEY AUTOINCREMENT, job_name TEXT, action TEXT, caller TEXT, detail TEXT, created_at INTEGER)",
+			"CREATE INDEX IF NOT EXISTS idx_audit_logs_job ON audit_logs (job_name, id)",
+		},
+		Mysql: []string{
+			"CREATE TABLE IF NOT EXISTS progress_histories (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `job_name` VARCHAR(512), `sync_state` VARCHAR(64), `sub_sync_state` VARCHAR(128), `prev_commit_seq` BIGINT, `commit_seq` BIGINT, `txn_id` BIGINT, `error_msg` TEXT, `created_at` BIGINT, INDEX idx_progress_histories_job (`job_name`, `id`))",
+			"CREATE TABLE IF NOT EXISTS audit_logs (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `job_name` VARCHAR(512), `action` VARCHAR(64), `caller` VARCHAR(256), `detail` TEXT, `created_at` BIGINT, INDEX idx_audit_logs_job (`job_name`, `id`))",
+		},
+	},
+	{
+		Version:     3,
+		Description: "create snapshots",
+		SQLite: []string{
+			"CREATE TABLE IF NOT EXISTS snapshots (id INTEGER PRIMARY KEY AUTOINCREMENT, job_name TEXT, kind TEXT, database_name TEXT, label TEXT, created_at INTEGER)",
+			"CREATE INDEX IF NOT EXISTS idx_snapshots_job ON snapshots (job_name, id)",
+		},
+		Mysql: []string{
+			"CREATE TABLE IF NOT EXISTS snapshots (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `job_name` VARCHAR(512), `kind` VARCHAR(16), `database_name` VARCHAR(256), `label` VARCHAR(512), `created_at` BIGINT, INDEX idx_snapshots_job (`job_name`, `id`))",
+		},
+	},
+}
+
+// LatestSchemaVersion is the schema version this binary knows
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+type Migrator struct {
+	db      *sql.DB
+	dialect string
+}
+
+func newMigrator(db *sql.DB, dialect string) *Migrator {
+	return &Migrator{
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+// runner is the db, or the connection holding the migration lock
+type runner interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (m *Migrator) createVersionTable(r runner) error {
+	var createSql string
+	switch m.dialect {
+	case dialectSQLite:
+		createSql = "CREATE TABLE IF NOT EXISTS schema_versions (version INTEGER PRIMARY KEY, description TEXT, applied_at INTEGER)"
+	case dialectMysql:
+		createSql = "CREATE TABLE IF NOT EXISTS schema_versions (`version` INT PRIMARY KEY, `description` VARCHAR(512), `applied_at` BIGINT)"
+	default:
+		return xerror.Errorf(xerror.DB, "unknown meta db dialect %s", m.dialect)
+	}
+
+	if _, err := r.ExecContext(context.Background(), createSql); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "%s: create table schema_versions failed", m.dialect)
+	}
+	return nil
+}
+
+func (m *Migrator) versionTableExists(r runner) (bool, error) {
+	var querySql string
+	switch m.dialect {
+	case dialectSQLite:
+		querySql = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_versions'"
+	case dialectMysql:
+		querySql = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_versions'"
+	default:
+		return false, xerror.Errorf(xerror.DB, "unknown meta db dialect %s", m.dialect)
+	}
+
+	var count int
+	if err := r.QueryRowContext(context.Background(), querySql).Scan(&count); err != nil {
+		return false, xerror.Wrapf(err, xerror.DB, "%s: check table schema_versions failed", m.dialect)
+	}
+	return count > 0, nil
+}
+
+// CurrentVersion returns the applied schema version, 0 means a fresh or unversioned db.
+// It is read only, the table schema_versions is created by Up.
+func (m *Migrator) CurrentVersion() (int, error) {
+	return m.currentVersion(m.db)
+}
+
+func (m *Migrator) currentVersion(r runner) (int, error) {
+	if exists, err := m.versionTableExists(r); err != nil {
+		return 0, err
+	} else if !exists {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := r.QueryRowContext(context.Background(), "SELECT MAX(version) FROM schema_versions").Scan(&version); err != nil {
+		return 0, xerror.Wrapf(err, xerror.DB, "%s: query schema version failed", m.dialect)
+	}
+	if !version.Valid {
+		return 0, nil
+	}
+	return int(version.Int64), nil
+}
+
+// Pending returns the migrations newer than the applied schema version.
+// It returns ErrSchemaTooNew if the db was migrated by a newer syncer.
+func (m *Migrator) Pending() ([]Migration, error) {
+	return m.pending(m.db)
+}
+
+func (m *Migrator) pending(r runner) ([]Migration, error) {
+	current, err := m.currentVersion(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest := LatestSchemaVersion(); current > latest {
+		return nil, xerror.Wrapf(ErrSchemaTooNew, xerror.DB, "%s: schema version %d, syncer knows %d", m.dialect, current, latest)
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) apply(r runner, migration *Migration) error {
+	log.Infof("%s: apply schema migration %d: %s", m.dialect, migration.Version, migration.Description)
+
+	// DDL is not transactional in mysql, so every statement of a migration must be idempotent
+	ctx := context.Background()
+	for _, stmt := range migration.statements(m.dialect) {
+		if _, err := r.ExecContext(ctx, stmt); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "%s: apply schema migration %d failed, sql: %s", m.dialect, migration.Version, stmt)
+		}
+	}
+
+	recordSql := "INSERT INTO schema_versions (version, description, applied_at) VALUES (?, ?, ?)"
+	if _, err := r.ExecContext(ctx, recordSql, migration.Version, migration.Description, time.Now().Unix()); err != nil {
+		// the version is recorded by another syncer, e.g. an old syncer migrates without the lock
+		var count int
+		if queryErr := r.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_versions WHERE version = ?", migration.Version).Scan(&count); queryErr == nil && count > 0 {
+			log.Infof("%s: schema migration %d is already recorded", m.dialect, migration.Version)
+			return nil
+		}
+		return xerror.Wrapf(err, xerror.DB, "%s: record schema migration %d failed", m.dialect, migration.Version)
+	}
+	return nil
+}
+
+// lock serializes the migrations of the syncers sharing the meta db, by GET_LOCK of mysql, or the write
+// transaction of sqlite which also makes the migrations atomic. unlock commits the transaction if err is nil.
+func (m *Migrator) lock(conn *sql.Conn) (unlock func(err error) error, err error) {
+	ctx := context.Background()
+	switch m.dialect {
+	case dialectSQLite:
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			return nil, xerror.Wrapf(err, xerror.DB, "%s: begin schema migration failed", m.dialect)
+		}
+		return func(err error) error {
+			if err != nil {
+				if _, rollbackErr := conn.ExecContext(ctx, "ROLLBACK"); rollbackErr != nil {
+					log.Warnf("%s: rollback schema migration failed, err: %+v", m.dialect, rollbackErr)
+				}
+				return err
+			}
+			if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+				return xerror.Wrapf(err, xerror.DB, "%s: commit schema migration failed", m.dialect)
+			}
+			return nil
+		}, nil
+	case dialectMysql:
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked); err != nil {
+			return nil, xerror.Wrapf(err, xerror.DB, "%s: get lock %s failed", m.dialect, migrationLockName)
+		}
+		if locked.Int64 != 1 {
+			return nil, xerror.Errorf(xerror.DB, "%s: get lock %s timeout after %ds", m.dialect, migrationLockName, migrationLockTimeout)
+		}
+		return func(err error) error {
+			var released sql.NullInt64
+			if releaseErr := conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName).Scan(&released); releaseErr != nil {
+				log.Warnf("%s: release lock %s failed, err: %+v", m.dialect, migrationLockName, releaseErr)
+			}
+			return err
+		}, nil
+	default:
+		return nil, xerror.Errorf(xerror.DB, "unknown meta db dialect %s", m.dialect)
+	}
+}
+
+// Up applies all pending migrations in order, the pending migrations are got again after the lock is held,
+// since they may be applied by another syncer during waiting
+func (m *Migrator) Up() error {
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "%s: get connection failed", m.dialect)
+	}
+	defer conn.Close()
+
+	unlock, err := m.lock(conn)
+	if err != nil {
+		return err
+	}
+	return unlock(m.up(conn))
+}
+
+func (m *Migrator) up(conn *sql.Conn) error {
+	if err := m.createVersionTable(conn); err != nil {
+		return err
+	}
+	pending, err := m.pending(conn)
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if err := m.apply(conn, &pending[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewSQLiteMigrator opens the sqlite meta db for offline migration
+func NewSQLiteMigrator(dbPath string) (*Migrator, error) {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db, dialectSQLite), nil
+}
+
+// NewMysqlMigrator opens the mysql meta db for offline migration
+func NewMysqlMigrator(host string, port int, user string, password string) (*Migrator, error) {
+	db, err := openMysql(host, port, user, password)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db, dialectMysql), nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
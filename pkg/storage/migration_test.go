@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationVersionsOrdered(t *testing.T) {
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version)
+		assert.NotEmpty(t, migration.SQLite)
+		assert.Equal(t, len(migration.SQLite) != 0, len(migration.Mysql) != 0)
+	}
+}
+
+func TestSQLiteMigrateUp(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ccr.db")
+
+	_, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+
+	migrator, err := NewSQLiteMigrator(dbPath)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	version, err := migrator.CurrentVersion()
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// reopen is a no-op
+	_, err = NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+}
+
+func TestSQLiteStatusReadOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ccr.db")
+
+	migrator, err := NewSQLiteMigrator(dbPath)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	version, err := migrator.CurrentVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, len(migrations))
+
+	// the status doesn't create schema_versions
+	exists, err := migrator.versionTableExists(migrator.db)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSQLiteRefuseNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ccr.db")
+
+	migrator, err := NewSQLiteMigrator(dbPath)
+	require.NoError(t, err)
+	defer migrator.Close()
+	require.NoError(t, migrator.Up())
+
+	_, err = migrator.db.Exec("INSERT INTO schema_versions (version, description, applied_at) VALUES (?, 'from the future', 0)", LatestSchemaVersion()+1)
+	require.NoError(t, err)
+
+	_, err = NewSQLiteDB(dbPath)
+	assert.True(t, errors.Is(err, ErrSchemaTooNew))
+}
+
+func TestSQLiteConcurrentMigrateUp(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ccr.db")
+
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			migrator, err := NewSQLiteMigrator(dbPath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer migrator.Close()
+			errs <- migrator.Up()
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		require.NoError(t, <-errs)
+	}
+
+	migrator, err := NewSQLiteMigrator(dbPath)
+	require.NoError(t, err)
+	defer migrator.Close()
+	version, err := migrator.CurrentVersion()
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+
+	// a version recorded by another syncer is applied
+	require.NoError(t, migrator.apply(migrator.db, &migrations[0]))
+}
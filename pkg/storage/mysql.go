@@ -19,10 +19,10 @@ type MysqlDB struct {
 	db *sql.DB
 }
 
-func NewMysqlDB(host string, port int, user string, password string) (DB, error) {
+func openMysql(host string, port int, user string, password string) (*sql.DB, error) {
 	dbForDDL, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/?maxAllowedPacket=%d", user, password, host, port, maxAllowedPacket))
 	if err != nil {
-		return nil, xerror.Wrapf(err, xerror.DB, "mysql: open %s@tcp(%s:%s) failed", user, host, password)
+		return nil, xerror.Wrapf(err, xerror.DB, "mysql: open %s@tcp(%s:%d) failed", user, host, port)
 	}
 
 	if _, err := dbForDDL.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", remoteDBName)); err != nil {
@@ -34,17 +34,18 @@ func NewMysqlDB(host string, port int, user string, password string) (DB, error)
 	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.DB, "mysql: open mysql in db %s@tcp(%s:%d)/%s failed", user, host, port, remoteDBName)
 	}
+	return db, nil
+}
 
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS jobs (`job_name` VARCHAR(512) PRIMARY KEY, `job_info` TEXT, `belong_to` VARCHAR(96))"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "mysql: create table jobs failed")
-	}
-
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS progresses (`job_name` VARCHAR(512) PRIMARY KEY, `progress` LONGTEXT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "mysql: create table progresses failed")
+func NewMysqlDB(host string, port int, user string, password string) (DB, error) {
+	db, err := openMysql(host, port, user, password)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS syncers (`host_info` VARCHAR(96) PRIMARY KEY, `timestamp` BIGINT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "mysql: create table syncers failed")
+	// create or upgrade all tables by schema migrations
+	if err := newMigrator(db, dialectMysql).Up(); err != nil {
+		return nil, err
 	}
 
 	return &MysqlDB{db: db}, nil
@@ -368,6 +369,155 @@ func (s *MysqlDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
 	return nil
 }
 
+func (s *MysqlDB) AddProgressHistory(history *ProgressHistory) error {
+	insertSql := "INSERT INTO progress_histories (job_name, sync_state, sub_sync_state, prev_commit_seq, commit_seq, txn_id, error_msg, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, history.JobName, history.SyncState, history.SubSyncState, history.PrevCommitSeq, history.CommitSeq, history.TxnId, history.ErrorMsg, history.CreatedAt); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: add progress history failed, name: %s", history.JobName)
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetProgressHistories(jobName string, limit int) ([]*ProgressHistory, error) {
+	querySql := "SELECT id, job_name, sync_state, sub_sync_state, prev_commit_seq, commit_seq, txn_id, error_msg, created_at FROM progress_histories WHERE job_name = ? ORDER BY id DESC LIMIT ?"
+	rows, err := s.db.Query(querySql, jobName, normalizeHistoryLimit(limit))
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "mysql: get progress histories failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	histories := make([]*ProgressHistory, 0)
+	for rows.Next() {
+		var history ProgressHistory
+		if err := rows.Scan(&history.Id, &history.JobName, &history.SyncState, &history.SubSyncState, &history.PrevCommitSeq, &history.CommitSeq, &history.TxnId, &history.ErrorMsg, &history.CreatedAt); err != nil {
+			return nil, xerror.Wrapf(err, xerror.DB, "mysql: scan progress history failed.")
+		}
+		histories = append(histories, &history)
+	}
+	return histories, nil
+}
+
+func (s *MysqlDB) AddAuditLog(auditLog *AuditLog) error {
+	insertSql := "INSERT INTO audit_logs (job_name, action, caller, detail, created_at) VALUES (?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, auditLog.JobName, auditLog.Action, auditLog.Caller, auditLog.Detail, auditLog.CreatedAt); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: add audit log failed, name: %s, action: %s", auditLog.JobName, auditLog.Action)
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetAuditLogs(jobName string, limit int) ([]*AuditLog, error) {
+	var rows *sql.Rows
+	var err error
+	if jobName == "" {
+		rows, err = s.db.Query("SELECT id, job_name, action, caller, detail, created_at FROM audit_logs ORDER BY id DESC LIMIT ?", normalizeHistoryLimit(limit))
+	} else {
+		rows, err = s.db.Query("SELECT id, job_name, action, caller, detail, created_at FROM audit_logs WHERE job_name = ? ORDER BY id DESC LIMIT ?", jobName, normalizeHistoryLimit(limit))
+	}
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "mysql: get audit logs failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	auditLogs := make([]*AuditLog, 0)
+	for rows.Next() {
+		var auditLog AuditLog
+		if err := rows.Scan(&auditLog.Id, &auditLog.JobName, &auditLog.Action, &auditLog.Caller, &auditLog.Detail, &auditLog.CreatedAt); err != nil {
+			return nil, xerror.Wrapf(err, xerror.DB, "mysql: scan audit log failed.")
+		}
+		auditLogs = append(auditLogs, &auditLog)
+	}
+	return auditLogs, nil
+}
+
+func (s *MysqlDB) pruneHistoryTable(table string, retention *HistoryRetention) error {
+	if expiredTime := retention.expiredTime(); expiredTime > 0 {
+		if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE created_at < ?", table), expiredTime); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: prune expired %s failed", table)
+		}
+	}
+
+	if retention.MaxRowsPerJob <= 0 {
+		return nil
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf("SELECT DISTINCT job_name FROM %s", table))
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: get job names of %s failed", table)
+	}
+	jobNames := make([]string, 0)
+	for rows.Next() {
+		var jobName string
+		if err := rows.Scan(&jobName); err != nil {
+			rows.Close()
+			return xerror.Wrapf(err, xerror.DB, "mysql: scan job name of %s failed", table)
+		}
+		jobNames = append(jobNames, jobName)
+	}
+	rows.Close()
+
+	for _, jobName := range jobNames {
+		// mysql can't delete from a table with a subquery on itself, so get the bound id first
+		var boundId int64
+		boundSql := fmt.Sprintf("SELECT id FROM %s WHERE job_name = ? ORDER BY id DESC LIMIT 1 OFFSET ?", table)
+		if err := s.db.QueryRow(boundSql, jobName, retention.MaxRowsPerJob).Scan(&boundId); err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: get bound id of %s failed, name: %s", table, jobName)
+		}
+
+		if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE job_name = ? AND id <= ?", table), jobName, boundId); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: prune %s failed, name: %s", table, jobName)
+		}
+	}
+	return nil
+}
+
+func (s *MysqlDB) PruneHistories(retention *HistoryRetention) error {
+	if err := s.pruneHistoryTable("progress_histories", retention); err != nil {
+		return err
+	}
+	return s.pruneHistoryTable("audit_logs", retention)
+}
+
+func (s *MysqlDB) AddSnapshot(snapshot *Snapshot) error {
+	insertSql := "INSERT INTO snapshots (job_name, kind, database_name, label, created_at) VALUES (?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, snapshot.JobName, snapshot.Kind, snapshot.Database, snapshot.Label, snapshot.CreatedAt); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: add snapshot failed, name: %s, label: %s", snapshot.JobName, snapshot.Label)
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetSnapshots(jobName string) ([]*Snapshot, error) {
+	rows, err := s.db.Query("SELECT id, job_name, kind, database_name, label, created_at FROM snapshots WHERE job_name = ? ORDER BY id", jobName)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "mysql: get snapshots failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*Snapshot, 0)
+	for rows.Next() {
+		var snapshot Snapshot
+		if err := rows.Scan(&snapshot.Id, &snapshot.JobName, &snapshot.Kind, &snapshot.Database, &snapshot.Label, &snapshot.CreatedAt); err != nil {
+			return nil, xerror.Wrapf(err, xerror.DB, "mysql: scan snapshot failed.")
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	return snapshots, nil
+}
+
+func (s *MysqlDB) RemoveSnapshot(id int64) error {
+	if _, err := s.db.Exec("DELETE FROM snapshots WHERE id = ?", id); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: remove snapshot failed, id: %d", id)
+	}
+	return nil
+}
+
+func (s *MysqlDB) Ping() error {
+	if err := s.db.Ping(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "mysql: ping failed")
+	}
+	return nil
+}
+
 func (s *MysqlDB) GetAllData() (map[string][]string, error) {
 	ans := make(map[string][]string)
 
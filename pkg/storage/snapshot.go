@@ -0,0 +1,17 @@
+package storage
+
+const (
+	SnapshotKindBackup  = "backup"
+	SnapshotKindRestore = "restore"
+)
+
+// Snapshot is a backup snapshot or a restore created by a job in full sync,
+// it is recorded until the snapshot is cleaned up, so the snapshots of abandoned attempts are not left behind
+type Snapshot struct {
+	Id        int64  `json:"id"`
+	JobName   string `json:"job_name"`
+	Kind      string `json:"kind"`
+	Database  string `json:"database"`
+	Label     string `json:"label"`
+	CreatedAt int64  `json:"created_at"` // unix milli
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteSnapshots(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	now := time.Now().UnixMilli()
+	require.NoError(t, db.AddSnapshot(&Snapshot{JobName: "job", Kind: SnapshotKindBackup, Database: "db1", Label: "ccrs_db1_1", CreatedAt: now}))
+	require.NoError(t, db.AddSnapshot(&Snapshot{JobName: "job", Kind: SnapshotKindRestore, Database: "db1", Label: "ccrs_db1_1_r_1", CreatedAt: now}))
+	require.NoError(t, db.AddSnapshot(&Snapshot{JobName: "other", Kind: SnapshotKindBackup, Database: "db2", Label: "ccrs_db2_1", CreatedAt: now}))
+
+	snapshots, err := db.GetSnapshots("job")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, SnapshotKindBackup, snapshots[0].Kind)
+	assert.Equal(t, "ccrs_db1_1", snapshots[0].Label)
+	assert.Equal(t, "db1", snapshots[1].Database)
+
+	// the snapshots are kept after the job is removed, until they are cleaned
+	require.NoError(t, db.RemoveJob("job"))
+	require.NoError(t, db.RemoveSnapshot(snapshots[0].Id))
+	snapshots, err = db.GetSnapshots("job")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, SnapshotKindRestore, snapshots[0].Kind)
+
+	snapshots, err = db.GetSnapshots("other")
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+}
@@ -14,24 +14,23 @@ type SQLiteDB struct {
 	db *sql.DB
 }
 
-func NewSQLiteDB(dbPath string) (DB, error) {
+func openSQLite(dbPath string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: open sqlite3 path %s failed", dbPath)
 	}
+	return db, nil
+}
 
-	// create table info && progress, if not exists
-	// all is tuple (string, string)
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS jobs (job_name TEXT PRIMARY KEY, job_info TEXT, belong_to TEXT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "sqlite: create table jobs failed")
-	}
-
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS progresses (job_name TEXT PRIMARY KEY, progress TEXT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "sqlite: create table progresses failed")
+func NewSQLiteDB(dbPath string) (DB, error) {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS syncers (host_info TEXT PRIMARY KEY, timestamp INTEGER)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "sqlite: create table syncers failed")
+	// create or upgrade all tables by schema migrations
+	if err := newMigrator(db, dialectSQLite).Up(); err != nil {
+		return nil, err
 	}
 
 	return &SQLiteDB{db: db}, nil
@@ -342,6 +341,155 @@ func (s *SQLiteDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
 	return nil
 }
 
+func (s *SQLiteDB) AddProgressHistory(history *ProgressHistory) error {
+	insertSql := "INSERT INTO progress_histories (job_name, sync_state, sub_sync_state, prev_commit_seq, commit_seq, txn_id, error_msg, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, history.JobName, history.SyncState, history.SubSyncState, history.PrevCommitSeq, history.CommitSeq, history.TxnId, history.ErrorMsg, history.CreatedAt); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: add progress history failed, name: %s", history.JobName)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetProgressHistories(jobName string, limit int) ([]*ProgressHistory, error) {
+	querySql := "SELECT id, job_name, sync_state, sub_sync_state, prev_commit_seq, commit_seq, txn_id, error_msg, created_at FROM progress_histories WHERE job_name = ? ORDER BY id DESC LIMIT ?"
+	rows, err := s.db.Query(querySql, jobName, normalizeHistoryLimit(limit))
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: get progress histories failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	histories := make([]*ProgressHistory, 0)
+	for rows.Next() {
+		var history ProgressHistory
+		if err := rows.Scan(&history.Id, &history.JobName, &history.SyncState, &history.SubSyncState, &history.PrevCommitSeq, &history.CommitSeq, &history.TxnId, &history.ErrorMsg, &history.CreatedAt); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan progress history failed.")
+		}
+		histories = append(histories, &history)
+	}
+	return histories, nil
+}
+
+func (s *SQLiteDB) AddAuditLog(auditLog *AuditLog) error {
+	insertSql := "INSERT INTO audit_logs (job_name, action, caller, detail, created_at) VALUES (?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, auditLog.JobName, auditLog.Action, auditLog.Caller, auditLog.Detail, auditLog.CreatedAt); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: add audit log failed, name: %s, action: %s", auditLog.JobName, auditLog.Action)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetAuditLogs(jobName string, limit int) ([]*AuditLog, error) {
+	var rows *sql.Rows
+	var err error
+	if jobName == "" {
+		rows, err = s.db.Query("SELECT id, job_name, action, caller, detail, created_at FROM audit_logs ORDER BY id DESC LIMIT ?", normalizeHistoryLimit(limit))
+	} else {
+		rows, err = s.db.Query("SELECT id, job_name, action, caller, detail, created_at FROM audit_logs WHERE job_name = ? ORDER BY id DESC LIMIT ?", jobName, normalizeHistoryLimit(limit))
+	}
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: get audit logs failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	auditLogs := make([]*AuditLog, 0)
+	for rows.Next() {
+		var auditLog AuditLog
+		if err := rows.Scan(&auditLog.Id, &auditLog.JobName, &auditLog.Action, &auditLog.Caller, &auditLog.Detail, &auditLog.CreatedAt); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan audit log failed.")
+		}
+		auditLogs = append(auditLogs, &auditLog)
+	}
+	return auditLogs, nil
+}
+
+func (s *SQLiteDB) pruneHistoryTable(table string, retention *HistoryRetention) error {
+	if expiredTime := retention.expiredTime(); expiredTime > 0 {
+		if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE created_at < ?", table), expiredTime); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: prune expired %s failed", table)
+		}
+	}
+
+	if retention.MaxRowsPerJob <= 0 {
+		return nil
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf("SELECT DISTINCT job_name FROM %s", table))
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: get job names of %s failed", table)
+	}
+	jobNames := make([]string, 0)
+	for rows.Next() {
+		var jobName string
+		if err := rows.Scan(&jobName); err != nil {
+			rows.Close()
+			return xerror.Wrapf(err, xerror.DB, "sqlite: scan job name of %s failed", table)
+		}
+		jobNames = append(jobNames, jobName)
+	}
+	rows.Close()
+
+	for _, jobName := range jobNames {
+		// the newest id out of the bound, all rows not newer than it will be removed
+		var boundId int64
+		boundSql := fmt.Sprintf("SELECT id FROM %s WHERE job_name = ? ORDER BY id DESC LIMIT 1 OFFSET ?", table)
+		if err := s.db.QueryRow(boundSql, jobName, retention.MaxRowsPerJob).Scan(&boundId); err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: get bound id of %s failed, name: %s", table, jobName)
+		}
+
+		if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE job_name = ? AND id <= ?", table), jobName, boundId); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: prune %s failed, name: %s", table, jobName)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteDB) PruneHistories(retention *HistoryRetention) error {
+	if err := s.pruneHistoryTable("progress_histories", retention); err != nil {
+		return err
+	}
+	return s.pruneHistoryTable("audit_logs", retention)
+}
+
+func (s *SQLiteDB) AddSnapshot(snapshot *Snapshot) error {
+	insertSql := "INSERT INTO snapshots (job_name, kind, database_name, label, created_at) VALUES (?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, snapshot.JobName, snapshot.Kind, snapshot.Database, snapshot.Label, snapshot.CreatedAt); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: add snapshot failed, name: %s, label: %s", snapshot.JobName, snapshot.Label)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetSnapshots(jobName string) ([]*Snapshot, error) {
+	rows, err := s.db.Query("SELECT id, job_name, kind, database_name, label, created_at FROM snapshots WHERE job_name = ? ORDER BY id", jobName)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: get snapshots failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*Snapshot, 0)
+	for rows.Next() {
+		var snapshot Snapshot
+		if err := rows.Scan(&snapshot.Id, &snapshot.JobName, &snapshot.Kind, &snapshot.Database, &snapshot.Label, &snapshot.CreatedAt); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan snapshot failed.")
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	return snapshots, nil
+}
+
+func (s *SQLiteDB) RemoveSnapshot(id int64) error {
+	if _, err := s.db.Exec("DELETE FROM snapshots WHERE id = ?", id); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: remove snapshot failed, id: %d", id)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) Ping() error {
+	if err := s.db.Ping(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "sqlite: ping failed")
+	}
+	return nil
+}
+
 func (s *SQLiteDB) GetAllData() (map[string][]string, error) {
 	ans := make(map[string][]string)
 
@@ -7,6 +7,7 @@ package test_util
 import (
 	reflect "reflect"
 
+	storage "github.com/selectdb/ccr_syncer/pkg/storage"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -33,6 +34,20 @@ func (m *MockDB) EXPECT() *MockDBMockRecorder {
 	return m.recorder
 }
 
+// AddAuditLog mocks base method.
+func (m *MockDB) AddAuditLog(auditLog *storage.AuditLog) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAuditLog", auditLog)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAuditLog indicates an expected call of AddAuditLog.
+func (mr *MockDBMockRecorder) AddAuditLog(auditLog interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAuditLog", reflect.TypeOf((*MockDB)(nil).AddAuditLog), auditLog)
+}
+
 // AddJob mocks base method.
 func (m *MockDB) AddJob(jobName, jobInfo, hostInfo string) error {
 	m.ctrl.T.Helper()
@@ -47,6 +62,34 @@ func (mr *MockDBMockRecorder) AddJob(jobName, jobInfo, hostInfo interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddJob", reflect.TypeOf((*MockDB)(nil).AddJob), jobName, jobInfo, hostInfo)
 }
 
+// AddProgressHistory mocks base method.
+func (m *MockDB) AddProgressHistory(history *storage.ProgressHistory) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddProgressHistory", history)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddProgressHistory indicates an expected call of AddProgressHistory.
+func (mr *MockDBMockRecorder) AddProgressHistory(history interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProgressHistory", reflect.TypeOf((*MockDB)(nil).AddProgressHistory), history)
+}
+
+// AddSnapshot mocks base method.
+func (m *MockDB) AddSnapshot(snapshot *storage.Snapshot) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSnapshot", snapshot)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddSnapshot indicates an expected call of AddSnapshot.
+func (mr *MockDBMockRecorder) AddSnapshot(snapshot interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSnapshot", reflect.TypeOf((*MockDB)(nil).AddSnapshot), snapshot)
+}
+
 // AddSyncer mocks base method.
 func (m *MockDB) AddSyncer(hostInfo string) error {
 	m.ctrl.T.Helper()
@@ -76,6 +119,21 @@ func (mr *MockDBMockRecorder) GetAllData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllData", reflect.TypeOf((*MockDB)(nil).GetAllData))
 }
 
+// GetAuditLogs mocks base method.
+func (m *MockDB) GetAuditLogs(jobName string, limit int) ([]*storage.AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditLogs", jobName, limit)
+	ret0, _ := ret[0].([]*storage.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditLogs indicates an expected call of GetAuditLogs.
+func (mr *MockDBMockRecorder) GetAuditLogs(jobName, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditLogs", reflect.TypeOf((*MockDB)(nil).GetAuditLogs), jobName, limit)
+}
+
 // GetDeadSyncers mocks base method.
 func (m *MockDB) GetDeadSyncers(expiredTime int64) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -136,6 +194,36 @@ func (mr *MockDBMockRecorder) GetProgress(jobName interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProgress", reflect.TypeOf((*MockDB)(nil).GetProgress), jobName)
 }
 
+// GetProgressHistories mocks base method.
+func (m *MockDB) GetProgressHistories(jobName string, limit int) ([]*storage.ProgressHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProgressHistories", jobName, limit)
+	ret0, _ := ret[0].([]*storage.ProgressHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProgressHistories indicates an expected call of GetProgressHistories.
+func (mr *MockDBMockRecorder) GetProgressHistories(jobName, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProgressHistories", reflect.TypeOf((*MockDB)(nil).GetProgressHistories), jobName, limit)
+}
+
+// GetSnapshots mocks base method.
+func (m *MockDB) GetSnapshots(jobName string) ([]*storage.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshots", jobName)
+	ret0, _ := ret[0].([]*storage.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshots indicates an expected call of GetSnapshots.
+func (mr *MockDBMockRecorder) GetSnapshots(jobName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshots", reflect.TypeOf((*MockDB)(nil).GetSnapshots), jobName)
+}
+
 // GetStampAndJobs mocks base method.
 func (m *MockDB) GetStampAndJobs(hostInfo string) (int64, []string, error) {
 	m.ctrl.T.Helper()
@@ -182,6 +270,34 @@ func (mr *MockDBMockRecorder) IsProgressExist(jobName interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsProgressExist", reflect.TypeOf((*MockDB)(nil).IsProgressExist), jobName)
 }
 
+// Ping mocks base method.
+func (m *MockDB) Ping() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockDBMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDB)(nil).Ping))
+}
+
+// PruneHistories mocks base method.
+func (m *MockDB) PruneHistories(retention *storage.HistoryRetention) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneHistories", retention)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PruneHistories indicates an expected call of PruneHistories.
+func (mr *MockDBMockRecorder) PruneHistories(retention interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneHistories", reflect.TypeOf((*MockDB)(nil).PruneHistories), retention)
+}
+
 // RebalanceLoadFromDeadSyncers mocks base method.
 func (m *MockDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
 	m.ctrl.T.Helper()
@@ -225,6 +341,20 @@ func (mr *MockDBMockRecorder) RemoveJob(jobName interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveJob", reflect.TypeOf((*MockDB)(nil).RemoveJob), jobName)
 }
 
+// RemoveSnapshot mocks base method.
+func (m *MockDB) RemoveSnapshot(id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveSnapshot", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveSnapshot indicates an expected call of RemoveSnapshot.
+func (mr *MockDBMockRecorder) RemoveSnapshot(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSnapshot", reflect.TypeOf((*MockDB)(nil).RemoveSnapshot), id)
+}
+
 // UpdateJob mocks base method.
 func (m *MockDB) UpdateJob(jobName, jobInfo string) error {
 	m.ctrl.T.Helper()
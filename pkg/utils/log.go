@@ -24,6 +24,24 @@ func init() {
 	flag.BoolVar(&logAlsoToStderr, "log_also_to_stderr", false, "log also to stderr")
 }
 
+// SetLogOptions overrides the log flags, it must be called before InitLog
+func SetLogOptions(level string, filename string, alsoToStderr bool) {
+	logLevel = level
+	logFilename = filename
+	logAlsoToStderr = alsoToStderr
+}
+
+// SetLogLevel changes the log level at runtime
+func SetLogLevel(level string) error {
+	parsedLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	logLevel = level
+	log.SetLevel(parsedLevel)
+	return nil
+}
+
 func InitLog() {
 	level, err := log.ParseLevel(logLevel)
 	if err != nil {
@@ -0,0 +1,126 @@
+package xmetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "ccr_syncer"
+
+// rpc methods of rpcDuration
+const (
+	MethodGetBinlog    = "get_binlog"
+	MethodBeginTxn     = "begin_txn"
+	MethodIngestBinlog = "ingest_binlog" // per tablet
+	MethodCommitTxn    = "commit_txn"
+)
+
+// full sync phases of fullSyncDuration
+const (
+	PhaseBackup  = "backup"
+	PhaseRestore = "restore"
+)
+
+var jobLabels = []string{"job"}
+
+var (
+	jobAdded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_added_total",
+		Help:      "Number of jobs added to this syncer.",
+	})
+
+	handlingCommitSeq = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "handling_commit_seq",
+		Help:      "Commit seq of the binlog the job is handling, -1 means not started.",
+	}, jobLabels)
+
+	prevCommitSeq = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "prev_commit_seq",
+		Help:      "Commit seq of the last binlog the job has synced.",
+	}, jobLabels)
+
+	lag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "lag",
+		Help:      "Number of binlogs in upstream not synced by the job yet.",
+	}, jobLabels)
+
+	handledBinlogs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handled_binlogs_total",
+		Help:      "Number of binlogs synced by the job.",
+	}, jobLabels)
+
+	rollbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rollbacks_total",
+		Help:      "Number of binlog rollbacks of the job.",
+	}, jobLabels)
+
+	ingestedTablets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingested_tablets_total",
+		Help:      "Number of tablets ingested successfully by the job.",
+	}, jobLabels)
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Duration of rpcs sent by the job, ingest_binlog is per tablet.",
+		// 5ms ~ 164s
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 16),
+	}, []string{"job", "method"})
+
+	fullSyncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "full_sync_duration_seconds",
+		Help:      "Duration of backup and restore in full sync of the job.",
+		// 1s ~ 9h
+		Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+	}, []string{"job", "phase"})
+
+	verifyMismatches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "verify_mismatched_partitions",
+		Help:      "Number of mismatched partitions between src and dest found by the last verify of the job.",
+	}, jobLabels)
+
+	metaCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "meta_cache_requests_total",
+		Help:      "Number of table metas requested from the meta cache of the job by cluster and result, miss means got from fe.",
+	}, []string{"job", "cluster", "result"})
+
+	jobErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Number of job errors by category and kind.",
+	}, []string{"category", "kind"})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Number of http api requests failed in auth.",
+	}, []string{"reason"})
+)
+
+// jobVecs are the metrics labeled by job, they are removed with the job
+var jobVecs = []*prometheus.MetricVec{
+	handlingCommitSeq.MetricVec,
+	prevCommitSeq.MetricVec,
+	lag.MetricVec,
+	handledBinlogs.MetricVec,
+	rollbacks.MetricVec,
+	ingestedTablets.MetricVec,
+	rpcDuration.MetricVec,
+	fullSyncDuration.MetricVec,
+	verifyMismatches.MetricVec,
+	metaCacheRequests.MetricVec,
+}
+
+func init() {
+	prometheus.MustRegister(jobAdded, handlingCommitSeq, prevCommitSeq, lag, handledBinlogs, rollbacks,
+		ingestedTablets, rpcDuration, fullSyncDuration, verifyMismatches, metaCacheRequests, jobErrors, authFailures)
+}
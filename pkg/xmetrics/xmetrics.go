@@ -1,46 +1,83 @@
 package xmetrics
 
 import (
-	"github.com/hashicorp/go-metrics"
-	"github.com/hashicorp/go-metrics/prometheus"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 )
 
-func InitGlobal(serviceName string) error {
-	sink, err := prometheus.NewPrometheusSink()
-	if err != nil {
-		return xerror.Wrap(err, xerror.Normal, "init prometheus sink falied")
+func AddError(err *xerror.XError) {
+	// use switch instead of ifelse maybe
+	kind := "unknown"
+	if err.IsRecoverable() {
+		kind = "recoverable"
+	} else if err.IsPanic() {
+		kind = "panic"
 	}
+	jobErrors.WithLabelValues(err.Category().Name(), kind).Inc()
+}
+
+func AddNewJob(jobName string) {
+	handlingCommitSeq.WithLabelValues(jobName).Set(-1)
+
+	jobAdded.Inc()
+}
 
-	if _, err := metrics.NewGlobal(metrics.DefaultConfig(serviceName), sink); err != nil {
-		return xerror.Wrap(err, xerror.Normal, "new global metrics falied")
+// RemoveJob removes all metrics of the job, so the deleted job is not exported any more
+func RemoveJob(jobName string) {
+	for _, vec := range jobVecs {
+		vec.DeletePartialMatch(prometheus.Labels{"job": jobName})
 	}
+}
 
-	return nil
+func HandlingBinlog(jobName string, commitSeq int64) {
+	handlingCommitSeq.WithLabelValues(jobName).Set(float64(commitSeq))
 }
 
-func AddError(err *xerror.XError) {
-	metrics.IncrCounter(ErrorMetrics(err).Tag(), 1)
+func Rollback(jobName string, commitSeq int64) {
+	handlingCommitSeq.WithLabelValues(jobName).Set(float64(commitSeq))
+	prevCommitSeq.WithLabelValues(jobName).Set(float64(commitSeq))
+	rollbacks.WithLabelValues(jobName).Inc()
 }
 
-func AddNewJob(jobName string) {
-	metrics.SetGauge(JobMetrics(jobName).HandlingCommitSeq().Tag(), -1)
+func ConsumeBinlog(jobName string, commitSeq int64) {
+	prevCommitSeq.WithLabelValues(jobName).Set(float64(commitSeq))
+	handledBinlogs.WithLabelValues(jobName).Inc()
+}
 
-	metrics.IncrCounter(DashboardMetrics().JobNum().Tag(), 1)
+func SetLag(jobName string, binlogLag int64) {
+	lag.WithLabelValues(jobName).Set(float64(binlogLag))
 }
 
-func HandlingBinlog(jobName string, commitSeq int64) {
-	metrics.SetGauge(JobMetrics(jobName).HandlingCommitSeq().Tag(), float32(commitSeq))
+func IngestTablet(jobName string) {
+	ingestedTablets.WithLabelValues(jobName).Inc()
 }
 
-func Rollback(jobName string, commitSeq int64) {
-	metrics.SetGauge(JobMetrics(jobName).HandlingCommitSeq().Tag(), float32(commitSeq))
-	metrics.SetGauge(JobMetrics(jobName).PrevCommitSeq().Tag(), float32(commitSeq))
+// ObserveRpc records the duration of rpc method since start
+func ObserveRpc(jobName string, method string, start time.Time) {
+	rpcDuration.WithLabelValues(jobName, method).Observe(time.Since(start).Seconds())
 }
 
-func ConsumeBinlog(jobName string, commitSeq int64) {
-	metrics.SetGauge(JobMetrics(jobName).PrevCommitSeq().Tag(), float32(commitSeq))
-	metrics.IncrCounter(JobMetrics(jobName).HandledBinlogNum().Tag(), 1)
+// ObserveFullSync records the duration of full sync phase since start
+func ObserveFullSync(jobName string, phase string, start time.Time) {
+	fullSyncDuration.WithLabelValues(jobName, phase).Observe(time.Since(start).Seconds())
+}
+
+func SetVerifyMismatches(jobName string, mismatches int) {
+	verifyMismatches.WithLabelValues(jobName).Set(float64(mismatches))
+}
+
+// MetaCacheHit records a table meta of cluster src or dest got from the meta cache
+func MetaCacheHit(jobName string, cluster string) {
+	metaCacheRequests.WithLabelValues(jobName, cluster, "hit").Inc()
+}
+
+// MetaCacheMiss records a table meta of cluster src or dest got from fe, since it is not cached or stale
+func MetaCacheMiss(jobName string, cluster string) {
+	metaCacheRequests.WithLabelValues(jobName, cluster, "miss").Inc()
+}
 
-	metrics.IncrCounter(DashboardMetrics().BinlogNum().Tag(), 1)
+func AuthFailed(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
 }
@@ -0,0 +1,32 @@
+package xmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobMetrics(t *testing.T) {
+	AddNewJob("job")
+	HandlingBinlog("job", 10)
+	ConsumeBinlog("job", 10)
+	Rollback("job", 10)
+	ObserveRpc("job", MethodGetBinlog, time.Now())
+	MetaCacheHit("job", "src")
+	MetaCacheMiss("job", "src")
+	SetLag("other", 3)
+
+	assert.Equal(t, float64(10), testutil.ToFloat64(prevCommitSeq.WithLabelValues("job")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(handledBinlogs.WithLabelValues("job")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rollbacks.WithLabelValues("job")))
+	assert.Equal(t, 1, testutil.CollectAndCount(rpcDuration))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metaCacheRequests.WithLabelValues("job", "src", "hit")))
+
+	RemoveJob("job")
+	assert.Equal(t, 0, testutil.CollectAndCount(rpcDuration))
+	assert.Equal(t, 0, testutil.CollectAndCount(prevCommitSeq))
+	assert.Equal(t, 0, testutil.CollectAndCount(metaCacheRequests))
+	assert.Equal(t, 1, testutil.CollectAndCount(lag), "other job is kept")
+}
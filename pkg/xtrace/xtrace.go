@@ -0,0 +1,176 @@
+package xtrace
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/modern-go/gls"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOtlp   = "otlp"   // otlp over http to the collector
+	ExporterStdout = "stdout" // for debug, spans are mixed with logs if logs go to stdout
+	ExporterFile   = "file"   // one json span per line, works offline
+)
+
+// gls key of the current span context, the job name is also kept in gls for logging
+const glsKey = "trace_ctx"
+
+type Config struct {
+	Exporter string
+	// otlp http endpoint, host:port
+	Endpoint string
+	Insecure bool
+	File     string
+	// 1 means tracing all binlogs
+	SampleRatio float64
+}
+
+const tracerName = "github.com/selectdb/ccr_syncer"
+
+// Init sets the global tracer provider by config, the returned shutdown flushes the pending spans
+func Init(config *Config, serviceVersion string) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOtlp:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var file *os.File
+		file, err = os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "open trace file %s failed", config.File)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, xerror.Errorf(xerror.Normal, "unknown trace exporter %s", config.Exporter)
+	}
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "new %s trace exporter failed", config.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName("ccr_syncer"),
+			semconv.ServiceVersion(serviceVersion),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Span is a started span, its context is the current one of the goroutine until End
+type Span struct {
+	span   trace.Span
+	ctx    context.Context
+	parent context.Context
+	once   sync.Once
+}
+
+// CurrentContext returns the span context of the goroutine, pass it to Attach in new goroutines
+func CurrentContext() context.Context {
+	if ctx, ok := gls.Get(glsKey).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// Attach sets ctx as the current span context of the goroutine, the gls must be enabled
+func Attach(ctx context.Context) {
+	if gls.IsGlsEnabled(gls.GoID()) {
+		gls.Set(glsKey, ctx)
+	}
+}
+
+// Start starts a span as the child of the current one, and makes it current.
+// It works without gls, but then the span is always a root.
+func Start(name string, attrs ...attribute.KeyValue) *Span {
+	// same as the log hook, the job name is set in gls by the job goroutine
+	if jobName, ok := gls.Get("job").(string); ok {
+		attrs = append(attrs, Job(jobName))
+	}
+
+	parent := CurrentContext()
+	// get tracer from the current global provider, it may be set after the package init
+	ctx, span := otel.Tracer(tracerName).Start(parent, name, trace.WithAttributes(attrs...))
+	Attach(ctx)
+	return &Span{span: span, ctx: ctx, parent: parent}
+}
+
+func (s *Span) SetAttributes(attrs ...attribute.KeyValue) {
+	s.span.SetAttributes(attrs...)
+}
+
+func (s *Span) Context() context.Context {
+	return s.ctx
+}
+
+// End ends the span with err and restores the parent as current, it is safe to call more than once
+func (s *Span) End(err error) {
+	s.once.Do(func() {
+		if err != nil {
+			s.span.RecordError(err)
+			s.span.SetStatus(codes.Error, err.Error())
+		}
+		s.span.End()
+		Attach(s.parent)
+	})
+}
+
+func Job(name string) attribute.KeyValue {
+	return attribute.String("ccr.job", name)
+}
+
+func CommitSeq(commitSeq int64) attribute.KeyValue {
+	return attribute.Int64("ccr.commit_seq", commitSeq)
+}
+
+func TxnId(txnId int64) attribute.KeyValue {
+	return attribute.Int64("ccr.txn_id", txnId)
+}
+
+func BinlogType(binlogType string) attribute.KeyValue {
+	return attribute.String("ccr.binlog_type", binlogType)
+}
+
+func SubSyncState(state string) attribute.KeyValue {
+	return attribute.String("ccr.sub_sync_state", state)
+}
+
+func Table(table string) attribute.KeyValue {
+	return attribute.String("ccr.table", table)
+}
+
+func TableIds(tableIds []int64) attribute.KeyValue {
+	return attribute.Int64Slice("ccr.table_ids", tableIds)
+}
+
+func TabletId(tabletId int64) attribute.KeyValue {
+	return attribute.Int64("ccr.tablet_id", tabletId)
+}
+
+func Address(addr string) attribute.KeyValue {
+	return attribute.String("net.peer.address", addr)
+}
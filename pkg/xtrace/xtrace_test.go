@@ -0,0 +1,58 @@
+package xtrace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modern-go/gls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpanParent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	done := make(chan struct{})
+	go gls.WithEmptyGls(func() {
+		defer close(done)
+		gls.Set("job", "job")
+
+		root := Start("root")
+		child := Start("child", TxnId(1))
+		child.End(errors.New("failed"))
+		child.End(nil)
+		sibling := Start("sibling")
+		sibling.End(nil)
+		root.End(nil)
+	})()
+	<-done
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	child, sibling, root := spans[0], spans[1], spans[2]
+	assert.Equal(t, root.SpanContext.SpanID(), child.Parent.SpanID())
+	assert.Equal(t, root.SpanContext.SpanID(), sibling.Parent.SpanID())
+	assert.Equal(t, codes.Error, child.Status.Code)
+	assert.Contains(t, root.Attributes, Job("job"))
+}
+
+func TestInitFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.json")
+	shutdown, err := Init(&Config{Exporter: ExporterFile, File: file, SampleRatio: 1}, "test")
+	require.NoError(t, err)
+
+	Start("span").End(nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"span"`)
+}